@@ -0,0 +1,200 @@
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AggregateMember is a physical UPS that is part of an AggregateUPS.
+type AggregateMember struct {
+	Backend Backend
+	UPS     string
+}
+
+// AggregateUPS combines several UPSes feeding the same load into one logical
+// UPS. It implements Backend, providing a single UPS, so it can be monitored,
+// alerted on and recorded like a physical one.
+//
+// The aggregate reports:
+//
+//	ups.status             OL while at least Quorum members are on line power, OB
+//	                       otherwise; LB when fewer than Quorum members can still
+//	                       supply power; RB, OVER and FSD when any member does
+//	battery.charge         lowest charge of the members
+//	battery.runtime        lowest runtime of the members
+//	ups.realpower          sum of the members' real power
+//	ups.realpower.nominal  sum of the members' nominal real power
+//	ups.load               load relative to the summed nominal power, or the mean
+//	                       load if a member reports no nominal power
+//	aggregate.members      number of members
+//	aggregate.online       number of reachable members on line power
+//
+// Unreachable members count as neither online nor able to supply power.
+type AggregateUPS struct {
+	Name        string
+	Description string
+	Members     []AggregateMember
+	// Quorum is the number of members required to carry the load; zero
+	// requires all of them.
+	Quorum int
+}
+
+// memberState is the part of a member's state used for aggregation.
+type memberState struct {
+	status  Status
+	values  map[string]string
+	reached bool
+}
+
+// ListUPS returns the aggregate itself.
+func (a *AggregateUPS) ListUPS(ctx context.Context) ([]BackendUPS, error) {
+	return []BackendUPS{{Name: a.Name, Description: a.Description}}, nil
+}
+
+// Variables polls every member and returns the aggregated variables. An error
+// is only returned if no member could be read.
+func (a *AggregateUPS) Variables(ctx context.Context, ups string) (map[string]string, error) {
+	if ups != a.Name {
+		return nil, errorForMessage(ErrCodeUnknownUPS)
+	}
+	if len(a.Members) == 0 {
+		return nil, fmt.Errorf("aggregate %s has no members", a.Name)
+	}
+
+	states := make([]memberState, len(a.Members))
+	var errs []error
+	for i, member := range a.Members {
+		values, err := member.Backend.Variables(ctx, member.UPS)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", member.UPS, err))
+			continue
+		}
+		states[i] = memberState{status: ParseStatus(values["ups.status"]), values: values, reached: true}
+	}
+	if len(errs) == len(a.Members) {
+		return nil, errors.Join(errs...)
+	}
+	return a.aggregate(states), nil
+}
+
+func (a *AggregateUPS) aggregate(states []memberState) map[string]string {
+	quorum := a.Quorum
+	if quorum <= 0 || quorum > len(states) {
+		quorum = len(states)
+	}
+
+	var online, supplying int
+	var flags []string
+	var charge, runtime, realpower, nominal, loadSum float64
+	var hasCharge, hasRuntime, hasRealpower bool
+	var loads int
+	allNominal := true
+	var combined Status
+	for _, state := range states {
+		if !state.reached {
+			continue
+		}
+		combined |= state.status
+		if state.status.Has(StatusOnline) && !state.status.Has(StatusOnBattery) {
+			online++
+		}
+		if !state.status.Has(StatusLowBattery) && !state.status.Has(StatusForcedShutdown) && !state.status.Has(StatusOff) {
+			supplying++
+		}
+
+		if v, ok := parseFloatVar(state.values, "battery.charge"); ok && (!hasCharge || v < charge) {
+			charge, hasCharge = v, true
+		}
+		if v, ok := parseFloatVar(state.values, "battery.runtime"); ok && (!hasRuntime || v < runtime) {
+			runtime, hasRuntime = v, true
+		}
+		derived := DerivedVariables(state.values, time.Time{})
+		if v, ok := parseFloatVar(state.values, "ups.realpower"); ok {
+			realpower, hasRealpower = realpower+v, true
+		} else if v, ok := parseFloatVar(derived, DerivedPrefix+"ups.realpower"); ok {
+			realpower, hasRealpower = realpower+v, true
+		}
+		if load, ok := parseFloatVar(state.values, "ups.load"); ok {
+			loadSum += load
+			loads++
+		}
+		if v, ok := parseFloatVar(state.values, "ups.realpower.nominal"); ok {
+			nominal += v
+		} else {
+			allNominal = false
+		}
+	}
+
+	if online >= quorum {
+		flags = append(flags, "OL")
+	} else {
+		flags = append(flags, "OB")
+	}
+	if supplying < quorum {
+		flags = append(flags, "LB")
+	}
+	for _, flag := range []struct {
+		status Status
+		name   string
+	}{{StatusReplaceBattery, "RB"}, {StatusOverload, "OVER"}, {StatusForcedShutdown, "FSD"}} {
+		if combined.Has(flag.status) {
+			flags = append(flags, flag.name)
+		}
+	}
+
+	values := map[string]string{
+		"ups.status":        strings.Join(flags, " "),
+		"aggregate.members": strconv.Itoa(len(states)),
+		"aggregate.online":  strconv.Itoa(online),
+	}
+	format := func(f float64) string {
+		return strconv.FormatFloat(round(f), 'f', -1, 64)
+	}
+	if hasCharge {
+		values["battery.charge"] = format(charge)
+	}
+	if hasRuntime {
+		values["battery.runtime"] = format(runtime)
+	}
+	if hasRealpower {
+		values["ups.realpower"] = format(realpower)
+	}
+	if allNominal && nominal > 0 {
+		values["ups.realpower.nominal"] = format(nominal)
+		if hasRealpower {
+			values["ups.load"] = format(realpower / nominal * 100)
+		}
+	} else if loads > 0 {
+		values["ups.load"] = format(loadSum / float64(loads))
+	}
+	return values
+}
+
+// Clients returns no clients; the aggregate has no attached clients of its own.
+func (a *AggregateUPS) Clients(ctx context.Context, ups string) ([]string, error) {
+	return []string{}, nil
+}
+
+// RunCommand is not supported by an aggregate; send commands to its members.
+func (a *AggregateUPS) RunCommand(ctx context.Context, ups, command string) error {
+	return errorForMessage(ErrCodeCmdNotSupported)
+}
+
+// SetVariable is not supported by an aggregate; set variables on its members.
+func (a *AggregateUPS) SetVariable(ctx context.Context, ups, variable, value string) error {
+	return errorForMessage(ErrCodeReadOnly)
+}
+
+// Close does nothing; the members' backends are owned by the caller.
+func (a *AggregateUPS) Close() error {
+	return nil
+}
+
+func parseFloatVar(values map[string]string, name string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(values[name]), 64)
+	return v, err == nil
+}
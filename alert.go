@@ -0,0 +1,305 @@
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThresholdCondition selects how a ThresholdRule compares a value to its limits.
+type ThresholdCondition int
+
+const (
+	ThresholdBelow   ThresholdCondition = iota // Value < Low
+	ThresholdAbove                             // Value > High
+	ThresholdOutside                           // Value < Low or value > High
+)
+
+// ThresholdRule raises an alert while a numeric variable crosses a limit.
+// Hysteresis keeps the alert raised until the value has recovered past the
+// limit by the given margin, so values hovering around a limit do not flap.
+type ThresholdRule struct {
+	Name       string // Alert name; defaults to the rule in ParseThresholdRule notation
+	Variable   string
+	Condition  ThresholdCondition
+	Low        float64 // Limit for ThresholdBelow, lower bound for ThresholdOutside
+	High       float64 // Limit for ThresholdAbove, upper bound for ThresholdOutside
+	Hysteresis float64
+}
+
+// ParseThresholdRule parses a rule such as "battery.charge < 30",
+// "ups.load > 80" or "input.voltage outside [210, 250]". Words may be
+// separated by any amount of white space, as in ParseRateRule.
+func ParseThresholdRule(rule string) (ThresholdRule, error) {
+	fields := strings.Fields(rule)
+	if len(fields) < 3 || (len(fields) > 3 && fields[1] != "outside") {
+		return ThresholdRule{}, fmt.Errorf("invalid threshold rule %q", rule)
+	}
+	parsed := ThresholdRule{Variable: fields[0]}
+	limit := strings.Join(fields[2:], " ")
+
+	var err error
+	switch fields[1] {
+	case "<":
+		parsed.Condition = ThresholdBelow
+		parsed.Low, err = strconv.ParseFloat(limit, 64)
+	case ">":
+		parsed.Condition = ThresholdAbove
+		parsed.High, err = strconv.ParseFloat(limit, 64)
+	case "outside":
+		parsed.Condition = ThresholdOutside
+		bounds := strings.TrimSuffix(strings.TrimPrefix(limit, "["), "]")
+		low, high, found := strings.Cut(bounds, ",")
+		if !found || len(bounds) != len(limit)-2 {
+			return ThresholdRule{}, fmt.Errorf("invalid threshold rule %q: expected [low, high]", rule)
+		}
+		parsed.Low, err = strconv.ParseFloat(strings.TrimSpace(low), 64)
+		if err == nil {
+			parsed.High, err = strconv.ParseFloat(strings.TrimSpace(high), 64)
+		}
+	default:
+		return ThresholdRule{}, fmt.Errorf("invalid threshold rule %q: unknown operator %q", rule, fields[1])
+	}
+	if err != nil {
+		return ThresholdRule{}, fmt.Errorf("invalid threshold rule %q: %w", rule, err)
+	}
+	return parsed, nil
+}
+
+// String returns the rule in ParseThresholdRule notation.
+func (r ThresholdRule) String() string {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	switch r.Condition {
+	case ThresholdBelow:
+		return fmt.Sprintf("%s < %s", r.Variable, format(r.Low))
+	case ThresholdAbove:
+		return fmt.Sprintf("%s > %s", r.Variable, format(r.High))
+	default:
+		return fmt.Sprintf("%s outside [%s, %s]", r.Variable, format(r.Low), format(r.High))
+	}
+}
+
+func (r ThresholdRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.String()
+}
+
+func (r ThresholdRule) validate() error {
+	if r.Variable == "" {
+		return fmt.Errorf("threshold rule %q has no variable", r.name())
+	}
+	if r.Hysteresis < 0 {
+		return fmt.Errorf("threshold rule %q has negative hysteresis", r.name())
+	}
+	if r.Condition == ThresholdOutside && r.Low > r.High {
+		return fmt.Errorf("threshold rule %q has low bound above high bound", r.name())
+	}
+	return nil
+}
+
+// evaluate reports whether the alert should be raised for sample, given whether
+// it currently is. ok is false if the variable is missing or not numeric.
+func (r ThresholdRule) evaluate(sample Snapshot, active bool) (raised, ok bool) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(sample.Variables[r.Variable]), 64)
+	if err != nil {
+		return false, false
+	}
+	margin := 0.0
+	if active {
+		margin = r.Hysteresis
+	}
+	switch r.Condition {
+	case ThresholdBelow:
+		return value < r.Low+margin, true
+	case ThresholdAbove:
+		return value > r.High-margin, true
+	default:
+		return value < r.Low+margin || value > r.High-margin, true
+	}
+}
+
+func (r ThresholdRule) event(sample Snapshot) Event {
+	return Event{Variable: r.Variable, NewValue: sample.Variables[r.Variable]}
+}
+
+// BatteryReplacementRule raises an alert while the UPS reports that its battery
+// needs replacing (RB in ups.status) or, if MaxAge is set, while the battery
+// is older than MaxAge according to battery.date or battery.mfr.date.
+type BatteryReplacementRule struct {
+	Name   string        // Alert name; defaults to "battery.replace"
+	MaxAge time.Duration // Maximum battery age; zero only watches RB
+}
+
+func (r BatteryReplacementRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return "battery.replace"
+}
+
+func (r BatteryReplacementRule) evaluate(sample Snapshot, active bool) (raised, ok bool) {
+	if sample.Status.Has(StatusReplaceBattery) {
+		return true, true
+	}
+	_, hasStatus := sample.Variables["ups.status"]
+	if r.MaxAge > 0 {
+		if age, ok := BatteryAge(sample.Variables, sample.Time); ok {
+			return age > r.MaxAge, true
+		}
+	}
+	return false, hasStatus
+}
+
+func (r BatteryReplacementRule) event(sample Snapshot) Event {
+	if sample.Status.Has(StatusReplaceBattery) || r.MaxAge <= 0 {
+		return Event{Variable: "ups.status", NewValue: sample.Variables["ups.status"]}
+	}
+	for _, name := range []string{"battery.date", "battery.mfr.date"} {
+		if value, ok := sample.Variables[name]; ok {
+			return Event{Variable: name, NewValue: value}
+		}
+	}
+	return Event{}
+}
+
+// alertRule is a condition evaluated by AlertRules against every sample.
+type alertRule interface {
+	name() string
+	evaluate(sample Snapshot, active bool) (raised, ok bool)
+	event(sample Snapshot) Event
+}
+
+// alertKey identifies the state of one rule for one UPS.
+type alertKey struct {
+	server, ups, rule string
+}
+
+// AlertRules evaluates alert rules against UPS snapshots and sends
+// EventAlertRaised and EventAlertCleared to its notifiers when a rule changes
+// state for a UPS.
+type AlertRules struct {
+	notifiers []Notifier
+
+	mu     sync.Mutex
+	rules  []alertRule
+	active map[alertKey]bool
+	clock  Clock
+}
+
+// NewAlertRules returns an empty rule set delivering alerts to notifiers.
+func NewAlertRules(notifiers ...Notifier) *AlertRules {
+	return &AlertRules{
+		notifiers: notifiers,
+		active:    map[alertKey]bool{},
+	}
+}
+
+// SetClock sets the clock that dates snapshots without a Time (default the
+// system clock), e.g. a fake clock in tests.
+func (a *AlertRules) SetClock(clock Clock) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clock = clock
+}
+
+// AddThreshold registers a threshold rule.
+func (a *AlertRules) AddThreshold(rule ThresholdRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	return a.add(rule)
+}
+
+// AddBatteryReplacement registers a battery replacement rule.
+func (a *AlertRules) AddBatteryReplacement(rule BatteryReplacementRule) error {
+	if rule.MaxAge < 0 {
+		return fmt.Errorf("battery replacement rule %q has negative maximum age", rule.name())
+	}
+	return a.add(rule)
+}
+
+func (a *AlertRules) add(rule alertRule) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, existing := range a.rules {
+		if existing.name() == rule.name() {
+			return fmt.Errorf("alert rule %q is already registered", rule.name())
+		}
+	}
+	a.rules = append(a.rules, rule)
+	return nil
+}
+
+// Evaluate checks every rule against the snapshots, e.g. from Monitor.Snapshots
+// or Fleet.AllUPS, and notifies about alerts raised or cleared since the
+// previous evaluation. Rules whose inputs are missing keep their state.
+// Snapshots without a Time are dated by the clock; see SetClock.
+func (a *AlertRules) Evaluate(ctx context.Context, snapshots ...Snapshot) error {
+	events := []Event{}
+
+	a.mu.Lock()
+	now := clockOrSystem(a.clock).Now()
+	for _, sample := range snapshots {
+		if sample.Time.IsZero() {
+			sample.Time = now
+		}
+		for _, rule := range a.rules {
+			key := alertKey{server: sample.Server, ups: sample.UPS, rule: rule.name()}
+			active := a.active[key]
+			raised, ok := rule.evaluate(sample, active)
+			if !ok || raised == active {
+				continue
+			}
+			if raised {
+				a.active[key] = true
+			} else {
+				delete(a.active, key)
+			}
+
+			event := rule.event(sample)
+			event.Time = sample.Time
+			event.Server = sample.Server
+			event.UPS = sample.UPS
+			event.Alert = rule.name()
+			event.Type = EventAlertCleared
+			if raised {
+				event.Type = EventAlertRaised
+			}
+			events = append(events, event)
+		}
+	}
+	a.mu.Unlock()
+
+	var errs []error
+	for _, event := range events {
+		for _, notifier := range a.notifiers {
+			if err := notifier.Notify(ctx, event); err != nil {
+				errs = append(errs, fmt.Errorf("notifying %s for %s: %w", event.Type, event.Alert, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Active returns the names of the alerts currently raised for a UPS, sorted.
+func (a *AlertRules) Active(server, ups string) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names := []string{}
+	for key := range a.active {
+		if key.server == server && key.ups == ups {
+			names = append(names, key.rule)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
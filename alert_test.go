@@ -0,0 +1,36 @@
+package nut_test
+
+import (
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestParseThresholdRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		want nut.ThresholdRule
+	}{
+		{"battery.charge < 30", nut.ThresholdRule{Variable: "battery.charge", Condition: nut.ThresholdBelow, Low: 30}},
+		{"battery.charge  <  30", nut.ThresholdRule{Variable: "battery.charge", Condition: nut.ThresholdBelow, Low: 30}},
+		{"ups.load\t>\t80", nut.ThresholdRule{Variable: "ups.load", Condition: nut.ThresholdAbove, High: 80}},
+		{" input.voltage outside [210, 250] ", nut.ThresholdRule{Variable: "input.voltage", Condition: nut.ThresholdOutside, Low: 210, High: 250}},
+		{"input.voltage\toutside  [210,250]", nut.ThresholdRule{Variable: "input.voltage", Condition: nut.ThresholdOutside, Low: 210, High: 250}},
+	}
+	for _, tt := range tests {
+		got, err := nut.ParseThresholdRule(tt.rule)
+		if err != nil {
+			t.Errorf("%q: %v", tt.rule, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %+v, want %+v", tt.rule, got, tt.want)
+		}
+	}
+
+	for _, rule := range []string{"", "battery.charge < ", "battery.charge <> 30", "battery.charge < 30 40", "input.voltage outside 210, 250", "ups.load > high"} {
+		if _, err := nut.ParseThresholdRule(rule); err == nil {
+			t.Errorf("%q: no error", rule)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package nut
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a single state-changing command issued to a UPS.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Server  string    `json:"server,omitempty"`
+	UPS     string    `json:"ups"`
+	User    string    `json:"user,omitempty"`
+	Action  string    `json:"action"`           // SET, INSTCMD or FSD
+	Target  string    `json:"target,omitempty"` // Variable or command name
+	Value   string    `json:"value,omitempty"`  // Value for SET
+	Success bool      `json:"success"`
+	DryRun  bool      `json:"dry_run,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// AuditSink receives audit records for state-changing commands. Implementations
+// must be safe for concurrent use.
+type AuditSink interface {
+	Audit(record AuditRecord)
+}
+
+// AuditFunc adapts a function to the AuditSink interface.
+type AuditFunc func(record AuditRecord)
+
+// Audit calls f(record).
+func (f AuditFunc) Audit(record AuditRecord) {
+	f(record)
+}
+
+// writerAuditSink writes audit records as JSON lines.
+type writerAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterAuditSink returns an AuditSink writing one JSON object per line to w.
+func NewWriterAuditSink(w io.Writer) AuditSink {
+	return &writerAuditSink{enc: json.NewEncoder(w)}
+}
+
+func (s *writerAuditSink) Audit(record AuditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(record)
+}
+
+// WithAuditSink records every SET, INSTCMD and FSD issued through the client,
+// including dry runs, to sink. Audit records are independent of debug logging.
+func WithAuditSink(sink AuditSink) ClientOption {
+	return func(c *Client) {
+		c.auditSink = sink
+	}
+}
+
+// audit reports a state-changing command on the UPS to the client's audit sink.
+func (u *UPS) audit(action, target, value string, success bool, err error) {
+	c := u.nutClient
+	if c == nil || c.auditSink == nil {
+		return
+	}
+
+	c.sessionMu.Lock()
+	user := c.username
+	c.sessionMu.Unlock()
+	record := AuditRecord{
+		Time:    time.Now(),
+		UPS:     u.Name,
+		User:    user,
+		Action:  action,
+		Target:  target,
+		Value:   value,
+		Success: success,
+		DryRun:  errors.Is(err, ErrDryRun),
+	}
+	if c.Hostname != nil {
+		record.Server = c.Hostname.String()
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	c.auditSink.Audit(record)
+}
@@ -0,0 +1,146 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BackendUPS identifies a UPS provided by a Backend.
+type BackendUPS struct {
+	Name        string
+	Description string
+}
+
+// Backend is a source of UPS data and commands. Watcher and Monitor poll
+// through a Backend, so sources other than upsd, such as other daemons or
+// simulations, plug into the monitoring, alerting and history subsystems by
+// implementing it. NewNUTBackend adapts a Client.
+//
+// Client, UPS and their Variable and Status helpers speak the NUT protocol
+// directly and are not built on Backend; the subsystems taking a Backend work
+// with variable values only.
+//
+// Variable values use NUT names (ups.status, battery.charge, ...). Backends
+// that do not support a method return an error from it.
+type Backend interface {
+	ListUPS(ctx context.Context) ([]BackendUPS, error)
+	Variables(ctx context.Context, ups string) (map[string]string, error)
+	Clients(ctx context.Context, ups string) ([]string, error)
+	RunCommand(ctx context.Context, ups, command string) error
+	SetVariable(ctx context.Context, ups, variable, value string) error
+	Close() error
+}
+
+// BackendAborter is implemented by Backends whose Close talks to the other
+// end, to release them without doing so after their connection failed.
+// Monitor aborts a backend that failed instead of closing it.
+type BackendAborter interface {
+	Abort() error
+}
+
+// BackendBrokenReporter is implemented by Backends that can tell when a failed
+// call left their connection out of sync with the other end, e.g. a Client
+// whose command ran past its WithOperationBudget with the reply still unread.
+// Monitor reconnects a backend that reports itself broken.
+type BackendBrokenReporter interface {
+	Broken() bool
+}
+
+// nutBackend is the Backend of a Client connected to upsd.
+type nutBackend struct {
+	client *Client
+}
+
+// NewNUTBackend returns a Backend issuing NUT protocol commands through client.
+// Close logs out and closes the client.
+func NewNUTBackend(client *Client) Backend {
+	return &nutBackend{client: client}
+}
+
+func (b *nutBackend) ups(name string) *UPS {
+	ups := newUPSValue(name, "", b.client)
+	return &ups
+}
+
+func (b *nutBackend) ListUPS(ctx context.Context) ([]BackendUPS, error) {
+	return b.client.listUPS(ctx)
+}
+
+func (b *nutBackend) Variables(ctx context.Context, ups string) (map[string]string, error) {
+	return b.ups(ups).variableValues(ctx)
+}
+
+func (b *nutBackend) Clients(ctx context.Context, ups string) ([]string, error) {
+	return b.ups(ups).getClients(ctx)
+}
+
+func (b *nutBackend) RunCommand(ctx context.Context, ups, command string) error {
+	ok, err := b.ups(ups).sendCommand(ctx, command)
+	if err == nil && !ok {
+		err = fmt.Errorf("unexpected response")
+	}
+	return err
+}
+
+func (b *nutBackend) SetVariable(ctx context.Context, ups, variable, value string) error {
+	ok, err := b.ups(ups).setVariable(ctx, variable, value)
+	if err == nil && !ok {
+		err = fmt.Errorf("unexpected response")
+	}
+	return err
+}
+
+func (b *nutBackend) Close() error {
+	_, err := b.client.Disconnect()
+	return err
+}
+
+// Abort closes the client without LOGOUT, which could only time out on a
+// failed connection.
+func (b *nutBackend) Abort() error {
+	return b.client.Close()
+}
+
+// Broken reports whether the client is broken; see Client.Broken.
+func (b *nutBackend) Broken() bool {
+	return b.client.Broken()
+}
+
+// ReadSnapshots reads the current state of UPSes from backend, labelled with
+// server. Without names, every UPS listed by the backend is read. Snapshots
+// are sorted by UPS name.
+func ReadSnapshots(ctx context.Context, backend Backend, server string, names ...string) ([]Snapshot, error) {
+	upsList, err := backend.ListUPS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	var snapshots []Snapshot
+	for _, ups := range upsList {
+		if len(wanted) > 0 && !wanted[ups.Name] {
+			continue
+		}
+		values, err := backend.Variables(ctx, ups.Name)
+		if err != nil {
+			return snapshots, fmt.Errorf("reading %s: %w", ups.Name, err)
+		}
+		now := time.Now()
+		snapshots = append(snapshots, Snapshot{
+			Time:        now,
+			Server:      server,
+			UPS:         ups.Name,
+			Description: ups.Description,
+			Status:      ParseStatus(values["ups.status"]),
+			Variables:   values,
+			Derived:     DerivedVariables(values, now),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].UPS < snapshots[j].UPS })
+	return snapshots, nil
+}
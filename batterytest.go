@@ -0,0 +1,145 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BatteryTestType selects the kind of battery self-test to run.
+type BatteryTestType int
+
+const (
+	// BatteryTestQuick runs a short battery test (test.battery.start.quick).
+	BatteryTestQuick BatteryTestType = iota
+	// BatteryTestDeep runs a deep battery test that discharges the battery
+	// (test.battery.start.deep).
+	BatteryTestDeep
+)
+
+func (t BatteryTestType) String() string {
+	if t == BatteryTestDeep {
+		return "deep"
+	}
+	return "quick"
+}
+
+// batteryTestPollInterval is how often ups.test.result is polled while a
+// battery test runs.
+const batteryTestPollInterval = 2 * time.Second
+
+// Default limits on RunBatteryTest when ctx has no deadline. Deep tests
+// discharge the battery and can take well over an hour.
+const (
+	quickBatteryTestTimeout = 10 * time.Minute
+	deepBatteryTestTimeout  = 3 * time.Hour
+)
+
+// BatteryTestResult is the outcome of a battery self-test.
+type BatteryTestResult struct {
+	Type     BatteryTestType
+	Command  string        // Instant command used to start the test
+	Passed   bool          // Test completed and the driver reported success
+	Result   string        // Final value of ups.test.result
+	Started  time.Time     // Time the test was started
+	Duration time.Duration // Time until the result was reported
+}
+
+// RunBatteryTest starts a battery self-test and polls ups.test.result until the
+// driver reports completion or ctx is done. If the UPS does not advertise the
+// requested test variant, the generic test.battery.start command is used.
+// Without a ctx deadline, polling gives up with context.DeadlineExceeded after
+// 10 minutes for quick tests and 3 hours for deep ones, in case the driver
+// never reports a result.
+func (u *UPS) RunBatteryTest(ctx context.Context, testType BatteryTestType) (BatteryTestResult, error) {
+	result := BatteryTestResult{Type: testType}
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := quickBatteryTestTimeout
+		if testType == BatteryTestDeep {
+			timeout = deepBatteryTestTimeout
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	names, err := u.commandNames()
+	if err != nil {
+		return result, err
+	}
+	preferred := "test.battery.start." + testType.String()
+	for _, name := range names {
+		if name == preferred {
+			result.Command = name
+			break
+		}
+		if name == "test.battery.start" {
+			result.Command = name
+		}
+	}
+	if result.Command == "" {
+		return result, fmt.Errorf("UPS %s does not support %s battery tests: %w", u.Name, testType, errorForMessage(ErrCodeCmdNotSupported))
+	}
+
+	// Remember the previous result so a stale "Done and passed" is not mistaken
+	// for the outcome of this test
+	initial, err := u.getVariableValue(ctx, "ups.test.result")
+	if err != nil && !hasErrorCode(err, ErrCodeVarNotSupported) {
+		return result, err
+	}
+
+	result.Started = time.Now()
+	if _, err := u.SendCommand(result.Command); err != nil {
+		return result, err
+	}
+
+	ticker := time.NewTicker(batteryTestPollInterval)
+	defer ticker.Stop()
+
+	running := false
+	for {
+		select {
+		case <-ctx.Done():
+			result.Duration = time.Since(result.Started)
+			return result, ctx.Err()
+		case <-ticker.C:
+		}
+
+		value, err := u.getVariableValue(ctx, "ups.test.result")
+		if err != nil {
+			if hasErrorCode(err, ErrCodeVarNotSupported) {
+				return result, fmt.Errorf("UPS %s does not report ups.test.result: %w", u.Name, err)
+			}
+			return result, err
+		}
+
+		if batteryTestInProgress(value) {
+			running = true
+			continue
+		}
+		if !running && value == initial {
+			// The driver has not picked up the new test yet
+			continue
+		}
+
+		result.Result = value
+		result.Passed = batteryTestPassed(value)
+		result.Duration = time.Since(result.Started)
+		return result, nil
+	}
+}
+
+// batteryTestInProgress reports whether a ups.test.result value describes a
+// test that has not finished yet.
+func batteryTestInProgress(value string) bool {
+	v := strings.ToLower(value)
+	return strings.Contains(v, "progress") || strings.Contains(v, "scheduled") || strings.Contains(v, "running")
+}
+
+// batteryTestPassed reports whether a ups.test.result value describes a
+// successful test, e.g. "Done and passed".
+func batteryTestPassed(value string) bool {
+	v := strings.ToLower(value)
+	return strings.Contains(v, "passed") || v == "done" || v == "ok"
+}
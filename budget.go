@@ -0,0 +1,37 @@
+package nut
+
+import (
+	"context"
+	"time"
+)
+
+// WithOperationBudget bounds the total duration of each command to budget:
+// waiting for the rate limiter and the connection, writing the command and
+// reading every line of the response. ReadTimeout and ListTimeout still apply
+// per line but never extend past the budget, so a slowly trickling LIST
+// response cannot take more than budget in total. Dialing in Connect and
+// Reconnect is bounded the same way. A deadline on the caller's context that
+// is earlier than the budget takes precedence.
+func WithOperationBudget(budget time.Duration) ClientOption {
+	return func(c *Client) {
+		c.operationBudget = budget
+	}
+}
+
+// withBudget returns ctx bounded by the operation budget, if one is set.
+func (c *Client) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.operationBudget <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.operationBudget)
+}
+
+// readDeadline returns the read deadline for the next response line: timeout
+// from now, capped at the deadline of the whole operation unless it is zero.
+func readDeadline(timeout time.Duration, operation time.Time) time.Time {
+	deadline := time.Now().Add(timeout)
+	if !operation.IsZero() && (timeout <= 0 || operation.Before(deadline)) {
+		deadline = operation
+	}
+	return deadline
+}
@@ -0,0 +1,96 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// calibrationPollInterval is how often ups.status is polled while waiting for a
+// runtime calibration to start or finish.
+const calibrationPollInterval = 5 * time.Second
+
+type calibrationOptions struct {
+	wait bool
+}
+
+// CalibrationOption configures UPS.StartCalibration.
+type CalibrationOption func(*calibrationOptions)
+
+// WaitForCalibration makes StartCalibration block until the CAL status flag has
+// appeared and cleared again, i.e. until the calibration has completed.
+func WaitForCalibration() CalibrationOption {
+	return func(o *calibrationOptions) {
+		o.wait = true
+	}
+}
+
+// IsCalibrating reports whether the CAL flag is set in ups.status.
+func (u *UPS) IsCalibrating(ctx context.Context) (bool, error) {
+	status, err := u.getStatus(ctx)
+	if err != nil {
+		return false, err
+	}
+	return status.Has(StatusCalibrating), nil
+}
+
+// StartCalibration starts a battery runtime calibration with calibrate.start.
+// It fails if a calibration is already running. With WaitForCalibration it
+// blocks until the calibration completes or ctx is done.
+func (u *UPS) StartCalibration(ctx context.Context, opts ...CalibrationOption) error {
+	options := calibrationOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	calibrating, err := u.IsCalibrating(ctx)
+	if err != nil {
+		return err
+	}
+	if calibrating {
+		return fmt.Errorf("UPS %s is already calibrating", u.Name)
+	}
+
+	if _, err := u.SendCommand("calibrate.start"); err != nil {
+		return err
+	}
+	if !options.wait {
+		return nil
+	}
+
+	if err := u.waitForCalibrating(ctx, true); err != nil {
+		return err
+	}
+	return u.waitForCalibrating(ctx, false)
+}
+
+// StopCalibration aborts a running calibration with calibrate.stop and waits
+// until the CAL flag clears or ctx is done.
+func (u *UPS) StopCalibration(ctx context.Context) error {
+	if _, err := u.SendCommand("calibrate.stop"); err != nil {
+		return err
+	}
+	return u.waitForCalibrating(ctx, false)
+}
+
+// waitForCalibrating polls ups.status until the CAL flag equals want.
+func (u *UPS) waitForCalibrating(ctx context.Context, want bool) error {
+	ticker := time.NewTicker(calibrationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		calibrating, err := u.IsCalibrating(ctx)
+		if err != nil {
+			return err
+		}
+		if calibrating == want {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
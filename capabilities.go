@@ -0,0 +1,128 @@
+package nut
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Capabilities describes the features supported by the connected server.
+type Capabilities struct {
+	ServerVersion   string          // upsd release from VER, e.g. "2.8.1"; empty if not reported
+	ProtocolVersion string          // NETVER, e.g. "1.3"
+	Commands        map[string]bool // Top-level commands listed by HELP
+
+	StartTLS  bool // STARTTLS is listed by HELP
+	ListRange bool // LIST RANGE is available (protocol 1.2 and later)
+	Primary   bool // PRIMARY is accepted as the name of MASTER (protocol 1.3 and later)
+	Tracking  bool // SET/GET TRACKING are available (protocol 1.3 and later)
+}
+
+// Has reports whether HELP listed command, e.g. "INSTCMD".
+func (c Capabilities) Has(command string) bool {
+	return c.Commands[strings.ToUpper(command)]
+}
+
+// Capabilities returns the features of the connected server, determined from
+// HELP, VER and NETVER. The result is cached until Reconnect.
+func (c *Client) Capabilities() (Capabilities, error) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	if c.capabilities != nil {
+		return *c.capabilities, nil
+	}
+
+	if c.Version == "" {
+		if _, err := c.GetVersion(); err != nil {
+			return Capabilities{}, err
+		}
+	}
+	if c.ProtocolVersion == "" {
+		if _, err := c.GetNetworkProtocolVersion(); err != nil {
+			return Capabilities{}, err
+		}
+	}
+	help, err := c.Help()
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	caps := parseCapabilities(c.Version, c.ProtocolVersion, help)
+	c.capabilities = &caps
+	return caps, nil
+}
+
+// parseCapabilities derives capabilities from the VER, NETVER and HELP
+// responses.
+func parseCapabilities(banner, netver, help string) Capabilities {
+	caps := Capabilities{
+		ServerVersion:   bannerVersion(banner),
+		ProtocolVersion: strings.TrimSpace(netver),
+		Commands:        map[string]bool{},
+	}
+	_, commands, found := strings.Cut(help, ":")
+	if !found {
+		commands = help
+	}
+	for _, command := range strings.Fields(commands) {
+		caps.Commands[strings.ToUpper(command)] = true
+	}
+
+	caps.StartTLS = caps.Commands["STARTTLS"]
+	caps.ListRange = compareVersions(caps.ProtocolVersion, "1.2") >= 0
+	caps.Primary = compareVersions(caps.ProtocolVersion, "1.3") >= 0 || caps.Commands["PRIMARY"]
+	caps.Tracking = compareVersions(caps.ProtocolVersion, "1.3") >= 0
+	return caps
+}
+
+// bannerVersion extracts the release from a VER banner such as
+// "Network UPS Tools upsd 2.8.1 - https://www.networkupstools.org/".
+func bannerVersion(banner string) string {
+	for _, field := range strings.Fields(banner) {
+		if field[0] >= '0' && field[0] <= '9' && strings.Contains(field, ".") {
+			return field
+		}
+	}
+	return ""
+}
+
+// compareVersions compares dotted version numbers numerically, returning -1, 0
+// or 1. Non-numeric suffixes of a component, as in "2.8.1-rc1", are ignored.
+// An empty version compares lower than any other.
+func compareVersions(a, b string) int {
+	if a == "" || b == "" {
+		switch {
+		case a == b:
+			return 0
+		case a == "":
+			return -1
+		default:
+			return 1
+		}
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = leadingInt(as[i])
+		}
+		if i < len(bs) {
+			y = leadingInt(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func leadingInt(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
@@ -0,0 +1,220 @@
+package nut
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// urgentNotifyTypes are delivered with raised priority by the chat notifiers.
+var urgentNotifyTypes = map[string]bool{"ONBATT": true, "LOWBATT": true, "FSD": true, "COMMBAD": true}
+
+// ChatDelivery holds the delivery settings shared by TelegramConfig and
+// PushoverConfig. Failed requests are retried as by WebhookNotifier.
+type ChatDelivery struct {
+	Timeout    time.Duration // Timeout per attempt (default 10s)
+	Retries    int           // Retries after a failed attempt (default 2, -1 for none)
+	RetryDelay time.Duration // Delay before the first retry, doubled for each further one (default 1s)
+	HTTPClient *http.Client  // Client used for requests (default http.DefaultClient)
+}
+
+// webhook returns a WebhookNotifier posting to apiURL with the delivery
+// settings, used for its retry handling.
+func (d ChatDelivery) webhook(apiURL, contentType string) (*WebhookNotifier, error) {
+	switch {
+	case d.Retries == 0:
+		d.Retries = 2
+	case d.Retries == -1:
+		d.Retries = 0
+	case d.Retries < 0:
+		return nil, fmt.Errorf("invalid retries %d", d.Retries)
+	}
+	return NewWebhookNotifier(WebhookConfig{
+		URLs:        []string{apiURL},
+		Timeout:     d.Timeout,
+		Retries:     d.Retries,
+		RetryDelay:  d.RetryDelay,
+		HTTPClient:  d.HTTPClient,
+		ContentType: contentType,
+	})
+}
+
+// chatMessage returns the NOTIFYTYPE of event and its message, rendered with
+// tmpl if set.
+func chatMessage(tmpl *MessageTemplate, event Event) (notifyType, message string, err error) {
+	notifyType, message = upsmonNotification(event)
+	if tmpl != nil {
+		rendered, err := tmpl.Render(event)
+		if err != nil {
+			return "", "", fmt.Errorf("rendering message: %w", err)
+		}
+		message = strings.TrimSpace(rendered)
+	}
+	return notifyType, message, nil
+}
+
+// redactSecret removes secret from err's message, e.g. a bot token embedded
+// in the request URL of a *url.Error.
+func redactSecret(err error, secret string) error {
+	if err == nil || secret == "" || !strings.Contains(err.Error(), secret) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), secret, "REDACTED"))
+}
+
+// TelegramConfig configures a TelegramNotifier.
+type TelegramConfig struct {
+	Token   string   // Bot token issued by @BotFather
+	ChatIDs []string // Chats, groups or channels (e.g. "@mychannel") receiving messages
+	APIURL  string   // Bot API base URL (default https://api.telegram.org)
+
+	// Message, if set, is a MessageTemplate replacing the default message.
+	// Snapshots optionally provides UPS state to the template.
+	Message   string
+	Snapshots SnapshotSource
+
+	ChatDelivery
+}
+
+// TelegramNotifier is a Notifier sending every event as a message from a
+// Telegram bot. Messages are sent silently except for power failures, low
+// battery, forced shutdowns and lost communication.
+type TelegramNotifier struct {
+	config  TelegramConfig
+	message *MessageTemplate
+	webhook *WebhookNotifier
+}
+
+// NewTelegramNotifier validates config and returns a TelegramNotifier.
+func NewTelegramNotifier(config TelegramConfig) (*TelegramNotifier, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("telegram bot token is required")
+	}
+	if len(config.ChatIDs) == 0 {
+		return nil, fmt.Errorf("at least one telegram chat ID is required")
+	}
+	if config.APIURL == "" {
+		config.APIURL = "https://api.telegram.org"
+	}
+	n := &TelegramNotifier{config: config}
+	apiURL := strings.TrimSuffix(config.APIURL, "/") + "/bot" + config.Token + "/sendMessage"
+	webhook, err := config.webhook(apiURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	n.webhook = webhook
+	if config.Message != "" {
+		if n.message, err = NewMessageTemplate(config.Message, config.Snapshots); err != nil {
+			return nil, fmt.Errorf("parsing message template: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Notify sends event to every configured chat. It returns the errors of the
+// chats that could not be reached.
+func (n *TelegramNotifier) Notify(ctx context.Context, event Event) error {
+	notifyType, message, err := chatMessage(n.message, event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, chatID := range n.config.ChatIDs {
+		body, err := json.Marshal(map[string]interface{}{
+			"chat_id":              chatID,
+			"text":                 message,
+			"disable_notification": !urgentNotifyTypes[notifyType],
+		})
+		if err != nil {
+			return err
+		}
+		if err := n.webhook.deliver(ctx, n.webhook.config.URLs[0], body); err != nil {
+			errs = append(errs, fmt.Errorf("telegram chat %s: %w", chatID, redactSecret(err, n.config.Token)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PushoverConfig configures a PushoverNotifier.
+type PushoverConfig struct {
+	Token  string // Application API token
+	User   string // User or group key receiving messages
+	Device string // Optional device name to limit delivery to
+	Title  string // Message title (default "UPS")
+	APIURL string // Messages endpoint (default https://api.pushover.net/1/messages.json)
+
+	// Message, if set, is a MessageTemplate replacing the default message.
+	// Snapshots optionally provides UPS state to the template.
+	Message   string
+	Snapshots SnapshotSource
+
+	ChatDelivery
+}
+
+// PushoverNotifier is a Notifier sending every event as a Pushover message.
+// Power failures, low battery, forced shutdowns and lost communication are
+// sent with high priority, which bypasses the recipient's quiet hours.
+type PushoverNotifier struct {
+	config  PushoverConfig
+	message *MessageTemplate
+	webhook *WebhookNotifier
+}
+
+// NewPushoverNotifier validates config and returns a PushoverNotifier.
+func NewPushoverNotifier(config PushoverConfig) (*PushoverNotifier, error) {
+	if config.Token == "" || config.User == "" {
+		return nil, fmt.Errorf("pushover application token and user key are required")
+	}
+	if config.Title == "" {
+		config.Title = "UPS"
+	}
+	if config.APIURL == "" {
+		config.APIURL = "https://api.pushover.net/1/messages.json"
+	}
+	n := &PushoverNotifier{config: config}
+	webhook, err := config.webhook(config.APIURL, "application/x-www-form-urlencoded")
+	if err != nil {
+		return nil, err
+	}
+	n.webhook = webhook
+	if config.Message != "" {
+		if n.message, err = NewMessageTemplate(config.Message, config.Snapshots); err != nil {
+			return nil, fmt.Errorf("parsing message template: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// Notify sends event to the configured user.
+func (n *PushoverNotifier) Notify(ctx context.Context, event Event) error {
+	notifyType, message, err := chatMessage(n.message, event)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"token":   {n.config.Token},
+		"user":    {n.config.User},
+		"title":   {n.config.Title},
+		"message": {message},
+	}
+	if !event.Time.IsZero() {
+		form.Set("timestamp", fmt.Sprint(event.Time.Unix()))
+	}
+	if n.config.Device != "" {
+		form.Set("device", n.config.Device)
+	}
+	if urgentNotifyTypes[notifyType] {
+		form.Set("priority", "1")
+	}
+	if err := n.webhook.deliver(ctx, n.config.APIURL, []byte(form.Encode())); err != nil {
+		return fmt.Errorf("pushover: %w", redactSecret(err, n.config.Token))
+	}
+	return nil
+}
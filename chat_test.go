@@ -0,0 +1,50 @@
+package nut_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestChatRetries(t *testing.T) {
+	tests := []struct {
+		retries  int
+		attempts int32
+	}{
+		{0, 3},
+		{-1, 1},
+		{1, 2},
+	}
+	for _, tt := range tests {
+		var attempts int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		notifier, err := nut.NewTelegramNotifier(nut.TelegramConfig{
+			Token:        "123:abc",
+			ChatIDs:      []string{"42"},
+			APIURL:       server.URL,
+			ChatDelivery: nut.ChatDelivery{Retries: tt.retries, RetryDelay: time.Millisecond},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := notifier.Notify(context.Background(), nut.Event{Type: nut.EventServerDown, UPS: "ups1"}); err == nil {
+			t.Fatalf("retries %d: expected an error", tt.retries)
+		}
+		server.Close()
+		if got := atomic.LoadInt32(&attempts); got != tt.attempts {
+			t.Fatalf("retries %d: %d attempts, want %d", tt.retries, got, tt.attempts)
+		}
+	}
+
+	if _, err := nut.NewTelegramNotifier(nut.TelegramConfig{Token: "123:abc", ChatIDs: []string{"42"}, ChatDelivery: nut.ChatDelivery{Retries: -2}}); err == nil {
+		t.Fatal("expected an error for retries -2")
+	}
+}
@@ -0,0 +1,67 @@
+package nut
+
+import "time"
+
+// Clock is the source of time for the Watcher, Monitor, Fleet,
+// SelfTestScheduler, SubscribeStatus and WaitForStatus, WatchVariable,
+// RunHistoryCompaction, AlertRules (for snapshots without a time), the
+// readiness handler, and through WithClientClock for WithDriverRetry backoff,
+// WaitForDriver and LoadShedder, so that their timing can be tested
+// deterministically with a fake clock such as nuttest.Clock instead of
+// sleeping. Network timeouts always use the system clock. A nil Clock in a
+// configuration means the system clock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a timer created by a Clock, like time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker is a ticker created by a Clock, like time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// WithClientClock sets the clock timing WithDriverRetry backoff, WaitForDriver
+// and LoadShedder.Run (default the system clock), e.g. a fake clock in tests.
+// Read and connect timeouts are not affected.
+func WithClientClock(clock Clock) ClientOption {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// SystemClock returns the Clock of the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// clockOrSystem returns clock, or the system clock if it is nil.
+func clockOrSystem(clock Clock) Clock {
+	if clock == nil {
+		return systemClock{}
+	}
+	return clock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
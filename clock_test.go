@@ -0,0 +1,374 @@
+package nut_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+var clockStart = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeBackend serves the variables of one UPS and signals every read.
+type fakeBackend struct {
+	mu     sync.Mutex
+	values map[string]string
+	polled chan time.Time
+	clock  nut.Clock
+}
+
+func newFakeBackend(clock nut.Clock, status string) *fakeBackend {
+	return &fakeBackend{values: map[string]string{"ups.status": status}, polled: make(chan time.Time, 16), clock: clock}
+}
+
+func (b *fakeBackend) set(name, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[name] = value
+}
+
+func (b *fakeBackend) ListUPS(ctx context.Context) ([]nut.BackendUPS, error) {
+	return []nut.BackendUPS{{Name: "ups1"}}, nil
+}
+
+func (b *fakeBackend) Variables(ctx context.Context, ups string) (map[string]string, error) {
+	b.mu.Lock()
+	values := make(map[string]string, len(b.values))
+	for name, value := range b.values {
+		values[name] = value
+	}
+	b.mu.Unlock()
+	b.polled <- b.clock.Now()
+	return values, nil
+}
+
+func (b *fakeBackend) Clients(ctx context.Context, ups string) ([]string, error) { return nil, nil }
+func (b *fakeBackend) RunCommand(ctx context.Context, ups, command string) error { return nil }
+func (b *fakeBackend) SetVariable(ctx context.Context, ups, variable, value string) error {
+	return nil
+}
+func (b *fakeBackend) Close() error { return nil }
+
+// advance waits for the code under test to wait on the clock, then advances
+// it by d.
+func advance(clock *nuttest.Clock, d time.Duration) {
+	clock.BlockUntil(1)
+	clock.Advance(d)
+}
+
+func TestWatcherFakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := nuttest.NewClock(clockStart)
+	backend := newFakeBackend(clock, "OL")
+	events := make(chan nut.Event, 16)
+	watcher := nut.NewBackendWatcher(backend, "ups1", nut.WithClock(clock), nut.WithWatchInterval(time.Minute),
+		nut.WithEventHandler(func(event nut.Event) { events <- event }))
+	go watcher.Run(ctx)
+
+	<-backend.polled // Baseline
+	backend.set("ups.status", "OB")
+	advance(clock, time.Minute)
+	<-backend.polled
+	event := <-events
+	if event.Type != nut.EventVariableChanged || event.Variable != "ups.status" || event.OldValue != "OL" || event.NewValue != "OB" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if !event.Time.Equal(clockStart.Add(time.Minute)) {
+		t.Fatalf("event at %v, want %v", event.Time, clockStart.Add(time.Minute))
+	}
+}
+
+func TestWatcherAdaptiveInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := nuttest.NewClock(clockStart)
+	backend := newFakeBackend(clock, "OB")
+	watcher := nut.NewBackendWatcher(backend, "ups1", nut.WithClock(clock),
+		nut.WithWatchInterval(time.Hour), nut.WithAdaptiveInterval(10*time.Second, time.Minute))
+	go watcher.Run(ctx)
+	<-backend.polled
+
+	// On battery, and for a minute after the last urgent poll, polls are fast
+	backend.set("ups.status", "OL")
+	for i := 1; i <= 6; i++ {
+		advance(clock, 10*time.Second)
+		if at := <-backend.polled; !at.Equal(clockStart.Add(time.Duration(i) * 10 * time.Second)) {
+			t.Fatalf("poll %d at %v", i, at)
+		}
+	}
+
+	// Calm for a minute: back to the normal interval
+	advance(clock, 59*time.Minute)
+	select {
+	case at := <-backend.polled:
+		t.Fatalf("polled at %v before the normal interval", at)
+	case <-time.After(50 * time.Millisecond):
+	}
+	advance(clock, time.Minute)
+	if at := <-backend.polled; !at.Equal(clockStart.Add(time.Minute + time.Hour)) {
+		t.Fatalf("slow poll at %v", at)
+	}
+}
+
+func TestWatchVariableFakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL", "battery.charge": "100"})
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(ctx, host, nil, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+
+	clock := nuttest.NewClock(clockStart)
+	updates, err := ups.WatchVariable(ctx, "battery.charge", time.Minute, nut.WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update := <-updates; update.NewValue != "100" || !update.Time.Equal(clockStart) {
+		t.Fatalf("initial update %+v", update)
+	}
+	server.SetVar("ups1", "battery.charge", "90")
+	advance(clock, time.Minute)
+	update := <-updates
+	if update.OldValue != "100" || update.NewValue != "90" || !update.Time.Equal(clockStart.Add(time.Minute)) {
+		t.Fatalf("update %+v", update)
+	}
+}
+
+func TestWatchVariableRejectsWatcherOptions(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"battery.charge": "100"})
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, nil, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+
+	for name, opt := range map[string]nut.WatcherOption{
+		"WatchVariables":    nut.WatchVariables("battery.charge"),
+		"WithWatchInterval": nut.WithWatchInterval(time.Second),
+		"WatchClients":      nut.WatchClients(),
+	} {
+		if _, err := ups.WatchVariable(context.Background(), "battery.charge", time.Minute, opt); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestWaitForStatusFakeClock(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OB"})
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, nil, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+
+	clock := nuttest.NewClock(clockStart)
+	done := make(chan nut.Status, 1)
+	go func() {
+		status, err := ups.WaitForStatus(context.Background(), func(s nut.Status) bool { return s.Has(nut.StatusOnline) }, time.Hour, nut.WithStatusClock(clock))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- status
+	}()
+	advance(clock, time.Hour) // Still on battery
+	clock.BlockUntil(1)       // Polled and waiting again
+	server.SetVar("ups1", "ups.status", "OL")
+	advance(clock, time.Hour)
+	if status := <-done; !status.Has(nut.StatusOnline) {
+		t.Fatalf("status = %v", status)
+	}
+}
+
+// signalingStore is a memory history store signaling compactions and appended
+// events.
+type signalingStore struct {
+	nut.HistoryStore
+	compacted chan time.Time
+	appended  chan []nut.Event
+}
+
+func newSignalingStore() *signalingStore {
+	return &signalingStore{HistoryStore: nut.NewMemoryHistoryStore(0), compacted: make(chan time.Time, 16), appended: make(chan []nut.Event, 16)}
+}
+
+func (s *signalingStore) Compact(ctx context.Context, policy nut.RetentionPolicy, now time.Time) error {
+	err := s.HistoryStore.(nut.HistoryCompactor).Compact(ctx, policy, now)
+	s.compacted <- now
+	return err
+}
+
+func (s *signalingStore) AppendEvents(ctx context.Context, events []nut.Event) error {
+	err := s.HistoryStore.AppendEvents(ctx, events)
+	s.appended <- events
+	return err
+}
+
+func TestHistoryCompactionFakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := nuttest.NewClock(clockStart)
+	store := newSignalingStore()
+	for i := 0; i < 3; i++ {
+		sample := nut.Snapshot{Time: clockStart.Add(time.Duration(i-2) * time.Hour), UPS: "ups1", Variables: map[string]string{"battery.charge": "100"}}
+		store.AppendSamples(ctx, []nut.Snapshot{sample})
+	}
+	policy := nut.RetentionPolicy{Raw: 90 * time.Minute, Clock: clock}
+	go nut.RunHistoryCompaction(ctx, store, policy, time.Hour)
+
+	// Each hourly compaction expires one more sample
+	for i, want := range []int{2, 1, 0} {
+		if at := <-store.compacted; !at.Equal(clockStart.Add(time.Duration(i) * time.Hour)) {
+			t.Fatalf("compaction %d at %v", i, at)
+		}
+		if samples, _ := store.Samples(ctx, nut.HistoryQuery{}); len(samples) != want {
+			t.Fatalf("%d samples after compaction %d, want %d", len(samples), i, want)
+		}
+		if i < 2 {
+			advance(clock, time.Hour)
+		}
+	}
+}
+
+func TestSelfTestSchedulerFakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"}) // No test commands
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(ctx, host, nil, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+
+	clock := nuttest.NewClock(clockStart)
+	store := newSignalingStore()
+	scheduler, err := nut.NewSelfTestScheduler(nut.SelfTestConfig{Schedule: nut.Every(24 * time.Hour), History: store, Clock: clock}, &ups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go scheduler.Run(ctx)
+
+	for day := 1; day <= 2; day++ {
+		advance(clock, 24*time.Hour)
+		events := <-store.appended
+		if len(events) != 1 || events[0].Type != nut.EventSelfTestFailed || events[0].Err == nil {
+			t.Fatalf("day %d: events %+v", day, events)
+		}
+		if want := clockStart.Add(time.Duration(day) * 24 * time.Hour); !events[0].Time.Equal(want) {
+			t.Fatalf("day %d: event at %v, want %v", day, events[0].Time, want)
+		}
+	}
+}
+
+func TestDriverRetryFakeClock(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"battery.charge": "100"})
+	host, port := server.HostPort()
+	clock := nuttest.NewClock(clockStart)
+	opts := []nut.ClientOption{nut.WithDriverRetry(time.Second), nut.WithClientClock(clock)}
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, opts, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+	server.SetError("ups1", "DRIVER-NOT-CONNECTED")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ups.GetVariableValue("battery.charge")
+		done <- err
+	}()
+	// Backoff of 250ms, then 500ms, then the remaining 250ms of the budget
+	for _, delay := range []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, 250 * time.Millisecond} {
+		select {
+		case err := <-done:
+			t.Fatalf("gave up before %v: %v", clock.Now().Sub(clockStart), err)
+		default:
+		}
+		advance(clock, delay)
+	}
+	if err := <-done; err == nil {
+		t.Fatal("no error after the retry budget")
+	}
+
+	server.SetError("ups1", "")
+	if value, err := ups.GetVariableValue("battery.charge"); err != nil || value != "100" {
+		t.Fatalf("GetVariableValue = %q, %v", value, err)
+	}
+}
+
+func TestAlertRulesFakeClock(t *testing.T) {
+	events := make(chan nut.Event, 4)
+	rules := nut.NewAlertRules(nut.NotifierFunc(func(ctx context.Context, event nut.Event) error {
+		events <- event
+		return nil
+	}))
+	clock := nuttest.NewClock(clockStart)
+	rules.SetClock(clock)
+	if err := rules.AddRate(nut.RateRule{Variable: "input.voltage", Direction: nut.RateFalling, Delta: 10, Window: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	sample := func(voltage string) nut.Snapshot {
+		return nut.Snapshot{UPS: "ups1", Variables: map[string]string{"input.voltage": voltage}}
+	}
+
+	// Undated snapshots are dated by the clock, so a drop spread over more
+	// than the window does not alert
+	for _, voltage := range []string{"230", "225", "220"} {
+		if err := rules.Evaluate(context.Background(), sample(voltage)); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(45 * time.Second)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+
+	if err := rules.Evaluate(context.Background(), sample("205")); err != nil {
+		t.Fatal(err)
+	}
+	event := <-events
+	if event.Type != nut.EventAlertRaised || !event.Time.Equal(clockStart.Add(135*time.Second)) {
+		t.Fatalf("event %+v", event)
+	}
+}
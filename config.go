@@ -0,0 +1,106 @@
+package nut
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvHost     = "NUT_HOST"
+	EnvPort     = "NUT_PORT"
+	EnvUsername = "NUT_USERNAME"
+	EnvPassword = "NUT_PASSWORD"
+	EnvTLS      = "NUT_TLS"
+)
+
+// ConfigFromEnv returns a MonitorConfig with the connection settings from the
+// NUT_HOST, NUT_PORT, NUT_USERNAME, NUT_PASSWORD and NUT_TLS environment
+// variables. Unset variables leave the defaults: localhost, port 3493, no
+// authentication and no STARTTLS. NUT_TLS accepts the values of
+// strconv.ParseBool.
+func ConfigFromEnv() (MonitorConfig, error) {
+	config := MonitorConfig{
+		Host:     "localhost",
+		Port:     3493,
+		Username: os.Getenv(EnvUsername),
+		Password: os.Getenv(EnvPassword),
+	}
+	if host := os.Getenv(EnvHost); host != "" {
+		config.Host = host
+	}
+	if port := os.Getenv(EnvPort); port != "" {
+		portNum, err := strconv.Atoi(port)
+		if err != nil || portNum <= 0 || portNum > 65535 {
+			return config, fmt.Errorf("invalid %s %q", EnvPort, port)
+		}
+		config.Port = portNum
+	}
+	if useTLS := os.Getenv(EnvTLS); useTLS != "" {
+		enabled, err := strconv.ParseBool(useTLS)
+		if err != nil {
+			return config, fmt.Errorf("invalid %s %q", EnvTLS, useTLS)
+		}
+		config.StartTLS = enabled
+	}
+	return config, nil
+}
+
+// RegisterFlags defines -host, -port, -username, -password and -tls flags on
+// fs that set the connection settings of config. The current values are the
+// flags' defaults, so flags override settings from ConfigFromEnv:
+//
+//	config, err := nut.ConfigFromEnv()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	config.RegisterFlags(flag.CommandLine)
+//	flag.Parse()
+func (config *MonitorConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&config.Host, "host", config.Host, "NUT server hostname (env "+EnvHost+")")
+	fs.IntVar(&config.Port, "port", config.Port, "NUT server port (env "+EnvPort+")")
+	fs.StringVar(&config.Username, "username", config.Username, "NUT username (env "+EnvUsername+")")
+	fs.StringVar(&config.Password, "password", config.Password, "NUT password (env "+EnvPassword+")")
+	fs.BoolVar(&config.StartTLS, "tls", config.StartTLS, "Upgrade the connection with STARTTLS (env "+EnvTLS+")")
+}
+
+// Connect connects to the configured server, upgrading with STARTTLS and
+// authenticating as configured. If Credentials is set, the settings it
+// returns for the endpoint are used instead of Username, Password and
+// StartTLS.
+func (config MonitorConfig) Connect(ctx context.Context) (*Client, error) {
+	if config.Port == 0 {
+		config.Port = 3493
+	}
+	opts := config.ClientOptions
+	if config.Credentials != nil {
+		creds, err := config.Credentials.Credentials(ctx, config.server())
+		if err != nil {
+			return nil, fmt.Errorf("credentials for %s: %w", config.server(), err)
+		}
+		config.Username, config.Password, config.StartTLS = creds.Username, creds.Password, creds.StartTLS
+		if creds.TLSConfig != nil {
+			opts = append(opts[:len(opts):len(opts)], WithTLSConfig(creds.TLSConfig))
+		}
+	}
+	client, err := ConnectWithOptionsAndConfig(ctx, config.Host, opts, config.Port)
+	if err != nil {
+		return nil, err
+	}
+	if config.StartTLS {
+		if err := client.StartTLS(); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	if config.Username != "" {
+		if _, err := client.Authenticate(config.Username, config.Password); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
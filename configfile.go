@@ -0,0 +1,492 @@
+package nut
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config is the schema of a configuration file for a Monitor, a Fleet and
+// their alert rules and notifiers, shared by daemons and embedding programs:
+//
+//	interval: 5s
+//	credentials:
+//	  monitor:
+//	    username: upsmon
+//	    password: env:UPSMON_PASSWORD
+//	endpoints:
+//	  - host: nut1.example.com
+//	    credentials: monitor
+//	  - host: nut2.example.com
+//	    ups: [rack1, rack2]
+//	alerts:
+//	  - threshold: battery.charge < 30
+//	  - rate: input.voltage drops 20 within 10s
+//	  - battery_replacement: true
+//	    max_age: 26280h
+//	notifiers:
+//	  - type: telegram
+//	    token: file:/run/secrets/telegram-token
+//	    chat_ids: ["123456"]
+//
+// Passwords, tokens and secrets may be given literally or as references:
+// "env:NAME" reads an environment variable and "file:PATH" a file, without its
+// trailing newline. References are resolved when the configuration is used,
+// and credential references again on every connection attempt.
+type Config struct {
+	Interval       ConfigDuration `json:"interval"`        // Default polling interval of endpoints
+	FastInterval   ConfigDuration `json:"fast_interval"`   // Default MonitorConfig.FastInterval
+	ReconnectDelay ConfigDuration `json:"reconnect_delay"` // Default delay between reconnection attempts
+	EventBuffer    int            `json:"event_buffer"`    // FleetConfig.EventBuffer
+
+	Credentials map[string]CredentialConfig `json:"credentials"` // Referenced by name from endpoints
+	Endpoints   []EndpointConfig            `json:"endpoints"`
+	Alerts      []AlertConfig               `json:"alerts"`
+	Notifiers   []NotifierConfig            `json:"notifiers"`
+}
+
+// ConfigDuration is a duration in a configuration file, written as a
+// time.ParseDuration string such as "30s" or as a number of seconds.
+type ConfigDuration time.Duration
+
+// UnmarshalJSON accepts a duration string or a number of seconds.
+func (d *ConfigDuration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = ConfigDuration(parsed)
+	case float64:
+		*d = ConfigDuration(v * float64(time.Second))
+	case nil:
+		*d = 0
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d ConfigDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// CredentialConfig holds the credentials of endpoints. Password may be a
+// secret reference.
+type CredentialConfig struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	StartTLS bool   `json:"starttls"`
+}
+
+// EndpointConfig configures one upsd endpoint; unset intervals default to the
+// Config's.
+type EndpointConfig struct {
+	Host           string         `json:"host"`
+	Port           int            `json:"port"`        // Default 3493
+	Credentials    string         `json:"credentials"` // Name of an entry in Config.Credentials
+	UPS            []string       `json:"ups"`         // UPSes to monitor; empty monitors all
+	Interval       ConfigDuration `json:"interval"`
+	FastInterval   ConfigDuration `json:"fast_interval"`
+	ReconnectDelay ConfigDuration `json:"reconnect_delay"`
+	WatchClients   bool           `json:"watch_clients"`
+}
+
+// AlertConfig configures one alert rule: Threshold, in ParseThresholdRule
+// notation, Rate, in ParseRateRule notation, or BatteryReplacement.
+type AlertConfig struct {
+	Name               string         `json:"name"`
+	Threshold          string         `json:"threshold"`  // e.g. "battery.charge < 30"
+	Hysteresis         float64        `json:"hysteresis"` // For Threshold
+	Rate               string         `json:"rate"`       // e.g. "input.voltage drops 20 within 10s"
+	BatteryReplacement bool           `json:"battery_replacement"`
+	MaxAge             ConfigDuration `json:"max_age"` // For BatteryReplacement
+}
+
+// NotifierConfig configures one notifier. Type selects it and the fields that
+// apply:
+//
+//	exec      command, args, env, message, timeout
+//	webhook   urls, headers, secret, body, content_type, retries, timeout
+//	email     addr, from, to, username, password, implicit_tls, starttls, subject, body, timeout
+//	telegram  token, chat_ids, message, retries, timeout
+//	pushover  token, user, device, title, message, retries, timeout
+//	syslog    network, address, facility, app_name
+//	desktop   app_name, types
+//	eventlog  path
+//
+// Password, secret and token may be secret references. Telegram and pushover
+// retry twice unless retries is set, -1 disabling retries.
+type NotifierConfig struct {
+	Type    string         `json:"type"`
+	Message string         `json:"message"` // MessageTemplate replacing the default message
+	Timeout ConfigDuration `json:"timeout"`
+	Retries int            `json:"retries"`
+
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Env     []string `json:"env"`
+
+	URLs        []string          `json:"urls"`
+	Headers     map[string]string `json:"headers"`
+	Secret      string            `json:"secret"`
+	Body        string            `json:"body"`
+	ContentType string            `json:"content_type"`
+
+	Addr        string   `json:"addr"`
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+	Username    string   `json:"username"`
+	Password    string   `json:"password"`
+	ImplicitTLS bool     `json:"implicit_tls"`
+	StartTLS    bool     `json:"starttls"`
+	Subject     string   `json:"subject"`
+
+	Token   string   `json:"token"`
+	ChatIDs []string `json:"chat_ids"`
+	User    string   `json:"user"`
+	Device  string   `json:"device"`
+	Title   string   `json:"title"`
+
+	Network  string   `json:"network"`
+	Address  string   `json:"address"`
+	Facility int      `json:"facility"`
+	AppName  string   `json:"app_name"`
+	Types    []string `json:"types"`
+
+	Path string `json:"path"`
+}
+
+// LoadConfig reads and validates the configuration file at path. The format
+// is chosen by the extension: .yaml or .yml, .toml, or .json.
+func LoadConfig(path string) (*Config, error) {
+	var format Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".toml":
+		format = FormatTOML
+	case ".json":
+		format = FormatJSON
+	default:
+		return nil, fmt.Errorf("unknown configuration format of %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates a configuration in the given format.
+// Unknown keys are errors, so that typos do not go unnoticed. YAML and TOML
+// are read with built-in parsers for their commonly used subsets; see Config.
+// Unquoted values of string fields are read as written, so "password:
+// 12345678" and "chat_ids: [123456]" need no quotes.
+func ParseConfig(data []byte, format Format) (*Config, error) {
+	config := &Config{}
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(config); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+	case FormatYAML, FormatTOML:
+		document, err := parseDocument(string(data), format)
+		if err != nil {
+			return nil, err
+		}
+		if err := decodeDocument(document, config); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported configuration format %q", format)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the configuration without resolving secret references and
+// returns all problems found.
+func (c *Config) Validate() error {
+	var errs []error
+	problem := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for name, d := range map[string]ConfigDuration{"interval": c.Interval, "fast_interval": c.FastInterval, "reconnect_delay": c.ReconnectDelay} {
+		if d < 0 {
+			problem("%s must not be negative", name)
+		}
+	}
+	if c.EventBuffer < 0 {
+		problem("event_buffer must not be negative")
+	}
+
+	seen := map[string]bool{}
+	for i, endpoint := range c.Endpoints {
+		monitor, err := c.monitorConfig(endpoint).withDefaults()
+		if err != nil {
+			problem("endpoints[%d]: %v", i, err)
+			continue
+		}
+		if server := monitor.server(); seen[server] {
+			problem("endpoints[%d]: duplicate endpoint %s", i, server)
+		} else {
+			seen[server] = true
+		}
+		if endpoint.Port < 0 || endpoint.Port > 65535 {
+			problem("endpoints[%d]: invalid port %d", i, endpoint.Port)
+		}
+		if _, ok := c.Credentials[endpoint.Credentials]; endpoint.Credentials != "" && !ok {
+			problem("endpoints[%d]: unknown credentials %q", i, endpoint.Credentials)
+		}
+		if endpoint.Interval < 0 || endpoint.FastInterval < 0 || endpoint.ReconnectDelay < 0 {
+			problem("endpoints[%d]: intervals must not be negative", i)
+		}
+	}
+	for name, creds := range c.Credentials {
+		if creds.Username == "" && creds.Password != "" {
+			problem("credentials %q: password without username", name)
+		}
+	}
+
+	if _, err := c.BuildAlertRules(); err != nil {
+		errs = append(errs, err)
+	}
+	for i, notifier := range c.Notifiers {
+		if err := notifier.validate(); err != nil {
+			problem("notifiers[%d]: %v", i, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BuildFleetConfig returns the configuration of a Fleet monitoring the endpoints.
+func (c *Config) BuildFleetConfig() FleetConfig {
+	config := FleetConfig{EventBuffer: c.EventBuffer}
+	for _, endpoint := range c.Endpoints {
+		config.Endpoints = append(config.Endpoints, c.monitorConfig(endpoint))
+	}
+	return config
+}
+
+// monitorConfig returns the MonitorConfig of an endpoint, with intervals
+// defaulting to the Config's.
+func (c *Config) monitorConfig(endpoint EndpointConfig) MonitorConfig {
+	config := MonitorConfig{
+		Host:           endpoint.Host,
+		Port:           endpoint.Port,
+		UPS:            endpoint.UPS,
+		Interval:       time.Duration(firstNonZero(endpoint.Interval, c.Interval)),
+		FastInterval:   time.Duration(firstNonZero(endpoint.FastInterval, c.FastInterval)),
+		ReconnectDelay: time.Duration(firstNonZero(endpoint.ReconnectDelay, c.ReconnectDelay)),
+		WatchClients:   endpoint.WatchClients,
+	}
+	if creds, ok := c.Credentials[endpoint.Credentials]; ok {
+		config.Credentials = CredentialFunc(func(ctx context.Context, server string) (Credentials, error) {
+			password, err := ResolveSecret(creds.Password)
+			if err != nil {
+				return Credentials{}, fmt.Errorf("credentials %q: %w", endpoint.Credentials, err)
+			}
+			return Credentials{Username: creds.Username, Password: password, StartTLS: creds.StartTLS}, nil
+		})
+	}
+	return config
+}
+
+func firstNonZero(values ...ConfigDuration) ConfigDuration {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// BuildAlertRules returns alert rules for the configured alerts, notifying
+// notifiers.
+func (c *Config) BuildAlertRules(notifiers ...Notifier) (*AlertRules, error) {
+	rules := NewAlertRules(notifiers...)
+	var errs []error
+	for i, alert := range c.Alerts {
+		kinds := 0
+		for _, set := range []bool{alert.Threshold != "", alert.Rate != "", alert.BatteryReplacement} {
+			if set {
+				kinds++
+			}
+		}
+		var err error
+		switch {
+		case kinds > 1:
+			err = fmt.Errorf("threshold, rate and battery_replacement are exclusive")
+		case alert.Threshold != "":
+			var rule ThresholdRule
+			if rule, err = ParseThresholdRule(alert.Threshold); err == nil {
+				rule.Name, rule.Hysteresis = alert.Name, alert.Hysteresis
+				err = rules.AddThreshold(rule)
+			}
+		case alert.Rate != "":
+			var rule RateRule
+			if rule, err = ParseRateRule(alert.Rate); err == nil {
+				rule.Name = alert.Name
+				err = rules.AddRate(rule)
+			}
+		case alert.BatteryReplacement:
+			err = rules.AddBatteryReplacement(BatteryReplacementRule{Name: alert.Name, MaxAge: time.Duration(alert.MaxAge)})
+		default:
+			err = fmt.Errorf("threshold, rate or battery_replacement is required")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alerts[%d]: %w", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return rules, nil
+}
+
+// BuildNotifiers creates the configured notifiers, resolving secret references.
+// snapshots, if not nil, provides UPS state to message templates, e.g.
+// Fleet.AllUPS.
+func (c *Config) BuildNotifiers(snapshots SnapshotSource) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0, len(c.Notifiers))
+	for i, config := range c.Notifiers {
+		notifier, err := config.build(snapshots)
+		if err != nil {
+			return nil, fmt.Errorf("notifiers[%d] (%s): %w", i, config.Type, err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
+
+// validate checks the fields required by the notifier type.
+func (n NotifierConfig) validate() error {
+	type field struct {
+		name string
+		set  bool
+	}
+	require := func(fields ...field) error {
+		for _, f := range fields {
+			if !f.set {
+				return fmt.Errorf("%s notifier requires %s", n.Type, f.name)
+			}
+		}
+		return nil
+	}
+	switch n.Type {
+	case "exec":
+		return require(field{"command", n.Command != ""})
+	case "webhook":
+		return require(field{"urls", len(n.URLs) > 0})
+	case "email":
+		return require(field{"addr", n.Addr != ""}, field{"from", n.From != ""}, field{"to", len(n.To) > 0})
+	case "telegram":
+		return require(field{"token", n.Token != ""}, field{"chat_ids", len(n.ChatIDs) > 0})
+	case "pushover":
+		return require(field{"token", n.Token != ""}, field{"user", n.User != ""})
+	case "eventlog":
+		return require(field{"path", n.Path != ""})
+	case "syslog", "desktop":
+		return nil
+	case "":
+		return fmt.Errorf("type is required")
+	default:
+		return fmt.Errorf("unknown notifier type %q", n.Type)
+	}
+}
+
+// build creates the notifier.
+func (n NotifierConfig) build(snapshots SnapshotSource) (Notifier, error) {
+	if err := n.validate(); err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(n.Timeout)
+	switch n.Type {
+	case "exec":
+		return NewExecNotifier(ExecConfig{Command: n.Command, Args: n.Args, Env: n.Env, Timeout: timeout, Message: n.Message, Snapshots: snapshots})
+	case "webhook":
+		secret, err := ResolveSecret(n.Secret)
+		if err != nil {
+			return nil, err
+		}
+		config := WebhookConfig{URLs: n.URLs, Headers: n.Headers, Timeout: timeout, Retries: n.Retries,
+			Body: n.Body, ContentType: n.ContentType, Snapshots: snapshots}
+		if secret != "" {
+			config.Secret = []byte(secret)
+		}
+		return NewWebhookNotifier(config)
+	case "email":
+		password, err := ResolveSecret(n.Password)
+		if err != nil {
+			return nil, err
+		}
+		return NewEmailNotifier(EmailConfig{Addr: n.Addr, From: n.From, To: n.To, Username: n.Username, Password: password,
+			ImplicitTLS: n.ImplicitTLS, StartTLS: n.StartTLS, Subject: n.Subject, Body: n.Body, Snapshots: snapshots, Timeout: timeout})
+	case "telegram":
+		token, err := ResolveSecret(n.Token)
+		if err != nil {
+			return nil, err
+		}
+		return NewTelegramNotifier(TelegramConfig{Token: token, ChatIDs: n.ChatIDs, Message: n.Message, Snapshots: snapshots,
+			ChatDelivery: ChatDelivery{Timeout: timeout, Retries: n.Retries}})
+	case "pushover":
+		token, err := ResolveSecret(n.Token)
+		if err != nil {
+			return nil, err
+		}
+		return NewPushoverNotifier(PushoverConfig{Token: token, User: n.User, Device: n.Device, Title: n.Title, Message: n.Message,
+			Snapshots: snapshots, ChatDelivery: ChatDelivery{Timeout: timeout, Retries: n.Retries}})
+	case "syslog":
+		return NewSyslogNotifier(SyslogConfig{Network: n.Network, Address: n.Address, Facility: SyslogFacility(n.Facility), AppName: n.AppName, Timeout: timeout})
+	case "desktop":
+		return NewDesktopNotifier(DesktopConfig{AppName: n.AppName, Types: n.Types, Snapshots: snapshots, Timeout: timeout}), nil
+	default: // eventlog
+		return OpenEventLog(n.Path)
+	}
+}
+
+// ResolveSecret returns the secret named by a reference: "env:NAME" is the
+// value of an environment variable, which must be set, and "file:PATH" the
+// contents of a file without trailing newlines. Other values are returned
+// as-is.
+func ResolveSecret(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("reading secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return ref, nil
+	}
+}
@@ -0,0 +1,201 @@
+package nut_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestLoadConfig(t *testing.T) {
+	files := map[string]string{
+		"nut.yaml": `
+interval: 10s
+credentials:
+  monitor:
+    username: upsmon
+    password: env:UPSMON_PASSWORD
+endpoints:
+  - host: nut1.example.com
+    credentials: monitor
+  - host: nut2.example.com
+    port: 3494
+    ups: [rack1, rack2]
+    interval: 30s
+alerts:
+  - threshold: battery.charge < 30
+    hysteresis: 5
+  - rate: input.voltage drops 20 within 10s
+  - battery_replacement: true
+    max_age: 26280h
+notifiers:
+  - type: webhook
+    urls: [https://example.com/hook]
+    retries: 3
+`,
+		"nut.toml": `
+interval = "10s"
+
+[credentials.monitor]
+username = "upsmon"
+password = "env:UPSMON_PASSWORD"
+
+[[endpoints]]
+host = "nut1.example.com"
+credentials = "monitor"
+
+[[endpoints]]
+host = "nut2.example.com"
+port = 3494
+ups = ["rack1", "rack2"]
+interval = "30s"
+
+[[alerts]]
+threshold = "battery.charge < 30"
+hysteresis = 5
+
+[[alerts]]
+rate = "input.voltage drops 20 within 10s"
+
+[[alerts]]
+battery_replacement = true
+max_age = "26280h"
+
+[[notifiers]]
+type = "webhook"
+urls = ["https://example.com/hook"]
+retries = 3
+`,
+		"nut.json": `{
+  "interval": "10s",
+  "credentials": {"monitor": {"username": "upsmon", "password": "env:UPSMON_PASSWORD"}},
+  "endpoints": [
+    {"host": "nut1.example.com", "credentials": "monitor"},
+    {"host": "nut2.example.com", "port": 3494, "ups": ["rack1", "rack2"], "interval": 30}
+  ],
+  "alerts": [
+    {"threshold": "battery.charge < 30", "hysteresis": 5},
+    {"rate": "input.voltage drops 20 within 10s"},
+    {"battery_replacement": true, "max_age": "26280h"}
+  ],
+  "notifiers": [{"type": "webhook", "urls": ["https://example.com/hook"], "retries": 3}]
+}`,
+	}
+	want := nut.Config{
+		Interval:    nut.ConfigDuration(10 * time.Second),
+		Credentials: map[string]nut.CredentialConfig{"monitor": {Username: "upsmon", Password: "env:UPSMON_PASSWORD"}},
+		Endpoints: []nut.EndpointConfig{
+			{Host: "nut1.example.com", Credentials: "monitor"},
+			{Host: "nut2.example.com", Port: 3494, UPS: []string{"rack1", "rack2"}, Interval: nut.ConfigDuration(30 * time.Second)},
+		},
+		Alerts: []nut.AlertConfig{
+			{Threshold: "battery.charge < 30", Hysteresis: 5},
+			{Rate: "input.voltage drops 20 within 10s"},
+			{BatteryReplacement: true, MaxAge: nut.ConfigDuration(26280 * time.Hour)},
+		},
+		Notifiers: []nut.NotifierConfig{{Type: "webhook", URLs: []string{"https://example.com/hook"}, Retries: 3}},
+	}
+
+	dir := t.TempDir()
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := nut.LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*config, want) {
+				t.Fatalf("got %+v\nwant %+v", *config, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigUnknownExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nut.ini")
+	if err := os.WriteFile(path, []byte("interval = 5s\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nut.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "unknown configuration format") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := func() nut.Config {
+		return nut.Config{
+			Endpoints: []nut.EndpointConfig{{Host: "nut1.example.com"}},
+			Alerts:    []nut.AlertConfig{{Threshold: "battery.charge < 30"}},
+			Notifiers: []nut.NotifierConfig{{Type: "syslog"}},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(*nut.Config)
+		want   string
+	}{
+		{"valid", func(c *nut.Config) {}, ""},
+		{"missing endpoint host", func(c *nut.Config) { c.Endpoints = append(c.Endpoints, nut.EndpointConfig{Port: 3493}) }, "endpoints[1]: hostname is required"},
+		{"duplicate endpoint", func(c *nut.Config) {
+			c.Endpoints = append(c.Endpoints, nut.EndpointConfig{Host: "nut1.example.com", Port: 3493})
+		}, "duplicate endpoint"},
+		{"invalid port", func(c *nut.Config) { c.Endpoints[0].Port = 70000 }, "invalid port 70000"},
+		{"unknown credentials", func(c *nut.Config) { c.Endpoints[0].Credentials = "monitor" }, `unknown credentials "monitor"`},
+		{"bad interval", func(c *nut.Config) { c.Interval = nut.ConfigDuration(-time.Second) }, "interval must not be negative"},
+		{"bad endpoint interval", func(c *nut.Config) { c.Endpoints[0].FastInterval = nut.ConfigDuration(-time.Second) }, "endpoints[0]: intervals must not be negative"},
+		{"negative event buffer", func(c *nut.Config) { c.EventBuffer = -1 }, "event_buffer must not be negative"},
+		{"password without username", func(c *nut.Config) {
+			c.Credentials = map[string]nut.CredentialConfig{"monitor": {Password: "secret"}}
+		}, "password without username"},
+		{"unknown notifier", func(c *nut.Config) { c.Notifiers[0].Type = "pager" }, `notifiers[0]: unknown notifier type "pager"`},
+		{"notifier without type", func(c *nut.Config) { c.Notifiers[0].Type = "" }, "notifiers[0]: type is required"},
+		{"notifier missing field", func(c *nut.Config) { c.Notifiers[0].Type = "webhook" }, "webhook notifier requires urls"},
+		{"bad alert rule", func(c *nut.Config) { c.Alerts[0].Threshold = "battery.charge <> 30" }, "alerts[0]"},
+		{"empty alert", func(c *nut.Config) { c.Alerts[0].Threshold = "" }, "threshold, rate or battery_replacement is required"},
+		{"exclusive alert kinds", func(c *nut.Config) { c.Alerts[0].BatteryReplacement = true }, "are exclusive"},
+		{"duplicate alert name", func(c *nut.Config) {
+			c.Alerts[0].Name = "battery.low"
+			c.Alerts = append(c.Alerts, nut.AlertConfig{Name: "battery.low", Threshold: "battery.charge < 20"})
+		}, "already registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(&config)
+			err := config.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigValidateReportsAllProblems(t *testing.T) {
+	config := nut.Config{
+		Interval:  nut.ConfigDuration(-time.Second),
+		Endpoints: []nut.EndpointConfig{{}},
+		Notifiers: []nut.NotifierConfig{{Type: "pager"}},
+	}
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("no error")
+	}
+	for _, want := range []string{"interval", "endpoints[0]", "notifiers[0]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
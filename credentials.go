@@ -0,0 +1,48 @@
+package nut
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// Credentials are the login and TLS settings for one upsd endpoint.
+type Credentials struct {
+	Username  string // Empty to skip authentication
+	Password  string
+	StartTLS  bool        // Upgrade the connection with STARTTLS before authenticating
+	TLSConfig *tls.Config // Optional TLS configuration for STARTTLS
+}
+
+// CredentialProvider supplies the credentials for an endpoint (host:port).
+// It is consulted on every connection attempt, so rotated secrets are picked
+// up on the next reconnect. Implementations must be safe for concurrent use.
+type CredentialProvider interface {
+	Credentials(ctx context.Context, server string) (Credentials, error)
+}
+
+// CredentialFunc adapts a function to the CredentialProvider interface.
+type CredentialFunc func(ctx context.Context, server string) (Credentials, error)
+
+// Credentials calls f(ctx, server).
+func (f CredentialFunc) Credentials(ctx context.Context, server string) (Credentials, error) {
+	return f(ctx, server)
+}
+
+// StaticCredentials is a CredentialProvider backed by a map keyed by endpoint
+// (host:port) or by host alone, the former taking precedence. Endpoints not in
+// the map connect without authentication or TLS.
+type StaticCredentials map[string]Credentials
+
+// Credentials returns the entry for server, or for its host.
+func (s StaticCredentials) Credentials(ctx context.Context, server string) (Credentials, error) {
+	if creds, ok := s[server]; ok {
+		return creds, nil
+	}
+	if host, _, err := net.SplitHostPort(server); err == nil {
+		if creds, ok := s[host]; ok {
+			return creds, nil
+		}
+	}
+	return Credentials{}, nil
+}
@@ -0,0 +1,172 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OutletDelays holds the shutdown and start delays of a single outlet. A nil
+// field means the outlet does not expose the delay (GetDelays) or should be
+// left unchanged (SetDelays).
+type OutletDelays struct {
+	Shutdown *time.Duration // outlet.N.delay.shutdown
+	Start    *time.Duration // outlet.N.delay.start
+}
+
+// Delays holds the UPS shutdown timing configuration. A nil field means the
+// UPS does not expose the delay (GetDelays) or should be left unchanged
+// (SetDelays).
+type Delays struct {
+	Shutdown *time.Duration        // ups.delay.shutdown
+	Start    *time.Duration        // ups.delay.start
+	Reboot   *time.Duration        // ups.delay.reboot
+	Outlets  map[int]*OutletDelays // Keyed by outlet number
+}
+
+// Delay returns a pointer to d, for filling Delays.
+func Delay(d time.Duration) *time.Duration {
+	return &d
+}
+
+// GetDelays reads ups.delay.* and outlet.N.delay.* in a single LIST VAR.
+func (u *UPS) GetDelays(ctx context.Context) (Delays, error) {
+	delays := Delays{Outlets: map[int]*OutletDelays{}}
+
+	values, err := u.variableValues(ctx)
+	if err != nil {
+		return delays, err
+	}
+
+	for name, value := range values {
+		target := delays.field(name)
+		if target == nil {
+			continue
+		}
+		seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return delays, fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+		}
+		*target = Delay(time.Duration(seconds) * time.Second)
+	}
+
+	return delays, nil
+}
+
+// SetDelays writes all non-nil delays. Every value is validated first against
+// the variable's writability and its LIST RANGE constraints; nothing is written
+// if any value is invalid. Delays are truncated to whole seconds.
+func (u *UPS) SetDelays(ctx context.Context, delays Delays) error {
+	settings := delays.variables()
+
+	// Sort for a deterministic order of validation and writes
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := u.validateDelay(name, settings[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		value := strconv.FormatInt(int64(settings[name]/time.Second), 10)
+		if _, err := u.SetVariable(name, value); err != nil {
+			return fmt.Errorf("setting %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateDelay checks that variableName is writable and that d lies within one
+// of its ranges, if any are defined.
+func (u *UPS) validateDelay(variableName string, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("invalid %s: negative delay %v", variableName, d)
+	}
+
+	serverType, err := u.GetVariableServerType(variableName)
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", variableName, err)
+	}
+	if !serverType.Writable {
+		return fmt.Errorf("%s: %w", variableName, errorForMessage(ErrCodeReadOnly))
+	}
+
+	ranges, err := u.GetVariableRanges(variableName)
+	if err != nil && !hasErrorCode(err, ErrCodeInvalidArgument) {
+		return fmt.Errorf("checking range of %s: %w", variableName, err)
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	seconds := float64(d / time.Second)
+	for _, r := range ranges {
+		if r.Contains(seconds) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %v outside allowed ranges %v: %w", variableName, d, ranges, errorForMessage(ErrCodeInvalidValue))
+}
+
+// field returns where the value of the delay variable name is stored, creating
+// outlet entries as needed, or nil if name is not a delay variable.
+func (d *Delays) field(name string) **time.Duration {
+	switch name {
+	case "ups.delay.shutdown":
+		return &d.Shutdown
+	case "ups.delay.start":
+		return &d.Start
+	case "ups.delay.reboot":
+		return &d.Reboot
+	}
+
+	var outlet int
+	var kind string
+	if _, err := fmt.Sscanf(name, "outlet.%d.delay.%s", &outlet, &kind); err != nil {
+		return nil
+	}
+	if d.Outlets[outlet] == nil {
+		d.Outlets[outlet] = &OutletDelays{}
+	}
+	switch kind {
+	case "shutdown":
+		return &d.Outlets[outlet].Shutdown
+	case "start":
+		return &d.Outlets[outlet].Start
+	}
+	return nil
+}
+
+// variables returns the non-nil delays keyed by NUT variable name.
+func (d Delays) variables() map[string]time.Duration {
+	settings := map[string]time.Duration{}
+	add := func(name string, value *time.Duration) {
+		if value != nil {
+			settings[name] = *value
+		}
+	}
+	add("ups.delay.shutdown", d.Shutdown)
+	add("ups.delay.start", d.Start)
+	add("ups.delay.reboot", d.Reboot)
+	for outlet, od := range d.Outlets {
+		if od == nil {
+			continue
+		}
+		add(fmt.Sprintf("outlet.%d.delay.shutdown", outlet), od.Shutdown)
+		add(fmt.Sprintf("outlet.%d.delay.start", outlet), od.Start)
+	}
+	return settings
+}
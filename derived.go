@@ -0,0 +1,83 @@
+package nut
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DerivedPrefix starts the names of values computed by DerivedVariables, which
+// are not reported by the driver.
+const DerivedPrefix = "derived."
+
+// batteryDateLayouts are the formats drivers use for battery.date and
+// battery.mfr.date.
+var batteryDateLayouts = []string{"2006/01/02", "2006-01-02", "01/02/06", "01/02/2006"}
+
+// DerivedVariables computes values that drivers often omit from the reported
+// variables, named with DerivedPrefix:
+//
+//	derived.ups.realpower               real power in W (ups.load × ups.realpower.nominal)
+//	derived.ups.power                   apparent power in VA (ups.load × ups.power.nominal)
+//	derived.battery.energy.remaining    energy left in the battery in Wh (runtime × real power)
+//	derived.battery.age                 seconds since battery.date or battery.mfr.date
+//
+// A value is only derived when its inputs are present and numeric. now is used
+// for the battery age.
+func DerivedVariables(values map[string]string, now time.Time) map[string]string {
+	derived := map[string]string{}
+	number := func(name string) (float64, bool) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(values[name]), 64)
+		return f, err == nil
+	}
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	load, hasLoad := number("ups.load")
+	realpower, hasRealpower := number("ups.realpower")
+	if nominal, ok := number("ups.realpower.nominal"); ok && hasLoad {
+		derived[DerivedPrefix+"ups.realpower"] = format(round(load / 100 * nominal))
+		if !hasRealpower {
+			realpower, hasRealpower = load/100*nominal, true
+		}
+	}
+	if nominal, ok := number("ups.power.nominal"); ok && hasLoad {
+		derived[DerivedPrefix+"ups.power"] = format(round(load / 100 * nominal))
+	}
+	if runtime, ok := number("battery.runtime"); ok && hasRealpower {
+		derived[DerivedPrefix+"battery.energy.remaining"] = format(round(runtime / 3600 * realpower))
+	}
+
+	if age, ok := BatteryAge(values, now); ok {
+		derived[DerivedPrefix+"battery.age"] = strconv.FormatInt(int64(age/time.Second), 10)
+	}
+	return derived
+}
+
+// BatteryAge returns the time elapsed at now since the battery was installed,
+// per battery.date, or else manufactured, per battery.mfr.date. ok is false if
+// neither is present, parseable and in the past.
+func BatteryAge(values map[string]string, now time.Time) (age time.Duration, ok bool) {
+	for _, name := range []string{"battery.date", "battery.mfr.date"} {
+		if date, ok := parseBatteryDate(values[name]); ok && !date.After(now) {
+			return now.Sub(date), true
+		}
+	}
+	return 0, false
+}
+
+// round rounds f to two decimals.
+func round(f float64) float64 {
+	return float64(int64(f*100+0.5)) / 100
+}
+
+func parseBatteryDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range batteryDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
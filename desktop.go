@@ -0,0 +1,148 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDesktopTypes are the events shown by a DesktopNotifier by default,
+// as upsmon NOTIFYTYPEs or upper-cased event types.
+var defaultDesktopTypes = []string{"ONBATT", "ONLINE", "LOWBATT", "FSD", "REPLBATT", "COMMBAD", "COMMOK", "ALERT_RAISED", "SELFTEST_FAILED"}
+
+// criticalDesktopTypes are shown with critical urgency, which desktops keep on
+// screen until dismissed.
+var criticalDesktopTypes = map[string]bool{"LOWBATT": true, "FSD": true, "COMMBAD": true}
+
+// DesktopConfig configures a DesktopNotifier.
+type DesktopConfig struct {
+	AppName   string         // Application name shown with the notification (default "UPS")
+	Types     []string       // NOTIFYTYPEs or upper-cased event types to show (default power events)
+	Snapshots SnapshotSource // Optional UPS state, to show the remaining runtime
+	Timeout   time.Duration  // Maximum run time of the helper command (default 10s)
+}
+
+// DesktopNotifier is a Notifier showing desktop notifications such as "UPS
+// rack1@server on battery — 12 minutes remaining". On macOS they are shown
+// with osascript; elsewhere they are sent to the freedesktop.org notification
+// service on the session D-Bus using notify-send or, if that is not installed,
+// gdbus. The module has no D-Bus client of its own, so one of these must be
+// available.
+type DesktopNotifier struct {
+	config DesktopConfig
+	types  map[string]bool
+}
+
+// NewDesktopNotifier returns a DesktopNotifier.
+func NewDesktopNotifier(config DesktopConfig) *DesktopNotifier {
+	if config.AppName == "" {
+		config.AppName = "UPS"
+	}
+	if len(config.Types) == 0 {
+		config.Types = defaultDesktopTypes
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	types := make(map[string]bool, len(config.Types))
+	for _, t := range config.Types {
+		types[strings.ToUpper(t)] = true
+	}
+	return &DesktopNotifier{config: config, types: types}
+}
+
+// Notify shows event if its type is selected, and ignores it otherwise.
+func (n *DesktopNotifier) Notify(ctx context.Context, event Event) error {
+	notifyType, message := upsmonNotification(event)
+	if !n.types[notifyType] {
+		return nil
+	}
+	if remaining := n.remaining(event); remaining != "" {
+		message += " — " + remaining
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+	cmd, err := n.command(ctx, notifyType, message)
+	if err != nil {
+		return err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("showing notification: %w: %s", err, out)
+		}
+		return fmt.Errorf("showing notification: %w", err)
+	}
+	return nil
+}
+
+// remaining returns the battery runtime of the event's UPS while it is on
+// battery, e.g. "12 minutes remaining", or an empty string.
+func (n *DesktopNotifier) remaining(event Event) string {
+	if n.config.Snapshots == nil {
+		return ""
+	}
+	for _, snapshot := range n.config.Snapshots() {
+		if snapshot.UPS != event.UPS || snapshot.Server != event.Server || !snapshot.Status.Has(StatusOnBattery) {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(strings.TrimSpace(snapshot.Variables["battery.runtime"]), 64)
+		if err != nil {
+			return ""
+		}
+		if minutes := int(seconds / 60); minutes != 1 {
+			return fmt.Sprintf("%d minutes remaining", minutes)
+		}
+		return "1 minute remaining"
+	}
+	return ""
+}
+
+// command returns the helper command showing a notification on this platform.
+func (n *DesktopNotifier) command(ctx context.Context, notifyType, message string) (*exec.Cmd, error) {
+	critical := criticalDesktopTypes[notifyType]
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(n.config.AppName))
+		if critical {
+			script += ` sound name "Basso"`
+		}
+		return exec.CommandContext(ctx, "osascript", "-e", script), nil
+	}
+
+	urgency := "normal"
+	if critical {
+		urgency = "critical"
+	}
+	if path, err := exec.LookPath("notify-send"); err == nil {
+		return exec.CommandContext(ctx, path, "--app-name", n.config.AppName, "--urgency", urgency, n.config.AppName, message), nil
+	}
+	if path, err := exec.LookPath("gdbus"); err == nil {
+		urgencyByte := 1
+		if critical {
+			urgencyByte = 2
+		}
+		return exec.CommandContext(ctx, path, "call", "--session",
+			"--dest", "org.freedesktop.Notifications",
+			"--object-path", "/org/freedesktop/Notifications",
+			"--method", "org.freedesktop.Notifications.Notify",
+			gvariantString(n.config.AppName), "0", "''", gvariantString(n.config.AppName), gvariantString(message),
+			"[]", fmt.Sprintf("{'urgency': <byte %d>}", urgencyByte), "-1"), nil
+	}
+	return nil, fmt.Errorf("showing notification: neither notify-send nor gdbus is installed")
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// gvariantString quotes s as a GVariant text format string.
+func gvariantString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,85 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DeviceSpec identifies a UPS on a NUT server, as written upsname[@host[:port]]
+// by the NUT command-line tools.
+type DeviceSpec struct {
+	UPS  string
+	Host string // Defaults to localhost
+	Port int    // Defaults to 3493
+}
+
+// ParseDeviceSpec parses a device specification such as "myups",
+// "myups@server", "myups@server:3493" or "myups@[::1]:3493".
+func ParseDeviceSpec(s string) (DeviceSpec, error) {
+	spec := DeviceSpec{Host: "localhost", Port: 3493}
+	name, server, hasServer := strings.Cut(s, "@")
+	if name == "" {
+		return spec, fmt.Errorf("invalid device %q: missing UPS name", s)
+	}
+	spec.UPS = name
+	if !hasServer {
+		return spec, nil
+	}
+	if server == "" {
+		return spec, fmt.Errorf("invalid device %q: missing host", s)
+	}
+
+	host, port := server, ""
+	if strings.HasPrefix(server, "[") {
+		end := strings.Index(server, "]")
+		if end < 0 {
+			return spec, fmt.Errorf("invalid device %q: unterminated IPv6 address", s)
+		}
+		host, port = server[1:end], strings.TrimPrefix(server[end+1:], ":")
+		if rest := server[end+1:]; rest != "" && !strings.HasPrefix(rest, ":") {
+			return spec, fmt.Errorf("invalid device %q: unexpected %q after host", s, rest)
+		}
+	} else if i := strings.LastIndex(server, ":"); i >= 0 && strings.Count(server, ":") == 1 {
+		host, port = server[:i], server[i+1:]
+	}
+	if host == "" {
+		return spec, fmt.Errorf("invalid device %q: missing host", s)
+	}
+	spec.Host = host
+	if port != "" {
+		portNum, err := strconv.Atoi(port)
+		if err != nil || portNum <= 0 || portNum > 65535 {
+			return spec, fmt.Errorf("invalid device %q: invalid port %q", s, port)
+		}
+		spec.Port = portNum
+	}
+	return spec, nil
+}
+
+// String returns the specification as upsname@host:port.
+func (d DeviceSpec) String() string {
+	return d.UPS + "@" + net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+}
+
+// DialDevice connects to the server named by a device specification and
+// returns a handle for its UPS, failing if the server does not know the UPS.
+// Disconnect the returned client when done.
+func DialDevice(ctx context.Context, spec string, opts ...ClientOption) (*Client, *UPS, error) {
+	device, err := ParseDeviceSpec(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := ConnectWithOptionsAndConfig(ctx, device.Host, opts, device.Port)
+	if err != nil {
+		return nil, nil, err
+	}
+	ups := newUPSValue(device.UPS, "", client)
+	if _, err := ups.GetDescription(); err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("%s: %w", device, err)
+	}
+	return client, &ups, nil
+}
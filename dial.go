@@ -0,0 +1,185 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// defaultDialStagger is the delay between starting connection attempts to
+// successive addresses of a multi-homed host (RFC 8305 recommends 250ms).
+const defaultDialStagger = 250 * time.Millisecond
+
+// WithDialStagger sets the delay between parallel connection attempts when a
+// hostname resolves to multiple addresses. A value of 0 dials addresses
+// sequentially, waiting for each attempt to fail before starting the next.
+func WithDialStagger(delay time.Duration) ClientOption {
+	return func(c *Client) {
+		c.dialStagger = delay
+	}
+}
+
+// WithResolver resolves hostnames with resolver instead of the system resolver,
+// e.g. for split-horizon DNS or a resolver with a custom Dial function in tests.
+// It also applies to the SRV lookup of ConnectSRV.
+func WithResolver(resolver *net.Resolver) ClientOption {
+	return func(c *Client) {
+		c.resolver = resolver
+	}
+}
+
+// lookupResolver returns the resolver configured with WithResolver, or the
+// default one.
+func (c *Client) lookupResolver() *net.Resolver {
+	if c.resolver != nil {
+		return c.resolver
+	}
+	return net.DefaultResolver
+}
+
+// dial connects to hostname:port, through a proxy if one is configured.
+func (c *Client) dial(ctx context.Context, hostname string, port int) (net.Conn, error) {
+	if c.proxy != nil {
+		proxyURL, err := c.proxy(hostname, port)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			return c.dialProxy(ctx, proxyURL, hostname, port)
+		}
+	}
+	return c.dialDirect(ctx, hostname, port)
+}
+
+// dialDirect connects to hostname:port. When the hostname resolves to several
+// addresses, attempts are started in Happy Eyeballs order with a staggered
+// delay and the first successful connection wins.
+func (c *Client) dialDirect(ctx context.Context, hostname string, port int) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:  c.ConnectTimeout,
+		Resolver: c.resolver,
+	}
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(hostname, strconv.Itoa(port)))
+	}
+
+	ipAddrs, err := c.lookupResolver().LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	if len(ipAddrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", hostname)
+	}
+
+	addrs := interleaveAddrs(ipAddrs)
+	if len(addrs) == 1 {
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(addrs[0].String(), strconv.Itoa(port)))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan dialResult, len(addrs))
+
+	attempt := func(addr net.IPAddr) {
+		address := net.JoinHostPort(addr.String(), strconv.Itoa(port))
+		if logger := c.loggerFor(LogDebug); logger != nil {
+			logger.Printf("Dialing %s", address)
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		results <- dialResult{conn, err}
+	}
+
+	// closeRemaining discards connections from attempts still in flight
+	closeRemaining := func(remaining int) {
+		go func() {
+			for i := 0; i < remaining; i++ {
+				if r := <-results; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}()
+	}
+
+	go attempt(addrs[0])
+	started, finished := 1, 0
+
+	var firstErr error
+	for finished < len(addrs) {
+		var stagger <-chan time.Time
+		var timer *time.Timer
+		if started < len(addrs) && c.dialStagger > 0 {
+			timer = time.NewTimer(c.dialStagger)
+			stagger = timer.C
+		}
+
+		select {
+		case res := <-results:
+			finished++
+			if res.err == nil {
+				if timer != nil {
+					timer.Stop()
+				}
+				closeRemaining(started - finished)
+				return res.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			// Start the next attempt immediately when one fails
+			if started < len(addrs) {
+				go attempt(addrs[started])
+				started++
+			}
+		case <-stagger:
+			go attempt(addrs[started])
+			started++
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			closeRemaining(started - finished)
+			return nil, ctx.Err()
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	return nil, firstErr
+}
+
+// interleaveAddrs orders addresses alternating between IPv6 and IPv4,
+// starting with the family of the first address returned by the resolver.
+func interleaveAddrs(addrs []net.IPAddr) []net.IPAddr {
+	var primary, fallback []net.IPAddr
+	firstIsV4 := addrs[0].IP.To4() != nil
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == firstIsV4 {
+			primary = append(primary, addr)
+		} else {
+			fallback = append(fallback, addr)
+		}
+	}
+
+	ordered := make([]net.IPAddr, 0, len(addrs))
+	for len(primary) > 0 || len(fallback) > 0 {
+		if len(primary) > 0 {
+			ordered = append(ordered, primary[0])
+			primary = primary[1:]
+		}
+		if len(fallback) > 0 {
+			ordered = append(ordered, fallback[0])
+			fallback = fallback[1:]
+		}
+	}
+	return ordered
+}
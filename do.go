@@ -0,0 +1,78 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Request is a raw protocol command: a verb followed by arguments, which are
+// quoted as needed when sent.
+type Request struct {
+	Verb string   // e.g. "GET" or a vendor-specific verb
+	Args []string // e.g. ["VAR", "myups", "ups.status"]
+}
+
+// String returns the command line as sent to upsd, without the newline.
+func (r Request) String() string {
+	parts := make([]string, 0, len(r.Args)+1)
+	parts = append(parts, r.Verb)
+	for _, arg := range r.Args {
+		if arg == "" {
+			arg = `""`
+		} else {
+			arg = quoteName(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Response is the raw response to a Request.
+type Response struct {
+	Lines []string // Response lines without newlines, including BEGIN/END LIST
+}
+
+// Body returns the lines of a LIST response between BEGIN LIST and END LIST,
+// or all lines of other responses.
+func (r Response) Body() []string {
+	lines := r.Lines
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "BEGIN LIST ") {
+		lines = lines[1:]
+	}
+	if n := len(lines); n > 0 && strings.HasPrefix(lines[n-1], "END LIST ") {
+		lines = lines[:n-1]
+	}
+	return lines
+}
+
+// Fields splits line i of the response into words, unquoting quoted strings,
+// e.g. ["VAR", "myups", "ups.status", "OL"].
+func (r Response) Fields(i int) ([]string, error) {
+	if i < 0 || i >= len(r.Lines) {
+		return nil, fmt.Errorf("response has no line %d", i)
+	}
+	return splitFields(r.Lines[i], false)
+}
+
+// Do sends a raw request and returns the response, for commands the typed API
+// does not cover, such as new protocol verbs or vendor extensions. The request
+// is subject to the same queueing, rate limiting, metrics, logging and error
+// mapping as other commands; ERR responses are returned as a *ProtocolError.
+// Requests with the LIST verb are read up to the matching END LIST line; all
+// others are expected to answer with a single line.
+func (c *Client) Do(ctx context.Context, req Request) (Response, error) {
+	if req.Verb == "" || strings.ContainsAny(req.Verb, " \"\n") {
+		return Response{}, fmt.Errorf("invalid verb %q", req.Verb)
+	}
+	for _, arg := range req.Args {
+		if strings.Contains(arg, "\n") {
+			return Response{}, fmt.Errorf("invalid argument %q: contains a newline", arg)
+		}
+	}
+	lines, err := c.SendCommandWithContext(ctx, req.String())
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Lines: lines}, nil
+}
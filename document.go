@@ -0,0 +1,852 @@
+package nut
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The module depends only on the standard library, so configuration files and
+// snapshots are read with small parsers for the commonly used subsets of YAML
+// and TOML. Both return documents as the generic values of encoding/json
+// (maps, slices, strings, bools and nil), except that unquoted scalars are
+// kept as plainScalar until decodeDocument types them by the field they are
+// decoded into, so that "password: 12345678" reads as a string.
+
+// plainScalar is the text of an unquoted scalar.
+type plainScalar string
+
+// parseDocument parses a YAML or TOML document.
+func parseDocument(data string, format Format) (interface{}, error) {
+	switch format {
+	case FormatYAML:
+		return parseYAMLDocument(data)
+	case FormatTOML:
+		return parseTOMLDocument(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// decodeDocument decodes a parsed document into target, a pointer, by a JSON
+// round trip. Unknown keys are errors.
+func decodeDocument(document, target interface{}) error {
+	encoded, err := json.Marshal(typeScalars(document, reflect.TypeOf(target)))
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.DisallowUnknownFields()
+	return dec.Decode(target)
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// typeScalars replaces the plain scalars of value, which is decoded into a
+// value of type t, with strings where t expects a string and with the typed
+// value of the scalar elsewhere. t may be nil when it is unknown.
+func typeScalars(value interface{}, t reflect.Type) interface{} {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case plainScalar:
+		if t != nil && t.Kind() == reflect.String && !reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+			if parseDocumentScalar(string(v)) == nil {
+				return nil
+			}
+			return string(v)
+		}
+		return parseDocumentScalar(string(v))
+	case map[string]interface{}:
+		for key, item := range v {
+			var itemType reflect.Type
+			switch {
+			case t == nil:
+			case t.Kind() == reflect.Map:
+				itemType = t.Elem()
+			case t.Kind() == reflect.Struct:
+				itemType = jsonFieldType(t, key)
+			}
+			v[key] = typeScalars(item, itemType)
+		}
+	case []interface{}:
+		var itemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			itemType = t.Elem()
+		}
+		for i, item := range v {
+			v[i] = typeScalars(item, itemType)
+		}
+	}
+	return value
+}
+
+// jsonFieldType returns the type of the field of struct type t that
+// encoding/json decodes key into, or nil.
+func jsonFieldType(t reflect.Type, key string) reflect.Type {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found := jsonFieldType(embedded, key); found != nil {
+					return found
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// parseDocumentScalar types an unquoted scalar: booleans, null, integers and
+// floats; anything else is a string.
+func parseDocumentScalar(s string) interface{} {
+	switch s {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case "", "null", "Null", "NULL", "~":
+		return nil
+	}
+	if i, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), 0, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// yamlLine is a non-empty line of a YAML document.
+type yamlLine struct {
+	no     int // Line number, from 1
+	indent int
+	text   string // Without indentation
+}
+
+// parseYAMLDocument parses a YAML document made of block mappings, block
+// sequences, flow sequences of scalars, "{}", quoted and plain scalars and
+// literal ("|") or folded (">") block scalars. Anchors, tags and multiple
+// documents are not supported.
+func parseYAMLDocument(data string) (interface{}, error) {
+	raw := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	p := &yamlParser{raw: raw}
+	for i, line := range raw {
+		text := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{no: i + 1, indent: len(line) - len(text), text: strings.TrimRight(text, " ")})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	value, next, err := p.block(0, p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[next].no)
+	}
+	return value, nil
+}
+
+type yamlParser struct {
+	raw   []string
+	lines []yamlLine
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or sequence starting at line i with the given
+// indentation and returns the index of the first line after it.
+func (p *yamlParser) block(i, indent int) (interface{}, int, error) {
+	if isYAMLSequenceItem(p.lines[i].text) {
+		return p.sequence(i, indent)
+	}
+	return p.mapping(i, indent)
+}
+
+func (p *yamlParser) sequence(i, indent int) (interface{}, int, error) {
+	items := []interface{}{}
+	for i < len(p.lines) && p.lines[i].indent == indent && isYAMLSequenceItem(p.lines[i].text) {
+		line := p.lines[i]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		switch {
+		case rest == "":
+			if i+1 < len(p.lines) && p.lines[i+1].indent > indent {
+				value, next, err := p.block(i+1, p.lines[i+1].indent)
+				if err != nil {
+					return nil, 0, err
+				}
+				items, i = append(items, value), next
+				continue
+			}
+			items, i = append(items, nil), i+1
+		case isYAMLSequenceItem(rest) || isYAMLMappingEntry(rest):
+			// The item is a block starting on the same line as the dash
+			p.lines[i] = yamlLine{no: line.no, indent: indent + len(line.text) - len(rest), text: rest}
+			value, next, err := p.block(i, p.lines[i].indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			items, i = append(items, value), next
+		default:
+			value, next, err := p.scalar(i, rest, indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			items, i = append(items, value), next
+		}
+	}
+	return items, i, nil
+}
+
+// isYAMLMappingEntry reports whether text starts with a "key:" entry.
+func isYAMLMappingEntry(text string) bool {
+	if text[0] == '[' || text[0] == '{' {
+		return false
+	}
+	_, _, err := splitYAMLKey(text)
+	return err == nil
+}
+
+// splitYAMLKey splits a "key: value" entry, where the colon must be followed
+// by a space or the end of the line.
+func splitYAMLKey(text string) (key, rest string, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		return splitDocumentKey(text, ":")
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), nil
+		}
+		if text[i] == ' ' && i+1 < len(text) && text[i+1] == '#' {
+			break
+		}
+	}
+	return "", "", fmt.Errorf("expected key: value")
+}
+
+func (p *yamlParser) mapping(i, indent int) (interface{}, int, error) {
+	values := map[string]interface{}{}
+	for i < len(p.lines) && p.lines[i].indent == indent {
+		line := p.lines[i]
+		if isYAMLSequenceItem(line.text) {
+			return nil, 0, fmt.Errorf("line %d: unexpected sequence item", line.no)
+		}
+		key, rest, err := splitYAMLKey(line.text)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line.no, err)
+		}
+		if _, ok := values[key]; ok {
+			return nil, 0, fmt.Errorf("line %d: duplicate key %q", line.no, key)
+		}
+
+		if rest == "" || strings.HasPrefix(rest, "#") {
+			// A nested block, which may be a sequence at the same indentation
+			if next := i + 1; next < len(p.lines) && (p.lines[next].indent > indent ||
+				(p.lines[next].indent == indent && isYAMLSequenceItem(p.lines[next].text))) {
+				value, after, err := p.block(next, p.lines[next].indent)
+				if err != nil {
+					return nil, 0, err
+				}
+				values[key], i = value, after
+				continue
+			}
+			values[key], i = nil, i+1
+			continue
+		}
+		value, next, err := p.scalar(i, rest, indent)
+		if err != nil {
+			return nil, 0, err
+		}
+		values[key], i = value, next
+	}
+	if i < len(p.lines) && p.lines[i].indent > indent {
+		return nil, 0, fmt.Errorf("line %d: unexpected indentation", p.lines[i].no)
+	}
+	return values, i, nil
+}
+
+// scalar parses the value text of line i, whose parent has the given
+// indentation, and returns the index of the next line.
+func (p *yamlParser) scalar(i int, text string, indent int) (interface{}, int, error) {
+	line := p.lines[i]
+	switch text[0] {
+	case '|', '>':
+		return p.blockScalar(i, text, indent)
+	case '"', '\'':
+		value, err := parseDocumentValue(text)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line.no, err)
+		}
+		return value, i + 1, nil
+	case '[':
+		value, err := parseYAMLFlowSequence(text)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", line.no, err)
+		}
+		return value, i + 1, nil
+	case '{':
+		if stripYAMLComment(text) != "{}" {
+			return nil, 0, fmt.Errorf("line %d: flow mappings other than {} are not supported", line.no)
+		}
+		return map[string]interface{}{}, i + 1, nil
+	}
+	return plainScalar(stripYAMLComment(text)), i + 1, nil
+}
+
+// blockScalar reads a literal or folded block scalar from the raw lines
+// indented deeper than the parent.
+func (p *yamlParser) blockScalar(i int, header string, indent int) (interface{}, int, error) {
+	start := p.lines[i].no // Raw index of the first content line
+	var content []string
+	end := start
+	for ; end < len(p.raw); end++ {
+		line := p.raw[end]
+		if strings.TrimSpace(line) != "" && len(line)-len(strings.TrimLeft(line, " ")) <= indent {
+			break
+		}
+		content = append(content, line)
+	}
+	for len(content) > 0 && strings.TrimSpace(content[len(content)-1]) == "" {
+		content = content[:len(content)-1]
+	}
+
+	blockIndent := -1
+	for _, line := range content {
+		if strings.TrimSpace(line) != "" {
+			if n := len(line) - len(strings.TrimLeft(line, " ")); blockIndent < 0 || n < blockIndent {
+				blockIndent = n
+			}
+		}
+	}
+	for j, line := range content {
+		if len(line) >= blockIndent && blockIndent >= 0 {
+			content[j] = line[blockIndent:]
+		} else {
+			content[j] = ""
+		}
+	}
+
+	var value string
+	if header[0] == '|' {
+		value = strings.Join(content, "\n")
+	} else {
+		value = strings.Join(strings.Fields(strings.Join(content, " ")), " ")
+	}
+	if !strings.HasPrefix(stripYAMLComment(header[1:]), "-") && len(content) > 0 {
+		value += "\n"
+	}
+
+	// Skip the parsed lines that belonged to the block
+	next := i + 1
+	for next < len(p.lines) && p.lines[next].no <= end {
+		next++
+	}
+	return value, next, nil
+}
+
+// parseYAMLFlowSequence parses a single-line flow sequence of scalars.
+func parseYAMLFlowSequence(text string) ([]interface{}, error) {
+	items := []interface{}{}
+	rest := strings.TrimSpace(text[1:])
+	for {
+		if rest == "" {
+			return nil, fmt.Errorf("unterminated sequence")
+		}
+		if rest[0] == ']' {
+			if tail := stripYAMLComment(rest[1:]); tail != "" {
+				return nil, fmt.Errorf("unexpected %q after sequence", tail)
+			}
+			return items, nil
+		}
+
+		var item interface{}
+		if rest[0] == '"' || rest[0] == '\'' {
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			value, err := parseDocumentValue(rest[:end+1])
+			if err != nil {
+				return nil, err
+			}
+			item, rest = value, strings.TrimSpace(rest[end+1:])
+		} else {
+			end := strings.IndexAny(rest, ",]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated sequence")
+			}
+			item, rest = plainScalar(strings.TrimSpace(rest[:end])), rest[end:]
+		}
+		items = append(items, item)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, fmt.Errorf("expected , or ] in sequence")
+		}
+	}
+}
+
+// stripYAMLComment removes a trailing comment from a plain scalar.
+func stripYAMLComment(text string) string {
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = text[:i]
+	}
+	return strings.TrimSpace(text)
+}
+
+// parseTOMLDocument parses a TOML document with tables, arrays of tables,
+// dotted keys, inline tables, arrays and basic, literal and multi-line
+// strings. Dates and times are returned as strings.
+func parseTOMLDocument(data string) (map[string]interface{}, error) {
+	p := &tomlParser{s: strings.ReplaceAll(data, "\r\n", "\n"), line: 1}
+	root := map[string]interface{}{}
+	table := root
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.s) {
+			return root, nil
+		}
+
+		if p.s[p.pos] == '[' {
+			array := strings.HasPrefix(p.s[p.pos:], "[[")
+			p.pos++
+			if array {
+				p.pos++
+			}
+			path, err := p.keyPath()
+			if err != nil {
+				return nil, err
+			}
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			if !strings.HasPrefix(p.s[p.pos:], closing) {
+				return nil, p.errorf("expected %s", closing)
+			}
+			p.pos += len(closing)
+			if table, err = tomlTable(root, path, array); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		path, err := p.keyPath()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+			return nil, p.errorf("expected =")
+		}
+		p.pos++
+		p.skipSpaces()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := tomlSet(table, path, value); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tomlTable returns the table named by path, creating it, or appends a new
+// table to the array of tables named by path.
+func tomlTable(root map[string]interface{}, path []string, array bool) (map[string]interface{}, error) {
+	table := root
+	for i, key := range path {
+		last := i == len(path)-1
+		switch existing := table[key].(type) {
+		case nil:
+			if last && array {
+				next := map[string]interface{}{}
+				table[key] = []interface{}{next}
+				return next, nil
+			}
+			next := map[string]interface{}{}
+			table[key] = next
+			table = next
+		case map[string]interface{}:
+			if last && array {
+				return nil, fmt.Errorf("%s is a table, not an array of tables", strings.Join(path, "."))
+			}
+			table = existing
+		case []interface{}:
+			if len(existing) == 0 {
+				return nil, fmt.Errorf("%s is not a table", strings.Join(path[:i+1], "."))
+			}
+			if last && array {
+				next := map[string]interface{}{}
+				table[key] = append(existing, next)
+				return next, nil
+			}
+			// Refers to the latest table of the array
+			next, ok := existing[len(existing)-1].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s is not a table", strings.Join(path[:i+1], "."))
+			}
+			table = next
+		default:
+			return nil, fmt.Errorf("%s is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+// tomlSet sets a dotted key in table.
+func tomlSet(table map[string]interface{}, path []string, value interface{}) error {
+	for _, key := range path[:len(path)-1] {
+		next, ok := table[key].(map[string]interface{})
+		if !ok {
+			if table[key] != nil {
+				return fmt.Errorf("%s is not a table", key)
+			}
+			next = map[string]interface{}{}
+			table[key] = next
+		}
+		table = next
+	}
+	key := path[len(path)-1]
+	if _, ok := table[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	table[key] = value
+	return nil
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) skipSpaces() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine consumes spaces, an optional comment and the newline.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if p.pos < len(p.s) && p.s[p.pos] == '#' {
+		for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+			p.pos++
+		}
+	}
+	if p.pos < len(p.s) {
+		if p.s[p.pos] != '\n' {
+			return p.errorf("unexpected %q", p.s[p.pos:strings.IndexByte(p.s[p.pos:]+"\n", '\n')+p.pos])
+		}
+		p.pos++
+		p.line++
+	}
+	return nil
+}
+
+// keyPath parses a possibly dotted key of bare or quoted parts.
+func (p *tomlParser) keyPath() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.s) {
+			return nil, p.errorf("expected key")
+		}
+		switch p.s[p.pos] {
+		case '"', '\'':
+			value, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, value)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isTOMLBareKeyChar(p.s[p.pos]) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, p.errorf("invalid key")
+			}
+			path = append(path, p.s[start:p.pos])
+		}
+		p.skipSpaces()
+		if p.pos >= len(p.s) || p.s[p.pos] != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+func isTOMLBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("expected value")
+	}
+	switch p.s[p.pos] {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		p.pos++
+		items := []interface{}{}
+		for {
+			p.skipBlank()
+			if p.pos < len(p.s) && p.s[p.pos] == ']' {
+				p.pos++
+				return items, nil
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			p.skipBlank()
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != ']' {
+				return nil, p.errorf("expected , or ] in array")
+			}
+		}
+	case '{':
+		p.pos++
+		table := map[string]interface{}{}
+		for {
+			p.skipSpaces()
+			if p.pos < len(p.s) && p.s[p.pos] == '}' {
+				p.pos++
+				return table, nil
+			}
+			path, err := p.keyPath()
+			if err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+				return nil, p.errorf("expected =")
+			}
+			p.pos++
+			p.skipSpaces()
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if err := tomlSet(table, path, value); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			p.skipSpaces()
+			if p.pos < len(p.s) && p.s[p.pos] == ',' {
+				p.pos++
+			} else if p.pos >= len(p.s) || p.s[p.pos] != '}' {
+				return nil, p.errorf("expected , or } in inline table")
+			}
+		}
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(",]}#\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	raw := strings.TrimSpace(p.s[start:p.pos])
+	if parseDocumentScalar(raw) == nil {
+		return nil, p.errorf("invalid value %q", raw)
+	}
+	return plainScalar(raw), nil
+}
+
+// str parses a basic, literal or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	quote := p.s[p.pos]
+	if strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(quote), 3)) {
+		delim := strings.Repeat(string(quote), 3)
+		end := strings.Index(p.s[p.pos+3:], delim)
+		if end < 0 {
+			return "", p.errorf("unterminated string")
+		}
+		body := p.s[p.pos+3 : p.pos+3+end]
+		p.line += strings.Count(body, "\n")
+		p.pos += 3 + end + 3
+		body = strings.TrimPrefix(body, "\n")
+		if quote == '\'' {
+			return body, nil
+		}
+		value, err := unescapeTOMLMultiline(body)
+		if err != nil {
+			return "", p.errorf("invalid string: %v", err)
+		}
+		return value, nil
+	}
+
+	end := strings.IndexByte(p.s[p.pos+1:], quote)
+	if quote == '"' {
+		end = closingQuote(p.s[p.pos:]) - 1
+	}
+	if end < 0 || strings.Contains(p.s[p.pos:p.pos+1+end], "\n") {
+		return "", p.errorf("unterminated string")
+	}
+	raw := p.s[p.pos : p.pos+end+2]
+	p.pos += end + 2
+	if quote == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	value, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", p.errorf("invalid string %s", raw)
+	}
+	return value, nil
+}
+
+// unescapeTOMLMultiline processes the escapes of a multi-line basic string,
+// including line-ending backslashes, which trim the following whitespace.
+func unescapeTOMLMultiline(body string) (string, error) {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\\' && strings.TrimLeft(body[i+1:strings.IndexByte(body[i:]+"\n", '\n')+i], " \t") == "":
+			// Line-ending backslash
+			for i+1 < len(body) && strings.IndexByte(" \t\n", body[i+1]) >= 0 {
+				i++
+			}
+		case c == '\\' && i+1 < len(body):
+			quoted.WriteByte(c)
+			quoted.WriteByte(body[i+1])
+			i++
+		case c == '"':
+			quoted.WriteString(`\"`)
+		case c == '\n':
+			quoted.WriteString(`\n`)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+	return strconv.Unquote(quoted.String())
+}
+
+// splitDocumentKey splits a "key: value" or "key = value" line, where the key
+// may be quoted. rest is the trimmed text after the separator.
+func splitDocumentKey(line, separator string) (key, rest string, err error) {
+	if line[0] == '"' || line[0] == '\'' {
+		end := closingQuote(line)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated key")
+		}
+		if key, err = parseDocumentValue(line[:end+1]); err != nil {
+			return "", "", err
+		}
+		line = strings.TrimSpace(line[end+1:])
+		if !strings.HasPrefix(line, separator) {
+			return "", "", fmt.Errorf("missing %q after key", separator)
+		}
+		return key, strings.TrimSpace(line[len(separator):]), nil
+	}
+	key, rest, ok := strings.Cut(line, separator)
+	if !ok {
+		return "", "", fmt.Errorf("missing %q", separator)
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(rest), nil
+}
+
+// parseDocumentValue parses a scalar: a double-quoted string with escapes, a
+// single-quoted string, or a bare value up to a comment.
+func parseDocumentValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	switch s[0] {
+	case '"':
+		end := closingQuote(s)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strconv.Unquote(s[:end+1])
+	case '\'':
+		end := closingQuote(s)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strings.ReplaceAll(s[1:end], "''", "'"), nil
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// closingQuote returns the index of the quote closing the string starting at
+// s[0], or -1.
+func closingQuote(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++ // Escaped quote in a YAML single-quoted string
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
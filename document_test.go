@@ -0,0 +1,255 @@
+package nut_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestParseConfigScalars(t *testing.T) {
+	tests := []struct {
+		name   string
+		format nut.Format
+		data   string
+	}{
+		{"yaml plain", nut.FormatYAML, `
+interval: 5
+credentials:
+  monitor:
+    username: upsmon
+    password: 12345678
+    starttls: true
+endpoints:
+  - host: nut1.example.com
+    port: 3494
+    credentials: monitor
+    ups: [1500, rack2]
+notifiers:
+  - type: telegram
+    token: 0123
+    chat_ids: [-100123456, 42]
+`},
+		{"yaml quoted", nut.FormatYAML, `
+interval: "5s"
+credentials:
+  monitor:
+    username: "upsmon"
+    password: '12345678'
+    starttls: true
+endpoints:
+  - host: "nut1.example.com"
+    port: 3494
+    credentials: monitor
+    ups: ["1500", 'rack2']
+notifiers:
+  - type: telegram
+    token: "0123"
+    chat_ids: ["-100123456", "42"]
+`},
+		{"yaml block sequences", nut.FormatYAML, `
+interval: 5s # seconds
+credentials:
+  monitor:
+    username: 'upsmon'
+    password: "12345678"
+    starttls: True
+endpoints:
+- host: nut1.example.com
+  port: 3494
+  credentials: monitor
+  ups:
+    - 1500
+    - rack2
+notifiers:
+- type: telegram
+  token: "0123"
+  chat_ids:
+  - -100123456
+  - 42
+`},
+		{"toml plain", nut.FormatTOML, `
+interval = 5
+
+[credentials.monitor]
+username = "upsmon"
+password = 12345678
+starttls = true
+
+[[endpoints]]
+host = "nut1.example.com"
+port = 3494
+credentials = "monitor"
+ups = [1500, "rack2"]
+
+[[notifiers]]
+type = "telegram"
+token = 0123
+chat_ids = [-100123456, 42]
+`},
+	}
+
+	want := nut.Config{
+		Interval:    nut.ConfigDuration(5 * time.Second),
+		Credentials: map[string]nut.CredentialConfig{"monitor": {Username: "upsmon", Password: "12345678", StartTLS: true}},
+		Endpoints:   []nut.EndpointConfig{{Host: "nut1.example.com", Port: 3494, Credentials: "monitor", UPS: []string{"1500", "rack2"}}},
+		Notifiers:   []nut.NotifierConfig{{Type: "telegram", Token: "0123", ChatIDs: []string{"-100123456", "42"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := nut.ParseConfig([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*config, want) {
+				t.Fatalf("got %+v\nwant %+v", *config, want)
+			}
+		})
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format nut.Format
+		data   string
+		want   string
+	}{
+		{"unknown key", nut.FormatYAML, "intervall: 5s\n", "unknown field"},
+		{"unknown nested key", nut.FormatTOML, "[[endpoints]]\nhots = \"nut1\"\n", "unknown field"},
+		{"number for bool", nut.FormatYAML, "credentials:\n  monitor:\n    username: a\n    starttls: 1\n", "cannot unmarshal"},
+		{"text for int", nut.FormatTOML, "event_buffer = many\n", "cannot unmarshal"},
+		{"bad duration", nut.FormatYAML, "interval: soon\n", "invalid duration"},
+		{"tab indentation", nut.FormatYAML, "credentials:\n\tmonitor: {}\n", "line 2"},
+		{"duplicate key", nut.FormatYAML, "interval: 5s\ninterval: 6s\n", "line 2: duplicate key"},
+		{"unterminated string", nut.FormatTOML, "interval = \"5s\n", "line 1: unterminated string"},
+		{"missing value", nut.FormatTOML, "interval =\n", "line 1: invalid value"},
+		{"flow mapping", nut.FormatYAML, "credentials: {monitor: {}}\n", "line 1: flow mappings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := nut.ParseConfig([]byte(tt.data), tt.format)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfigNullString(t *testing.T) {
+	config, err := nut.ParseConfig([]byte("credentials:\n  monitor:\n    username: ~\n    password: null\n"), nut.FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds := config.Credentials["monitor"]; creds.Username != "" || creds.Password != "" {
+		t.Fatalf("null read as %+v", creds)
+	}
+}
+
+func TestParseConfigBlockScalars(t *testing.T) {
+	data := `
+notifiers:
+  - type: webhook
+    urls: [https://example.com/hook]
+    body: |
+      {"ups": {{ json .UPS }}}
+    message: >
+      UPS {{ .UPS }}
+      is on battery
+`
+	config, err := nut.ParseConfig([]byte(data), nut.FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier := config.Notifiers[0]
+	if notifier.Body != "{\"ups\": {{ json .UPS }}}\n" || notifier.Message != "UPS {{ .UPS }} is on battery\n" {
+		t.Fatalf("body = %q, message = %q", notifier.Body, notifier.Message)
+	}
+	if len(notifier.URLs) != 1 || notifier.URLs[0] != "https://example.com/hook" {
+		t.Fatalf("urls = %q", notifier.URLs)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	snapshot := nut.Snapshot{
+		Time:        time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC),
+		Server:      "nut1.example.com:3493",
+		UPS:         "ups1",
+		Description: `Rack "A" UPS`,
+		Status:      nut.ParseStatus("OB LB"),
+		Variables:   map[string]string{"ups.status": "OB LB", "battery.charge": "20", "device.serial": "0012"},
+		Derived:     map[string]string{"battery.runtime.estimate": "300"},
+		Commands:    []string{"beeper.disable", "test.battery.start"},
+		Clients:     []string{"10.0.0.2"},
+	}
+	for _, format := range []nut.Format{nut.FormatJSON, nut.FormatYAML, nut.FormatTOML} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := snapshot.Export(&buf, format); err != nil {
+				t.Fatal(err)
+			}
+			got, err := nut.ReadSnapshot(&buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Time.Equal(snapshot.Time) {
+				t.Fatalf("time = %v, want %v", got.Time, snapshot.Time)
+			}
+			got.Time = snapshot.Time
+			if !reflect.DeepEqual(got, snapshot) {
+				t.Fatalf("got %+v\nwant %+v", got, snapshot)
+			}
+		})
+	}
+}
+
+func TestReadHandEditedSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		format nut.Format
+		data   string
+	}{
+		{"yaml", nut.FormatYAML, `
+# Captured before the battery swap
+time: 2026-03-01T12:30:00Z
+ups: ups1
+variables:
+  battery.charge: 100   # plain number
+  device.serial: 0012
+  ups.status: OL
+commands: [beeper.disable]
+`},
+		{"toml", nut.FormatTOML, `
+time = 2026-03-01T12:30:00Z
+ups = "ups1"
+commands = ["beeper.disable"]
+
+[variables]
+"battery.charge" = 100
+"device.serial" = "0012"
+"ups.status" = 'OL'
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nut.ReadSnapshot(strings.NewReader(tt.data), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{"battery.charge": "100", "device.serial": "0012", "ups.status": "OL"}
+			if got.UPS != "ups1" || !reflect.DeepEqual(got.Variables, want) || !got.Status.Has(nut.StatusOnline) ||
+				len(got.Commands) != 1 || !got.Time.Equal(time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)) {
+				t.Fatalf("unexpected snapshot %+v", got)
+			}
+		})
+	}
+}
+
+func TestReadSnapshotUnknownKey(t *testing.T) {
+	if _, err := nut.ReadSnapshot(strings.NewReader("ups: ups1\nmodel: X\n"), nut.FormatYAML); err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+}
@@ -0,0 +1,97 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Backoff between retries of commands failing with DRIVER-NOT-CONNECTED.
+const (
+	driverRetryInitialDelay = 250 * time.Millisecond
+	driverRetryMaxDelay     = 5 * time.Second
+)
+
+// driverWaitInterval is the polling interval of UPS.WaitForDriver.
+const driverWaitInterval = 500 * time.Millisecond
+
+// WithDriverRetry makes commands failing with DRIVER-NOT-CONNECTED, as upsd
+// answers while a driver restarts, wait and retry for up to maxWait in total.
+// The delay between attempts starts at 250ms and doubles up to 5s. Other
+// errors are returned immediately, and the connection is released between
+// attempts so that other commands are not held up.
+func WithDriverRetry(maxWait time.Duration) ClientOption {
+	return func(c *Client) {
+		c.driverRetryWait = maxWait
+	}
+}
+
+// sendCommandRetryingDriver sends a command, retrying it while upsd reports
+// DRIVER-NOT-CONNECTED until the retry budget or ctx runs out.
+func (c *Client) sendCommandRetryingDriver(ctx context.Context, cmd string) ([]string, error) {
+	clock := clockOrSystem(c.clock)
+	deadline := clock.Now().Add(c.driverRetryWait)
+	delay := driverRetryInitialDelay
+	for {
+		resp, err := c.sendCommandOnce(ctx, cmd)
+		if err == nil || !hasErrorCode(err, ErrCodeDriverNotConnected) {
+			return resp, err
+		}
+		remaining := deadline.Sub(clock.Now())
+		if remaining <= 0 {
+			return resp, err
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		if logger := c.loggerFor(LogDebug); logger != nil {
+			logger.Printf("Driver not connected, retrying %s in %v", c.redact(cmd), delay)
+		}
+
+		timer := clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C():
+		}
+		if delay *= 2; delay > driverRetryMaxDelay {
+			delay = driverRetryMaxDelay
+		}
+	}
+}
+
+// WaitForDriver blocks until upsd serves fresh data for the UPS, i.e. its
+// driver is connected and ups.status no longer fails with DRIVER-NOT-CONNECTED
+// or DATA-STALE. It polls ups.status with PollStatus every 500ms. Other errors
+// are returned immediately; if ctx is done first, its error is returned along
+// with the last reason the driver was not ready.
+func (u *UPS) WaitForDriver(ctx context.Context) error {
+	clock := clockOrSystem(u.nutClient.clock)
+	for {
+		_, err := u.PollStatus(ctx)
+		if err == nil {
+			return nil
+		}
+		if !driverNotReady(err) {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+		}
+
+		timer := clock.NewTimer(driverWaitInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("waiting for driver of %s: %w (last error: %v)", u.Name, ctx.Err(), err)
+		case <-timer.C():
+		}
+	}
+}
+
+// driverNotReady reports whether err means that upsd has no fresh data from
+// the UPS's driver yet.
+func driverNotReady(err error) bool {
+	return hasErrorCode(err, ErrCodeDriverNotConnected) || hasErrorCode(err, ErrCodeDataStale)
+}
@@ -0,0 +1,187 @@
+package nut
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Default templates of an EmailNotifier; see MessageTemplate.
+const (
+	DefaultEmailSubject = `[{{.Type}}] {{with .UPS}}{{.}}{{else}}{{.Server}}{{end}}{{with .Alert}}: {{.}}{{end}}`
+	DefaultEmailBody    = `Time:   {{.Time.Format "2006-01-02 15:04:05 MST"}}
+Event:  {{.Type}}
+{{with .Server}}Server: {{.}}
+{{end}}{{with .UPS}}UPS:    {{.}}
+{{end}}{{with .Alert}}Alert:  {{.}}
+{{end}}{{with .Variable}}Variable: {{.}} = {{$.NewValue}}{{with $.OldValue}} (was {{.}}){{end}}
+{{end}}{{with .Client}}Client: {{.}}
+{{end}}{{with .Err}}Error:  {{.}}
+{{end}}`
+)
+
+// EmailConfig configures an EmailNotifier.
+type EmailConfig struct {
+	Addr        string         // SMTP server as host:port
+	From        string         // Envelope and header sender
+	To          []string       // Recipients
+	Username    string         // Optional username for PLAIN authentication
+	Password    string         // Optional password for PLAIN authentication
+	ImplicitTLS bool           // Connect with TLS (port 465) instead of plain SMTP
+	StartTLS    bool           // Require STARTTLS on a plain connection
+	TLSConfig   *tls.Config    // TLS settings; ServerName defaults to the host of Addr
+	Subject     string         // MessageTemplate for the subject (default DefaultEmailSubject)
+	Body        string         // MessageTemplate for the body (default DefaultEmailBody)
+	Snapshots   SnapshotSource // Optional UPS state for the templates
+	Timeout     time.Duration  // Timeout for a whole delivery (default 30s)
+	MaxPerHour  float64        // Mails per hour before ErrRateLimited is returned; 0 is unlimited
+	Burst       int            // Mails that may be sent in a burst under MaxPerHour (default 1)
+}
+
+// EmailNotifier is a Notifier that sends every event as a plain-text mail.
+type EmailNotifier struct {
+	config  EmailConfig
+	host    string
+	subject *MessageTemplate
+	body    *MessageTemplate
+	limiter *rateLimiter
+}
+
+// NewEmailNotifier validates config, parses its templates and returns an
+// EmailNotifier.
+func NewEmailNotifier(config EmailConfig) (*EmailNotifier, error) {
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", config.Addr, err)
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("sender and at least one recipient are required")
+	}
+	if config.Subject == "" {
+		config.Subject = DefaultEmailSubject
+	}
+	if config.Body == "" {
+		config.Body = DefaultEmailBody
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	n := &EmailNotifier{config: config, host: host}
+	if n.subject, err = NewMessageTemplate(config.Subject, config.Snapshots); err != nil {
+		return nil, fmt.Errorf("parsing subject template: %w", err)
+	}
+	if n.body, err = NewMessageTemplate(config.Body, config.Snapshots); err != nil {
+		return nil, fmt.Errorf("parsing body template: %w", err)
+	}
+	if config.MaxPerHour > 0 {
+		n.limiter = newRateLimiter(config.MaxPerHour/3600, config.Burst)
+	}
+	return n, nil
+}
+
+// Notify renders and sends a mail for event. It returns ErrRateLimited without
+// sending if MaxPerHour has been exceeded.
+func (n *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	if n.limiter != nil {
+		if err := n.limiter.wait(ctx, true); err != nil {
+			return err
+		}
+	}
+
+	subject, err := n.subject.Render(event)
+	if err != nil {
+		return fmt.Errorf("rendering subject: %w", err)
+	}
+	body, err := n.body.Render(event)
+	if err != nil {
+		return fmt.Errorf("rendering body: %w", err)
+	}
+	return n.send(ctx, n.message(strings.TrimSpace(subject), body))
+}
+
+// message builds an RFC 5322 message with CRLF line endings.
+func (n *EmailNotifier) message(subject, body string) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(subject, "\n", " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes()
+}
+
+func (n *EmailNotifier) send(ctx context.Context, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	tlsConfig := n.config.TLSConfig.Clone()
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = n.host
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", n.config.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if n.config.ImplicitTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !n.config.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if n.config.StartTLS {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", n.config.Addr)
+		}
+	}
+	if n.config.Username != "" {
+		auth := smtp.PlainAuth("", n.config.Username, n.config.Password, n.host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.config.From); err != nil {
+		return err
+	}
+	for _, rcpt := range n.config.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
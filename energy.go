@@ -0,0 +1,251 @@
+package nut
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultEnergyMaxGap is the longest interval between two samples that
+// EnergyAccumulator integrates over when MaxGap is zero.
+const defaultEnergyMaxGap = 5 * time.Minute
+
+// EnergyTotal is the energy drawn by one UPS's load since Since.
+type EnergyTotal struct {
+	Server    string    `json:"server"`
+	UPS       string    `json:"ups"`
+	WattHours float64   `json:"watt_hours"`
+	Since     time.Time `json:"since"`      // First sample or last reset
+	Last      time.Time `json:"last"`       // Time of the last sample
+	LastWatts float64   `json:"last_watts"` // Real power of the last sample
+}
+
+// KilowattHours returns the total in kWh.
+func (t EnergyTotal) KilowattHours() float64 {
+	return t.WattHours / 1000
+}
+
+// EnergyStore persists energy totals across restarts. Implementations must be
+// safe for concurrent use.
+type EnergyStore interface {
+	LoadEnergy() ([]EnergyTotal, error)
+	SaveEnergy(totals []EnergyTotal) error
+}
+
+// EnergyAccumulator integrates the real power of UPS snapshots over time into
+// energy totals per UPS. Power is read from ups.realpower, or derived from the
+// load and nominal power when the driver does not report it.
+type EnergyAccumulator struct {
+	// MaxGap is the longest interval between consecutive samples of a UPS that
+	// is integrated; longer gaps, e.g. while the server was unreachable, are
+	// skipped. Defaults to 5 minutes.
+	MaxGap time.Duration
+
+	store  EnergyStore
+	mu     sync.Mutex
+	totals map[string]*EnergyTotal
+}
+
+// NewEnergyAccumulator returns an accumulator, restoring totals from store if
+// it is not nil.
+func NewEnergyAccumulator(store EnergyStore) (*EnergyAccumulator, error) {
+	a := &EnergyAccumulator{store: store, totals: map[string]*EnergyTotal{}}
+	if store == nil {
+		return a, nil
+	}
+	totals, err := store.LoadEnergy()
+	if err != nil {
+		return nil, fmt.Errorf("loading energy totals: %w", err)
+	}
+	for i := range totals {
+		total := totals[i]
+		a.totals[energyKey(total.Server, total.UPS)] = &total
+	}
+	return a, nil
+}
+
+// Add integrates snapshots into the totals of their UPSes using the trapezoidal
+// rule. Snapshots without a known real power, or older than the last sample of
+// their UPS, are ignored.
+func (a *EnergyAccumulator) Add(snapshots ...Snapshot) {
+	maxGap := a.MaxGap
+	if maxGap <= 0 {
+		maxGap = defaultEnergyMaxGap
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, snapshot := range snapshots {
+		watts, ok := snapshotWatts(snapshot)
+		if !ok {
+			continue
+		}
+		key := energyKey(snapshot.Server, snapshot.UPS)
+		total, ok := a.totals[key]
+		if !ok {
+			a.totals[key] = &EnergyTotal{
+				Server:    snapshot.Server,
+				UPS:       snapshot.UPS,
+				Since:     snapshot.Time,
+				Last:      snapshot.Time,
+				LastWatts: watts,
+			}
+			continue
+		}
+		if !snapshot.Time.After(total.Last) {
+			continue
+		}
+		if gap := snapshot.Time.Sub(total.Last); gap <= maxGap && !total.Last.IsZero() {
+			total.WattHours += (total.LastWatts + watts) / 2 * gap.Hours()
+		}
+		total.Last = snapshot.Time
+		total.LastWatts = watts
+	}
+}
+
+// Total returns the energy total of a UPS.
+func (a *EnergyAccumulator) Total(server, ups string) (EnergyTotal, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	total, ok := a.totals[energyKey(server, ups)]
+	if !ok {
+		return EnergyTotal{}, false
+	}
+	return *total, true
+}
+
+// Totals returns the totals of all UPSes, sorted by server and UPS name.
+func (a *EnergyAccumulator) Totals() []EnergyTotal {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sortedTotals()
+}
+
+// Reset zeroes the total of a UPS, e.g. at the start of a billing period, and
+// returns its value before the reset.
+func (a *EnergyAccumulator) Reset(server, ups string, now time.Time) EnergyTotal {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	total, ok := a.totals[energyKey(server, ups)]
+	if !ok {
+		return EnergyTotal{}
+	}
+	previous := *total
+	total.WattHours = 0
+	total.Since = now
+	return previous
+}
+
+// Save writes the current totals to the store. It does nothing if the
+// accumulator has no store.
+func (a *EnergyAccumulator) Save() error {
+	if a.store == nil {
+		return nil
+	}
+	a.mu.Lock()
+	totals := a.sortedTotals()
+	a.mu.Unlock()
+	if err := a.store.SaveEnergy(totals); err != nil {
+		return fmt.Errorf("saving energy totals: %w", err)
+	}
+	return nil
+}
+
+func (a *EnergyAccumulator) sortedTotals() []EnergyTotal {
+	totals := make([]EnergyTotal, 0, len(a.totals))
+	for _, total := range a.totals {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Server != totals[j].Server {
+			return totals[i].Server < totals[j].Server
+		}
+		return totals[i].UPS < totals[j].UPS
+	})
+	return totals
+}
+
+func energyKey(server, ups string) string {
+	return server + "/" + ups
+}
+
+// snapshotWatts returns the real power drawn by the load of a snapshot.
+func snapshotWatts(snapshot Snapshot) (float64, bool) {
+	if watts, err := strconv.ParseFloat(snapshot.Variables["ups.realpower"], 64); err == nil {
+		return watts, true
+	}
+	derived := snapshot.Derived
+	if derived == nil {
+		derived = DerivedVariables(snapshot.Variables, snapshot.Time)
+	}
+	watts, err := strconv.ParseFloat(derived[DerivedPrefix+"ups.realpower"], 64)
+	return watts, err == nil
+}
+
+// fileEnergyStore stores energy totals as a JSON file.
+type fileEnergyStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileEnergyStore returns an EnergyStore keeping totals in a JSON file at
+// path. The file is replaced atomically on every save; a missing file loads as
+// no totals.
+func NewFileEnergyStore(path string) EnergyStore {
+	return &fileEnergyStore{path: path}
+}
+
+func (s *fileEnergyStore) LoadEnergy() ([]EnergyTotal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var totals []EnergyTotal
+	if err := json.Unmarshal(data, &totals); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+	return totals, nil
+}
+
+func (s *fileEnergyStore) SaveEnergy(totals []EnergyTotal) error {
+	data, err := json.MarshalIndent(totals, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic replaces the file at path with data by writing a temporary
+// file in the same directory and renaming it.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,59 +1,286 @@
 package nut
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
-// errorForMessage returns an error for the specified NUT error code.
-func errorForMessage(message string) (err error) {
-	switch message {
-	case "ACCESS-DENIED":
-		err = errors.New("client's host and/or authentication details (username, password) are not sufficient to execute the requested command")
-	case "UNKNOWN-UPS":
-		err = errors.New("UPS specified in the request is not known to upsd. This usually means that it didn't match anything in ups.conf")
-	case "VAR-NOT-SUPPORTED":
-		err = errors.New("specified UPS doesn't support the variable in the request. This is also sent for unrecognized variables which are in a space which is handled by upsd, such as server.*")
-	case "CMD-NOT-SUPPORTED":
-		err = errors.New("specified UPS doesn't support the instant command in the request")
-	case "INVALID-ARGUMENT":
-		err = errors.New("client sent an argument to a command which is not recognized or is otherwise invalid in this context. This is typically caused by sending a valid command like GET with an invalid subcommand")
-	case "INSTCMD-FAILED":
-		err = errors.New("upsd failed to deliver the instant command request to the driver. No further information is available to the client. This typically indicates a dead or broken driver")
-	case "SET-FAILED":
-		err = errors.New("upsd failed to deliver the set request to the driver. This is just like INSTCMD-FAILED above")
-	case "READONLY":
-		err = errors.New("requested variable in a SET command is not writable")
-	case "TOO-LONG":
-		err = errors.New("requested value in a SET command is too long")
-	case "FEATURE-NOT-SUPPORTED":
-		err = errors.New("instance of upsd does not support the requested feature. This is only used for TLS/SSL mode (STARTTLS) at the moment")
-	case "FEATURE-NOT-CONFIGURED":
-		err = errors.New("instance of upsd hasn't been configured properly to allow the requested feature to operate. This is also limited to STARTTLS for now")
-	case "ALREADY-SSL-MODE":
-		err = errors.New("TLS/SSL mode is already enabled on this connection, so upsd can't start it again")
-	case "DRIVER-NOT-CONNECTED":
-		err = errors.New("upsd can't perform the requested command, since the driver for that UPS is not connected. This usually means that the driver is not running, or if it is, the ups.conf is misconfigured")
-	case "DATA-STALE":
-		err = errors.New("upsd is connected to the driver for the UPS, but that driver isn't providing regular updates or has specifically marked the data as stale. upsd refuses to provide variables on stale units to avoid false readings. This generally means that the driver is running, but it has lost communications with the hardware. Check the physical connection to the equipment")
-	case "ALREADY-LOGGED-IN":
-		err = errors.New("client already sent LOGIN for a UPS and can't do it again. There is presently a limit of one LOGIN record per connection")
-	case "INVALID-PASSWORD":
-		err = errors.New("client sent an invalid PASSWORD - perhaps an empty one")
-	case "ALREADY-SET-PASSWORD":
-		err = errors.New("client already set a PASSWORD and can't set another. This also should never happen with normal NUT clients")
-	case "INVALID-USERNAME":
-		err = errors.New("client sent an invalid USERNAME")
-	case "ALREADY-SET-USERNAME":
-		err = errors.New("client has already set a USERNAME, and can't set another. This should never happen with normal NUT clients")
-	case "USERNAME-REQUIRED":
-		err = errors.New("requested command requires a username for authentication, but the client hasn't set one")
-	case "PASSWORD-REQUIRED":
-		err = errors.New("requested command requires a password for authentication, but the client hasn't set one")
-	case "UNKNOWN-COMMAND":
-		err = errors.New("upsd doesn't recognize the requested command")
-	case "INVALID-VALUE":
-		err = errors.New("value specified in the request is not valid. This usually applies to a SET of an ENUM type which is using a value which is not in the list of allowed values")
+// ErrorCode is an error code sent by upsd in an "ERR <code>" response.
+type ErrorCode string
+
+// Error codes defined by the NUT network protocol.
+const (
+	ErrCodeAccessDenied         ErrorCode = "ACCESS-DENIED"
+	ErrCodeUnknownUPS           ErrorCode = "UNKNOWN-UPS"
+	ErrCodeVarNotSupported      ErrorCode = "VAR-NOT-SUPPORTED"
+	ErrCodeCmdNotSupported      ErrorCode = "CMD-NOT-SUPPORTED"
+	ErrCodeInvalidArgument      ErrorCode = "INVALID-ARGUMENT"
+	ErrCodeInstCmdFailed        ErrorCode = "INSTCMD-FAILED"
+	ErrCodeSetFailed            ErrorCode = "SET-FAILED"
+	ErrCodeReadOnly             ErrorCode = "READONLY"
+	ErrCodeTooLong              ErrorCode = "TOO-LONG"
+	ErrCodeFeatureNotSupported  ErrorCode = "FEATURE-NOT-SUPPORTED"
+	ErrCodeFeatureNotConfigured ErrorCode = "FEATURE-NOT-CONFIGURED"
+	ErrCodeAlreadySSLMode       ErrorCode = "ALREADY-SSL-MODE"
+	ErrCodeDriverNotConnected   ErrorCode = "DRIVER-NOT-CONNECTED"
+	ErrCodeDataStale            ErrorCode = "DATA-STALE"
+	ErrCodeAlreadyLoggedIn      ErrorCode = "ALREADY-LOGGED-IN"
+	ErrCodeInvalidPassword      ErrorCode = "INVALID-PASSWORD"
+	ErrCodeAlreadySetPassword   ErrorCode = "ALREADY-SET-PASSWORD"
+	ErrCodeInvalidUsername      ErrorCode = "INVALID-USERNAME"
+	ErrCodeAlreadySetUsername   ErrorCode = "ALREADY-SET-USERNAME"
+	ErrCodeUsernameRequired     ErrorCode = "USERNAME-REQUIRED"
+	ErrCodePasswordRequired     ErrorCode = "PASSWORD-REQUIRED"
+	ErrCodeUnknownCommand       ErrorCode = "UNKNOWN-COMMAND"
+	ErrCodeInvalidValue         ErrorCode = "INVALID-VALUE"
+)
+
+// unknownCodeDescription is the description of codes not defined by the protocol.
+const unknownCodeDescription = "unknown error code"
+
+// Description returns a human-readable explanation of the error code.
+func (code ErrorCode) Description() string {
+	switch code {
+	case ErrCodeAccessDenied:
+		return "client's host and/or authentication details (username, password) are not sufficient to execute the requested command"
+	case ErrCodeUnknownUPS:
+		return "UPS specified in the request is not known to upsd. This usually means that it didn't match anything in ups.conf"
+	case ErrCodeVarNotSupported:
+		return "specified UPS doesn't support the variable in the request. This is also sent for unrecognized variables which are in a space which is handled by upsd, such as server.*"
+	case ErrCodeCmdNotSupported:
+		return "specified UPS doesn't support the instant command in the request"
+	case ErrCodeInvalidArgument:
+		return "client sent an argument to a command which is not recognized or is otherwise invalid in this context. This is typically caused by sending a valid command like GET with an invalid subcommand"
+	case ErrCodeInstCmdFailed:
+		return "upsd failed to deliver the instant command request to the driver. No further information is available to the client. This typically indicates a dead or broken driver"
+	case ErrCodeSetFailed:
+		return "upsd failed to deliver the set request to the driver. This is just like INSTCMD-FAILED above"
+	case ErrCodeReadOnly:
+		return "requested variable in a SET command is not writable"
+	case ErrCodeTooLong:
+		return "requested value in a SET command is too long"
+	case ErrCodeFeatureNotSupported:
+		return "instance of upsd does not support the requested feature. This is only used for TLS/SSL mode (STARTTLS) at the moment"
+	case ErrCodeFeatureNotConfigured:
+		return "instance of upsd hasn't been configured properly to allow the requested feature to operate. This is also limited to STARTTLS for now"
+	case ErrCodeAlreadySSLMode:
+		return "TLS/SSL mode is already enabled on this connection, so upsd can't start it again"
+	case ErrCodeDriverNotConnected:
+		return "upsd can't perform the requested command, since the driver for that UPS is not connected. This usually means that the driver is not running, or if it is, the ups.conf is misconfigured"
+	case ErrCodeDataStale:
+		return "upsd is connected to the driver for the UPS, but that driver isn't providing regular updates or has specifically marked the data as stale. upsd refuses to provide variables on stale units to avoid false readings. This generally means that the driver is running, but it has lost communications with the hardware. Check the physical connection to the equipment"
+	case ErrCodeAlreadyLoggedIn:
+		return "client already sent LOGIN for a UPS and can't do it again. There is presently a limit of one LOGIN record per connection"
+	case ErrCodeInvalidPassword:
+		return "client sent an invalid PASSWORD - perhaps an empty one"
+	case ErrCodeAlreadySetPassword:
+		return "client already set a PASSWORD and can't set another. This also should never happen with normal NUT clients"
+	case ErrCodeInvalidUsername:
+		return "client sent an invalid USERNAME"
+	case ErrCodeAlreadySetUsername:
+		return "client has already set a USERNAME, and can't set another. This should never happen with normal NUT clients"
+	case ErrCodeUsernameRequired:
+		return "requested command requires a username for authentication, but the client hasn't set one"
+	case ErrCodePasswordRequired:
+		return "requested command requires a password for authentication, but the client hasn't set one"
+	case ErrCodeUnknownCommand:
+		return "upsd doesn't recognize the requested command"
+	case ErrCodeInvalidValue:
+		return "value specified in the request is not valid. This usually applies to a SET of an ENUM type which is using a value which is not in the list of allowed values"
 	default:
-		err = errors.New("unknown error code")
+		return unknownCodeDescription
+	}
+}
+
+// ProtocolError is an error reported by upsd in an "ERR <code>" response. Codes
+// not defined by the protocol are preserved as-is in Code and Line.
+type ProtocolError struct {
+	code ErrorCode
+	line string // Raw response line, if known
+}
+
+// Code returns the NUT error code.
+func (e *ProtocolError) Code() ErrorCode {
+	return e.code
+}
+
+// Line returns the raw "ERR ..." response line, or an empty string if the error
+// was not created from a server response.
+func (e *ProtocolError) Line() string {
+	return e.line
+}
+
+func (e *ProtocolError) Error() string {
+	description := e.code.Description()
+	if description != unknownCodeDescription {
+		return description
+	}
+	if e.line != "" {
+		return fmt.Sprintf("%s: %s", description, strings.TrimPrefix(e.line, "ERR "))
+	}
+	return fmt.Sprintf("%s: %s", description, e.code)
+}
+
+// ErrorCodeOf returns the NUT error code carried by err, if any.
+func ErrorCodeOf(err error) (ErrorCode, bool) {
+	var perr *ProtocolError
+	if errors.As(err, &perr) {
+		return perr.code, true
+	}
+	return "", false
+}
+
+// hasErrorCode reports whether err is a protocol error with the given code.
+func hasErrorCode(err error, code ErrorCode) bool {
+	c, ok := ErrorCodeOf(err)
+	return ok && c == code
+}
+
+// errorForMessage returns an error for the specified NUT error code.
+func errorForMessage(code ErrorCode) error {
+	return &ProtocolError{code: code}
+}
+
+// errorForResponse returns an error for a raw "ERR <code> [<extra>]" response line.
+func errorForResponse(line string) error {
+	fields := strings.Fields(line)
+	code := ErrCodeUnknownCommand
+	if len(fields) > 1 {
+		code = ErrorCode(fields[1])
 	}
+	return &ProtocolError{code: code, line: line}
+}
+
+// ErrRateLimited is returned when a command exceeds the client-side rate limit
+// configured with WithRateLimit and the limiter is set to fail fast.
+var ErrRateLimited = errors.New("client-side rate limit exceeded")
+
+// ErrDryRun is matched (via errors.Is) by the DryRunError returned by
+// state-changing operations when the client is in dry-run mode.
+var ErrDryRun = errors.New("dry run: command not sent")
+
+// DryRunError is returned by SetVariable, SendCommand and ForceShutdown when the
+// client is in dry-run mode. Command holds the exact line that would have been sent.
+type DryRunError struct {
+	Command string
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("dry run: would have sent %q", e.Command)
+}
+
+// Is reports whether target is ErrDryRun.
+func (e *DryRunError) Is(target error) bool {
+	return target == ErrDryRun
+}
+
+// ErrShutdownNotConfirmed is returned by UPS.Shutdown when it is called without
+// the ConfirmShutdown option.
+var ErrShutdownNotConfirmed = errors.New("shutdown not confirmed: pass ConfirmShutdown() to cut power")
+
+// ErrDestructiveNotAllowed is returned for FSD and for instant commands that
+// cut power to the load, such as shutdown.*, load.off and load.cycle, however
+// they are sent, when the client was not created with WithAllowDestructive.
+var ErrDestructiveNotAllowed = errors.New("destructive command refused: pass WithAllowDestructive() to allow it")
+
+// ErrClosed is returned by operations on a client whose connection has been
+// closed with Close or Disconnect, or after a fatal protocol error.
+var ErrClosed = errors.New("connection closed")
+
+// ErrConnectionBroken is reported to PoolHooks.OnHealthCheckFailed for clients
+// discarded because a command failed mid-exchange; see Client.Broken.
+var ErrConnectionBroken = errors.New("connection broken")
+
+// ErrPoolClosed is returned by Pool.Get once the pool has been closed.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// Authentication failure reasons, matched by *AuthError with errors.Is.
+var (
+	ErrInvalidUsername = errors.New("invalid username")
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrAccessDenied    = errors.New("access denied")
+	ErrAlreadyLoggedIn = errors.New("already logged in")
+)
+
+// AuthError is returned by Authenticate when the server rejects the USERNAME or
+// PASSWORD step.
+type AuthError struct {
+	Step   string    // "USERNAME" or "PASSWORD"
+	Code   ErrorCode // NUT error code, empty for unexpected non-error responses
+	Line   string    // Raw server response line
+	reason error
+	err    error
+}
+
+func (e *AuthError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("authentication failed at %s: unexpected response %q", e.Step, e.Line)
+	}
+	return fmt.Sprintf("authentication failed at %s: %s (%s)", e.Step, e.Code, e.err)
+}
+
+// Is reports whether target is the sentinel describing the failure reason.
+func (e *AuthError) Is(target error) bool {
+	return e.reason != nil && target == e.reason
+}
+
+// Unwrap returns the underlying protocol or connection error.
+func (e *AuthError) Unwrap() error {
+	return e.err
+}
+
+// authError wraps an error from the USERNAME or PASSWORD step. Connection
+// errors are returned unchanged.
+func authError(step string, err error) error {
+	var perr *ProtocolError
+	if !errors.As(err, &perr) {
+		return err
+	}
+
+	authErr := &AuthError{Step: step, Code: perr.code, Line: perr.line, err: err}
+	switch perr.code {
+	case ErrCodeInvalidUsername:
+		authErr.reason = ErrInvalidUsername
+	case ErrCodeInvalidPassword:
+		authErr.reason = ErrInvalidPassword
+	case ErrCodeAccessDenied:
+		authErr.reason = ErrAccessDenied
+	case ErrCodeAlreadySetUsername, ErrCodeAlreadySetPassword, ErrCodeAlreadyLoggedIn:
+		authErr.reason = ErrAlreadyLoggedIn
+	}
+	return authErr
+}
+
+// unexpectedAuthResponse reports a USERNAME or PASSWORD response other than OK.
+func unexpectedAuthResponse(step string, resp []string) error {
+	line := ""
+	if len(resp) > 0 {
+		line = resp[0]
+	}
+	return &AuthError{Step: step, Line: line}
+}
+
+// ErrResponseTooLarge is returned when a response exceeds the limits set with
+// WithMaxResponseLines or WithMaxResponseBytes.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ErrSetNotApplied is matched by *SetMismatchError with errors.Is.
+var ErrSetNotApplied = errors.New("set not applied")
+
+// SetMismatchError is returned by SetVariableVerified when upsd accepted a SET
+// but the variable did not take the requested value.
+type SetMismatchError struct {
+	Variable string
+	Expected string // Value sent with SET
+	Actual   string // Value read back
+}
+
+func (e *SetMismatchError) Error() string {
+	return fmt.Sprintf("%s: set to %q but reads back %q", e.Variable, e.Expected, e.Actual)
+}
 
-	return err
+// Is reports whether target is ErrSetNotApplied.
+func (e *SetMismatchError) Is(target error) bool {
+	return target == ErrSetNotApplied
 }
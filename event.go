@@ -0,0 +1,98 @@
+package nut
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Event types emitted by Watcher, Monitor, Fleet and AlertRules.
+const (
+	EventVariableChanged    EventType = "variable_changed"
+	EventClientConnected    EventType = "client_connected"
+	EventClientDisconnected EventType = "client_disconnected"
+	EventError              EventType = "error"
+	EventServerUp           EventType = "server_up"
+	EventServerDown         EventType = "server_down"
+	EventAlertRaised        EventType = "alert_raised"
+	EventAlertCleared       EventType = "alert_cleared"
+	EventSelfTestPassed     EventType = "selftest_passed"
+	EventSelfTestFailed     EventType = "selftest_failed"
+	EventCommLost           EventType = "comm_lost"
+	EventCommRestored       EventType = "comm_restored"
+)
+
+// Event describes a change observed on a UPS.
+type Event struct {
+	Time     time.Time
+	Server   string // Endpoint (host:port) for events from a Monitor or Fleet
+	UPS      string
+	Type     EventType
+	Variable string // Variable name for EventVariableChanged
+	OldValue string // Previous value for EventVariableChanged
+	NewValue string // Current value for EventVariableChanged
+	Client   string // Client address for EventClientConnected/EventClientDisconnected
+	Err      error  // Polling error for EventError and EventCommLost
+	Alert    string // Rule name for EventAlertRaised/EventAlertCleared
+}
+
+// eventJSON is the JSON representation of an Event.
+type eventJSON struct {
+	Time     time.Time `json:"time"`
+	Server   string    `json:"server,omitempty"`
+	UPS      string    `json:"ups,omitempty"`
+	Type     EventType `json:"type"`
+	Variable string    `json:"variable,omitempty"`
+	OldValue string    `json:"old_value,omitempty"`
+	NewValue string    `json:"new_value,omitempty"`
+	Client   string    `json:"client,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Alert    string    `json:"alert,omitempty"`
+}
+
+// MarshalJSON encodes the event as a JSON object with snake_case keys and the
+// error, if any, as a string.
+func (e Event) MarshalJSON() ([]byte, error) {
+	out := eventJSON{
+		Time:     e.Time,
+		Server:   e.Server,
+		UPS:      e.UPS,
+		Type:     e.Type,
+		Variable: e.Variable,
+		OldValue: e.OldValue,
+		NewValue: e.NewValue,
+		Client:   e.Client,
+		Alert:    e.Alert,
+	}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes an event encoded by MarshalJSON. The error, if any, is
+// restored as an error with the same message.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var in eventJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*e = Event{
+		Time:     in.Time,
+		Server:   in.Server,
+		UPS:      in.UPS,
+		Type:     in.Type,
+		Variable: in.Variable,
+		OldValue: in.OldValue,
+		NewValue: in.NewValue,
+		Client:   in.Client,
+		Alert:    in.Alert,
+	}
+	if in.Error != "" {
+		e.Err = errors.New(in.Error)
+	}
+	return nil
+}
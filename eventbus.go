@@ -0,0 +1,167 @@
+package nut
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultSubscriptionBuffer is the channel capacity of a Subscription.
+const defaultSubscriptionBuffer = 64
+
+// EventSeverity ranks events for filtering.
+type EventSeverity int
+
+// Event severities, from least to most severe.
+const (
+	SeverityInfo     EventSeverity = iota // Routine changes, e.g. variable updates
+	SeverityNotice                        // Recoveries, e.g. back on line power
+	SeverityWarning                       // On battery, communication lost, failed tests
+	SeverityCritical                      // Low battery and forced shutdown
+)
+
+var severityNames = []string{"info", "notice", "warning", "critical"}
+
+// String returns the lower-case name of the severity.
+func (s EventSeverity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("EventSeverity(%d)", int(s))
+}
+
+// eventSeverities maps upsmon NOTIFYTYPEs and upper-cased event types to
+// severities; unlisted events are SeverityInfo.
+var eventSeverities = map[string]EventSeverity{
+	"ONLINE":          SeverityNotice,
+	"ONBATT":          SeverityWarning,
+	"LOWBATT":         SeverityCritical,
+	"FSD":             SeverityCritical,
+	"REPLBATT":        SeverityWarning,
+	"COMMOK":          SeverityNotice,
+	"COMMBAD":         SeverityWarning,
+	"ERROR":           SeverityWarning,
+	"ALERT_RAISED":    SeverityWarning,
+	"ALERT_CLEARED":   SeverityNotice,
+	"SELFTEST_FAILED": SeverityWarning,
+}
+
+// Severity classifies the event like upsmon would: a ups.status change to LB
+// is critical, to OB a warning and so on.
+func (e Event) Severity() EventSeverity {
+	notifyType, _ := upsmonNotification(e)
+	return eventSeverities[notifyType]
+}
+
+// EventFilter selects events. Empty lists match everything.
+type EventFilter struct {
+	Servers     []string    // Endpoints (host:port)
+	UPS         []string    // UPS names
+	Types       []EventType // Event types
+	MinSeverity EventSeverity
+}
+
+// Match reports whether the filter selects event.
+func (f EventFilter) Match(event Event) bool {
+	if len(f.Servers) > 0 && !containsString(f.Servers, event.Server) {
+		return false
+	}
+	if len(f.UPS) > 0 && !containsString(f.UPS, event.UPS) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsEventType(f.Types, event.Type) {
+		return false
+	}
+	return f.MinSeverity == SeverityInfo || event.Severity() >= f.MinSeverity
+}
+
+func containsEventType(types []EventType, t EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
+
+// EventBus fans events out to any number of subscribers, each with its own
+// filter and buffer, so that a slow consumer only loses its own events.
+// Monitor and Fleet publish to a bus; see their Subscribe methods.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewEventBus returns an EventBus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscribe registers a subscriber receiving the events selected by filter
+// through a channel of the given capacity (default 64). Events are dropped
+// when the channel is full; see Subscription.Dropped.
+func (b *EventBus) Subscribe(filter EventFilter, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = defaultSubscriptionBuffer
+	}
+	sub := &Subscription{bus: b, filter: filter, events: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Publish delivers event to every matching subscriber without blocking.
+func (b *EventBus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Subscription is a subscriber of an EventBus.
+type Subscription struct {
+	bus     *EventBus
+	filter  EventFilter
+	events  chan Event
+	dropped uint64
+}
+
+// Events returns the channel receiving the selected events. It is closed by
+// Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events discarded because the channel was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close unsubscribes and closes the Events channel. It is safe to call more
+// than once.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.events)
+	}
+}
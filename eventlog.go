@@ -0,0 +1,66 @@
+package nut
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// EventLog writes events as JSON lines, one object per event in the format of
+// Event.MarshalJSON, for consumption by jq, Loki or a SIEM pipeline:
+//
+//	{"time":"2025-01-15T10:30:45Z","server":"ups1:3493","ups":"rack1","type":"variable_changed","variable":"ups.status","old_value":"OL","new_value":"OB"}
+//
+// It is a Notifier, and Handle can be used as a Monitor or Watcher event
+// handler. It is safe for concurrent use.
+type EventLog struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewEventLog returns an EventLog writing to w.
+func NewEventLog(w io.Writer) *EventLog {
+	return &EventLog{enc: json.NewEncoder(w)}
+}
+
+// OpenEventLog returns an EventLog appending to the file at path, creating it
+// if needed. Close the log to close the file.
+func OpenEventLog(path string) (*EventLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLog{enc: json.NewEncoder(f), closer: f}, nil
+}
+
+// Write writes event as a line.
+func (l *EventLog) Write(event Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(event)
+}
+
+// Notify writes event as a line.
+func (l *EventLog) Notify(ctx context.Context, event Event) error {
+	return l.Write(event)
+}
+
+// Handle writes event as a line, ignoring write errors, for use as
+// MonitorConfig.EventHandler or with WithEventHandler.
+func (l *EventLog) Handle(event Event) {
+	_ = l.Write(event)
+}
+
+// Close closes the file opened by OpenEventLog. It does nothing for logs
+// created with NewEventLog.
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
@@ -0,0 +1,165 @@
+package nut
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ExecConfig configures an ExecNotifier.
+type ExecConfig struct {
+	Command string        // Program to run, looked up in PATH
+	Args    []string      // Arguments placed before the notification message
+	Env     []string      // Additional KEY=value environment entries
+	Timeout time.Duration // Maximum run time per event (default 30s)
+
+	// Message, if set, is a MessageTemplate replacing the default message
+	// argument. Snapshots optionally provides UPS state to the template.
+	Message   string
+	Snapshots SnapshotSource
+
+	// OutputHandler, if set, receives the combined stdout and stderr of every
+	// run, including failed ones.
+	OutputHandler func(event Event, output []byte)
+}
+
+// ExecNotifier is a Notifier that runs a command per event, like upsmon's
+// NOTIFYCMD. The command receives a human-readable message as its last argument
+// and the event in the environment:
+//
+//	UPSNAME       ups@server, as set by upsmon
+//	NOTIFYTYPE    ONLINE, ONBATT, LOWBATT, FSD, REPLBATT, COMMOK or COMMBAD for
+//	              the matching events, the upper-cased event type otherwise
+//	NUT_EVENT     event type, e.g. variable_changed
+//	NUT_SERVER, NUT_UPS, NUT_VARIABLE, NUT_OLD_VALUE, NUT_NEW_VALUE,
+//	NUT_CLIENT, NUT_ALERT, NUT_ERROR
+type ExecNotifier struct {
+	config  ExecConfig
+	message *MessageTemplate
+}
+
+// NewExecNotifier validates config and returns an ExecNotifier.
+func NewExecNotifier(config ExecConfig) (*ExecNotifier, error) {
+	if config.Command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	n := &ExecNotifier{config: config}
+	if config.Message != "" {
+		message, err := NewMessageTemplate(config.Message, config.Snapshots)
+		if err != nil {
+			return nil, fmt.Errorf("parsing message template: %w", err)
+		}
+		n.message = message
+	}
+	return n, nil
+}
+
+// Notify runs the command for event and waits for it to exit. A non-zero exit
+// status is returned as an error including the command's output.
+func (n *ExecNotifier) Notify(ctx context.Context, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	notifyType, message := upsmonNotification(event)
+	if n.message != nil {
+		rendered, err := n.message.Render(event)
+		if err != nil {
+			return fmt.Errorf("rendering message: %w", err)
+		}
+		message = strings.TrimSpace(rendered)
+	}
+	args := append(append([]string{}, n.config.Args...), message)
+	cmd := exec.CommandContext(ctx, n.config.Command, args...)
+	cmd.Env = append(os.Environ(),
+		"UPSNAME="+upsmonName(event),
+		"NOTIFYTYPE="+notifyType,
+		"NUT_EVENT="+string(event.Type),
+		"NUT_SERVER="+event.Server,
+		"NUT_UPS="+event.UPS,
+		"NUT_VARIABLE="+event.Variable,
+		"NUT_OLD_VALUE="+event.OldValue,
+		"NUT_NEW_VALUE="+event.NewValue,
+		"NUT_CLIENT="+event.Client,
+		"NUT_ALERT="+event.Alert,
+	)
+	if event.Err != nil {
+		cmd.Env = append(cmd.Env, "NUT_ERROR="+event.Err.Error())
+	}
+	cmd.Env = append(cmd.Env, n.config.Env...)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if n.config.OutputHandler != nil {
+		n.config.OutputHandler(event, output.Bytes())
+	}
+	if err != nil {
+		if out := strings.TrimSpace(output.String()); out != "" {
+			return fmt.Errorf("running %s: %w: %s", n.config.Command, err, out)
+		}
+		return fmt.Errorf("running %s: %w", n.config.Command, err)
+	}
+	return nil
+}
+
+// upsmonName returns the UPS of event in upsmon's ups@host notation.
+func upsmonName(event Event) string {
+	switch {
+	case event.UPS == "":
+		return event.Server
+	case event.Server == "":
+		return event.UPS
+	default:
+		return event.UPS + "@" + event.Server
+	}
+}
+
+// upsmonNotification returns the upsmon NOTIFYTYPE and message for event.
+func upsmonNotification(event Event) (notifyType, message string) {
+	name := upsmonName(event)
+	switch event.Type {
+	case EventServerUp, EventCommRestored:
+		return "COMMOK", fmt.Sprintf("Communications with UPS %s established", name)
+	case EventServerDown, EventCommLost:
+		return "COMMBAD", fmt.Sprintf("Communications with UPS %s lost", name)
+	case EventVariableChanged:
+		if event.Variable != "ups.status" {
+			break
+		}
+		old, current := ParseStatus(event.OldValue), ParseStatus(event.NewValue)
+		raised := func(flag Status) bool { return current.Has(flag) && !old.Has(flag) }
+		switch {
+		case raised(StatusForcedShutdown):
+			return "FSD", fmt.Sprintf("UPS %s: forced shutdown in progress", name)
+		case raised(StatusLowBattery):
+			return "LOWBATT", fmt.Sprintf("UPS %s battery is low", name)
+		case raised(StatusOnBattery):
+			return "ONBATT", fmt.Sprintf("UPS %s on battery", name)
+		case raised(StatusOnline) || (old.Has(StatusOnBattery) && !current.Has(StatusOnBattery)):
+			return "ONLINE", fmt.Sprintf("UPS %s on line power", name)
+		case raised(StatusReplaceBattery):
+			return "REPLBATT", fmt.Sprintf("UPS %s battery needs to be replaced", name)
+		}
+	}
+
+	message = fmt.Sprintf("UPS %s: %s", name, event.Type)
+	switch {
+	case event.Alert != "":
+		message += " " + event.Alert
+	case event.Variable != "":
+		message += fmt.Sprintf(" %s=%s", event.Variable, event.NewValue)
+	case event.Client != "":
+		message += " " + event.Client
+	case event.Err != nil:
+		message += " " + event.Err.Error()
+	}
+	return strings.ToUpper(string(event.Type)), message
+}
@@ -0,0 +1,416 @@
+package nut
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expression is a compiled boolean expression over the variables and status
+// flags of a UPS snapshot, such as
+//
+//	status.OB && battery.runtime < 300
+//	(input.voltage < 210 || input.voltage > 250) && !status.BYPASS
+//	ups.beeper.status == "disabled"
+//
+// Identifiers name variables, except status.<FLAG> which is true while the flag
+// is set in ups.status. Variables are compared numerically when both sides are
+// numbers and as strings otherwise. Supported operators are ||, &&, !, <, <=,
+// >, >=, == and !=, with parentheses for grouping.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// CompileExpression parses source into an Expression.
+func CompileExpression(source string) (*Expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against sample. It fails if a referenced
+// variable is missing or a comparison mixes incompatible values.
+func (e *Expression) Eval(sample Snapshot) (bool, error) {
+	value, err := e.root.eval(sample)
+	if err != nil {
+		return false, err
+	}
+	if value.kind != exprBool {
+		return false, fmt.Errorf("expression %q is not boolean", e.source)
+	}
+	return value.b, nil
+}
+
+// ExpressionRule raises an alert while Expression evaluates to true.
+type ExpressionRule struct {
+	Name       string // Alert name; defaults to the expression source
+	Expression *Expression
+}
+
+func (r ExpressionRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Expression.String()
+}
+
+func (r ExpressionRule) evaluate(sample Snapshot, active bool) (raised, ok bool) {
+	raised, err := r.Expression.Eval(sample)
+	return raised, err == nil
+}
+
+func (r ExpressionRule) event(sample Snapshot) Event {
+	return Event{}
+}
+
+// AddExpression compiles expression and registers it as a rule named name.
+func (a *AlertRules) AddExpression(name, expression string) error {
+	compiled, err := CompileExpression(expression)
+	if err != nil {
+		return err
+	}
+	return a.add(ExpressionRule{Name: name, Expression: compiled})
+}
+
+type exprKind int
+
+const (
+	exprBool exprKind = iota
+	exprNumber
+	exprString
+)
+
+type exprValue struct {
+	kind exprKind
+	b    bool
+	num  float64
+	str  string
+}
+
+type exprNode interface {
+	eval(sample Snapshot) (exprValue, error)
+}
+
+type exprLiteral exprValue
+
+func (n exprLiteral) eval(Snapshot) (exprValue, error) {
+	return exprValue(n), nil
+}
+
+type exprVariable string
+
+func (n exprVariable) eval(sample Snapshot) (exprValue, error) {
+	raw, ok := sample.Variables[string(n)]
+	if !ok {
+		return exprValue{}, fmt.Errorf("variable %s is not available", string(n))
+	}
+	raw = strings.TrimSpace(raw)
+	if num, err := strconv.ParseFloat(raw, 64); err == nil {
+		return exprValue{kind: exprNumber, num: num, str: raw}, nil
+	}
+	return exprValue{kind: exprString, str: raw}, nil
+}
+
+type exprStatus Status
+
+func (n exprStatus) eval(sample Snapshot) (exprValue, error) {
+	return exprValue{kind: exprBool, b: sample.Status.Has(Status(n))}, nil
+}
+
+type exprNot struct {
+	operand exprNode
+}
+
+func (n exprNot) eval(sample Snapshot) (exprValue, error) {
+	value, err := evalBool(n.operand, sample)
+	return exprValue{kind: exprBool, b: !value}, err
+}
+
+type exprLogical struct {
+	op          string // "&&" or "||"
+	left, right exprNode
+}
+
+func (n exprLogical) eval(sample Snapshot) (exprValue, error) {
+	left, err := evalBool(n.left, sample)
+	if err != nil {
+		return exprValue{}, err
+	}
+	// Short-circuit so that unavailable variables on the right do not matter
+	if (n.op == "&&" && !left) || (n.op == "||" && left) {
+		return exprValue{kind: exprBool, b: left}, nil
+	}
+	right, err := evalBool(n.right, sample)
+	return exprValue{kind: exprBool, b: right}, err
+}
+
+type exprCompare struct {
+	op          string
+	left, right exprNode
+}
+
+func (n exprCompare) eval(sample Snapshot) (exprValue, error) {
+	left, err := n.left.eval(sample)
+	if err != nil {
+		return exprValue{}, err
+	}
+	right, err := n.right.eval(sample)
+	if err != nil {
+		return exprValue{}, err
+	}
+
+	var cmp int
+	switch {
+	case left.kind == exprNumber && right.kind == exprNumber:
+		switch {
+		case left.num < right.num:
+			cmp = -1
+		case left.num > right.num:
+			cmp = 1
+		}
+	case left.kind == exprBool || right.kind == exprBool:
+		if left.kind != right.kind || (n.op != "==" && n.op != "!=") {
+			return exprValue{}, fmt.Errorf("cannot compare boolean with %s", n.op)
+		}
+		if left.b != right.b {
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(left.str, right.str)
+	}
+
+	result := false
+	switch n.op {
+	case "<":
+		result = cmp < 0
+	case "<=":
+		result = cmp <= 0
+	case ">":
+		result = cmp > 0
+	case ">=":
+		result = cmp >= 0
+	case "==":
+		result = cmp == 0
+	case "!=":
+		result = cmp != 0
+	}
+	return exprValue{kind: exprBool, b: result}, nil
+}
+
+func evalBool(node exprNode, sample Snapshot) (bool, error) {
+	value, err := node.eval(sample)
+	if err != nil {
+		return false, err
+	}
+	if value.kind != exprBool {
+		return false, fmt.Errorf("%q is not boolean", value.str)
+	}
+	return value.b, nil
+}
+
+type exprTokenKind int
+
+const (
+	tokenIdent exprTokenKind = iota
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+// tokenizeExpression splits source into identifiers, numbers, quoted strings
+// and operators.
+func tokenizeExpression(source string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			value, rest, ok := parseQuoted(source[i:])
+			if !ok {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, exprToken{tokenString, value})
+			i = len(source) - len(rest)
+		case strings.ContainsRune("()", rune(c)):
+			tokens = append(tokens, exprToken{tokenOperator, string(c)})
+			i++
+		case strings.ContainsRune("<>=!&|", rune(c)):
+			op := string(c)
+			if i+1 < len(source) {
+				switch two := source[i : i+2]; two {
+				case "<=", ">=", "==", "!=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "=" || op == "&" || op == "|" {
+				return nil, fmt.Errorf("unknown operator %q at offset %d", op, i)
+			}
+			tokens = append(tokens, exprToken{tokenOperator, op})
+			i += len(op)
+		case c == '-' || c == '.' || unicode.IsDigit(rune(c)):
+			start := i
+			i++
+			for i < len(source) && (source[i] == '.' || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, exprToken{tokenNumber, source[start:i]})
+		case unicode.IsLetter(rune(c)) || c == '_':
+			start := i
+			for i < len(source) && isIdentRune(rune(source[i])) {
+				i++
+			}
+			tokens = append(tokens, exprToken{tokenIdent, source[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-'
+}
+
+// exprParser is a recursive descent parser over the expression grammar:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | comparison
+//	comparison = operand [ ( "<" | "<=" | ">" | ">=" | "==" | "!=" ) operand ]
+//	operand    = number | string | identifier | "(" or ")"
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peekOperator(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		if _, ok := p.peekOperator("||"); !ok {
+			break
+		}
+		p.pos++
+		var right exprNode
+		right, err = p.parseAnd()
+		left = exprLogical{op: "||", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		if _, ok := p.peekOperator("&&"); !ok {
+			break
+		}
+		p.pos++
+		var right exprNode
+		right, err = p.parseUnary()
+		left = exprLogical{op: "&&", left: left, right: right}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.peekOperator("!"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		return exprNot{operand: operand}, err
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.peekOperator("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	return exprCompare{op: op, left: left, right: right}, err
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case tokenNumber:
+		num, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token.text)
+		}
+		return exprLiteral{kind: exprNumber, num: num, str: token.text}, nil
+	case tokenString:
+		return exprLiteral{kind: exprString, str: token.text}, nil
+	case tokenIdent:
+		switch token.text {
+		case "true", "false":
+			return exprLiteral{kind: exprBool, b: token.text == "true"}, nil
+		}
+		if flag, ok := strings.CutPrefix(token.text, "status."); ok {
+			status := ParseStatus(flag)
+			if status == 0 || strings.ContainsAny(flag, " ") {
+				return nil, fmt.Errorf("unknown status flag %q", flag)
+			}
+			return exprStatus(status), nil
+		}
+		return exprVariable(token.text), nil
+	}
+
+	if token.text != "(" {
+		return nil, fmt.Errorf("unexpected %q", token.text)
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.peekOperator(")"); !ok {
+		return nil, fmt.Errorf("missing closing parenthesis")
+	}
+	p.pos++
+	return node, nil
+}
@@ -0,0 +1,313 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultFleetEventBuffer is the capacity of the fleet event channel.
+const defaultFleetEventBuffer = 256
+
+// FleetConfig configures a Fleet.
+type FleetConfig struct {
+	Endpoints   []MonitorConfig // One entry per upsd endpoint
+	EventBuffer int             // Capacity of the Events channel (default 256)
+
+	// Credentials supplies the credentials of endpoints that do not set
+	// their own MonitorConfig.Credentials.
+	Credentials CredentialProvider
+
+	// Clock is the clock of endpoints that do not set their own
+	// MonitorConfig.Clock (default the system clock).
+	Clock Clock
+}
+
+// fleetMember is a Monitor managed by a Fleet together with its worker state.
+type fleetMember struct {
+	monitor *Monitor
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Fleet runs a Monitor per upsd endpoint concurrently and merges their events
+// into a single channel. Endpoints can be added, removed and reconfigured while
+// the fleet is running.
+type Fleet struct {
+	events  chan Event
+	dropped uint64
+	bus     *EventBus
+
+	mu          sync.Mutex
+	credentials CredentialProvider
+	clock       Clock
+	members     map[string]*fleetMember // Keyed by endpoint (host:port)
+	ctx         context.Context         // Set while Run is active
+}
+
+// NewFleet validates config and returns a Fleet. Call Run to start it.
+func NewFleet(config FleetConfig) (*Fleet, error) {
+	if config.EventBuffer <= 0 {
+		config.EventBuffer = defaultFleetEventBuffer
+	}
+
+	f := &Fleet{
+		events:      make(chan Event, config.EventBuffer),
+		bus:         NewEventBus(),
+		members:     map[string]*fleetMember{},
+		credentials: config.Credentials,
+		clock:       config.Clock,
+	}
+	for _, endpoint := range config.Endpoints {
+		if err := f.AddEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Run starts a worker per endpoint and blocks until ctx is done and all workers
+// have stopped. Endpoints added while running are started immediately.
+func (f *Fleet) Run(ctx context.Context) error {
+	f.mu.Lock()
+	if f.ctx != nil {
+		f.mu.Unlock()
+		return fmt.Errorf("fleet is already running")
+	}
+	f.ctx = ctx
+	for _, member := range f.members {
+		f.start(member)
+	}
+	f.mu.Unlock()
+
+	<-ctx.Done()
+
+	f.mu.Lock()
+	f.ctx = nil
+	members := make([]*fleetMember, 0, len(f.members))
+	for _, member := range f.members {
+		members = append(members, member)
+	}
+	f.mu.Unlock()
+
+	for _, member := range members {
+		f.stop(member)
+	}
+	return ctx.Err()
+}
+
+// AddEndpoint starts monitoring a new endpoint.
+func (f *Fleet) AddEndpoint(config MonitorConfig) error {
+	monitor, err := NewMonitor(f.endpointConfig(config))
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.members[monitor.Server()]; exists {
+		return fmt.Errorf("endpoint %s is already monitored", monitor.Server())
+	}
+	member := &fleetMember{monitor: monitor}
+	f.members[monitor.Server()] = member
+	if f.ctx != nil {
+		f.start(member)
+	}
+	return nil
+}
+
+// RemoveEndpoint stops monitoring the endpoint (host:port), closing its
+// connection, and waits for its worker to exit.
+func (f *Fleet) RemoveEndpoint(server string) error {
+	f.mu.Lock()
+	member, ok := f.members[server]
+	delete(f.members, server)
+	f.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("endpoint %s is not monitored", server)
+	}
+	f.stop(member)
+	return nil
+}
+
+// Reload reconciles the fleet with config: endpoints no longer listed are
+// removed, new ones are added and existing ones are reconfigured in place
+// with Monitor.Reload, so unrelated endpoints keep monitoring uninterrupted.
+func (f *Fleet) Reload(config FleetConfig) error {
+	wanted := map[string]MonitorConfig{}
+	for _, endpoint := range config.Endpoints {
+		endpoint, err := endpoint.withDefaults()
+		if err != nil {
+			return err
+		}
+		wanted[endpoint.server()] = endpoint
+	}
+
+	f.mu.Lock()
+	f.credentials = config.Credentials
+	existing := map[string]*fleetMember{}
+	for server, member := range f.members {
+		existing[server] = member
+	}
+	f.mu.Unlock()
+
+	for server := range existing {
+		if _, ok := wanted[server]; !ok {
+			if err := f.RemoveEndpoint(server); err != nil {
+				return err
+			}
+		}
+	}
+	for server, endpoint := range wanted {
+		if member, ok := existing[server]; ok {
+			if err := member.monitor.Reload(f.endpointConfig(endpoint)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := f.AddEndpoint(endpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Monitor returns the monitor for the endpoint (host:port), or nil.
+func (f *Fleet) Monitor(server string) *Monitor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if member, ok := f.members[server]; ok {
+		return member.monitor
+	}
+	return nil
+}
+
+// Events returns the channel receiving events from all endpoints. Events are
+// dropped when the channel is full; see Dropped.
+func (f *Fleet) Events() <-chan Event {
+	return f.events
+}
+
+// Dropped returns the number of events discarded because the Events channel
+// was full.
+func (f *Fleet) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// Subscribe registers a subscriber for the events of all endpoints, e.g. one
+// for critical events of any UPS and another for everything about one UPS,
+// each with its own buffer; see EventBus.Subscribe. Unlike Events, a
+// subscriber that falls behind does not cause events to be lost for others.
+func (f *Fleet) Subscribe(filter EventFilter, buffer int) *Subscription {
+	return f.bus.Subscribe(filter, buffer)
+}
+
+// AllUPS returns the latest snapshot of every UPS across all endpoints, sorted
+// by server and UPS name.
+func (f *Fleet) AllUPS() []Snapshot {
+	snapshots := []Snapshot{}
+	for _, monitor := range f.monitors() {
+		snapshots = append(snapshots, monitor.Snapshots()...)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].Server != snapshots[j].Server {
+			return snapshots[i].Server < snapshots[j].Server
+		}
+		return snapshots[i].UPS < snapshots[j].UPS
+	})
+	return snapshots
+}
+
+// Running reports whether Run is active.
+func (f *Fleet) Running() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ctx != nil
+}
+
+// Health returns the connection health of every endpoint, sorted by server.
+func (f *Fleet) Health() []MonitorHealth {
+	monitors := f.monitors()
+	health := make([]MonitorHealth, 0, len(monitors))
+	for _, monitor := range monitors {
+		health = append(health, monitor.Health())
+	}
+	return health
+}
+
+// now returns the current time of the fleet's clock.
+func (f *Fleet) now() time.Time {
+	f.mu.Lock()
+	clock := f.clock
+	f.mu.Unlock()
+	return clockOrSystem(clock).Now()
+}
+
+// monitors returns the fleet's monitors sorted by endpoint.
+func (f *Fleet) monitors() []*Monitor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	monitors := make([]*Monitor, 0, len(f.members))
+	for _, member := range f.members {
+		monitors = append(monitors, member.monitor)
+	}
+	sort.Slice(monitors, func(i, j int) bool { return monitors[i].Server() < monitors[j].Server() })
+	return monitors
+}
+
+// start launches the worker of member. f.mu must be held and f.ctx set.
+func (f *Fleet) start(member *fleetMember) {
+	ctx, cancel := context.WithCancel(f.ctx)
+	member.cancel = cancel
+	member.done = make(chan struct{})
+	go func() {
+		defer close(member.done)
+		member.monitor.Run(ctx)
+	}()
+}
+
+// stop cancels the worker of member, if running, and waits for it to exit.
+func (f *Fleet) stop(member *fleetMember) {
+	if member.cancel == nil {
+		return
+	}
+	member.cancel()
+	<-member.done
+}
+
+// endpointConfig makes config's events flow into the fleet's event channel in
+// addition to any handler configured on the endpoint, and applies the fleet's
+// credential provider and clock unless the endpoint has its own.
+func (f *Fleet) endpointConfig(config MonitorConfig) MonitorConfig {
+	f.mu.Lock()
+	if config.Credentials == nil {
+		config.Credentials = f.credentials
+	}
+	if config.Clock == nil {
+		config.Clock = f.clock
+	}
+	f.mu.Unlock()
+	handler := config.EventHandler
+	config.EventHandler = func(event Event) {
+		if handler != nil {
+			handler(event)
+		}
+		f.publish(event)
+	}
+	return config
+}
+
+func (f *Fleet) publish(event Event) {
+	f.bus.Publish(event)
+	select {
+	case f.events <- event:
+	default:
+		atomic.AddUint64(&f.dropped, 1)
+	}
+}
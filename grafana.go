@@ -0,0 +1,231 @@
+package nut
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// grafanaSearchWindow is how far back /search looks for variables to offer.
+const grafanaSearchWindow = 24 * time.Hour
+
+// grafanaDefaultInterval is the aggregation window used when a query does not
+// specify intervalMs.
+const grafanaDefaultInterval = time.Minute
+
+// GrafanaHandler returns an http.Handler implementing the Grafana simple JSON
+// datasource protocol on top of a history store, for use with the
+// "simpod-json-datasource" or "grafana-simple-json-datasource" plugins:
+//
+//	GET  /             connection test
+//	POST /search       lists targets seen in the last 24 hours
+//	POST /query        returns time series for targets
+//	POST /annotations  returns events as annotations
+//
+// A target names a variable of a UPS as "ups@server/variable", or "ups/variable"
+// for samples without a server; derived variables can be used too. Series are
+// averaged over the query's interval. The annotation query, if not empty, is a
+// comma-separated list of event types to show, e.g. "alert_raised,server_down".
+// Mount the handler under a prefix with http.StripPrefix.
+func GrafanaHandler(store HistoryStore) http.Handler {
+	g := &grafanaHandler{store: store}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", g.root)
+	mux.HandleFunc("/search", g.search)
+	mux.HandleFunc("/query", g.query)
+	mux.HandleFunc("/annotations", g.annotations)
+	return mux
+}
+
+type grafanaHandler struct {
+	store HistoryStore
+}
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type grafanaQueryRequest struct {
+	Range      grafanaRange `json:"range"`
+	IntervalMs int64        `json:"intervalMs"`
+	Targets    []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+}
+
+type grafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"` // [value, unix milliseconds]
+}
+
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange `json:"range"`
+	Annotation struct {
+		Name  string `json:"name"`
+		Query string `json:"query"`
+	} `json:"annotation"`
+}
+
+type grafanaAnnotation struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+func (g *grafanaHandler) root(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (g *grafanaHandler) search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Target string `json:"target"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req) // An empty body searches everything
+
+	samples, err := g.store.Samples(r.Context(), HistoryQuery{From: time.Now().Add(-grafanaSearchWindow)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	seen := map[string]bool{}
+	for _, sample := range samples {
+		for _, values := range []map[string]string{sample.Variables, sample.Derived} {
+			for name := range values {
+				seen[grafanaTarget(sample.Server, sample.UPS, name)] = true
+			}
+		}
+	}
+	targets := []string{}
+	for target := range seen {
+		if strings.Contains(target, req.Target) {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	writeJSON(w, targets)
+}
+
+func (g *grafanaHandler) query(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	window := time.Duration(req.IntervalMs) * time.Millisecond
+	if window <= 0 {
+		window = grafanaDefaultInterval
+	}
+
+	result := []grafanaTimeSeries{}
+	for _, target := range req.Targets {
+		server, ups, variable, err := parseGrafanaTarget(target.Target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := HistoryQuery{Server: server, UPS: ups, From: req.Range.From, To: req.Range.To}
+		series, err := QuerySeries(r.Context(), g.store, query, variable, window)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := grafanaTimeSeries{Target: target.Target, Datapoints: [][2]float64{}}
+		for _, s := range series {
+			// Samples without a server also match a query for any server
+			if s.Server != server {
+				continue
+			}
+			for _, point := range s.Points {
+				out.Datapoints = append(out.Datapoints, [2]float64{point.Avg, float64(point.Time.UnixMilli())})
+			}
+		}
+		result = append(result, out)
+	}
+	writeJSON(w, result)
+}
+
+func (g *grafanaHandler) annotations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req grafanaAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid annotation query: %v", err), http.StatusBadRequest)
+		return
+	}
+	var types []EventType
+	for _, t := range strings.Split(req.Annotation.Query, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, EventType(t))
+		}
+	}
+
+	events, err := QueryEvents(r.Context(), g.store, HistoryQuery{From: req.Range.From, To: req.Range.To}, types...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	annotation, _ := json.Marshal(req.Annotation)
+	result := make([]grafanaAnnotation, 0, len(events))
+	for _, event := range events {
+		_, text := upsmonNotification(event)
+		tags := []string{string(event.Type)}
+		if event.UPS != "" {
+			tags = append(tags, event.UPS)
+		}
+		if event.Server != "" {
+			tags = append(tags, event.Server)
+		}
+		result = append(result, grafanaAnnotation{
+			Annotation: annotation,
+			Time:       event.Time.UnixMilli(),
+			Title:      string(event.Type),
+			Text:       text,
+			Tags:       tags,
+		})
+	}
+	writeJSON(w, result)
+}
+
+// grafanaTarget returns the target name of a variable of a UPS.
+func grafanaTarget(server, ups, variable string) string {
+	if server == "" {
+		return ups + "/" + variable
+	}
+	return ups + "@" + server + "/" + variable
+}
+
+// parseGrafanaTarget parses a target name returned by grafanaTarget.
+func parseGrafanaTarget(target string) (server, ups, variable string, err error) {
+	i := strings.LastIndex(target, "/")
+	if i <= 0 || i == len(target)-1 {
+		return "", "", "", fmt.Errorf("invalid target %q: expected ups@server/variable", target)
+	}
+	ups, variable = target[:i], target[i+1:]
+	ups, server, _ = strings.Cut(ups, "@")
+	return server, ups, variable, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,98 @@
+package nut
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// endpointHealthJSON is the JSON representation of a MonitorHealth in health
+// check responses.
+type endpointHealthJSON struct {
+	Server              string     `json:"server"`
+	Connected           bool       `json:"connected"`
+	LastPoll            *time.Time `json:"last_poll,omitempty"`
+	LastPollAge         float64    `json:"last_poll_age_seconds,omitempty"`
+	Error               string     `json:"error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Ready               bool       `json:"ready"`
+}
+
+// healthResponseJSON is the body of LivenessHandler and ReadinessHandler
+// responses.
+type healthResponseJSON struct {
+	Status    string               `json:"status"`
+	Endpoints []endpointHealthJSON `json:"endpoints"`
+}
+
+// LivenessHandler returns an http.Handler for a /healthz endpoint. It responds
+// 200 while fleet.Run is active and 503 otherwise, with a JSON body describing
+// every endpoint. Connection failures do not fail liveness, as the fleet
+// reconnects by itself; use ReadinessHandler to report them.
+func LivenessHandler(fleet *Fleet) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, code := "ok", http.StatusOK
+		if !fleet.Running() {
+			status, code = "stopped", http.StatusServiceUnavailable
+		}
+		writeHealth(w, code, status, fleet.Health(), 0, fleet.now())
+	})
+}
+
+// ReadinessHandler returns an http.Handler for a /readyz endpoint. It responds
+// 200 when fleet.Run is active, the fleet has at least one endpoint and every
+// endpoint is connected and was polled successfully within maxAge, and 503
+// otherwise. The JSON body tells which endpoints are not ready. A maxAge of
+// zero disables the poll age check. Poll ages are measured by the fleet's
+// clock (see FleetConfig.Clock).
+func ReadinessHandler(fleet *Fleet, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health, now := fleet.Health(), fleet.now()
+		ready := fleet.Running() && len(health) > 0
+		for _, endpoint := range health {
+			if !endpointReady(endpoint, maxAge, now) {
+				ready = false
+			}
+		}
+		status, code := "ready", http.StatusOK
+		if !ready {
+			status, code = "not ready", http.StatusServiceUnavailable
+		}
+		writeHealth(w, code, status, health, maxAge, now)
+	})
+}
+
+// endpointReady reports whether an endpoint is connected and was polled within
+// maxAge of now.
+func endpointReady(health MonitorHealth, maxAge time.Duration, now time.Time) bool {
+	if !health.Connected || health.LastPoll.IsZero() {
+		return false
+	}
+	return maxAge <= 0 || now.Sub(health.LastPoll) <= maxAge
+}
+
+func writeHealth(w http.ResponseWriter, code int, status string, health []MonitorHealth, maxAge time.Duration, now time.Time) {
+	body := healthResponseJSON{Status: status, Endpoints: make([]endpointHealthJSON, 0, len(health))}
+	for _, endpoint := range health {
+		out := endpointHealthJSON{
+			Server:              endpoint.Server,
+			Connected:           endpoint.Connected,
+			ConsecutiveFailures: endpoint.ConsecutiveFailures,
+			Ready:               endpointReady(endpoint, maxAge, now),
+		}
+		if !endpoint.LastPoll.IsZero() {
+			lastPoll := endpoint.LastPoll
+			out.LastPoll = &lastPoll
+			out.LastPollAge = now.Sub(lastPoll).Seconds()
+		}
+		if endpoint.LastError != nil {
+			out.Error = endpoint.LastError.Error()
+		}
+		body.Endpoints = append(body.Endpoints, out)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,367 @@
+package nut
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HistoryQuery selects stored samples or events. Empty fields match everything;
+// From is inclusive and To exclusive.
+type HistoryQuery struct {
+	Server string
+	UPS    string
+	From   time.Time
+	To     time.Time
+}
+
+func (q HistoryQuery) matches(server, ups string, t time.Time) bool {
+	return (q.Server == "" || q.Server == server) &&
+		(q.UPS == "" || q.UPS == ups) &&
+		(q.From.IsZero() || !t.Before(q.From)) &&
+		(q.To.IsZero() || t.Before(q.To))
+}
+
+// HistoryStore records UPS snapshots and events so they survive restarts.
+// Results are returned in time order. Implementations must be safe for
+// concurrent use.
+//
+// The package provides an in-memory and a JSON-lines file store. The bbolt and
+// SQLite stores are separate modules, github.com/bearx3f/go.nut/historybolt
+// and github.com/bearx3f/go.nut/historysqlite, to keep this package
+// dependency-free.
+type HistoryStore interface {
+	AppendSamples(ctx context.Context, samples []Snapshot) error
+	AppendEvents(ctx context.Context, events []Event) error
+	Samples(ctx context.Context, query HistoryQuery) ([]Snapshot, error)
+	Events(ctx context.Context, query HistoryQuery) ([]Event, error)
+	Close() error
+}
+
+// memoryHistory keeps history in memory.
+type memoryHistory struct {
+	mu        sync.Mutex
+	retention time.Duration
+	samples   []Snapshot
+	events    []Event
+}
+
+// NewMemoryHistoryStore returns a HistoryStore keeping samples and events in
+// memory. Entries older than retention, relative to the newest entry, are
+// discarded; zero keeps everything. For downsampling, use a RetentionPolicy.
+func NewMemoryHistoryStore(retention time.Duration) HistoryStore {
+	return &memoryHistory{retention: retention}
+}
+
+func (h *memoryHistory) AppendSamples(ctx context.Context, samples []Snapshot) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sample := range samples {
+		h.samples = insertByTime(h.samples, sample, func(s Snapshot) time.Time { return s.Time })
+	}
+	if h.retention > 0 && len(h.samples) > 0 {
+		cutoff := h.samples[len(h.samples)-1].Time.Add(-h.retention)
+		i := sort.Search(len(h.samples), func(i int) bool { return !h.samples[i].Time.Before(cutoff) })
+		h.samples = h.samples[i:]
+	}
+	return nil
+}
+
+func (h *memoryHistory) AppendEvents(ctx context.Context, events []Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, event := range events {
+		h.events = insertByTime(h.events, event, func(e Event) time.Time { return e.Time })
+	}
+	if h.retention > 0 && len(h.events) > 0 {
+		cutoff := h.events[len(h.events)-1].Time.Add(-h.retention)
+		i := sort.Search(len(h.events), func(i int) bool { return !h.events[i].Time.Before(cutoff) })
+		h.events = h.events[i:]
+	}
+	return nil
+}
+
+// insertByTime inserts item into items, which are in time order, after any
+// items of the same time. Items usually arrive in order and are appended.
+func insertByTime[T any](items []T, item T, timeOf func(T) time.Time) []T {
+	t := timeOf(item)
+	if len(items) == 0 || !t.Before(timeOf(items[len(items)-1])) {
+		return append(items, item)
+	}
+	i := sort.Search(len(items), func(i int) bool { return t.Before(timeOf(items[i])) })
+	var zero T
+	items = append(items, zero)
+	copy(items[i+1:], items[i:])
+	items[i] = item
+	return items
+}
+
+func (h *memoryHistory) Samples(ctx context.Context, query HistoryQuery) ([]Snapshot, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var samples []Snapshot
+	for _, sample := range h.samples {
+		if query.matches(sample.Server, sample.UPS, sample.Time) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+func (h *memoryHistory) Events(ctx context.Context, query HistoryQuery) ([]Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var events []Event
+	for _, event := range h.events {
+		if query.matches(event.Server, event.UPS, event.Time) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (h *memoryHistory) Close() error {
+	return nil
+}
+
+// historySample is the stored form of a Snapshot; Status and Derived are
+// recomputed from the variables when loading.
+type historySample struct {
+	Time        time.Time         `json:"time"`
+	Server      string            `json:"server,omitempty"`
+	UPS         string            `json:"ups"`
+	Description string            `json:"description,omitempty"`
+	Variables   map[string]string `json:"variables"`
+}
+
+// fileHistory appends history to JSON-lines files in a directory.
+type fileHistory struct {
+	mu      sync.Mutex
+	dir     string
+	samples *os.File
+	events  *os.File
+}
+
+// NewFileHistoryStore returns a HistoryStore appending samples and events as
+// JSON lines to samples.jsonl and events.jsonl in dir, which is created if
+// needed. Queries scan the files, so this store suits modest histories; bound
+// them with a RetentionPolicy or rotate the files externally.
+func NewFileHistoryStore(dir string) (HistoryStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating history directory: %w", err)
+	}
+	samples, err := openHistoryFile(filepath.Join(dir, "samples.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	events, err := openHistoryFile(filepath.Join(dir, "events.jsonl"))
+	if err != nil {
+		samples.Close()
+		return nil, err
+	}
+	return &fileHistory{dir: dir, samples: samples, events: events}, nil
+}
+
+// openHistoryFile opens a JSON-lines file for appending, creating it or
+// removing a torn last line.
+func openHistoryFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := truncateTornLine(path); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("repairing %s: %w", filepath.Base(path), err)
+	}
+	return f, nil
+}
+
+func (h *fileHistory) AppendSamples(ctx context.Context, samples []Snapshot) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.samples == nil {
+		return os.ErrClosed
+	}
+	return writeHistorySamples(h.samples, samples)
+}
+
+// writeHistorySamples appends samples to w as lines of samples.jsonl.
+func writeHistorySamples(w io.Writer, samples []Snapshot) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, sample := range samples {
+		if err := enc.Encode(historySample{
+			Time:        sample.Time,
+			Server:      sample.Server,
+			UPS:         sample.UPS,
+			Description: sample.Description,
+			Variables:   sample.Variables,
+		}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (h *fileHistory) AppendEvents(ctx context.Context, events []Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.events == nil {
+		return os.ErrClosed
+	}
+	return writeHistoryEvents(h.events, events)
+}
+
+// writeHistoryEvents appends events to w as lines of events.jsonl.
+func writeHistoryEvents(w io.Writer, events []Event) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (h *fileHistory) Samples(ctx context.Context, query HistoryQuery) ([]Snapshot, error) {
+	var samples []Snapshot
+	err := h.scan(ctx, "samples.jsonl", func(line []byte) error {
+		sample, err := decodeHistorySample(line)
+		if err != nil {
+			return err
+		}
+		if query.matches(sample.Server, sample.UPS, sample.Time) {
+			samples = append(samples, sample)
+		}
+		return nil
+	})
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	return samples, err
+}
+
+// decodeHistorySample decodes a line of samples.jsonl.
+func decodeHistorySample(line []byte) (Snapshot, error) {
+	var stored historySample
+	if err := json.Unmarshal(line, &stored); err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{
+		Time:        stored.Time,
+		Server:      stored.Server,
+		UPS:         stored.UPS,
+		Description: stored.Description,
+		Status:      ParseStatus(stored.Variables["ups.status"]),
+		Variables:   stored.Variables,
+		Derived:     DerivedVariables(stored.Variables, stored.Time),
+	}, nil
+}
+
+func (h *fileHistory) Events(ctx context.Context, query HistoryQuery) ([]Event, error) {
+	var events []Event
+	err := h.scan(ctx, "events.jsonl", func(line []byte) error {
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		if query.matches(event.Server, event.UPS, event.Time) {
+			events = append(events, event)
+		}
+		return nil
+	})
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, err
+}
+
+// scan calls fn for each non-empty line of the named file.
+func (h *fileHistory) scan(ctx context.Context, name string, fn func(line []byte) error) error {
+	h.mu.Lock()
+	closed := h.samples == nil
+	h.mu.Unlock()
+	if closed {
+		return os.ErrClosed
+	}
+	return h.scanFile(ctx, name, fn)
+}
+
+// scanFile is scan without the check for a closed store. A last line without
+// a newline is skipped: it is being appended, or was torn by a crash.
+func (h *fileHistory) scanFile(ctx context.Context, name string, fn func(line []byte) error) error {
+	f, err := os.Open(filepath.Join(h.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for lineNo := 1; ; lineNo++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if line = bytes.TrimSuffix(line, []byte("\n")); len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, lineNo, err)
+		}
+	}
+}
+
+// truncateTornLine removes a last line without a newline, left by a crash
+// during an append, so that the next append starts on a line of its own.
+func truncateTornLine(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	end := info.Size()
+	buf := make([]byte, 4096)
+	for offset := end; offset > 0; {
+		n := int64(len(buf))
+		if n > offset {
+			n = offset
+		}
+		offset -= n
+		if _, err := f.ReadAt(buf[:n], offset); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			if offset+int64(i)+1 == end {
+				return nil
+			}
+			return f.Truncate(offset + int64(i) + 1)
+		}
+	}
+	return f.Truncate(0)
+}
+
+func (h *fileHistory) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.samples == nil {
+		return os.ErrClosed
+	}
+	err := errors.Join(h.samples.Close(), h.events.Close())
+	h.samples, h.events = nil, nil
+	return err
+}
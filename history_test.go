@@ -0,0 +1,191 @@
+package nut_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+var historyStart = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func historySample(offset time.Duration, charge string) nut.Snapshot {
+	return nut.Snapshot{
+		Time:      historyStart.Add(offset),
+		Server:    "nut1:3493",
+		UPS:       "ups1",
+		Variables: map[string]string{"ups.status": "OL", "battery.charge": charge},
+	}
+}
+
+func sampleTimes(samples []nut.Snapshot) []time.Duration {
+	offsets := make([]time.Duration, len(samples))
+	for i, sample := range samples {
+		offsets[i] = sample.Time.Sub(historyStart)
+	}
+	return offsets
+}
+
+func TestMemoryHistoryOrder(t *testing.T) {
+	ctx := context.Background()
+	store := nut.NewMemoryHistoryStore(0)
+	store.AppendSamples(ctx, []nut.Snapshot{historySample(2*time.Second, "90"), historySample(4*time.Second, "80")})
+	store.AppendSamples(ctx, []nut.Snapshot{historySample(time.Second, "95"), historySample(3*time.Second, "85")})
+	store.AppendSamples(ctx, []nut.Snapshot{historySample(3*time.Second, "84")})
+
+	samples, err := store.Samples(ctx, nut.HistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second, 4 * time.Second}
+	if got := sampleTimes(samples); !reflect.DeepEqual(got, want) {
+		t.Fatalf("times = %v, want %v", got, want)
+	}
+	// Equal times keep their order of arrival
+	if samples[2].Variables["battery.charge"] != "85" || samples[3].Variables["battery.charge"] != "84" {
+		t.Fatalf("equal times reordered: %v, %v", samples[2].Variables, samples[3].Variables)
+	}
+}
+
+func TestMemoryHistoryRetention(t *testing.T) {
+	ctx := context.Background()
+	store := nut.NewMemoryHistoryStore(time.Minute)
+	for i := 0; i < 5; i++ {
+		store.AppendSamples(ctx, []nut.Snapshot{historySample(time.Duration(i)*30*time.Second, "100")})
+		store.AppendEvents(ctx, []nut.Event{{Time: historyStart.Add(time.Duration(i) * 30 * time.Second), UPS: "ups1", Type: nut.EventServerDown}})
+	}
+	samples, _ := store.Samples(ctx, nut.HistoryQuery{})
+	events, _ := store.Events(ctx, nut.HistoryQuery{})
+	if got := sampleTimes(samples); len(got) != 3 || got[0] != time.Minute {
+		t.Fatalf("samples kept at %v, want 1m0s to 2m0s", got)
+	}
+	if len(events) != 3 {
+		t.Fatalf("%d events kept, want 3", len(events))
+	}
+}
+
+func TestFileHistoryTornLine(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := nut.NewFileHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AppendSamples(ctx, []nut.Snapshot{historySample(0, "100"), historySample(time.Second, "99")}); err != nil {
+		t.Fatal(err)
+	}
+	// A crash in the middle of an append leaves a partial line
+	path := filepath.Join(dir, "samples.jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2026-03-01T12:00:02Z","ups":"up`)
+	f.Close()
+
+	samples, err := store.Samples(ctx, nut.HistoryQuery{})
+	if err != nil || len(samples) != 2 {
+		t.Fatalf("samples = %d, err = %v; want 2 samples", len(samples), err)
+	}
+	if err := store.(nut.HistoryCompactor).Compact(ctx, nut.RetentionPolicy{Raw: time.Hour}, historyStart.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// Reopening repairs a torn line so that appends start on a line of their own
+	f, _ = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"time":"2026-03-01T12:00:02Z"`)
+	f.Close()
+	store, err = nut.NewFileHistoryStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.AppendSamples(ctx, []nut.Snapshot{historySample(3*time.Second, "98")}); err != nil {
+		t.Fatal(err)
+	}
+	samples, err = store.Samples(ctx, nut.HistoryQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sampleTimes(samples); len(got) != 3 || got[2] != 3*time.Second {
+		t.Fatalf("samples at %v, want 0s, 1s and 3s", got)
+	}
+}
+
+func TestFileHistoryQuery(t *testing.T) {
+	ctx := context.Background()
+	store, err := nut.NewFileHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	other := historySample(2*time.Second, "50")
+	other.UPS = "ups2"
+	store.AppendSamples(ctx, []nut.Snapshot{historySample(3*time.Second, "97"), other, historySample(time.Second, "99")})
+	store.AppendEvents(ctx, []nut.Event{{Time: historyStart, UPS: "ups1", Type: nut.EventServerDown}})
+
+	samples, err := store.Samples(ctx, nut.HistoryQuery{UPS: "ups1", From: historyStart.Add(time.Second), To: historyStart.Add(3 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Variables["battery.charge"] != "99" || !samples[0].Status.Has(nut.StatusOnline) {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+	events, err := store.Events(ctx, nut.HistoryQuery{UPS: "ups1"})
+	if err != nil || len(events) != 1 || events[0].Type != nut.EventServerDown {
+		t.Fatalf("events = %+v, err = %v", events, err)
+	}
+}
+
+func TestCompactionKeepsExtremes(t *testing.T) {
+	ctx := context.Background()
+	store := nut.NewMemoryHistoryStore(0)
+	for i, voltage := range []string{"230", "180", "232", "260"} {
+		sample := historySample(time.Duration(i)*15*time.Second, "100")
+		sample.Variables["input.voltage"] = voltage
+		sample.Variables["input.voltage.nominal"] = "230"
+		store.AppendSamples(ctx, []nut.Snapshot{sample})
+	}
+	policy := nut.RetentionPolicy{
+		Raw: time.Minute,
+		Tiers: []nut.RetentionTier{
+			{Resolution: time.Minute, Keep: time.Hour},
+			{Resolution: time.Hour, Keep: 24 * time.Hour},
+		},
+	}
+	// Merge into a one-minute sample, then that into an hourly one
+	for _, now := range []time.Duration{2 * time.Minute, 2 * time.Hour} {
+		if err := store.(nut.HistoryCompactor).Compact(ctx, policy, historyStart.Add(now)); err != nil {
+			t.Fatal(err)
+		}
+		samples, _ := store.Samples(ctx, nut.HistoryQuery{})
+		if len(samples) != 1 {
+			t.Fatalf("%d samples after compaction at %v, want 1", len(samples), now)
+		}
+		vars := samples[0].Variables
+		if vars["input.voltage"] != "225.5" || vars["input.voltage.min"] != "180" || vars["input.voltage.max"] != "260" || vars["input.voltage.count"] != "4" {
+			t.Fatalf("compacted at %v to %v", now, vars)
+		}
+		if _, ok := vars["input.voltage.min.min"]; ok {
+			t.Fatalf("statistics aggregated as variables: %v", vars)
+		}
+	}
+
+	reports, err := nut.QueryPowerQuality(ctx, store, nut.HistoryQuery{}, time.Hour, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := reports[0].Total
+	if total.Samples != 4 || total.Voltage.Min != 180 || total.Voltage.Max != 260 || total.Voltage.Count != 4 || total.OutOfTolerance != 1 {
+		t.Fatalf("power quality of compacted history: %+v", total)
+	}
+	series, _ := nut.QuerySeries(ctx, store, nut.HistoryQuery{}, "input.voltage", time.Hour)
+	if point := series[0].Points[0]; point.Min != 180 || point.Max != 260 || point.Count != 4 {
+		t.Fatalf("series of compacted history: %+v", point)
+	}
+}
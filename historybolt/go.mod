@@ -0,0 +1,12 @@
+module github.com/bearx3f/go.nut/historybolt
+
+go 1.22
+
+require (
+	github.com/bearx3f/go.nut v0.0.0
+	go.etcd.io/bbolt v1.3.11
+)
+
+require golang.org/x/sys v0.4.0 // indirect
+
+replace github.com/bearx3f/go.nut => ../
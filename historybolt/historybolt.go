@@ -0,0 +1,255 @@
+// Package historybolt stores UPS history in a bbolt database, a single file
+// that needs no server:
+//
+//	store, err := historybolt.Open("/var/lib/ups-monitor/history.db")
+//	...
+//	store.AppendSamples(ctx, fleet.AllUPS())
+//
+// Entries are keyed by time, so queries seek to their From time instead of
+// scanning the whole history. The store implements nut.HistoryCompactor.
+//
+// It is a separate module so that the main module keeps depending only on the
+// standard library.
+package historybolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	samplesBucket = []byte("samples")
+	eventsBucket  = []byte("events")
+)
+
+// Store is a nut.HistoryStore in a bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+var _ nut.HistoryCompactor = (*Store)(nil)
+
+// Open opens or creates the database at path. bbolt locks the file, so only
+// one process can use it at a time; Open fails after one second if another
+// holds it.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening history database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{samplesBucket, eventsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening history database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// storedSample is the stored form of a nut.Snapshot; Status and Derived are
+// recomputed from the variables when loading.
+type storedSample struct {
+	Server      string            `json:"server,omitempty"`
+	UPS         string            `json:"ups"`
+	Description string            `json:"description,omitempty"`
+	Variables   map[string]string `json:"variables"`
+}
+
+// AppendSamples stores samples.
+func (s *Store) AppendSamples(ctx context.Context, samples []nut.Snapshot) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putSamples(tx.Bucket(samplesBucket), samples)
+	})
+}
+
+func putSamples(bucket *bolt.Bucket, samples []nut.Snapshot) error {
+	for _, sample := range samples {
+		value, err := json.Marshal(storedSample{
+			Server:      sample.Server,
+			UPS:         sample.UPS,
+			Description: sample.Description,
+			Variables:   sample.Variables,
+		})
+		if err != nil {
+			return err
+		}
+		if err := put(bucket, sample.Time, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendEvents stores events.
+func (s *Store) AppendEvents(ctx context.Context, events []nut.Event) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putEvents(tx.Bucket(eventsBucket), events)
+	})
+}
+
+func putEvents(bucket *bolt.Bucket, events []nut.Event) error {
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := put(bucket, event.Time, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put stores value under its time and a sequence number, which orders
+// entries of the same time by arrival.
+func put(bucket *bolt.Bucket, t time.Time, value []byte) error {
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return bucket.Put(key, value)
+}
+
+// Samples returns the samples matching query in time order.
+func (s *Store) Samples(ctx context.Context, query nut.HistoryQuery) ([]nut.Snapshot, error) {
+	var samples []nut.Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		samples, err = readSamples(ctx, tx, query)
+		return err
+	})
+	return samples, err
+}
+
+func readSamples(ctx context.Context, tx *bolt.Tx, query nut.HistoryQuery) ([]nut.Snapshot, error) {
+	var samples []nut.Snapshot
+	err := scan(ctx, tx, samplesBucket, query, func(t time.Time, value []byte) error {
+		var stored storedSample
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return err
+		}
+		if matches(query, stored.Server, stored.UPS) {
+			samples = append(samples, nut.Snapshot{
+				Time:        t,
+				Server:      stored.Server,
+				UPS:         stored.UPS,
+				Description: stored.Description,
+				Status:      nut.ParseStatus(stored.Variables["ups.status"]),
+				Variables:   stored.Variables,
+				Derived:     nut.DerivedVariables(stored.Variables, t),
+			})
+		}
+		return nil
+	})
+	return samples, err
+}
+
+// Events returns the events matching query in time order.
+func (s *Store) Events(ctx context.Context, query nut.HistoryQuery) ([]nut.Event, error) {
+	var events []nut.Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		events, err = readEvents(ctx, tx, query)
+		return err
+	})
+	return events, err
+}
+
+func readEvents(ctx context.Context, tx *bolt.Tx, query nut.HistoryQuery) ([]nut.Event, error) {
+	var events []nut.Event
+	err := scan(ctx, tx, eventsBucket, query, func(t time.Time, value []byte) error {
+		var event nut.Event
+		if err := json.Unmarshal(value, &event); err != nil {
+			return err
+		}
+		if matches(query, event.Server, event.UPS) {
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// scan calls fn for the entries of the named bucket within the query's time
+// range.
+func scan(ctx context.Context, tx *bolt.Tx, name []byte, query nut.HistoryQuery, fn func(t time.Time, value []byte) error) error {
+	cursor := tx.Bucket(name).Cursor()
+	var key, value []byte
+	if query.From.IsZero() {
+		key, value = cursor.First()
+	} else {
+		from := make([]byte, 8)
+		binary.BigEndian.PutUint64(from, uint64(query.From.UnixNano()))
+		key, value = cursor.Seek(from)
+	}
+	for ; key != nil; key, value = cursor.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t := time.Unix(0, int64(binary.BigEndian.Uint64(key))).UTC()
+		if !query.To.IsZero() && !t.Before(query.To) {
+			return nil
+		}
+		if err := fn(t, value); err != nil {
+			return fmt.Errorf("%s entry at %s: %w", name, t.Format(time.RFC3339Nano), err)
+		}
+	}
+	return nil
+}
+
+func matches(query nut.HistoryQuery, server, ups string) bool {
+	return (query.Server == "" || query.Server == server) && (query.UPS == "" || query.UPS == ups)
+}
+
+// Compact applies policy, rewriting both buckets in one transaction.
+func (s *Store) Compact(ctx context.Context, policy nut.RetentionPolicy, now time.Time) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		samples, err := readSamples(ctx, tx, nut.HistoryQuery{})
+		if err != nil {
+			return err
+		}
+		events, err := readEvents(ctx, tx, nut.HistoryQuery{})
+		if err != nil {
+			return err
+		}
+		if samples, events, err = policy.Apply(samples, events, now); err != nil {
+			return err
+		}
+		for _, name := range [][]byte{samplesBucket, eventsBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		if err := putSamples(tx.Bucket(samplesBucket), samples); err != nil {
+			return err
+		}
+		return putEvents(tx.Bucket(eventsBucket), events)
+	})
+	if err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,99 @@
+package historybolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+var start = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func sample(offset time.Duration, ups, charge string) nut.Snapshot {
+	return nut.Snapshot{
+		Time:      start.Add(offset),
+		Server:    "nut1:3493",
+		UPS:       ups,
+		Variables: map[string]string{"ups.status": "OL", "battery.charge": charge},
+	}
+}
+
+func openStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, path
+}
+
+func TestSamplesSurviveReopen(t *testing.T) {
+	ctx := context.Background()
+	store, path := openStore(t)
+	err := store.AppendSamples(ctx, []nut.Snapshot{
+		sample(2*time.Second, "ups1", "98"),
+		sample(time.Second, "ups2", "50"),
+		sample(time.Second, "ups1", "99"),
+		sample(3*time.Second, "ups1", "97"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.AppendEvents(ctx, []nut.Event{{Time: start.Add(time.Second), Server: "nut1:3493", UPS: "ups1", Type: nut.EventServerDown}})
+	store.Close()
+
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	samples, err := store.Samples(ctx, nut.HistoryQuery{UPS: "ups1", From: start.Add(time.Second), To: start.Add(3 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0].Variables["battery.charge"] != "99" || samples[1].Variables["battery.charge"] != "98" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+	if !samples[0].Time.Equal(start.Add(time.Second)) || !samples[0].Status.Has(nut.StatusOnline) {
+		t.Fatalf("unexpected sample %+v", samples[0])
+	}
+	events, err := store.Events(ctx, nut.HistoryQuery{})
+	if err != nil || len(events) != 1 || events[0].Type != nut.EventServerDown || events[0].UPS != "ups1" {
+		t.Fatalf("events = %+v, err = %v", events, err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	store, _ := openStore(t)
+	defer store.Close()
+	for i := 0; i < 4; i++ {
+		store.AppendSamples(ctx, []nut.Snapshot{sample(time.Duration(i)*30*time.Second, "ups1", "90")})
+	}
+	store.AppendEvents(ctx, []nut.Event{
+		{Time: start, UPS: "ups1", Type: nut.EventServerDown},
+		{Time: start.Add(time.Hour), UPS: "ups1", Type: nut.EventServerUp},
+	})
+
+	policy := nut.RetentionPolicy{Raw: time.Minute, Tiers: []nut.RetentionTier{{Resolution: time.Minute, Keep: time.Hour}}}
+	now := start.Add(time.Hour + 30*time.Second)
+	if err := store.Compact(ctx, policy, now); err != nil {
+		t.Fatal(err)
+	}
+	samples, _ := store.Samples(ctx, nut.HistoryQuery{})
+	// The sample at 0s expired; 30s, 60s and 90s were merged into minutes
+	if len(samples) != 2 || !samples[0].Time.Equal(start) || !samples[1].Time.Equal(start.Add(time.Minute)) {
+		t.Fatalf("unexpected samples after compaction %+v", samples)
+	}
+	events, _ := store.Events(ctx, nut.HistoryQuery{})
+	if len(events) != 1 || events[0].Type != nut.EventServerUp {
+		t.Fatalf("unexpected events after compaction %+v", events)
+	}
+
+	if err := store.Compact(ctx, nut.RetentionPolicy{}, now); err == nil {
+		t.Fatal("expected an error for an invalid policy")
+	}
+}
@@ -0,0 +1,148 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SeriesPoint aggregates the numeric values of a variable within one window of
+// a series.
+type SeriesPoint struct {
+	Time  time.Time // Start of the window
+	Min   float64
+	Max   float64
+	Avg   float64
+	Last  float64 // Value of the latest sample in the window
+	Count int     // Number of samples in the window
+}
+
+// Series is the downsampled history of one variable of one UPS.
+type Series struct {
+	Server   string
+	UPS      string
+	Variable string
+	Unit     Unit
+	Window   time.Duration
+	Points   []SeriesPoint // Windows without samples are omitted
+}
+
+// QuerySeries reads the samples matching query from store and aggregates the
+// numeric values of variable into windows of the given length, aligned as by
+// time.Time.Truncate. Derived variables (see DerivedVariables) can be queried
+// by their full name. A series is returned per UPS matching query, in order of first
+// appearance; samples where the variable is missing or not numeric are skipped.
+func QuerySeries(ctx context.Context, store HistoryStore, query HistoryQuery, variable string, window time.Duration) ([]Series, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	samples, err := store.Samples(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []Series
+	index := map[string]int{}
+	for _, sample := range samples {
+		point, ok := variableStats(sample.Variables, variable)
+		if !ok {
+			point, ok = variableStats(sample.Derived, variable)
+		}
+		if !ok {
+			continue
+		}
+
+		key := energyKey(sample.Server, sample.UPS)
+		i, ok := index[key]
+		if !ok {
+			i = len(series)
+			index[key] = i
+			series = append(series, Series{
+				Server:   sample.Server,
+				UPS:      sample.UPS,
+				Variable: variable,
+				Unit:     UnitFor(variable),
+				Window:   window,
+			})
+		}
+		s := &series[i]
+
+		start := sample.Time.Truncate(window)
+		if n := len(s.Points); n == 0 || !s.Points[n-1].Time.Equal(start) {
+			s.Points = append(s.Points, SeriesPoint{Time: start})
+		}
+		s.Points[len(s.Points)-1].merge(point)
+	}
+	return series, nil
+}
+
+// variableStats returns the numeric value of a variable as a point. A sample
+// downsampled by a RetentionPolicy contributes the minimum, maximum and
+// number of samples it merged.
+func variableStats(values map[string]string, name string) (SeriesPoint, bool) {
+	value, err := strconv.ParseFloat(values[name], 64)
+	if err != nil {
+		return SeriesPoint{}, false
+	}
+	point := SeriesPoint{Min: value, Max: value, Avg: value, Last: value, Count: 1}
+	lo, errMin := strconv.ParseFloat(values[name+".min"], 64)
+	hi, errMax := strconv.ParseFloat(values[name+".max"], 64)
+	count, errCount := strconv.Atoi(values[name+".count"])
+	if errMin == nil && errMax == nil && errCount == nil && count > 0 {
+		point.Min, point.Max, point.Count = lo, hi, count
+	}
+	return point, true
+}
+
+// isStatName reports whether name is the .min, .max or .count companion of a
+// variable downsampled by a RetentionPolicy.
+func isStatName(values map[string]string, name string) bool {
+	for _, suffix := range []string{".min", ".max", ".count"} {
+		base := strings.TrimSuffix(name, suffix)
+		if base == name {
+			continue
+		}
+		_, hasMin := values[base+".min"]
+		_, hasMax := values[base+".max"]
+		_, hasCount := values[base+".count"]
+		_, hasBase := values[base]
+		return hasBase && hasMin && hasMax && hasCount
+	}
+	return false
+}
+
+// merge aggregates another point into p.
+func (p *SeriesPoint) merge(other SeriesPoint) {
+	if other.Count == 0 {
+		return
+	}
+	if p.Count == 0 {
+		p.Min, p.Max = other.Min, other.Max
+	}
+	p.Min = min(p.Min, other.Min)
+	p.Max = max(p.Max, other.Max)
+	p.Avg += (other.Avg - p.Avg) * float64(other.Count) / float64(p.Count+other.Count)
+	p.Last = other.Last
+	p.Count += other.Count
+}
+
+// QueryEvents reads the events matching query from store, keeping only the
+// given types if any are specified.
+func QueryEvents(ctx context.Context, store HistoryStore, query HistoryQuery, types ...EventType) ([]Event, error) {
+	events, err := store.Events(ctx, query)
+	if err != nil || len(types) == 0 {
+		return events, err
+	}
+	var filtered []Event
+	for _, event := range events {
+		for _, t := range types {
+			if event.Type == t {
+				filtered = append(filtered, event)
+				break
+			}
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,367 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RetentionTier keeps samples downsampled to one per Resolution, per UPS, until
+// they are Keep old.
+type RetentionTier struct {
+	Resolution time.Duration
+	Keep       time.Duration
+}
+
+// RetentionPolicy bounds the size of a history store, e.g. raw samples for a
+// day and one-minute averages for 30 days:
+//
+//	nut.RetentionPolicy{
+//		Raw:   24 * time.Hour,
+//		Tiers: []nut.RetentionTier{{Resolution: time.Minute, Keep: 30 * 24 * time.Hour}},
+//	}
+//
+// When samples age out of one level they are merged into windows of the next
+// tier's resolution: numeric variables are averaged, with their minimum,
+// maximum and number of samples kept as <name>.min, <name>.max and
+// <name>.count so that QuerySeries and QueryPowerQuality still see peaks, and
+// other variables take their latest value. Samples older than the last tier
+// are deleted.
+type RetentionPolicy struct {
+	Raw   time.Duration   // How long samples are kept as recorded
+	Tiers []RetentionTier // Downsampled tiers, with increasing Resolution and Keep
+	// Events is how long events are kept (default the Keep of the last tier,
+	// or Raw without tiers).
+	Events time.Duration
+	// Clock times RunHistoryCompaction (default the system clock).
+	Clock Clock
+}
+
+// HistoryCompactor is implemented by history stores that can apply a
+// RetentionPolicy. Both built-in stores implement it.
+type HistoryCompactor interface {
+	Compact(ctx context.Context, policy RetentionPolicy, now time.Time) error
+}
+
+// validate checks that the tiers get coarser and longer-lived.
+func (p RetentionPolicy) validate() error {
+	if p.Raw <= 0 {
+		return fmt.Errorf("raw sample retention must be positive")
+	}
+	if p.Events < 0 {
+		return fmt.Errorf("event retention must not be negative")
+	}
+	resolution, keep := time.Duration(0), p.Raw
+	for _, tier := range p.Tiers {
+		if tier.Resolution <= resolution {
+			return fmt.Errorf("retention tier resolutions must increase")
+		}
+		if tier.Keep <= keep {
+			return fmt.Errorf("retention tier durations must increase")
+		}
+		resolution, keep = tier.Resolution, tier.Keep
+	}
+	return nil
+}
+
+// eventRetention returns how long events are kept.
+func (p RetentionPolicy) eventRetention() time.Duration {
+	if p.Events > 0 {
+		return p.Events
+	}
+	if n := len(p.Tiers); n > 0 {
+		return p.Tiers[n-1].Keep
+	}
+	return p.Raw
+}
+
+// compactEvents drops the events that are too old as of now.
+func (p RetentionPolicy) compactEvents(events []Event, now time.Time) []Event {
+	cutoff := now.Add(-p.eventRetention())
+	kept := make([]Event, 0, len(events))
+	for _, event := range events {
+		if !event.Time.Before(cutoff) {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// compactSamples downsamples and drops samples as of now and returns the
+// result in time order. A sample is merged into a tier only once the tier's
+// whole window has aged out of the previous level, so windows are never split.
+func (p RetentionPolicy) compactSamples(samples []Snapshot, now time.Time) []Snapshot {
+	type bucketKey struct {
+		server, ups string
+		tier        int
+		start       time.Time
+	}
+	buckets := map[bucketKey]*sampleBucket{}
+	var order []bucketKey
+	kept := make([]Snapshot, 0, len(samples))
+
+	for _, sample := range samples {
+		cutoff := now.Add(-p.Raw)
+		if !sample.Time.Before(cutoff) {
+			kept = append(kept, sample)
+			continue
+		}
+		tier := -1
+		for i, t := range p.Tiers {
+			if !sample.Time.Before(now.Add(-t.Keep)) {
+				tier = i
+				break
+			}
+			cutoff = now.Add(-t.Keep)
+		}
+		if tier < 0 {
+			continue // Older than every tier
+		}
+		resolution := p.Tiers[tier].Resolution
+		start := sample.Time.Truncate(resolution)
+		if start.Add(resolution).After(cutoff) {
+			kept = append(kept, sample)
+			continue
+		}
+
+		key := bucketKey{sample.Server, sample.UPS, tier, start}
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = newSampleBucket()
+			buckets[key] = bucket
+			order = append(order, key)
+		}
+		bucket.add(sample)
+	}
+
+	for _, key := range order {
+		kept = append(kept, buckets[key].snapshot(key.start))
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Time.Before(kept[j].Time) })
+	return kept
+}
+
+// sampleBucket merges the samples of one UPS within one window.
+type sampleBucket struct {
+	latest    Snapshot
+	variables valueAggregate
+	derived   valueAggregate
+}
+
+func newSampleBucket() *sampleBucket {
+	return &sampleBucket{variables: newValueAggregate(), derived: newValueAggregate()}
+}
+
+func (b *sampleBucket) add(sample Snapshot) {
+	if b.latest.Time.IsZero() || !sample.Time.Before(b.latest.Time) {
+		b.latest = sample
+	}
+	b.variables.add(sample.Variables)
+	b.derived.add(sample.Derived)
+}
+
+// snapshot returns the merged sample, timestamped with the window start.
+func (b *sampleBucket) snapshot(start time.Time) Snapshot {
+	variables := b.variables.values()
+	return Snapshot{
+		Time:        start,
+		Server:      b.latest.Server,
+		UPS:         b.latest.UPS,
+		Description: b.latest.Description,
+		Status:      ParseStatus(variables["ups.status"]),
+		Variables:   variables,
+		Derived:     b.derived.values(),
+	}
+}
+
+// valueAggregate aggregates numeric values by name and keeps the latest value
+// of names with any non-numeric value.
+type valueAggregate struct {
+	stats map[string]*SeriesPoint
+	last  map[string]string
+	text  map[string]bool
+}
+
+func newValueAggregate() valueAggregate {
+	return valueAggregate{stats: map[string]*SeriesPoint{}, last: map[string]string{}, text: map[string]bool{}}
+}
+
+func (a valueAggregate) add(values map[string]string) {
+	for name, raw := range values {
+		if isStatName(values, name) {
+			continue
+		}
+		a.last[name] = raw
+		point, ok := variableStats(values, name)
+		if !ok {
+			a.text[name] = true
+			continue
+		}
+		if a.stats[name] == nil {
+			a.stats[name] = &SeriesPoint{}
+		}
+		a.stats[name].merge(point)
+	}
+}
+
+func (a valueAggregate) values() map[string]string {
+	if len(a.last) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(a.last))
+	for name, last := range a.last {
+		if a.text[name] {
+			values[name] = last
+			continue
+		}
+		point := a.stats[name]
+		values[name] = strconv.FormatFloat(point.Avg, 'f', -1, 64)
+		values[name+".min"] = strconv.FormatFloat(point.Min, 'f', -1, 64)
+		values[name+".max"] = strconv.FormatFloat(point.Max, 'f', -1, 64)
+		values[name+".count"] = strconv.Itoa(point.Count)
+	}
+	return values
+}
+
+// Apply returns samples and events compacted by the policy as of now, for
+// HistoryCompactor implementations of stores in other packages.
+func (p RetentionPolicy) Apply(samples []Snapshot, events []Event, now time.Time) ([]Snapshot, []Event, error) {
+	if err := p.validate(); err != nil {
+		return nil, nil, err
+	}
+	sorted := append([]Snapshot(nil), samples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	return p.compactSamples(sorted, now), p.compactEvents(events, now), nil
+}
+
+// RunHistoryCompaction applies policy to store now and then every interval
+// (default 1 hour) until ctx is done, for long-running daemons. It returns an
+// error at once if the policy is invalid, the store does not implement
+// HistoryCompactor or the first compaction fails; later failures are retried
+// at the next interval.
+func RunHistoryCompaction(ctx context.Context, store HistoryStore, policy RetentionPolicy, interval time.Duration) error {
+	compactor, ok := store.(HistoryCompactor)
+	if !ok {
+		return fmt.Errorf("history store %T does not support compaction", store)
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	clock := clockOrSystem(policy.Clock)
+	if err := compactor.Compact(ctx, policy, clock.Now()); err != nil {
+		return err
+	}
+
+	ticker := clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+		_ = compactor.Compact(ctx, policy, clock.Now())
+	}
+}
+
+// Compact applies policy to the samples and events in memory.
+func (h *memoryHistory) Compact(ctx context.Context, policy RetentionPolicy, now time.Time) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples = policy.compactSamples(h.samples, now)
+	h.events = policy.compactEvents(h.events, now)
+	return nil
+}
+
+// Compact applies policy by rewriting samples.jsonl and events.jsonl. Appends
+// wait until it is done; queries running concurrently read the old files.
+func (h *fileHistory) Compact(ctx context.Context, policy RetentionPolicy, now time.Time) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.samples == nil {
+		return os.ErrClosed
+	}
+
+	var samples []Snapshot
+	err := h.scanFile(ctx, "samples.jsonl", func(line []byte) error {
+		sample, err := decodeHistorySample(line)
+		if err == nil {
+			samples = append(samples, sample)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+	var events []Event
+	err = h.scanFile(ctx, "events.jsonl", func(line []byte) error {
+		var event Event
+		err := event.UnmarshalJSON(line)
+		if err == nil {
+			events = append(events, event)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	samples = policy.compactSamples(samples, now)
+	if h.samples, err = h.rewrite("samples.jsonl", h.samples, func(f *os.File) error {
+		return writeHistorySamples(f, samples)
+	}); err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+	events = policy.compactEvents(events, now)
+	if h.events, err = h.rewrite("events.jsonl", h.events, func(f *os.File) error {
+		return writeHistoryEvents(f, events)
+	}); err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+	return nil
+}
+
+// rewrite replaces the named file with the output of write through a
+// temporary file and returns the file reopened for appending. On failure the
+// old file is left in place and returned.
+func (h *fileHistory) rewrite(name string, old *os.File, write func(f *os.File) error) (*os.File, error) {
+	path := filepath.Join(h.dir, name)
+	tmp, err := os.CreateTemp(h.dir, name+".*.tmp")
+	if err != nil {
+		return old, err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return old, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return old, err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return old, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return old, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return old, err
+	}
+	old.Close()
+	return f, nil
+}
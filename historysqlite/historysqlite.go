@@ -0,0 +1,252 @@
+// Package historysqlite stores UPS history in an SQLite database, using the
+// pure Go driver modernc.org/sqlite so that no C toolchain is needed:
+//
+//	store, err := historysqlite.Open("/var/lib/ups-monitor/history.sqlite")
+//	...
+//	store.AppendSamples(ctx, fleet.AllUPS())
+//
+// Samples and events are rows indexed by time and UPS, so the database can
+// also be queried directly with SQL. The store implements nut.HistoryCompactor.
+//
+// It is a separate module so that the main module keeps depending only on the
+// standard library.
+package historysqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the tables. Times are Unix nanoseconds; variables and
+// events are JSON.
+const schema = `
+CREATE TABLE IF NOT EXISTS samples (
+	id          INTEGER PRIMARY KEY,
+	time        INTEGER NOT NULL,
+	server      TEXT NOT NULL,
+	ups         TEXT NOT NULL,
+	description TEXT NOT NULL,
+	variables   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_time ON samples (time);
+CREATE INDEX IF NOT EXISTS samples_ups_time ON samples (ups, time);
+CREATE TABLE IF NOT EXISTS events (
+	id     INTEGER PRIMARY KEY,
+	time   INTEGER NOT NULL,
+	server TEXT NOT NULL,
+	ups    TEXT NOT NULL,
+	event  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_time ON events (time);
+CREATE INDEX IF NOT EXISTS events_ups_time ON events (ups, time);
+`
+
+// Store is a nut.HistoryStore in an SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+var _ nut.HistoryCompactor = (*Store)(nil)
+
+// Open opens or creates the database at path.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("opening history database: %w", err)
+	}
+	// SQLite allows one writer; a single connection also keeps Compact's
+	// transaction from waiting on the store's own readers
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening history database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// AppendSamples stores samples.
+func (s *Store) AppendSamples(ctx context.Context, samples []nut.Snapshot) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return insertSamples(ctx, tx, samples)
+	})
+}
+
+func insertSamples(ctx context.Context, db execer, samples []nut.Snapshot) error {
+	for _, sample := range samples {
+		variables, err := json.Marshal(sample.Variables)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO samples (time, server, ups, description, variables) VALUES (?, ?, ?, ?, ?)`,
+			sample.Time.UnixNano(), sample.Server, sample.UPS, sample.Description, string(variables))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendEvents stores events.
+func (s *Store) AppendEvents(ctx context.Context, events []nut.Event) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return insertEvents(ctx, tx, events)
+	})
+}
+
+func insertEvents(ctx context.Context, db execer, events []nut.Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO events (time, server, ups, event) VALUES (?, ?, ?, ?)`,
+			event.Time.UnixNano(), event.Server, event.UPS, string(data))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Samples returns the samples matching query in time order.
+func (s *Store) Samples(ctx context.Context, query nut.HistoryQuery) ([]nut.Snapshot, error) {
+	return readSamples(ctx, s.db, query)
+}
+
+func readSamples(ctx context.Context, db execer, query nut.HistoryQuery) ([]nut.Snapshot, error) {
+	where, args := whereClause(query)
+	rows, err := db.QueryContext(ctx, `SELECT time, server, ups, description, variables FROM samples`+where+` ORDER BY time, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []nut.Snapshot
+	for rows.Next() {
+		var nanos int64
+		var sample nut.Snapshot
+		var variables string
+		if err := rows.Scan(&nanos, &sample.Server, &sample.UPS, &sample.Description, &variables); err != nil {
+			return samples, err
+		}
+		if err := json.Unmarshal([]byte(variables), &sample.Variables); err != nil {
+			return samples, fmt.Errorf("sample at %d: %w", nanos, err)
+		}
+		sample.Time = time.Unix(0, nanos).UTC()
+		sample.Status = nut.ParseStatus(sample.Variables["ups.status"])
+		sample.Derived = nut.DerivedVariables(sample.Variables, sample.Time)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// Events returns the events matching query in time order.
+func (s *Store) Events(ctx context.Context, query nut.HistoryQuery) ([]nut.Event, error) {
+	return readEvents(ctx, s.db, query)
+}
+
+func readEvents(ctx context.Context, db execer, query nut.HistoryQuery) ([]nut.Event, error) {
+	where, args := whereClause(query)
+	rows, err := db.QueryContext(ctx, `SELECT event FROM events`+where+` ORDER BY time, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []nut.Event
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return events, err
+		}
+		var event nut.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// whereClause returns the WHERE clause selecting the rows matching query.
+func whereClause(query nut.HistoryQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if query.Server != "" {
+		conditions, args = append(conditions, "server = ?"), append(args, query.Server)
+	}
+	if query.UPS != "" {
+		conditions, args = append(conditions, "ups = ?"), append(args, query.UPS)
+	}
+	if !query.From.IsZero() {
+		conditions, args = append(conditions, "time >= ?"), append(args, query.From.UnixNano())
+	}
+	if !query.To.IsZero() {
+		conditions, args = append(conditions, "time < ?"), append(args, query.To.UnixNano())
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Compact applies policy, rewriting both tables in one transaction.
+func (s *Store) Compact(ctx context.Context, policy nut.RetentionPolicy, now time.Time) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		samples, err := readSamples(ctx, tx, nut.HistoryQuery{})
+		if err != nil {
+			return err
+		}
+		events, err := readEvents(ctx, tx, nut.HistoryQuery{})
+		if err != nil {
+			return err
+		}
+		if samples, events, err = policy.Apply(samples, events, now); err != nil {
+			return err
+		}
+		for _, table := range []string{"samples", "events"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return err
+			}
+		}
+		if err := insertSamples(ctx, tx, samples); err != nil {
+			return err
+		}
+		return insertEvents(ctx, tx, events)
+	})
+	if err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (s *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
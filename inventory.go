@@ -0,0 +1,158 @@
+package nut
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InventoryItem describes the hardware of one UPS for asset management.
+// Fields the driver does not report are empty.
+type InventoryItem struct {
+	Server        string `json:"server"`
+	UPS           string `json:"ups"`
+	Description   string `json:"description,omitempty"`    // desc= from ups.conf
+	Location      string `json:"location,omitempty"`       // device.location
+	Manufacturer  string `json:"manufacturer,omitempty"`   // device.mfr or ups.mfr
+	Model         string `json:"model,omitempty"`          // device.model or ups.model
+	Serial        string `json:"serial,omitempty"`         // device.serial or ups.serial
+	Firmware      string `json:"firmware,omitempty"`       // ups.firmware
+	Driver        string `json:"driver,omitempty"`         // driver.name
+	DriverVersion string `json:"driver_version,omitempty"` // driver.version
+	BatteryDate   string `json:"battery_date,omitempty"`   // battery.date or battery.mfr.date
+	Status        string `json:"status,omitempty"`
+}
+
+// InventoryError records an endpoint that could not be queried.
+type InventoryError struct {
+	Server string `json:"server"`
+	Error  string `json:"error"`
+}
+
+// Inventory lists the UPSes of a fleet; see Fleet.Inventory.
+type Inventory struct {
+	Time   time.Time        `json:"time"`
+	Items  []InventoryItem  `json:"items"`  // Sorted by server and UPS name
+	Errors []InventoryError `json:"errors"` // Sorted by server
+}
+
+// inventoryFields are the columns of Inventory.WriteCSV.
+var inventoryFields = []string{
+	"server", "ups", "description", "location", "manufacturer", "model", "serial",
+	"firmware", "driver", "driver_version", "battery_date", "status",
+}
+
+// NewInventoryItem extracts the inventory fields of a snapshot.
+func NewInventoryItem(snapshot Snapshot) InventoryItem {
+	first := func(names ...string) string {
+		for _, name := range names {
+			if value := snapshot.Variables[name]; value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	return InventoryItem{
+		Server:        snapshot.Server,
+		UPS:           snapshot.UPS,
+		Description:   snapshot.Description,
+		Location:      first("device.location", "ups.location"),
+		Manufacturer:  first("device.mfr", "ups.mfr"),
+		Model:         first("device.model", "ups.model"),
+		Serial:        first("device.serial", "ups.serial"),
+		Firmware:      first("ups.firmware"),
+		Driver:        first("driver.name"),
+		DriverVersion: first("driver.version"),
+		BatteryDate:   first("battery.date", "battery.mfr.date"),
+		Status:        snapshot.Variables["ups.status"],
+	}
+}
+
+// Inventory reads the current variables of every monitored UPS across all
+// endpoints, concurrently, and returns their hardware details. For an
+// endpoint that is not connected or fails, the state of its last poll is used
+// if there is one, and the failure is listed in Errors.
+func (f *Fleet) Inventory(ctx context.Context) Inventory {
+	monitors := f.monitors()
+	items := make([][]InventoryItem, len(monitors))
+	errs := make([]error, len(monitors))
+
+	var wg sync.WaitGroup
+	for i, monitor := range monitors {
+		wg.Add(1)
+		go func(i int, monitor *Monitor) {
+			defer wg.Done()
+			snapshots, err := monitor.readSnapshots(ctx)
+			if err != nil {
+				snapshots = monitor.Snapshots()
+			}
+			for _, snapshot := range snapshots {
+				items[i] = append(items[i], NewInventoryItem(snapshot))
+			}
+			errs[i] = err
+		}(i, monitor)
+	}
+	wg.Wait()
+
+	inventory := Inventory{Time: time.Now(), Items: []InventoryItem{}, Errors: []InventoryError{}}
+	for i, monitor := range monitors {
+		inventory.Items = append(inventory.Items, items[i]...)
+		if errs[i] != nil {
+			inventory.Errors = append(inventory.Errors, InventoryError{Server: monitor.Server(), Error: errs[i].Error()})
+		}
+	}
+	sort.SliceStable(inventory.Items, func(i, j int) bool {
+		if inventory.Items[i].Server != inventory.Items[j].Server {
+			return inventory.Items[i].Server < inventory.Items[j].Server
+		}
+		return inventory.Items[i].UPS < inventory.Items[j].UPS
+	})
+	return inventory
+}
+
+// readSnapshots reads the current state of the monitored UPSes through the
+// monitor's session.
+func (m *Monitor) readSnapshots(ctx context.Context) ([]Snapshot, error) {
+	m.mu.Lock()
+	backend := m.backend
+	names := make([]string, 0, len(m.watchers))
+	for name := range m.watchers {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		names = append(names, m.config.UPS...)
+	}
+	m.mu.Unlock()
+
+	if backend == nil {
+		return nil, fmt.Errorf("endpoint %s is not connected", m.server)
+	}
+	return ReadSnapshots(ctx, backend, m.server, names...)
+}
+
+// WriteJSON writes the inventory as an indented JSON document.
+func (inv Inventory) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inv)
+}
+
+// WriteCSV writes the items of the inventory as CSV with a header row, for
+// spreadsheets and asset databases. Errors are not included.
+func (inv Inventory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(inventoryFields)
+	for _, item := range inv.Items {
+		cw.Write([]string{
+			item.Server, item.UPS, item.Description, item.Location, item.Manufacturer, item.Model, item.Serial,
+			item.Firmware, item.Driver, item.DriverVersion, item.BatteryDate, item.Status,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,146 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OutletRule describes when an outlet may be switched off to extend runtime.
+type OutletRule struct {
+	Outlet     int           // Outlet number, as in outlet.N.load.off
+	MinCharge  float64       // Shed when battery.charge (percent) drops below this; 0 disables
+	MinRuntime time.Duration // Shed when battery.runtime drops below this; 0 disables
+}
+
+// LoadShedder switches off low-priority outlets while a UPS is on battery and
+// restores them once line power returns.
+type LoadShedder struct {
+	ups   *UPS
+	rules []OutletRule
+
+	mu   sync.Mutex
+	shed map[int]bool
+}
+
+// NewLoadShedder returns a LoadShedder for ups. rules are ordered from lowest to
+// highest priority; lower priority outlets should use higher thresholds so they
+// are shed first. The rules count as confirmation for switching off their
+// outlets, so the client does not need WithAllowDestructive.
+func NewLoadShedder(ups *UPS, rules []OutletRule) *LoadShedder {
+	return &LoadShedder{
+		ups:   ups,
+		rules: rules,
+		shed:  make(map[int]bool),
+	}
+}
+
+// Shed returns the outlets currently switched off by the shedder.
+func (l *LoadShedder) Shed() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	outlets := []int{}
+	for _, rule := range l.rules {
+		if l.shed[rule.Outlet] {
+			outlets = append(outlets, rule.Outlet)
+		}
+	}
+	return outlets
+}
+
+// Evaluate reads the UPS status, battery charge and runtime once and sheds or
+// restores outlets accordingly.
+func (l *LoadShedder) Evaluate(ctx context.Context) error {
+	status, err := l.ups.getStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if status.Has(StatusOnline) && !status.Has(StatusOnBattery) {
+		return l.restore(ctx)
+	}
+	if !status.Has(StatusOnBattery) {
+		return nil
+	}
+
+	charge, chargeErr := l.readFloat(ctx, "battery.charge")
+	runtime, runtimeErr := l.readFloat(ctx, "battery.runtime")
+	if chargeErr != nil && runtimeErr != nil {
+		return fmt.Errorf("reading battery state: %w", chargeErr)
+	}
+
+	for _, rule := range l.rules {
+		if l.shed[rule.Outlet] {
+			continue
+		}
+		below := (chargeErr == nil && rule.MinCharge > 0 && charge < rule.MinCharge) ||
+			(runtimeErr == nil && rule.MinRuntime > 0 && time.Duration(runtime)*time.Second < rule.MinRuntime)
+		if !below {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := l.ups.instCmd(ctx, fmt.Sprintf("outlet.%d.load.off", rule.Outlet), true); err != nil {
+			return fmt.Errorf("shedding outlet %d: %w", rule.Outlet, err)
+		}
+		l.shed[rule.Outlet] = true
+		if logger := l.ups.nutClient.loggerFor(LogInfo); logger != nil {
+			logger.Printf("Shed outlet %d on %s (charge %.0f%%, runtime %.0fs)", rule.Outlet, l.ups.Name, charge, runtime)
+		}
+	}
+	return nil
+}
+
+// restore switches shed outlets back on, highest priority first. l.mu must be held.
+func (l *LoadShedder) restore(ctx context.Context) error {
+	for i := len(l.rules) - 1; i >= 0; i-- {
+		outlet := l.rules[i].Outlet
+		if !l.shed[outlet] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := l.ups.SendCommand(fmt.Sprintf("outlet.%d.load.on", outlet)); err != nil {
+			return fmt.Errorf("restoring outlet %d: %w", outlet, err)
+		}
+		delete(l.shed, outlet)
+		if logger := l.ups.nutClient.loggerFor(LogInfo); logger != nil {
+			logger.Printf("Restored outlet %d on %s", outlet, l.ups.Name)
+		}
+	}
+	return nil
+}
+
+// Run calls Evaluate every interval until ctx is done. Evaluation errors are
+// logged and do not stop the loop.
+func (l *LoadShedder) Run(ctx context.Context, interval time.Duration) error {
+	ticker := clockOrSystem(l.ups.nutClient.clock).NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.Evaluate(ctx); err != nil && ctx.Err() == nil {
+			l.ups.nutClient.logf(LogError, "Load shedding on %s failed: %v", l.ups.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+func (l *LoadShedder) readFloat(ctx context.Context, name string) (float64, error) {
+	value, err := l.ups.getVariableValue(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
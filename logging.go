@@ -0,0 +1,155 @@
+package nut
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LogLevel selects which messages a Client logs.
+type LogLevel int
+
+// Log levels, from least to most verbose. Each level includes the ones
+// before it.
+const (
+	LogOff   LogLevel = iota
+	LogError          // Failed connections and commands
+	LogWarn           // Errors reported by upsd and recoverable problems
+	LogInfo           // Connections and state-changing operations
+	LogDebug          // Every command sent and response received (default)
+)
+
+var logLevelNames = []string{"off", "error", "warn", "info", "debug"}
+
+// String returns the lower-case name of the level.
+func (l LogLevel) String() string {
+	if l >= 0 && int(l) < len(logLevelNames) {
+		return logLevelNames[l]
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLogLevel parses a level name as returned by LogLevel.String.
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogOff, fmt.Errorf("unknown log level %q", name)
+}
+
+// logConfig is the logger and level of a Client once changed at runtime.
+type logConfig struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+// clientLogging holds a Client's runtime logging configuration. Until
+// SetLogger or SetLogLevel is called, the Client's Logger field and the level
+// from WithLogLevel apply.
+type clientLogging struct {
+	mu      sync.Mutex // Serializes updates
+	current atomic.Pointer[logConfig]
+	level   LogLevel // Level from WithLogLevel
+	leveled bool
+}
+
+// WithLogLevel limits logging to messages of the given level or more severe.
+// Without it, everything is logged at LogDebug.
+func WithLogLevel(level LogLevel) ClientOption {
+	return func(c *Client) {
+		c.logging.level, c.logging.leveled = level, true
+	}
+}
+
+// logConfig returns the logger and level in effect.
+func (c *Client) logConfig() logConfig {
+	if current := c.logging.current.Load(); current != nil {
+		return *current
+	}
+	config := logConfig{logger: c.Logger, level: LogDebug}
+	if c.logging.leveled {
+		config.level = c.logging.level
+	}
+	return config
+}
+
+// SetLogger replaces the logger of a live client, e.g. to start debugging a
+// misbehaving production connection without reconnecting; nil disables
+// logging. It is safe to call concurrently with commands, unlike assigning
+// the Logger field, which only takes effect until the first call.
+func (c *Client) SetLogger(logger *log.Logger) {
+	c.logging.mu.Lock()
+	defer c.logging.mu.Unlock()
+	config := c.logConfig()
+	config.logger = logger
+	c.logging.current.Store(&config)
+}
+
+// SetLogLevel changes the log level of a live client.
+func (c *Client) SetLogLevel(level LogLevel) {
+	c.logging.mu.Lock()
+	defer c.logging.mu.Unlock()
+	config := c.logConfig()
+	config.level = level
+	c.logging.current.Store(&config)
+}
+
+// LogLevel returns the log level in effect.
+func (c *Client) LogLevel() LogLevel {
+	return c.logConfig().level
+}
+
+// loggerFor returns the logger if messages of the given level are logged, or
+// nil.
+func (c *Client) loggerFor(level LogLevel) *log.Logger {
+	config := c.logConfig()
+	if config.logger == nil || level > config.level {
+		return nil
+	}
+	return config.logger
+}
+
+// logf logs a message of the given level.
+func (c *Client) logf(level LogLevel, format string, args ...interface{}) {
+	if logger := c.loggerFor(level); logger != nil {
+		logger.Printf(format, args...)
+	}
+}
+
+// SetLogger replaces the logger of the monitor's current session and of the
+// sessions it opens after reconnecting; nil disables logging. It has no effect
+// on a custom MonitorConfig.Backend that does not talk to upsd.
+func (m *Monitor) SetLogger(logger *log.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger, m.loggerSet = logger, true
+	m.applyLogging()
+}
+
+// SetLogLevel changes the log level of the monitor's current session and of
+// the sessions it opens after reconnecting.
+func (m *Monitor) SetLogLevel(level LogLevel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logLevel, m.logLevelSet = level, true
+	m.applyLogging()
+}
+
+// applyLogging applies the logger and level set on the monitor, if any, to
+// the current session. m.mu must be held.
+func (m *Monitor) applyLogging() {
+	nb, ok := m.backend.(*nutBackend)
+	if !ok {
+		return
+	}
+	if m.loggerSet {
+		nb.client.SetLogger(m.logger)
+	}
+	if m.logLevelSet {
+		nb.client.SetLogLevel(m.logLevel)
+	}
+}
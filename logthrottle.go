@@ -0,0 +1,96 @@
+package nut
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewThrottledLogger returns a logger that forwards messages to logger, at
+// most limit identical messages per window. Suppressed messages are counted
+// and summarized once the window has passed, as
+//
+//	suppressed 42 identical messages: Sending command: GET VAR ups ups.status
+//
+// Messages differing only in their request ID prefix, such as "[42]
+// Sending command: ...", count as identical.
+//
+// The summary is written with the next message logged after the window, so a
+// burst that is never followed by another message is not summarized. Use it
+// with WithLogger to keep debug logging affordable when polling many UPSes:
+//
+//	logger := log.New(os.Stderr, "[NUT] ", log.LstdFlags)
+//	client, err := nut.ConnectWithOptionsAndConfig(ctx, "localhost",
+//		[]nut.ClientOption{nut.WithLogger(nut.NewThrottledLogger(logger, 5, time.Minute))}, 3493)
+func NewThrottledLogger(logger *log.Logger, limit int, window time.Duration) *log.Logger {
+	if limit <= 0 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = time.Minute
+	}
+	w := &throttledWriter{
+		out:    logger,
+		limit:  limit,
+		window: window,
+		counts: map[string]int{},
+	}
+	return log.New(w, "", 0)
+}
+
+// throttledWriter counts the messages written in the current window, keyed by
+// their text without the request ID prefix (see throttleKey).
+type throttledWriter struct {
+	out    *log.Logger
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	msg := string(p)
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.Sub(w.start) >= w.window {
+		w.flush()
+		w.start = now
+	}
+	key := throttleKey(msg)
+	w.counts[key]++
+	if w.counts[key] <= w.limit {
+		if err := w.out.Output(4, msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// flush summarizes the messages suppressed in the window and starts a new one.
+func (w *throttledWriter) flush() {
+	for msg, count := range w.counts {
+		if suppressed := count - w.limit; suppressed > 0 {
+			w.out.Output(5, fmt.Sprintf("suppressed %d identical messages: %s", suppressed, msg))
+		}
+	}
+	w.counts = map[string]int{}
+}
+
+// throttleKey returns msg without the "[<request ID>] " prefix of command
+// logs, so that messages differing only in their request ID count as
+// identical.
+func throttleKey(msg string) string {
+	if !strings.HasPrefix(msg, "[") {
+		return msg
+	}
+	if i := strings.Index(msg, "] "); i > 0 {
+		return msg[i+2:]
+	}
+	return msg
+}
@@ -0,0 +1,30 @@
+package nut_test
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestThrottledLoggerIgnoresRequestIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := nut.NewThrottledLogger(log.New(&buf, "", 0), 2, time.Hour)
+	for id := 1; id <= 5; id++ {
+		logger.Printf("[%d] Sending command: GET VAR ups1 ups.status", id)
+	}
+	logger.Printf("[6] Sending command: LIST UPS")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"[1] Sending command: GET VAR ups1 ups.status",
+		"[2] Sending command: GET VAR ups1 ups.status",
+		"[6] Sending command: LIST UPS",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("logged:\n%s\nwant:\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+}
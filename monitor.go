@@ -0,0 +1,579 @@
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MonitorConfig configures a Monitor for a single upsd endpoint.
+type MonitorConfig struct {
+	Host           string         // NUT server hostname
+	Port           int            // NUT server port (default 3493)
+	Username       string         // Optional username for Authenticate
+	Password       string         // Optional password for Authenticate
+	StartTLS       bool           // Upgrade the connection with STARTTLS before authenticating
+	ClientOptions  []ClientOption // Options to apply to the client
+	UPS            []string       // UPS names to monitor; empty monitors all UPSes on the server
+	Interval       time.Duration  // Polling interval (default 5s)
+	FastInterval   time.Duration  // Optional interval while any UPS is OB, LB, ALARM or FSD and for a minute after
+	ReconnectDelay time.Duration  // Delay between reconnection attempts (default Interval)
+	WatchClients   bool           // Also emit client attach/detach events
+	EventHandler   func(Event)    // Receives all events; called from the monitor goroutine
+	Clock          Clock          // Clock timing polls and events (default the system clock); not changed by Reload
+
+	// Credentials, if set, supplies the username, password and TLS settings
+	// on every connection attempt, replacing Username, Password and StartTLS.
+	Credentials CredentialProvider
+
+	// Backend, if set, opens the data source instead of connecting to upsd;
+	// Host and Port then only identify the endpoint, and the connection
+	// settings above are ignored.
+	Backend func(ctx context.Context) (Backend, error)
+}
+
+// MonitorHealth describes the state of a Monitor's connection.
+type MonitorHealth struct {
+	Server              string
+	Connected           bool
+	LastPoll            time.Time // Time of the last successful poll
+	LastError           error     // Connection error, or the errors of individual UPSes in the last poll
+	ConsecutiveFailures int       // Consecutive failed connection attempts or polls
+}
+
+// Monitor maintains a connection to one upsd endpoint, polls its UPSes and
+// emits events for changes. It reconnects automatically after failures. A UPS
+// whose driver upsd reports as not connected is skipped, with EventCommLost
+// when that starts and EventCommRestored when it is polled again. Any other
+// error upsd reports for a single UPS is emitted as an EventError for that
+// UPS, and the other UPSes are still polled.
+type Monitor struct {
+	config MonitorConfig
+	server string
+
+	mu       sync.Mutex
+	backend  Backend
+	watchers map[string]*Watcher
+	descs    map[string]string
+	ignored  map[string]bool // UPSes removed while monitoring all UPSes
+	outages  map[string]*outageTracker
+	commLost map[string]bool // UPSes whose driver is not connected to upsd
+	health   MonitorHealth
+	clock    Clock
+	bus      *EventBus
+
+	// Set by SetLogger and SetLogLevel, applied to every session
+	logger      *log.Logger
+	loggerSet   bool
+	logLevel    LogLevel
+	logLevelSet bool
+
+	// Set by Reload and AddUPS, applied by the Run goroutine on its next poll
+	reconnectPending bool
+	rebindPending    bool
+}
+
+// NewMonitor validates config and returns a Monitor. Call Run to start it.
+func NewMonitor(config MonitorConfig) (*Monitor, error) {
+	config, err := config.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	server := config.server()
+	return &Monitor{
+		config:   config,
+		server:   server,
+		watchers: map[string]*Watcher{},
+		descs:    map[string]string{},
+		ignored:  map[string]bool{},
+		outages:  map[string]*outageTracker{},
+		commLost: map[string]bool{},
+		health:   MonitorHealth{Server: server},
+		clock:    clockOrSystem(config.Clock),
+		bus:      NewEventBus(),
+	}, nil
+}
+
+// withDefaults validates the configuration and fills in default values.
+func (config MonitorConfig) withDefaults() (MonitorConfig, error) {
+	if config.Host == "" {
+		return config, fmt.Errorf("hostname is required")
+	}
+	if config.Port == 0 {
+		config.Port = 3493
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultWatchInterval
+	}
+	if config.ReconnectDelay <= 0 {
+		config.ReconnectDelay = config.Interval
+	}
+	return config, nil
+}
+
+// server returns the endpoint address as host:port.
+func (config MonitorConfig) server() string {
+	return net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+}
+
+// sameConnection reports whether two configurations would establish identical
+// sessions. Client options cannot be compared and count as a difference.
+func (config MonitorConfig) sameConnection(other MonitorConfig) bool {
+	return config.Host == other.Host &&
+		config.Port == other.Port &&
+		config.Username == other.Username &&
+		config.Password == other.Password &&
+		config.StartTLS == other.StartTLS &&
+		config.Credentials == nil && other.Credentials == nil &&
+		len(config.ClientOptions) == 0 && len(other.ClientOptions) == 0 &&
+		config.Backend == nil && other.Backend == nil
+}
+
+// Reload applies a new configuration without restarting the monitor. Watchers
+// for UPSes no longer listed are dropped immediately and new ones are added on
+// the next poll. If the connection settings changed, the session is closed and
+// re-established with the new settings on the next poll. The endpoint (host and
+// port) itself cannot change.
+func (m *Monitor) Reload(config MonitorConfig) error {
+	config, err := config.withDefaults()
+	if err != nil {
+		return err
+	}
+	if config.server() != m.server {
+		return fmt.Errorf("cannot change monitor endpoint from %s to %s", m.server, config.server())
+	}
+
+	m.mu.Lock()
+	reconnect := !m.config.sameConnection(config)
+	m.config = config
+	m.ignored = map[string]bool{}
+	if len(config.UPS) > 0 {
+		wanted := map[string]bool{}
+		for _, name := range config.UPS {
+			wanted[name] = true
+		}
+		for name := range m.watchers {
+			if !wanted[name] {
+				delete(m.watchers, name)
+				delete(m.descs, name)
+				delete(m.outages, name)
+			}
+		}
+	}
+	if reconnect {
+		m.reconnectPending = true
+	} else {
+		m.rebindPending = true
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// AddUPS starts monitoring the named UPS. It takes effect on the next poll.
+func (m *Monitor) AddUPS(name string) {
+	m.mu.Lock()
+	delete(m.ignored, name)
+	listed := len(m.config.UPS) == 0
+	for _, existing := range m.config.UPS {
+		if existing == name {
+			listed = true
+		}
+	}
+	if !listed {
+		m.config.UPS = append(append([]string{}, m.config.UPS...), name)
+	}
+	m.rebindPending = true
+	m.mu.Unlock()
+}
+
+// RemoveUPS stops monitoring the named UPS and discards its state.
+func (m *Monitor) RemoveUPS(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.watchers, name)
+	delete(m.descs, name)
+	delete(m.outages, name)
+	delete(m.commLost, name)
+	if len(m.config.UPS) == 0 {
+		m.ignored[name] = true
+		return
+	}
+	names := []string{}
+	for _, existing := range m.config.UPS {
+		if existing != name {
+			names = append(names, existing)
+		}
+	}
+	m.config.UPS = names
+}
+
+// Server returns the endpoint (host:port) of the monitor.
+func (m *Monitor) Server() string {
+	return m.server
+}
+
+// Run connects, polls every Interval and reconnects after failures until ctx
+// is done. The connection is closed when Run returns.
+func (m *Monitor) Run(ctx context.Context) error {
+	defer m.disconnect()
+
+	for {
+		m.mu.Lock()
+		delay, reconnectDelay, fast := m.config.Interval, m.config.ReconnectDelay, m.config.FastInterval
+		m.mu.Unlock()
+
+		if err := m.poll(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if m.isConnected() {
+				m.emit(Event{Type: EventError, Err: err})
+			} else {
+				delay = reconnectDelay
+			}
+		} else if fast > 0 && m.urgent() {
+			delay = fast
+		}
+
+		timer := m.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// urgent reports whether a watched UPS is or recently was on battery, low or
+// alarmed, for FastInterval.
+func (m *Monitor) urgent() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, w := range m.watchers {
+		if w.urgentWithin(defaultAdaptiveSettle) {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshots returns the state of every monitored UPS as of the last poll,
+// sorted by UPS name. Besides DerivedVariables, Derived holds the outage
+// counters derived.outage.count, derived.outage.duration.total and
+// derived.outage.duration.longest (in seconds); see OutageStats.
+func (m *Monitor) Snapshots() []Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshots := make([]Snapshot, 0, len(m.watchers))
+	for name, w := range m.watchers {
+		values := w.Values()
+		snapshots = append(snapshots, Snapshot{
+			Time:        m.health.LastPoll,
+			Server:      m.server,
+			UPS:         name,
+			Description: m.descs[name],
+			Status:      ParseStatus(values["ups.status"]),
+			Variables:   values,
+			Derived:     m.derived(name, values),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].UPS < snapshots[j].UPS })
+	return snapshots
+}
+
+// derived returns the derived variables of a UPS, including its outage
+// counters. m.mu must be held.
+func (m *Monitor) derived(name string, values map[string]string) map[string]string {
+	derived := DerivedVariables(values, m.health.LastPoll)
+	if tracker, ok := m.outages[name]; ok {
+		for k, v := range tracker.at(m.health.LastPoll).derived() {
+			derived[k] = v
+		}
+	}
+	return derived
+}
+
+// OutageStats returns the on-battery counters of a monitored UPS, up to now.
+// ok is false if the UPS has not been polled yet.
+func (m *Monitor) OutageStats(ups string) (stats OutageStats, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tracker, ok := m.outages[ups]
+	if !ok {
+		return OutageStats{}, false
+	}
+	return tracker.at(m.clock.Now()), true
+}
+
+// Health returns the connection health of the monitor.
+func (m *Monitor) Health() MonitorHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health
+}
+
+// poll connects if necessary and polls every watched UPS once.
+func (m *Monitor) poll(ctx context.Context) error {
+	m.mu.Lock()
+	backend, reconnect, rebind := m.backend, m.reconnectPending, m.rebindPending
+	m.reconnectPending, m.rebindPending = false, false
+	if reconnect && backend != nil {
+		m.backend = nil
+		m.health.Connected = false
+	}
+	m.mu.Unlock()
+
+	if reconnect && backend != nil {
+		backend.Close()
+	} else if rebind && backend != nil {
+		if err := m.rebind(ctx, backend); err != nil {
+			m.recordFailure(err)
+			m.dropConnection(err)
+			return err
+		}
+	}
+
+	if !m.isConnected() {
+		if err := m.connect(ctx); err != nil {
+			m.recordFailure(err)
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	backend = m.backend
+	watchers := make([]*Watcher, 0, len(m.watchers))
+	for _, w := range m.watchers {
+		watchers = append(watchers, w)
+	}
+	m.mu.Unlock()
+
+	var upsErrs []error
+	for _, w := range watchers {
+		err := w.Poll(ctx)
+		switch {
+		case err == nil:
+			m.setCommLost(w.name, nil)
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case hasErrorCode(err, ErrCodeDriverNotConnected):
+			// The driver is restarting or down; keep polling the other UPSes
+			m.setCommLost(w.name, err)
+		case isConnectionError(err) || backendBroken(backend):
+			m.recordFailure(err)
+			m.dropConnection(err)
+			return fmt.Errorf("polling %s: %w", w.name, err)
+		default:
+			// upsd rejected this UPS alone; keep polling the other UPSes
+			upsErrs = append(upsErrs, fmt.Errorf("polling %s: %w", w.name, err))
+			m.emit(Event{UPS: w.name, Type: EventError, Err: err})
+		}
+	}
+
+	m.mu.Lock()
+	m.health.LastPoll = m.clock.Now()
+	for name, w := range m.watchers {
+		status, ok := w.Values()["ups.status"]
+		if !ok {
+			continue
+		}
+		tracker, ok := m.outages[name]
+		if !ok {
+			tracker = &outageTracker{}
+			m.outages[name] = tracker
+		}
+		tracker.observe(ParseStatus(status), m.health.LastPoll)
+	}
+	m.health.LastError = errors.Join(upsErrs...)
+	m.health.ConsecutiveFailures = 0
+	m.mu.Unlock()
+	return nil
+}
+
+// connect establishes a session and binds the watchers to its UPSes.
+func (m *Monitor) connect(ctx context.Context) error {
+	m.mu.Lock()
+	config := m.config
+	m.mu.Unlock()
+
+	backend, err := config.openBackend(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.rebind(ctx, backend); err != nil {
+		return err
+	}
+	m.emit(Event{Type: EventServerUp})
+	return nil
+}
+
+// openBackend opens the configured backend, or connects to upsd.
+func (config MonitorConfig) openBackend(ctx context.Context) (Backend, error) {
+	if config.Backend != nil {
+		return config.Backend(ctx)
+	}
+
+	client, err := config.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewNUTBackend(client), nil
+}
+
+// rebind lists the UPSes of backend and binds a watcher to each monitored one,
+// keeping existing baselines so no spurious events follow a reconnect. The
+// backend is closed on failure.
+func (m *Monitor) rebind(ctx context.Context, backend Backend) error {
+	upsList, err := backend.ListUPS(ctx)
+	if err != nil {
+		closeBroken(backend)
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, name := range m.config.UPS {
+		wanted[name] = true
+	}
+
+	m.backend = backend
+	m.applyLogging()
+	for _, ups := range upsList {
+		if (len(wanted) > 0 && !wanted[ups.Name]) || m.ignored[ups.Name] {
+			continue
+		}
+		m.descs[ups.Name] = ups.Description
+		if w, ok := m.watchers[ups.Name]; ok {
+			w.backend = backend
+			w.watchClients = m.config.WatchClients
+			continue
+		}
+		opts := []WatcherOption{WithEventHandler(m.emit), WithClock(m.clock)}
+		if m.config.WatchClients {
+			opts = append(opts, WatchClients())
+		}
+		m.watchers[ups.Name] = NewBackendWatcher(backend, ups.Name, opts...)
+	}
+	m.health.Connected = true
+	return nil
+}
+
+// setCommLost records whether upsd has lost the driver of a UPS, emitting
+// EventCommLost or EventCommRestored when that changes.
+func (m *Monitor) setCommLost(ups string, cause error) {
+	m.mu.Lock()
+	lost := cause != nil
+	changed := m.commLost[ups] != lost
+	if lost {
+		m.commLost[ups] = true
+	} else {
+		delete(m.commLost, ups)
+	}
+	m.mu.Unlock()
+
+	switch {
+	case changed && lost:
+		m.emit(Event{UPS: ups, Type: EventCommLost, Err: cause})
+	case changed:
+		m.emit(Event{UPS: ups, Type: EventCommRestored})
+	}
+}
+
+// dropConnection closes a broken connection and reports the endpoint as down.
+func (m *Monitor) dropConnection(cause error) {
+	m.mu.Lock()
+	backend := m.backend
+	m.backend = nil
+	m.health.Connected = false
+	m.mu.Unlock()
+
+	if backend != nil {
+		closeBroken(backend)
+	}
+	m.emit(Event{Type: EventServerDown, Err: cause})
+}
+
+func (m *Monitor) disconnect() {
+	m.mu.Lock()
+	backend := m.backend
+	m.backend = nil
+	m.health.Connected = false
+	m.mu.Unlock()
+
+	if backend != nil {
+		backend.Close()
+	}
+}
+
+func (m *Monitor) isConnected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.backend != nil
+}
+
+// closeBroken closes a backend whose connection failed, aborting it if it
+// supports that.
+func closeBroken(backend Backend) {
+	if aborter, ok := backend.(BackendAborter); ok {
+		aborter.Abort()
+		return
+	}
+	backend.Close()
+}
+
+func (m *Monitor) recordFailure(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health.LastError = err
+	m.health.ConsecutiveFailures++
+}
+
+func (m *Monitor) emit(event Event) {
+	m.mu.Lock()
+	handler := m.config.EventHandler
+	m.mu.Unlock()
+
+	if event.Time.IsZero() {
+		event.Time = m.clock.Now()
+	}
+	event.Server = m.server
+	if handler != nil {
+		handler(event)
+	}
+	m.bus.Publish(event)
+}
+
+// Subscribe registers a subscriber for the monitor's events in addition to
+// MonitorConfig.EventHandler; see EventBus.Subscribe.
+func (m *Monitor) Subscribe(filter EventFilter, buffer int) *Subscription {
+	return m.bus.Subscribe(filter, buffer)
+}
+
+// isConnectionError reports whether err indicates a broken connection rather
+// than an error reported by upsd or a malformed response. Callers check their
+// own ctx first, so a deadline here is a client's operation budget, which
+// leaves the late reply unread on the connection.
+func isConnectionError(err error) bool {
+	var perr *ProtocolError
+	var parseErr *ParseError
+	return !errors.As(err, &perr) && !errors.As(err, &parseErr) && !errors.Is(err, context.Canceled)
+}
+
+// backendBroken reports whether backend says its connection is out of sync;
+// see BackendBrokenReporter.
+func backendBroken(backend Backend) bool {
+	reporter, ok := backend.(BackendBrokenReporter)
+	return ok && reporter.Broken()
+}
@@ -0,0 +1,219 @@
+package nut_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+// startMonitor runs a Monitor of the endpoint in config on a fake clock,
+// polling every minute, and returns it with the channel receiving its events.
+func startMonitor(t *testing.T, ctx context.Context, config nut.MonitorConfig, clock *nuttest.Clock) (*nut.Monitor, <-chan nut.Event) {
+	t.Helper()
+	events := make(chan nut.Event, 64)
+	config.Interval = time.Minute
+	config.Clock = clock
+	config.EventHandler = func(event nut.Event) { events <- event }
+	monitor, err := nut.NewMonitor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go monitor.Run(ctx)
+	return monitor, events
+}
+
+// nextEvent returns the next event of type kind, skipping others.
+func nextEvent(t *testing.T, events <-chan nut.Event, kind nut.EventType) nut.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == kind {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", kind)
+		}
+	}
+}
+
+func TestMonitorKeepsPollingAfterUPSError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	for _, name := range []string{"ups1", "ups2", "ups3"} {
+		server.AddUPS(name, "Test UPS", map[string]string{"ups.status": "OL", "battery.charge": "100"})
+	}
+	server.SetError("ups2", "DATA-STALE")
+
+	host, port := server.HostPort()
+	clock := nuttest.NewClock(clockStart)
+	monitor, events := startMonitor(t, ctx, nut.MonitorConfig{Host: host, Port: port}, clock)
+	if event := nextEvent(t, events, nut.EventError); event.UPS != "ups2" || !dataStale(event.Err) {
+		t.Fatalf("error event %+v", event)
+	}
+
+	// Map order varies between polls; the healthy UPSes must be polled every time
+	for i, charge := range []string{"90", "80", "70", "60"} {
+		clock.BlockUntil(1)
+		server.SetVar("ups1", "battery.charge", charge)
+		server.SetVar("ups3", "battery.charge", charge)
+		advance(clock, time.Minute)
+		changed := map[string]bool{}
+		for len(changed) < 2 {
+			event := nextEvent(t, events, nut.EventVariableChanged)
+			if event.NewValue != charge {
+				t.Fatalf("poll %d: event %+v", i, event)
+			}
+			changed[event.UPS] = true
+		}
+	}
+
+	health := monitor.Health()
+	if !health.Connected || health.ConsecutiveFailures != 0 || health.LastError == nil || health.LastPoll.IsZero() {
+		t.Fatalf("health %+v", health)
+	}
+	for _, snapshot := range monitor.Snapshots() {
+		if snapshot.UPS != "ups2" && !snapshot.Status.Has(nut.StatusOnline) {
+			t.Errorf("snapshot %+v", snapshot)
+		}
+	}
+}
+
+func TestMonitorReconnectsAfterOperationBudget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+	proxy, err := nuttest.NewFaultProxy(server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	host, port := proxy.HostPort()
+	clock := nuttest.NewClock(clockStart)
+	config := nut.MonitorConfig{Host: host, Port: port, ClientOptions: []nut.ClientOption{nut.WithOperationBudget(100 * time.Millisecond)}}
+	monitor, events := startMonitor(t, ctx, config, clock)
+	nextEvent(t, events, nut.EventServerUp)
+
+	// The reply arrives after the budget, so the connection must not be reused
+	clock.BlockUntil(1)
+	proxy.SetLatency(300 * time.Millisecond)
+	clock.Advance(time.Minute)
+	if event := nextEvent(t, events, nut.EventServerDown); event.Err == nil {
+		t.Fatalf("server down event %+v", event)
+	}
+
+	proxy.SetLatency(0)
+	advance(clock, time.Minute)
+	nextEvent(t, events, nut.EventServerUp)
+	if health := monitor.Health(); !health.Connected {
+		t.Fatalf("health %+v", health)
+	}
+}
+
+func TestMonitorReconnectsAfterDrop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL", "battery.charge": "100"})
+
+	host, port := server.HostPort()
+	clock := nuttest.NewClock(clockStart)
+	monitor, events := startMonitor(t, ctx, nut.MonitorConfig{Host: host, Port: port}, clock)
+	nextEvent(t, events, nut.EventServerUp)
+
+	clock.BlockUntil(1)
+	server.DropConnections()
+	clock.Advance(time.Minute)
+	nextEvent(t, events, nut.EventServerDown)
+	if health := monitor.Health(); health.Connected || health.ConsecutiveFailures != 1 || health.LastError == nil {
+		t.Fatalf("health after drop %+v", health)
+	}
+
+	// The baseline survives the reconnect, so only the real change is reported
+	clock.BlockUntil(1)
+	server.SetVar("ups1", "battery.charge", "95")
+	clock.Advance(time.Minute)
+	nextEvent(t, events, nut.EventServerUp)
+	if event := nextEvent(t, events, nut.EventVariableChanged); event.Variable != "battery.charge" || event.OldValue != "100" || event.NewValue != "95" {
+		t.Fatalf("event %+v", event)
+	}
+	clock.BlockUntil(1)
+	if health := monitor.Health(); !health.Connected || health.ConsecutiveFailures != 0 || health.LastError != nil {
+		t.Fatalf("health after reconnect %+v", health)
+	}
+}
+
+func TestFleetAllUPS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := nuttest.NewClock(clockStart)
+	servers := make([]*nuttest.Server, 2)
+	config := nut.FleetConfig{Clock: clock}
+	for i := range servers {
+		server, err := nuttest.NewServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+		server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+		servers[i] = server
+		host, port := server.HostPort()
+		config.Endpoints = append(config.Endpoints, nut.MonitorConfig{Host: host, Port: port, Interval: time.Minute})
+	}
+	fleet, err := nut.NewFleet(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go fleet.Run(ctx)
+
+	// Both endpoints have polled once when both wait for their next poll
+	clock.BlockUntil(2)
+	snapshots := fleet.AllUPS()
+	if len(snapshots) != 2 || snapshots[0].Server >= snapshots[1].Server {
+		t.Fatalf("snapshots %+v", snapshots)
+	}
+
+	down := servers[1].Addr()
+	servers[1].Close()
+	clock.Advance(time.Minute)
+	clock.BlockUntil(2)
+
+	for _, health := range fleet.Health() {
+		if up := health.Server != down; health.Connected != up || (health.LastError == nil) == !up {
+			t.Errorf("health %+v", health)
+		}
+	}
+	for _, snapshot := range fleet.AllUPS() {
+		want := clockStart.Add(time.Minute)
+		if snapshot.Server == down {
+			want = clockStart // Last successful poll
+		}
+		if !snapshot.Time.Equal(want) {
+			t.Errorf("snapshot of %s at %v, want %v", snapshot.Server, snapshot.Time, want)
+		}
+	}
+}
+
+func dataStale(err error) bool {
+	code, ok := nut.ErrorCodeOf(err)
+	return ok && code == nut.ErrCodeDataStale
+}
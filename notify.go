@@ -0,0 +1,17 @@
+package nut
+
+import "context"
+
+// Notifier delivers events to an external system such as a chat service,
+// pager or mail gateway.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, event Event) error
+
+// Notify calls f(ctx, event).
+func (f NotifierFunc) Notify(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
@@ -7,9 +7,11 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -26,10 +28,66 @@ type Client struct {
 	UseTLS          bool
 	TLSConfig       *tls.Config
 	ConnectTimeout  time.Duration
-	ReadTimeout     time.Duration
-	Logger          *log.Logger // Optional logger for debugging
-	mu              sync.Mutex  // Protects concurrent access to connection
+	ReadTimeout     time.Duration // Timeout for a whole response
+	ListTimeout     time.Duration // Timeout per line of LIST responses; if zero, ReadTimeout bounds the whole response
+	Logger          *log.Logger   // Optional logger for debugging; see SetLogger to change it while in use
+	queue           commandQueue  // Serializes access to connection by command priority
 	metrics         *ClientMetrics
+	skipHandshake   bool
+	dialStagger     time.Duration
+	proxy           func(host string, port int) (*url.URL, error)
+	resolver        *net.Resolver
+
+	limiter           *rateLimiter
+	rateLimitFailFast bool
+
+	dryRun           bool
+	allowDestructive bool
+	auditSink        AuditSink
+
+	sessionMu   sync.Mutex // Guards username, loginUPS and connectedAt
+	username    string
+	loginUPS    string
+	connectedAt time.Time
+
+	redactUsernames bool
+	noRedaction     bool
+
+	strictParsing     bool
+	parseErrorHandler func(*ParseError)
+
+	maxResponseLines int
+	maxResponseBytes int
+
+	rawValues     bool
+	staleFallback bool
+	staleMu       sync.Mutex
+	lastValues    map[string]map[string]string // Raw values by UPS and variable, kept for WithStaleFallback
+
+	driverRetryWait time.Duration // Total wait for DRIVER-NOT-CONNECTED retries; see WithDriverRetry
+	clock           Clock         // Times driver retries, WaitForDriver and LoadShedder; see WithClientClock
+
+	host          string // Hostname and port as passed to Connect, for Reconnect
+	port          int
+	state         int32 // ConnState, accessed atomically
+	onStateChange func(old, new ConnState)
+
+	capsMu       sync.Mutex
+	capabilities *Capabilities // Cached by Capabilities
+
+	operationBudget time.Duration
+
+	broken int32 // Set atomically when the connection may be out of sync
+
+	healthMu            sync.Mutex
+	lastSuccess         time.Time
+	lastError           error
+	consecutiveFailures int
+
+	requestSeq   uint64 // Last request ID assigned, accessed atomically
+	commandTrace func(CommandTrace)
+
+	logging clientLogging
 }
 
 // ClientMetrics holds statistics for a client connection
@@ -39,7 +97,8 @@ type ClientMetrics struct {
 	BytesSent       uint64
 	BytesReceived   uint64
 	Reconnects      uint64
-	LastCommandTime atomic.Value // time.Time
+	LastCommandTime atomic.Value // time.Time, filled in by GetMetrics
+	lastCommand     int64        // Unix nanoseconds; a time.Time in an atomic.Value would allocate per command
 }
 
 // GetMetrics returns a copy of the current metrics
@@ -47,13 +106,40 @@ func (c *Client) GetMetrics() ClientMetrics {
 	if c.metrics == nil {
 		return ClientMetrics{}
 	}
-	return ClientMetrics{
+	metrics := ClientMetrics{
 		CommandsSent:   atomic.LoadUint64(&c.metrics.CommandsSent),
 		CommandsFailed: atomic.LoadUint64(&c.metrics.CommandsFailed),
 		BytesSent:      atomic.LoadUint64(&c.metrics.BytesSent),
 		BytesReceived:  atomic.LoadUint64(&c.metrics.BytesReceived),
 		Reconnects:     atomic.LoadUint64(&c.metrics.Reconnects),
 	}
+	if last := atomic.LoadInt64(&c.metrics.lastCommand); last != 0 {
+		metrics.LastCommandTime.Store(time.Unix(0, last))
+	}
+	return metrics
+}
+
+// countSent records a command of n bytes sent to the server.
+func (c *Client) countSent(n int) {
+	if c.metrics != nil {
+		atomic.AddUint64(&c.metrics.CommandsSent, 1)
+		atomic.AddUint64(&c.metrics.BytesSent, uint64(n))
+		atomic.StoreInt64(&c.metrics.lastCommand, time.Now().UnixNano())
+	}
+}
+
+// countReceived records a response line of n bytes, without its newline.
+func (c *Client) countReceived(n int) {
+	if c.metrics != nil {
+		atomic.AddUint64(&c.metrics.BytesReceived, uint64(n+1))
+	}
+}
+
+// countFailed records a failed command.
+func (c *Client) countFailed() {
+	if c.metrics != nil {
+		atomic.AddUint64(&c.metrics.CommandsFailed, 1)
+	}
 }
 
 // ClientOption is a function that configures a Client
@@ -73,6 +159,32 @@ func WithReadTimeout(timeout time.Duration) ClientOption {
 	}
 }
 
+// WithListTimeout sets the timeout for multi-line LIST responses. It applies to
+// each line rather than the whole response, as ReadTimeout does, so large LIST
+// VAR replies from slow servers are not cut off as long as lines keep arriving.
+func WithListTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.ListTimeout = timeout
+	}
+}
+
+// WithMaxResponseLines limits the number of lines accepted in a single
+// response. Longer responses fail with ErrResponseTooLarge and the connection is
+// closed, since the remainder of the response cannot be skipped reliably.
+func WithMaxResponseLines(lines int) ClientOption {
+	return func(c *Client) {
+		c.maxResponseLines = lines
+	}
+}
+
+// WithMaxResponseBytes limits the total size of a single response. Larger
+// responses fail with ErrResponseTooLarge and the connection is closed.
+func WithMaxResponseBytes(bytes int) ClientOption {
+	return func(c *Client) {
+		c.maxResponseBytes = bytes
+	}
+}
+
 // WithTLSConfig sets a custom TLS configuration
 func WithTLSConfig(config *tls.Config) ClientOption {
 	return func(c *Client) {
@@ -87,6 +199,49 @@ func WithLogger(logger *log.Logger) ClientOption {
 	}
 }
 
+// WithSkipHandshake disables the VER/NETVER exchange performed during connect.
+// Some hardened upsd configurations or proxies reject these commands before
+// authentication; Version and ProtocolVersion stay empty until GetVersion or
+// GetNetworkProtocolVersion is called explicitly.
+func WithSkipHandshake() ClientOption {
+	return func(c *Client) {
+		c.skipHandshake = true
+	}
+}
+
+// WithDryRun puts the client in dry-run mode. SetVariable, SendCommand and
+// ForceShutdown validate their arguments against the server but do not send the
+// state-changing command; they return a *DryRunError holding the command line
+// that would have been sent.
+func WithDryRun() ClientOption {
+	return func(c *Client) {
+		c.dryRun = true
+	}
+}
+
+// WithAllowDestructive allows FSD and instant commands that cut power to the
+// load (shutdown.*, load.off, load.off.delay, load.cycle and their outlet.N
+// variants), whether sent with ForceShutdown, SendCommand, the UPS methods or
+// Do. Without it they fail with ErrDestructiveNotAllowed and nothing is sent.
+// UPS.Shutdown with ConfirmShutdown does not need it.
+func WithAllowDestructive() ClientOption {
+	return func(c *Client) {
+		c.allowDestructive = true
+	}
+}
+
+// WithStaleFallback serves the last known values when upsd reports DATA-STALE,
+// instead of the error: GetVariables returns the variables of its last
+// successful call on the UPS handle, marked Stale, and GetVariableValue and the
+// Backend used by Monitor and Watcher return the last values read on this
+// client. SampledAt tells the age of GetVariables' results. The error is still
+// returned if no value was read before.
+func WithStaleFallback() ClientOption {
+	return func(c *Client) {
+		c.staleFallback = true
+	}
+}
+
 // Connect accepts a hostname/IP string and an optional port, then creates a connection to NUT, returning a Client.
 func Connect(hostname string, _port ...int) (*Client, error) {
 	return ConnectWithOptions(context.Background(), hostname, _port...)
@@ -104,73 +259,122 @@ func ConnectWithOptionsAndConfig(ctx context.Context, hostname string, opts []Cl
 		portNum = port[0]
 	}
 
+	client := newClient(opts)
+	client.host, client.port = hostname, portNum
+	if err := client.connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// newClient returns a client with default settings and opts applied.
+func newClient(opts []ClientOption) *Client {
 	client := &Client{
 		ConnectTimeout: 5 * time.Second,
 		ReadTimeout:    2 * time.Second,
 		UseTLS:         false,
 		metrics:        &ClientMetrics{},
+		dialStagger:    defaultDialStagger,
 	}
 
 	// Apply options
 	for _, opt := range opts {
 		opt(client)
 	}
+	return client
+}
 
-	// Log connection attempt
-	if client.Logger != nil {
-		client.Logger.Printf("Connecting to %s:%d (timeout: %v)", hostname, portNum, client.ConnectTimeout)
+// NewClientFromConn returns a client using an established connection, e.g. one
+// through an SSH tunnel (see the sshtunnel module), a net.Pipe in tests or a
+// tunnel that is TLS from the start, and performs the version handshake over
+// it. The connection must support read deadlines. It is closed if the
+// handshake fails. Such a client cannot Reconnect.
+func NewClientFromConn(conn net.Conn, opts ...ClientOption) (*Client, error) {
+	client := newClient(opts)
+	client.setState(StateConnecting)
+	if _, ok := conn.(*tls.Conn); ok {
+		client.UseTLS = true
+	}
+	if err := client.handshake(conn); err != nil {
+		return nil, err
 	}
+	return client, nil
+}
 
-	// Use net.JoinHostPort to properly handle IPv6 addresses
-	address := net.JoinHostPort(hostname, fmt.Sprintf("%d", portNum))
+// connect dials the server and performs the version handshake. The client is
+// left in StateClosed on failure.
+func (c *Client) connect(ctx context.Context) error {
+	if c.State() != StateReconnecting {
+		c.setState(StateConnecting)
+	}
 
-	// Create dialer with timeout and context support
-	dialer := &net.Dialer{
-		Timeout: client.ConnectTimeout,
+	// Log connection attempt
+	if logger := c.loggerFor(LogDebug); logger != nil {
+		logger.Printf("Connecting to %s:%d (timeout: %v)", c.host, c.port, c.ConnectTimeout)
 	}
 
-	conn, err := dialer.DialContext(ctx, "tcp", address)
+	// Dial all resolved addresses with Happy Eyeballs and context support
+	dialCtx, cancel := c.withBudget(ctx)
+	conn, err := c.dial(dialCtx, c.host, c.port)
+	cancel()
 	if err != nil {
-		if client.Logger != nil {
-			client.Logger.Printf("Connection failed: %v", err)
+		if logger := c.loggerFor(LogError); logger != nil {
+			logger.Printf("Connection failed: %v", err)
 		}
-		return nil, err
+		c.setState(StateClosed)
+		return err
 	}
 
-	tcpConn, ok := conn.(*net.TCPConn)
-	if !ok {
-		conn.Close()
-		return nil, fmt.Errorf("failed to convert to TCP connection")
-	}
+	return c.handshake(conn)
+}
 
-	client.Hostname = tcpConn.RemoteAddr()
-	client.conn = tcpConn
-	client.reader = bufio.NewReader(tcpConn)
+// handshake installs conn as the client's connection and exchanges versions
+// unless disabled with WithSkipHandshake.
+func (c *Client) handshake(conn net.Conn) error {
+	c.queue.lock()
+	c.Hostname = conn.RemoteAddr()
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.sessionMu.Lock()
+	c.connectedAt = time.Now()
+	c.sessionMu.Unlock()
+	atomic.StoreInt32(&c.broken, 0)
+	c.queue.release()
+
+	if c.skipHandshake {
+		if logger := c.loggerFor(LogInfo); logger != nil {
+			logger.Printf("Connected successfully (handshake skipped)")
+		}
+		c.setState(StateConnected)
+		return nil
+	}
 
 	// Get version info, close connection on error
-	_, err = client.GetVersion()
+	_, err := c.GetVersion()
 	if err != nil {
-		tcpConn.Close()
-		if client.Logger != nil {
-			client.Logger.Printf("Failed to get version: %v", err)
+		conn.Close()
+		c.setState(StateClosed)
+		if logger := c.loggerFor(LogWarn); logger != nil {
+			logger.Printf("Failed to get version: %v", err)
 		}
-		return nil, fmt.Errorf("failed to get version: %w", err)
+		return fmt.Errorf("failed to get version: %w", err)
 	}
 
-	_, err = client.GetNetworkProtocolVersion()
+	_, err = c.GetNetworkProtocolVersion()
 	if err != nil {
-		tcpConn.Close()
-		if client.Logger != nil {
-			client.Logger.Printf("Failed to get network protocol version: %v", err)
+		conn.Close()
+		c.setState(StateClosed)
+		if logger := c.loggerFor(LogWarn); logger != nil {
+			logger.Printf("Failed to get network protocol version: %v", err)
 		}
-		return nil, fmt.Errorf("failed to get network protocol version: %w", err)
+		return fmt.Errorf("failed to get network protocol version: %w", err)
 	}
 
-	if client.Logger != nil {
-		client.Logger.Printf("Connected successfully. Version: %s, Protocol: %s", client.Version, client.ProtocolVersion)
+	if logger := c.loggerFor(LogInfo); logger != nil {
+		logger.Printf("Connected successfully. Version: %s, Protocol: %s", c.Version, c.ProtocolVersion)
 	}
-
-	return client, nil
+	c.setState(StateConnected)
+	return nil
 }
 
 // StartTLS initiates a TLS/SSL connection with the NUT server using STARTTLS command.
@@ -210,26 +414,31 @@ func (c *Client) StartTLS() error {
 	c.conn = tlsConn
 	c.reader = bufio.NewReader(tlsConn) // Reset reader for TLS connection
 	c.UseTLS = true
+	c.setState(StateTLS)
 	return nil
 }
 
 // Disconnect gracefully disconnects from NUT by sending the LOGOUT command.
 func (c *Client) Disconnect() (bool, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.queue.lock()
+	defer c.queue.release()
 
 	// Check if connection is still valid
 	if c.conn == nil {
-		return false, fmt.Errorf("connection already closed")
+		return false, ErrClosed
 	}
 
 	// Try to send LOGOUT, but don't fail if it errors
-	logoutResp, _ := c.sendCommandUnsafe("LOGOUT")
+	var logoutResp []string
+	if c.State() != StateClosed {
+		logoutResp, _ = c.sendCommandUnsafe("LOGOUT")
+	}
 
 	// Always close the connection
 	closeErr := c.conn.Close()
 	c.conn = nil
 	c.reader = nil
+	c.setState(StateClosed)
 
 	if closeErr != nil {
 		return false, closeErr
@@ -246,43 +455,38 @@ func (c *Client) Disconnect() (bool, error) {
 // Close closes the connection without sending LOGOUT command.
 // Use this if you just want to close the connection immediately.
 func (c *Client) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.queue.lock()
+	defer c.queue.release()
 
 	if c.conn == nil {
-		return fmt.Errorf("connection already closed")
+		return ErrClosed
 	}
 
 	err := c.conn.Close()
 	c.conn = nil
 	c.reader = nil
+	c.setState(StateClosed)
 	return err
 }
 
 // sendCommandUnsafe is an internal version without mutex lock for use within locked contexts
 func (c *Client) sendCommandUnsafe(cmd string) (resp []string, err error) {
+	defer func() { c.recordResult(err) }()
 	cmdTrimmed := strings.TrimSpace(cmd)
 	multiLineResponse := strings.HasPrefix(cmdTrimmed, "LIST ")
 
 	cmdWithNewline := cmd + "\n"
 	n, err := fmt.Fprint(c.conn, cmdWithNewline)
 	if err != nil {
-		if c.metrics != nil {
-			atomic.AddUint64(&c.metrics.CommandsFailed, 1)
-		}
+		c.countFailed()
+		c.markBroken()
 		return []string{}, fmt.Errorf("failed to send command: %w", err)
 	}
-
-	// Track metrics
-	if c.metrics != nil {
-		atomic.AddUint64(&c.metrics.CommandsSent, 1)
-		atomic.AddUint64(&c.metrics.BytesSent, uint64(n))
-		c.metrics.LastCommandTime.Store(time.Now())
-	}
+	c.countSent(n)
 
 	// Log command
-	if c.Logger != nil {
-		c.Logger.Printf("Sent command: %s", cmdTrimmed)
+	if logger := c.loggerFor(LogDebug); logger != nil {
+		logger.Printf("Sent command: %s", c.redact(cmdTrimmed))
 	}
 
 	endLine := "OK\n"
@@ -292,28 +496,17 @@ func (c *Client) sendCommandUnsafe(cmd string) (resp []string, err error) {
 
 	resp, err = c.ReadResponse(endLine, multiLineResponse)
 	if err != nil {
-		if c.metrics != nil {
-			atomic.AddUint64(&c.metrics.CommandsFailed, 1)
-		}
+		c.countFailed()
+		c.markBroken()
 		return []string{}, fmt.Errorf("failed to read response: %w", err)
 	}
-
-	// Track bytes received
-	if c.metrics != nil {
-		for _, line := range resp {
-			atomic.AddUint64(&c.metrics.BytesReceived, uint64(len(line)+1)) // +1 for newline
-		}
+	for _, line := range resp {
+		c.countReceived(len(line))
 	}
 
 	if len(resp) > 0 && strings.HasPrefix(resp[0], "ERR ") {
-		if c.metrics != nil {
-			atomic.AddUint64(&c.metrics.CommandsFailed, 1)
-		}
-		errCode := strings.Split(resp[0], " ")
-		if len(errCode) > 1 {
-			return []string{}, errorForMessage(errCode[1])
-		}
-		return []string{}, errorForMessage("UNKNOWN-COMMAND")
+		c.countFailed()
+		return []string{}, errorForResponse(resp[0])
 	}
 
 	return resp, nil
@@ -321,23 +514,88 @@ func (c *Client) sendCommandUnsafe(cmd string) (resp []string, err error) {
 
 // ReadResponse is a convenience function for reading newline delimited responses.
 func (c *Client) ReadResponse(endLine string, multiLineResponse bool) (resp []string, err error) {
-	if err := c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
-		return nil, fmt.Errorf("failed to set read deadline: %v", err)
+	return c.readLines(endLine, multiLineResponse)
+}
+
+// readLine reads a single line of at most limit bytes (unlimited if limit is
+// zero or negative), without buffering more than limit bytes of an oversized line.
+func (c *Client) readLine(limit int) (string, error) {
+	var line []byte
+	for {
+		fragment, err := c.reader.ReadSlice('\n')
+		if c.maxResponseBytes > 0 && len(line)+len(fragment) > limit {
+			return "", ErrResponseTooLarge
+		}
+		line = append(line, fragment...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(line), nil
 	}
+}
+
+// readLines reads a response, which must arrive within ReadTimeout. With
+// WithListTimeout, the list timeout applies to each line of multi-line
+// responses instead, so long LIST responses succeed as long as the server keeps
+// sending.
+func (c *Client) readLines(endLine string, multiLineResponse bool) ([]string, error) {
+	return c.readLinesUntil(endLine, multiLineResponse, time.Time{})
+}
+
+// listRejected reports whether the lines read of a multi-line response are a
+// single ERR line, with which upsd rejects a LIST instead of sending BEGIN and
+// END. Reading on would wait for an END that never comes.
+func listRejected(response []string) bool {
+	return len(response) == 1 && strings.HasPrefix(response[0], "ERR ")
+}
+
+// readLinesUntil is readLines with the whole response bounded by deadline,
+// unless it is zero.
+func (c *Client) readLinesUntil(endLine string, multiLineResponse bool, deadline time.Time) ([]string, error) {
+	perLine := multiLineResponse && c.ListTimeout > 0
+	responseDeadline := readDeadline(c.ReadTimeout, deadline)
 
 	response := []string{}
+	remaining := c.maxResponseBytes
 
 	for {
-		line, err := c.reader.ReadString('\n')
+		lineDeadline := responseDeadline
+		if perLine {
+			lineDeadline = readDeadline(c.ListTimeout, deadline)
+		}
+		if err := c.conn.SetReadDeadline(lineDeadline); err != nil {
+			return nil, fmt.Errorf("failed to set read deadline: %v", err)
+		}
+		line, err := c.readLine(remaining)
+		if errors.Is(err, ErrResponseTooLarge) {
+			// The rest of the response is still in flight; the connection cannot be reused
+			c.conn.Close()
+			c.setState(StateClosed)
+			return nil, fmt.Errorf("more than %d bytes: %w", c.maxResponseBytes, err)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("error reading response: %v", err)
 		}
+		if c.maxResponseBytes > 0 {
+			remaining -= len(line)
+		}
+		if c.maxResponseLines > 0 && len(response) >= c.maxResponseLines {
+			c.conn.Close()
+			c.setState(StateClosed)
+			return nil, fmt.Errorf("more than %d lines: %w", c.maxResponseLines, ErrResponseTooLarge)
+		}
 		if len(line) > 0 {
 			cleanLine := strings.TrimSuffix(line, "\n")
 			response = append(response, cleanLine)
 			if line == endLine || !multiLineResponse {
 				break
 			}
+			if listRejected(response) {
+				break
+			}
 		}
 	}
 
@@ -350,12 +608,59 @@ func (c *Client) SendCommand(cmd string) (resp []string, err error) {
 }
 
 // SendCommandWithContext sends a command with context support for cancellation.
+// Each command gets a request ID, taken from WithRequestID or else the next
+// number of the client's sequence, which prefixes its log lines and is
+// reported in CommandError and command traces. With WithDriverRetry, commands
+// failing with DRIVER-NOT-CONNECTED are retried. FSD and destructive instant
+// commands fail with ErrDestructiveNotAllowed unless WithAllowDestructive is
+// set.
 func (c *Client) SendCommandWithContext(ctx context.Context, cmd string) (resp []string, err error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	if err := c.checkDestructive(ctx, cmd); err != nil {
+		return []string{}, err
+	}
+	ctx, cancel := c.withBudget(ctx)
+	defer cancel()
 
-	if c.Logger != nil {
-		c.Logger.Printf("Sending command: %s", cmd)
+	if c.driverRetryWait > 0 {
+		return c.sendCommandRetryingDriver(ctx, cmd)
+	}
+	return c.sendCommandOnce(ctx, cmd)
+}
+
+// sendCommandOnce sends a command and reads its response once.
+func (c *Client) sendCommandOnce(ctx context.Context, cmd string) (resp []string, err error) {
+	// Wait for the rate limiter before taking the connection lock
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx, c.rateLimitFailFast); err != nil {
+			if logger := c.loggerFor(LogWarn); logger != nil {
+				logger.Printf("Rate limited: %v", err)
+			}
+			return []string{}, err
+		}
+	}
+
+	// Wait for the connection; urgent commands are served before bulk LISTs
+	if err := c.queue.acquire(ctx, priorityFor(ctx, cmd), upsFor(cmd)); err != nil {
+		return []string{}, err
+	}
+	defer c.queue.release()
+
+	if c.conn == nil || c.State() == StateClosed {
+		return []string{}, ErrClosed
+	}
+	id, start := c.requestID(ctx), time.Now()
+	defer func() {
+		c.recordResult(err)
+		if c.commandTrace != nil {
+			c.commandTrace(CommandTrace{RequestID: id, Command: c.redact(cmd), Start: start, Duration: time.Since(start), Err: err})
+		}
+		if err != nil {
+			err = &CommandError{RequestID: id, Command: c.redact(cmd), Err: err}
+		}
+	}()
+
+	if logger := c.loggerFor(LogDebug); logger != nil {
+		logger.Printf("[%s] Sending command: %s", id, c.redact(cmd))
 	}
 
 	// Check context before starting
@@ -371,13 +676,16 @@ func (c *Client) SendCommandWithContext(ctx context.Context, cmd string) (resp [
 
 	// Send the command with newline
 	cmdWithNewline := cmd + "\n"
-	_, err = fmt.Fprint(c.conn, cmdWithNewline)
+	n, err := fmt.Fprint(c.conn, cmdWithNewline)
 	if err != nil {
-		if c.Logger != nil {
-			c.Logger.Printf("Failed to send command: %v", err)
+		if logger := c.loggerFor(LogError); logger != nil {
+			logger.Printf("[%s] Failed to send command: %v", id, err)
 		}
+		c.countFailed()
+		c.markBroken()
 		return []string{}, fmt.Errorf("failed to send command: %w", err)
 	}
+	c.countSent(n)
 
 	// Calculate expected end line
 	endLine := "OK\n"
@@ -387,25 +695,27 @@ func (c *Client) SendCommandWithContext(ctx context.Context, cmd string) (resp [
 
 	resp, err = c.readResponseWithContext(ctx, endLine, multiLineResponse)
 	if err != nil {
-		if c.Logger != nil {
-			c.Logger.Printf("Failed to read response: %v", err)
+		if logger := c.loggerFor(LogError); logger != nil {
+			logger.Printf("[%s] Failed to read response: %v", id, err)
 		}
+		c.countFailed()
+		c.markBroken()
 		return []string{}, fmt.Errorf("failed to read response: %w", err)
 	}
+	for _, line := range resp {
+		c.countReceived(len(line))
+	}
 
 	if len(resp) > 0 && strings.HasPrefix(resp[0], "ERR ") {
-		errCode := strings.Split(resp[0], " ")
-		if len(errCode) > 1 {
-			if c.Logger != nil {
-				c.Logger.Printf("Server error: %s", errCode[1])
-			}
-			return []string{}, errorForMessage(errCode[1])
+		if logger := c.loggerFor(LogWarn); logger != nil {
+			logger.Printf("[%s] Server error: %s", id, strings.TrimPrefix(resp[0], "ERR "))
 		}
-		return []string{}, errorForMessage("UNKNOWN-COMMAND")
+		c.countFailed()
+		return []string{}, errorForResponse(resp[0])
 	}
 
-	if c.Logger != nil {
-		c.Logger.Printf("Command successful, received %d lines", len(resp))
+	if logger := c.loggerFor(LogDebug); logger != nil {
+		logger.Printf("[%s] Command successful, received %d lines", id, len(resp))
 	}
 
 	return resp, nil
@@ -413,34 +723,17 @@ func (c *Client) SendCommandWithContext(ctx context.Context, cmd string) (resp [
 
 // readResponseWithContext reads response with context support
 func (c *Client) readResponseWithContext(ctx context.Context, endLine string, multiLineResponse bool) (resp []string, err error) {
-	if err := c.conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
-		return nil, fmt.Errorf("failed to set read deadline: %v", err)
-	}
-
 	// Create channel for reading
 	type readResult struct {
 		lines []string
 		err   error
 	}
 	resultChan := make(chan readResult, 1)
+	deadline, _ := ctx.Deadline()
 
 	go func() {
-		lines := []string{}
-		for {
-			line, err := c.reader.ReadString('\n')
-			if err != nil {
-				resultChan <- readResult{nil, fmt.Errorf("error reading response: %v", err)}
-				return
-			}
-			if len(line) > 0 {
-				cleanLine := strings.TrimSuffix(line, "\n")
-				lines = append(lines, cleanLine)
-				if line == endLine || !multiLineResponse {
-					resultChan <- readResult{lines, nil}
-					return
-				}
-			}
-		}
+		lines, err := c.readLinesUntil(endLine, multiLineResponse, deadline)
+		resultChan <- readResult{lines, err}
 	}()
 
 	// Wait for result or context cancellation
@@ -456,40 +749,70 @@ func (c *Client) readResponseWithContext(ctx context.Context, endLine string, mu
 }
 
 // Authenticate accepts a username and passwords and uses them to authenticate the existing NUT session.
+// When the server rejects either step, the returned error is an *AuthError that
+// matches ErrInvalidUsername, ErrInvalidPassword, ErrAccessDenied or
+// ErrAlreadyLoggedIn with errors.Is.
 func (c *Client) Authenticate(username, password string) (bool, error) {
 	usernameResp, err := c.SendCommand(fmt.Sprintf("USERNAME %s", username))
 	if err != nil {
-		return false, err
+		return false, authError("USERNAME", err)
+	}
+	if len(usernameResp) == 0 || usernameResp[0] != "OK" {
+		return false, unexpectedAuthResponse("USERNAME", usernameResp)
 	}
 	passwordResp, err := c.SendCommand(fmt.Sprintf("PASSWORD %s", password))
 	if err != nil {
-		return false, err
+		return false, authError("PASSWORD", err)
 	}
-	if len(usernameResp) > 0 && usernameResp[0] == "OK" && len(passwordResp) > 0 && passwordResp[0] == "OK" {
-		return true, nil
+	if len(passwordResp) == 0 || passwordResp[0] != "OK" {
+		return false, unexpectedAuthResponse("PASSWORD", passwordResp)
 	}
-	return false, nil
+	c.sessionMu.Lock()
+	c.username = username
+	c.sessionMu.Unlock()
+	c.setState(StateAuthenticated)
+	return true, nil
 }
 
 // GetUPSList returns a list of all UPSes provided by this NUT instance.
 func (c *Client) GetUPSList() ([]UPS, error) {
 	upsList := []UPS{}
-	resp, err := c.SendCommand("LIST UPS")
+	listed, err := c.listUPS(context.Background())
 	if err != nil {
 		return upsList, err
 	}
-	for _, line := range resp {
-		if strings.HasPrefix(line, "UPS ") {
-			splitLine := strings.Split(strings.TrimPrefix(line, "UPS "), `"`)
-			if len(splitLine) < 1 {
-				continue
-			}
-			newUPS, err := NewUPS(strings.TrimSuffix(splitLine[0], " "), c)
-			if err != nil {
+	for _, listedUPS := range listed {
+		// The description is part of the listing, so GET UPSDESC is not needed
+		newUPS := newUPSValue(listedUPS.Name, listedUPS.Description, c)
+		newUPS.loadLogins()
+		upsList = append(upsList, newUPS)
+	}
+	return upsList, nil
+}
+
+// listUPS returns the names and descriptions of the UPSes from LIST UPS.
+// Malformed lines are reported and, unless parsing is strict, skipped.
+func (c *Client) listUPS(ctx context.Context) ([]BackendUPS, error) {
+	cmd := "LIST UPS"
+	resp, err := c.SendCommandWithContext(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := c.listBody(cmd, resp, "UPS ")
+	if err != nil {
+		return nil, err
+	}
+	upsList := make([]BackendUPS, 0, len(lines))
+	for _, line := range lines {
+		name, quoted, found := strings.Cut(line, " ")
+		description, _, ok := parseQuoted(quoted)
+		if !found || !ok || name == "" {
+			if err := c.malformed(cmd, line, "expected name and quoted description"); err != nil {
 				return upsList, err
 			}
-			upsList = append(upsList, newUPS)
+			continue // Skip malformed lines
 		}
+		upsList = append(upsList, BackendUPS{Name: name, Description: description})
 	}
 	return upsList, nil
 }
@@ -541,7 +864,24 @@ type Pool struct {
 	maxSize       int
 	mu            sync.Mutex
 	closed        bool
+	closing       chan struct{}        // Closed when the pool is closed
+	drained       chan struct{}        // Closed when the last checked-out client is returned after close
+	checkedOut    map[*Client]struct{} // Clients handed out by Get and not yet returned
 	activeClients int
+	hooks         PoolHooks
+	counters      poolCounters // Guarded by mu
+}
+
+// PoolHooks holds optional callbacks invoked on pool lifecycle events, e.g. to
+// export pool behavior to a metrics system or find clients that are never
+// returned. Callbacks run synchronously and must not block or call back into
+// the pool.
+type PoolHooks struct {
+	OnCreate            func(client *Client)            // A new connection was established
+	OnDestroy           func(client *Client)            // A connection was closed by the pool
+	OnCheckout          func(client *Client)            // A client was handed out by Get
+	OnReturn            func(client *Client)            // A client was given back with Put
+	OnHealthCheckFailed func(client *Client, err error) // An idle client was found unusable
 }
 
 // PoolConfig contains configuration for connection pool
@@ -550,6 +890,7 @@ type PoolConfig struct {
 	Hostname      string         // NUT server hostname
 	Port          int            // NUT server port (default 3493)
 	ClientOptions []ClientOption // Options to apply to each client
+	Hooks         PoolHooks      // Optional instrumentation callbacks
 }
 
 // NewPool creates a new connection pool with the given configuration.
@@ -565,22 +906,27 @@ func NewPool(config PoolConfig) (*Pool, error) {
 	}
 
 	pool := &Pool{
-		hostname: config.Hostname,
-		port:     config.Port,
-		opts:     config.ClientOptions,
-		clients:  make(chan *Client, config.MaxSize),
-		maxSize:  config.MaxSize,
+		hostname:   config.Hostname,
+		port:       config.Port,
+		opts:       config.ClientOptions,
+		clients:    make(chan *Client, config.MaxSize),
+		maxSize:    config.MaxSize,
+		hooks:      config.Hooks,
+		closing:    make(chan struct{}),
+		drained:    make(chan struct{}),
+		checkedOut: map[*Client]struct{}{},
 	}
 
 	return pool, nil
 }
 
 // Get retrieves a client from the pool, creating a new one if needed.
+// It returns ErrPoolClosed once the pool is closed.
 func (p *Pool) Get(ctx context.Context) (*Client, error) {
 	p.mu.Lock()
 	if p.closed {
 		p.mu.Unlock()
-		return nil, fmt.Errorf("pool is closed")
+		return nil, ErrPoolClosed
 	}
 	p.mu.Unlock()
 
@@ -588,12 +934,16 @@ func (p *Pool) Get(ctx context.Context) (*Client, error) {
 	select {
 	case client := <-p.clients:
 		// Test if connection is still alive
-		if client.conn != nil {
-			return client, nil
+		if client.IsConnected() && !client.Broken() {
+			return p.checkout(client)
 		}
 		// Connection is dead, create a new one
+		if p.hooks.OnHealthCheckFailed != nil {
+			p.hooks.OnHealthCheckFailed(client, ErrClosed)
+		}
 		p.mu.Lock()
 		p.activeClients--
+		p.counters.healthCheckFailures++
 		p.mu.Unlock()
 	default:
 		// No idle clients available
@@ -602,12 +952,19 @@ func (p *Pool) Get(ctx context.Context) (*Client, error) {
 	// Create new client if we haven't reached max size
 	p.mu.Lock()
 	if p.activeClients >= p.maxSize {
+		p.counters.waits++
 		p.mu.Unlock()
 		// Wait for an available client
+		start := time.Now()
 		select {
 		case client := <-p.clients:
-			return client, nil
+			p.recordWait(start, false)
+			return p.checkout(client)
+		case <-p.closing:
+			p.recordWait(start, false)
+			return nil, ErrPoolClosed
 		case <-ctx.Done():
+			p.recordWait(start, true)
 			return nil, ctx.Err()
 		}
 	}
@@ -619,66 +976,200 @@ func (p *Pool) Get(ctx context.Context) (*Client, error) {
 	if err != nil {
 		p.mu.Lock()
 		p.activeClients--
+		p.counters.createFailures++
 		p.mu.Unlock()
 		return nil, err
 	}
+	p.mu.Lock()
+	p.counters.created++
+	p.mu.Unlock()
 
 	if client.metrics != nil {
 		atomic.AddUint64(&client.metrics.Reconnects, 1)
 	}
 
+	if p.hooks.OnCreate != nil {
+		p.hooks.OnCreate(client)
+	}
+	return p.checkout(client)
+}
+
+// checkout records and reports a client handed out by Get. If the pool was
+// closed in the meantime, the client is destroyed and ErrPoolClosed returned.
+func (p *Pool) checkout(client *Client) (*Client, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.activeClients--
+		p.mu.Unlock()
+		p.destroy(client)
+		return nil, ErrPoolClosed
+	}
+	p.checkedOut[client] = struct{}{}
+	p.counters.checkouts++
+	p.mu.Unlock()
+
+	if p.hooks.OnCheckout != nil {
+		p.hooks.OnCheckout(client)
+	}
 	return client, nil
 }
 
-// Put returns a client to the pool. If the pool is full, the client is closed.
+// destroy closes a client removed from the pool and reports it.
+func (p *Pool) destroy(client *Client) error {
+	p.mu.Lock()
+	p.counters.closed++
+	p.mu.Unlock()
+	err := client.Close()
+	if p.hooks.OnDestroy != nil {
+		p.hooks.OnDestroy(client)
+	}
+	return err
+}
+
+// Put returns a client to the pool. If the pool is full or closed, or the
+// client is broken (see Client.Broken), the client is closed. Clients that are
+// not checked out, e.g. because CloseWithContext already closed them, are
+// ignored.
 func (p *Pool) Put(client *Client) error {
 	if client == nil {
 		return nil
 	}
 
 	p.mu.Lock()
-	if p.closed {
+	if _, ok := p.checkedOut[client]; !ok {
+		// Already returned, or force-closed by CloseWithContext
 		p.mu.Unlock()
-		return client.Close()
+		return nil
 	}
+	delete(p.checkedOut, client)
 	p.mu.Unlock()
 
-	// Try to return to pool
+	if p.hooks.OnReturn != nil {
+		p.hooks.OnReturn(client)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.activeClients--
+		if len(p.checkedOut) == 0 {
+			p.signalDrained()
+		}
+		p.mu.Unlock()
+		return p.destroy(client)
+	}
+
+	// A connection that failed mid-command cannot be trusted by the next
+	// borrower; discarding it lets Get dial a replacement
+	if client.Broken() || !client.IsConnected() {
+		p.activeClients--
+		p.counters.healthCheckFailures++
+		p.mu.Unlock()
+		if p.hooks.OnHealthCheckFailed != nil {
+			p.hooks.OnHealthCheckFailed(client, ErrConnectionBroken)
+		}
+		p.destroy(client)
+		return nil
+	}
+
+	// Try to return to pool; holding the lock keeps Close from draining concurrently
 	select {
 	case p.clients <- client:
+		p.mu.Unlock()
 		return nil
 	default:
 		// Pool is full, close the connection
-		p.mu.Lock()
 		p.activeClients--
 		p.mu.Unlock()
-		return client.Close()
+		return p.destroy(client)
 	}
 }
 
-// Close closes all clients in the pool and prevents new clients from being created.
+// Close closes all idle clients and prevents new checkouts. Clients that are
+// checked out are closed when they are returned with Put. Use CloseWithContext
+// to wait for them.
 func (p *Pool) Close() error {
+	return p.closeIdle()
+}
+
+// CloseWithContext stops new checkouts, closes idle clients and waits for all
+// checked-out clients to be returned. When ctx is done first, the remaining
+// clients are force-closed and ctx.Err() is returned. Concurrent and later
+// calls to Get fail with ErrPoolClosed.
+func (p *Pool) CloseWithContext(ctx context.Context) error {
+	err := p.closeIdle()
+
+	select {
+	case <-p.drained:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Deadline reached: force-close clients that were never returned
 	p.mu.Lock()
-	if p.closed {
-		p.mu.Unlock()
-		return nil
+	outstanding := make([]*Client, 0, len(p.checkedOut))
+	for client := range p.checkedOut {
+		outstanding = append(outstanding, client)
+	}
+	p.checkedOut = map[*Client]struct{}{}
+	p.activeClients -= len(outstanding)
+	p.counters.closed += uint64(len(outstanding))
+	p.signalDrained()
+	p.mu.Unlock()
+
+	for _, client := range outstanding {
+		if p.hooks.OnDestroy != nil {
+			p.hooks.OnDestroy(client)
+		}
+		client.Close()
+	}
+	return ctx.Err()
+}
+
+// closeIdle marks the pool closed and closes all idle clients.
+func (p *Pool) closeIdle() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.closing)
+		if len(p.checkedOut) == 0 {
+			p.signalDrained()
+		}
+	}
+
+	// Drain idle clients
+	idle := []*Client{}
+	for {
+		select {
+		case client := <-p.clients:
+			idle = append(idle, client)
+			p.activeClients--
+			continue
+		default:
+		}
+		break
 	}
-	p.closed = true
 	p.mu.Unlock()
 
-	// Close all clients in the pool
-	close(p.clients)
 	var lastErr error
-	for client := range p.clients {
-		if err := client.Close(); err != nil {
+	for _, client := range idle {
+		if err := p.destroy(client); err != nil {
 			lastErr = err
 		}
 	}
-
 	return lastErr
 }
 
-// Stats returns statistics about the pool
+// signalDrained closes the drained channel once. p.mu must be held.
+func (p *Pool) signalDrained() {
+	select {
+	case <-p.drained:
+	default:
+		close(p.drained)
+	}
+}
+
+// Stats returns the number of idle and active connections of the pool. See
+// Metrics for the full statistics.
 func (p *Pool) Stats() (idle int, active int) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -0,0 +1,5 @@
+package nutgrpc
+
+// Regenerate nutv1 from proto/nut/v1/nut.proto with protoc, protoc-gen-go and
+// protoc-gen-go-grpc.
+//go:generate protoc -I ../proto --go_out=. --go_opt=module=github.com/bearx3f/go.nut/nutgrpc --go-grpc_out=. --go-grpc_opt=module=github.com/bearx3f/go.nut/nutgrpc nut/v1/nut.proto
@@ -0,0 +1,760 @@
+// Service definition for exposing NUT data over gRPC.
+//
+// The generated code and a server backed by the client live in the separate
+// github.com/bearx3f/go.nut/nutgrpc module; see docs/OPTIONAL_FEATURES.md.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: nut/v1/nut.proto
+
+package nutv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UPS struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UPS) Reset() {
+	*x = UPS{}
+	mi := &file_nut_v1_nut_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UPS) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UPS) ProtoMessage() {}
+
+func (x *UPS) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UPS.ProtoReflect.Descriptor instead.
+func (*UPS) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UPS) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *UPS) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UPS) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type ListUPSRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUPSRequest) Reset() {
+	*x = ListUPSRequest{}
+	mi := &file_nut_v1_nut_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUPSRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUPSRequest) ProtoMessage() {}
+
+func (x *ListUPSRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUPSRequest.ProtoReflect.Descriptor instead.
+func (*ListUPSRequest) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{1}
+}
+
+type ListUPSResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ups           []*UPS                 `protobuf:"bytes,1,rep,name=ups,proto3" json:"ups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUPSResponse) Reset() {
+	*x = ListUPSResponse{}
+	mi := &file_nut_v1_nut_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUPSResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUPSResponse) ProtoMessage() {}
+
+func (x *ListUPSResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUPSResponse.ProtoReflect.Descriptor instead.
+func (*ListUPSResponse) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListUPSResponse) GetUps() []*UPS {
+	if x != nil {
+		return x.Ups
+	}
+	return nil
+}
+
+type Variable struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Unit          string                 `protobuf:"bytes,3,opt,name=unit,proto3" json:"unit,omitempty"`
+	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Writable      bool                   `protobuf:"varint,5,opt,name=writable,proto3" json:"writable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variable) Reset() {
+	*x = Variable{}
+	mi := &file_nut_v1_nut_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variable) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variable) ProtoMessage() {}
+
+func (x *Variable) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variable.ProtoReflect.Descriptor instead.
+func (*Variable) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Variable) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Variable) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Variable) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+func (x *Variable) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Variable) GetWritable() bool {
+	if x != nil {
+		return x.Writable
+	}
+	return false
+}
+
+type GetVariablesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Ups           string                 `protobuf:"bytes,2,opt,name=ups,proto3" json:"ups,omitempty"`
+	Names         []string               `protobuf:"bytes,3,rep,name=names,proto3" json:"names,omitempty"` // Empty reads all variables
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVariablesRequest) Reset() {
+	*x = GetVariablesRequest{}
+	mi := &file_nut_v1_nut_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVariablesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVariablesRequest) ProtoMessage() {}
+
+func (x *GetVariablesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVariablesRequest.ProtoReflect.Descriptor instead.
+func (*GetVariablesRequest) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetVariablesRequest) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *GetVariablesRequest) GetUps() string {
+	if x != nil {
+		return x.Ups
+	}
+	return ""
+}
+
+func (x *GetVariablesRequest) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type GetVariablesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Variables     []*Variable            `protobuf:"bytes,1,rep,name=variables,proto3" json:"variables,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVariablesResponse) Reset() {
+	*x = GetVariablesResponse{}
+	mi := &file_nut_v1_nut_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVariablesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVariablesResponse) ProtoMessage() {}
+
+func (x *GetVariablesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVariablesResponse.ProtoReflect.Descriptor instead.
+func (*GetVariablesResponse) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetVariablesResponse) GetVariables() []*Variable {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+type SendCommandRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"`
+	Ups           string                 `protobuf:"bytes,2,opt,name=ups,proto3" json:"ups,omitempty"`
+	Command       string                 `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendCommandRequest) Reset() {
+	*x = SendCommandRequest{}
+	mi := &file_nut_v1_nut_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendCommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendCommandRequest) ProtoMessage() {}
+
+func (x *SendCommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendCommandRequest.ProtoReflect.Descriptor instead.
+func (*SendCommandRequest) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SendCommandRequest) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *SendCommandRequest) GetUps() string {
+	if x != nil {
+		return x.Ups
+	}
+	return ""
+}
+
+func (x *SendCommandRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type SendCommandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	ErrorCode     string                 `protobuf:"bytes,2,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"` // NUT error code, e.g. CMD-NOT-SUPPORTED
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendCommandResponse) Reset() {
+	*x = SendCommandResponse{}
+	mi := &file_nut_v1_nut_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendCommandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendCommandResponse) ProtoMessage() {}
+
+func (x *SendCommandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendCommandResponse.ProtoReflect.Descriptor instead.
+func (*SendCommandResponse) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SendCommandResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *SendCommandResponse) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *SendCommandResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"` // Empty streams all servers
+	Ups           string                 `protobuf:"bytes,2,opt,name=ups,proto3" json:"ups,omitempty"`       // Empty streams all UPSes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_nut_v1_nut_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamEventsRequest) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetUps() string {
+	if x != nil {
+		return x.Ups
+	}
+	return ""
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Server        string                 `protobuf:"bytes,2,opt,name=server,proto3" json:"server,omitempty"`
+	Ups           string                 `protobuf:"bytes,3,opt,name=ups,proto3" json:"ups,omitempty"`
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"` // nut.EventType, e.g. variable_changed
+	Variable      string                 `protobuf:"bytes,5,opt,name=variable,proto3" json:"variable,omitempty"`
+	OldValue      string                 `protobuf:"bytes,6,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	NewValue      string                 `protobuf:"bytes,7,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	Client        string                 `protobuf:"bytes,8,opt,name=client,proto3" json:"client,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	Alert         string                 `protobuf:"bytes,10,opt,name=alert,proto3" json:"alert,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_nut_v1_nut_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_nut_v1_nut_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_nut_v1_nut_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *Event) GetUps() string {
+	if x != nil {
+		return x.Ups
+	}
+	return ""
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetVariable() string {
+	if x != nil {
+		return x.Variable
+	}
+	return ""
+}
+
+func (x *Event) GetOldValue() string {
+	if x != nil {
+		return x.OldValue
+	}
+	return ""
+}
+
+func (x *Event) GetNewValue() string {
+	if x != nil {
+		return x.NewValue
+	}
+	return ""
+}
+
+func (x *Event) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetAlert() string {
+	if x != nil {
+		return x.Alert
+	}
+	return ""
+}
+
+var File_nut_v1_nut_proto protoreflect.FileDescriptor
+
+const file_nut_v1_nut_proto_rawDesc = "" +
+	"\n" +
+	"\x10nut/v1/nut.proto\x12\x06nut.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"S\n" +
+	"\x03UPS\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\"\x10\n" +
+	"\x0eListUPSRequest\"0\n" +
+	"\x0fListUPSResponse\x12\x1d\n" +
+	"\x03ups\x18\x01 \x03(\v2\v.nut.v1.UPSR\x03ups\"\x86\x01\n" +
+	"\bVariable\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x12\n" +
+	"\x04unit\x18\x03 \x01(\tR\x04unit\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bwritable\x18\x05 \x01(\bR\bwritable\"U\n" +
+	"\x13GetVariablesRequest\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x10\n" +
+	"\x03ups\x18\x02 \x01(\tR\x03ups\x12\x14\n" +
+	"\x05names\x18\x03 \x03(\tR\x05names\"F\n" +
+	"\x14GetVariablesResponse\x12.\n" +
+	"\tvariables\x18\x01 \x03(\v2\x10.nut.v1.VariableR\tvariables\"X\n" +
+	"\x12SendCommandRequest\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x10\n" +
+	"\x03ups\x18\x02 \x01(\tR\x03ups\x12\x18\n" +
+	"\acommand\x18\x03 \x01(\tR\acommand\"Z\n" +
+	"\x13SendCommandResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\x02 \x01(\tR\terrorCode\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"?\n" +
+	"\x13StreamEventsRequest\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x10\n" +
+	"\x03ups\x18\x02 \x01(\tR\x03ups\"\x8f\x02\n" +
+	"\x05Event\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x16\n" +
+	"\x06server\x18\x02 \x01(\tR\x06server\x12\x10\n" +
+	"\x03ups\x18\x03 \x01(\tR\x03ups\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x1a\n" +
+	"\bvariable\x18\x05 \x01(\tR\bvariable\x12\x1b\n" +
+	"\told_value\x18\x06 \x01(\tR\boldValue\x12\x1b\n" +
+	"\tnew_value\x18\a \x01(\tR\bnewValue\x12\x16\n" +
+	"\x06client\x18\b \x01(\tR\x06client\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\x12\x14\n" +
+	"\x05alert\x18\n" +
+	" \x01(\tR\x05alert2\x99\x02\n" +
+	"\n" +
+	"NUTService\x12:\n" +
+	"\aListUPS\x12\x16.nut.v1.ListUPSRequest\x1a\x17.nut.v1.ListUPSResponse\x12I\n" +
+	"\fGetVariables\x12\x1b.nut.v1.GetVariablesRequest\x1a\x1c.nut.v1.GetVariablesResponse\x12F\n" +
+	"\vSendCommand\x12\x1a.nut.v1.SendCommandRequest\x1a\x1b.nut.v1.SendCommandResponse\x12<\n" +
+	"\fStreamEvents\x12\x1b.nut.v1.StreamEventsRequest\x1a\r.nut.v1.Event0\x01B/Z-github.com/bearx3f/go.nut/nutgrpc/nutv1;nutv1b\x06proto3"
+
+var (
+	file_nut_v1_nut_proto_rawDescOnce sync.Once
+	file_nut_v1_nut_proto_rawDescData []byte
+)
+
+func file_nut_v1_nut_proto_rawDescGZIP() []byte {
+	file_nut_v1_nut_proto_rawDescOnce.Do(func() {
+		file_nut_v1_nut_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_nut_v1_nut_proto_rawDesc), len(file_nut_v1_nut_proto_rawDesc)))
+	})
+	return file_nut_v1_nut_proto_rawDescData
+}
+
+var file_nut_v1_nut_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_nut_v1_nut_proto_goTypes = []any{
+	(*UPS)(nil),                   // 0: nut.v1.UPS
+	(*ListUPSRequest)(nil),        // 1: nut.v1.ListUPSRequest
+	(*ListUPSResponse)(nil),       // 2: nut.v1.ListUPSResponse
+	(*Variable)(nil),              // 3: nut.v1.Variable
+	(*GetVariablesRequest)(nil),   // 4: nut.v1.GetVariablesRequest
+	(*GetVariablesResponse)(nil),  // 5: nut.v1.GetVariablesResponse
+	(*SendCommandRequest)(nil),    // 6: nut.v1.SendCommandRequest
+	(*SendCommandResponse)(nil),   // 7: nut.v1.SendCommandResponse
+	(*StreamEventsRequest)(nil),   // 8: nut.v1.StreamEventsRequest
+	(*Event)(nil),                 // 9: nut.v1.Event
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_nut_v1_nut_proto_depIdxs = []int32{
+	0,  // 0: nut.v1.ListUPSResponse.ups:type_name -> nut.v1.UPS
+	3,  // 1: nut.v1.GetVariablesResponse.variables:type_name -> nut.v1.Variable
+	10, // 2: nut.v1.Event.time:type_name -> google.protobuf.Timestamp
+	1,  // 3: nut.v1.NUTService.ListUPS:input_type -> nut.v1.ListUPSRequest
+	4,  // 4: nut.v1.NUTService.GetVariables:input_type -> nut.v1.GetVariablesRequest
+	6,  // 5: nut.v1.NUTService.SendCommand:input_type -> nut.v1.SendCommandRequest
+	8,  // 6: nut.v1.NUTService.StreamEvents:input_type -> nut.v1.StreamEventsRequest
+	2,  // 7: nut.v1.NUTService.ListUPS:output_type -> nut.v1.ListUPSResponse
+	5,  // 8: nut.v1.NUTService.GetVariables:output_type -> nut.v1.GetVariablesResponse
+	7,  // 9: nut.v1.NUTService.SendCommand:output_type -> nut.v1.SendCommandResponse
+	9,  // 10: nut.v1.NUTService.StreamEvents:output_type -> nut.v1.Event
+	7,  // [7:11] is the sub-list for method output_type
+	3,  // [3:7] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_nut_v1_nut_proto_init() }
+func file_nut_v1_nut_proto_init() {
+	if File_nut_v1_nut_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nut_v1_nut_proto_rawDesc), len(file_nut_v1_nut_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_nut_v1_nut_proto_goTypes,
+		DependencyIndexes: file_nut_v1_nut_proto_depIdxs,
+		MessageInfos:      file_nut_v1_nut_proto_msgTypes,
+	}.Build()
+	File_nut_v1_nut_proto = out.File
+	file_nut_v1_nut_proto_goTypes = nil
+	file_nut_v1_nut_proto_depIdxs = nil
+}
@@ -0,0 +1,252 @@
+// Service definition for exposing NUT data over gRPC.
+//
+// The generated code and a server backed by the client live in the separate
+// github.com/bearx3f/go.nut/nutgrpc module; see docs/OPTIONAL_FEATURES.md.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: nut/v1/nut.proto
+
+package nutv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NUTService_ListUPS_FullMethodName      = "/nut.v1.NUTService/ListUPS"
+	NUTService_GetVariables_FullMethodName = "/nut.v1.NUTService/GetVariables"
+	NUTService_SendCommand_FullMethodName  = "/nut.v1.NUTService/SendCommand"
+	NUTService_StreamEvents_FullMethodName = "/nut.v1.NUTService/StreamEvents"
+)
+
+// NUTServiceClient is the client API for NUTService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NUTServiceClient interface {
+	// Lists the UPSes known to the server.
+	ListUPS(ctx context.Context, in *ListUPSRequest, opts ...grpc.CallOption) (*ListUPSResponse, error)
+	// Reads the variables of a UPS, or only the named ones.
+	GetVariables(ctx context.Context, in *GetVariablesRequest, opts ...grpc.CallOption) (*GetVariablesResponse, error)
+	// Runs an instant command on a UPS.
+	SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*SendCommandResponse, error)
+	// Streams events until the client cancels.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type nUTServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNUTServiceClient(cc grpc.ClientConnInterface) NUTServiceClient {
+	return &nUTServiceClient{cc}
+}
+
+func (c *nUTServiceClient) ListUPS(ctx context.Context, in *ListUPSRequest, opts ...grpc.CallOption) (*ListUPSResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUPSResponse)
+	err := c.cc.Invoke(ctx, NUTService_ListUPS_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nUTServiceClient) GetVariables(ctx context.Context, in *GetVariablesRequest, opts ...grpc.CallOption) (*GetVariablesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVariablesResponse)
+	err := c.cc.Invoke(ctx, NUTService_GetVariables_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nUTServiceClient) SendCommand(ctx context.Context, in *SendCommandRequest, opts ...grpc.CallOption) (*SendCommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendCommandResponse)
+	err := c.cc.Invoke(ctx, NUTService_SendCommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nUTServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NUTService_ServiceDesc.Streams[0], NUTService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NUTService_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// NUTServiceServer is the server API for NUTService service.
+// All implementations must embed UnimplementedNUTServiceServer
+// for forward compatibility.
+type NUTServiceServer interface {
+	// Lists the UPSes known to the server.
+	ListUPS(context.Context, *ListUPSRequest) (*ListUPSResponse, error)
+	// Reads the variables of a UPS, or only the named ones.
+	GetVariables(context.Context, *GetVariablesRequest) (*GetVariablesResponse, error)
+	// Runs an instant command on a UPS.
+	SendCommand(context.Context, *SendCommandRequest) (*SendCommandResponse, error)
+	// Streams events until the client cancels.
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedNUTServiceServer()
+}
+
+// UnimplementedNUTServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNUTServiceServer struct{}
+
+func (UnimplementedNUTServiceServer) ListUPS(context.Context, *ListUPSRequest) (*ListUPSResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListUPS not implemented")
+}
+func (UnimplementedNUTServiceServer) GetVariables(context.Context, *GetVariablesRequest) (*GetVariablesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetVariables not implemented")
+}
+func (UnimplementedNUTServiceServer) SendCommand(context.Context, *SendCommandRequest) (*SendCommandResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SendCommand not implemented")
+}
+func (UnimplementedNUTServiceServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedNUTServiceServer) mustEmbedUnimplementedNUTServiceServer() {}
+func (UnimplementedNUTServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeNUTServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NUTServiceServer will
+// result in compilation errors.
+type UnsafeNUTServiceServer interface {
+	mustEmbedUnimplementedNUTServiceServer()
+}
+
+func RegisterNUTServiceServer(s grpc.ServiceRegistrar, srv NUTServiceServer) {
+	// If the following call panics, it indicates UnimplementedNUTServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NUTService_ServiceDesc, srv)
+}
+
+func _NUTService_ListUPS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUPSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NUTServiceServer).ListUPS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NUTService_ListUPS_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NUTServiceServer).ListUPS(ctx, req.(*ListUPSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NUTService_GetVariables_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVariablesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NUTServiceServer).GetVariables(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NUTService_GetVariables_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NUTServiceServer).GetVariables(ctx, req.(*GetVariablesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NUTService_SendCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NUTServiceServer).SendCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NUTService_SendCommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NUTServiceServer).SendCommand(ctx, req.(*SendCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NUTService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(NUTServiceServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NUTService_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// NUTService_ServiceDesc is the grpc.ServiceDesc for NUTService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NUTService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nut.v1.NUTService",
+	HandlerType: (*NUTServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListUPS",
+			Handler:    _NUTService_ListUPS_Handler,
+		},
+		{
+			MethodName: "GetVariables",
+			Handler:    _NUTService_GetVariables_Handler,
+		},
+		{
+			MethodName: "SendCommand",
+			Handler:    _NUTService_SendCommand_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _NUTService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nut/v1/nut.proto",
+}
@@ -0,0 +1,301 @@
+// Package nutgrpc serves the nut.v1.NUTService gRPC API defined in
+// proto/nut/v1/nut.proto, backed by the NUT client, so that polyglot
+// infrastructure can list UPSes, read variables, run instant commands and
+// stream events through a typed RPC API:
+//
+//	pools := nut.NewPoolManager(nut.PoolConfig{MaxSize: 4})
+//	server, err := nutgrpc.NewServer(nutgrpc.Config{
+//		Pools:   pools,
+//		Servers: []string{"ups-a.example.com:3493", "ups-b.example.com:3493"},
+//		Events:  fleet,
+//	})
+//	grpcServer := grpc.NewServer()
+//	nutv1.RegisterNUTServiceServer(grpcServer, server)
+//
+// It is a separate module so that the main module keeps depending only on the
+// standard library.
+package nutgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nutgrpc/nutv1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultEventBuffer is the number of events buffered per StreamEvents call.
+const defaultEventBuffer = 64
+
+// EventSource provides the events of StreamEvents, e.g. a *nut.Fleet,
+// *nut.Monitor or *nut.EventBus.
+type EventSource interface {
+	Subscribe(filter nut.EventFilter, buffer int) *nut.Subscription
+}
+
+// Config contains the configuration of a Server.
+type Config struct {
+	Pools       *nut.PoolManager // Connections to the NUT servers
+	Servers     []string         // Endpoints (host:port) served; requests for others are rejected
+	Events      EventSource      // Source of StreamEvents; nil makes it return Unimplemented
+	EventBuffer int              // Events buffered per stream before dropping (default 64)
+}
+
+// Server implements nutv1.NUTServiceServer.
+type Server struct {
+	nutv1.UnimplementedNUTServiceServer
+	config Config
+}
+
+// NewServer returns a server for the given configuration.
+func NewServer(config Config) (*Server, error) {
+	if config.Pools == nil {
+		return nil, fmt.Errorf("pool manager is required")
+	}
+	if len(config.Servers) == 0 {
+		return nil, fmt.Errorf("at least one server is required")
+	}
+	if config.EventBuffer <= 0 {
+		config.EventBuffer = defaultEventBuffer
+	}
+	return &Server{config: config}, nil
+}
+
+// ListUPS lists the UPSes of every configured server. It fails if any server
+// cannot be queried.
+func (s *Server) ListUPS(ctx context.Context, req *nutv1.ListUPSRequest) (*nutv1.ListUPSResponse, error) {
+	resp := &nutv1.ListUPSResponse{}
+	for _, server := range s.config.Servers {
+		err := s.withBackend(ctx, server, func(backend nut.Backend) error {
+			list, err := backend.ListUPS(ctx)
+			if err != nil {
+				return err
+			}
+			for _, ups := range list {
+				resp.Ups = append(resp.Ups, &nutv1.UPS{Server: server, Name: ups.Name, Description: ups.Description})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, statusFor(err)
+		}
+	}
+	return resp, nil
+}
+
+// GetVariables reads the variables of a UPS, or only the named ones. Names the
+// UPS does not report are returned as NotFound.
+func (s *Server) GetVariables(ctx context.Context, req *nutv1.GetVariablesRequest) (*nutv1.GetVariablesResponse, error) {
+	server, err := s.server(req.GetServer())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetUps() == "" {
+		return nil, status.Error(codes.InvalidArgument, "ups is required")
+	}
+
+	resp := &nutv1.GetVariablesResponse{}
+	err = s.withClient(ctx, server, func(client *nut.Client) error {
+		values, err := nut.NewNUTBackend(client).Variables(ctx, req.GetUps())
+		if err != nil {
+			return err
+		}
+		names := req.GetNames()
+		if len(names) == 0 {
+			names = sortedKeys(values)
+		}
+		ups, err := nut.NewUPS(req.GetUps(), client)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			value, ok := values[name]
+			if !ok {
+				return status.Errorf(codes.NotFound, "variable %s not reported by %s", name, req.GetUps())
+			}
+			variable := &nutv1.Variable{Name: name, Value: value, Unit: string(nut.UnitFor(name))}
+			// Descriptions and types take a command each; stop early when the call is abandoned
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if variable.Description, err = ups.GetVariableDescription(name); err != nil {
+				return err
+			}
+			serverType, err := ups.GetVariableServerType(name)
+			if err != nil {
+				return err
+			}
+			variable.Writable = serverType.Writable
+			resp.Variables = append(resp.Variables, variable)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	return resp, nil
+}
+
+// SendCommand runs an instant command. Errors reported by upsd, including
+// refused destructive commands, are returned in the response; connection
+// failures as gRPC errors.
+func (s *Server) SendCommand(ctx context.Context, req *nutv1.SendCommandRequest) (*nutv1.SendCommandResponse, error) {
+	server, err := s.server(req.GetServer())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetUps() == "" || req.GetCommand() == "" {
+		return nil, status.Error(codes.InvalidArgument, "ups and command are required")
+	}
+
+	var cmdErr error
+	err = s.withBackend(ctx, server, func(backend nut.Backend) error {
+		cmdErr = backend.RunCommand(ctx, req.GetUps(), req.GetCommand())
+		if cmdErr != nil && !isCommandRejection(cmdErr) {
+			return cmdErr
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	if cmdErr != nil {
+		code, _ := nut.ErrorCodeOf(cmdErr)
+		return &nutv1.SendCommandResponse{ErrorCode: string(code), Error: cmdErr.Error()}, nil
+	}
+	return &nutv1.SendCommandResponse{Ok: true}, nil
+}
+
+// StreamEvents sends the events of the configured EventSource matching the
+// request until the client cancels.
+func (s *Server) StreamEvents(req *nutv1.StreamEventsRequest, stream nutv1.NUTService_StreamEventsServer) error {
+	if s.config.Events == nil {
+		return status.Error(codes.Unimplemented, "no event source configured")
+	}
+	var filter nut.EventFilter
+	if req.GetServer() != "" {
+		filter.Servers = []string{req.GetServer()}
+	}
+	if req.GetUps() != "" {
+		filter.UPS = []string{req.GetUps()}
+	}
+	sub := s.config.Events.Subscribe(filter, s.config.EventBuffer)
+	defer sub.Close()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(eventMessage(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// server returns the requested server, or the only configured one if none is
+// requested.
+func (s *Server) server(requested string) (string, error) {
+	if requested == "" {
+		if len(s.config.Servers) == 1 {
+			return s.config.Servers[0], nil
+		}
+		return "", status.Error(codes.InvalidArgument, "server is required")
+	}
+	for _, server := range s.config.Servers {
+		if server == requested {
+			return server, nil
+		}
+	}
+	return "", status.Errorf(codes.NotFound, "unknown server %s", requested)
+}
+
+// withClient runs fn with a pooled client for server.
+func (s *Server) withClient(ctx context.Context, server string, fn func(client *nut.Client) error) error {
+	client, err := s.config.Pools.GetFor(ctx, server)
+	if err != nil {
+		return err
+	}
+	defer s.config.Pools.Put(client)
+	return fn(client)
+}
+
+// withBackend runs fn with a Backend over a pooled client for server.
+func (s *Server) withBackend(ctx context.Context, server string, fn func(backend nut.Backend) error) error {
+	return s.withClient(ctx, server, func(client *nut.Client) error {
+		return fn(nut.NewNUTBackend(client))
+	})
+}
+
+// isCommandRejection reports whether a command failed because upsd or the
+// client refused it, rather than because the server could not be reached.
+func isCommandRejection(err error) bool {
+	_, ok := nut.ErrorCodeOf(err)
+	return ok || errors.Is(err, nut.ErrDestructiveNotAllowed)
+}
+
+// statusFor converts an error to a gRPC status.
+func statusFor(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	code, ok := nut.ErrorCodeOf(err)
+	if !ok {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	switch code {
+	case nut.ErrCodeUnknownUPS, nut.ErrCodeVarNotSupported, nut.ErrCodeCmdNotSupported:
+		return status.Error(codes.NotFound, err.Error())
+	case nut.ErrCodeAccessDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case nut.ErrCodeInvalidArgument, nut.ErrCodeInvalidValue:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case nut.ErrCodeDriverNotConnected, nut.ErrCodeDataStale:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// eventMessage converts an event to its protobuf message.
+func eventMessage(event nut.Event) *nutv1.Event {
+	msg := &nutv1.Event{
+		Time:     timestamppb.New(event.Time),
+		Server:   event.Server,
+		Ups:      event.UPS,
+		Type:     string(event.Type),
+		Variable: event.Variable,
+		OldValue: event.OldValue,
+		NewValue: event.NewValue,
+		Client:   event.Client,
+		Alert:    event.Alert,
+	}
+	if event.Err != nil {
+		msg.Error = event.Err.Error()
+	}
+	return msg
+}
@@ -0,0 +1,168 @@
+package nutgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nutgrpc/nutv1"
+	"github.com/bearx3f/go.nut/nuttest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestService serves a NUTService backed by a nuttest server over an
+// in-memory connection and returns a client for it, the NUT server, its
+// endpoint and the event bus of the service.
+func newTestService(t *testing.T) (nutv1.NUTServiceClient, *nuttest.Server, string, *nut.EventBus) {
+	t.Helper()
+	upsd, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { upsd.Close() })
+	upsd.AddUPS("ups1", "Rack UPS", map[string]string{"ups.status": "OL", "battery.charge": "100"}, "beeper.disable")
+
+	pools := nut.NewPoolManager(nut.PoolConfig{MaxSize: 2})
+	t.Cleanup(func() { pools.Close() })
+	bus := nut.NewEventBus()
+	server, err := NewServer(Config{Pools: pools, Servers: []string{upsd.Addr()}, Events: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	nutv1.RegisterNUTServiceServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return nutv1.NewNUTServiceClient(conn), upsd, upsd.Addr(), bus
+}
+
+func TestListUPS(t *testing.T) {
+	client, _, endpoint, _ := newTestService(t)
+	resp, err := client.ListUPS(context.Background(), &nutv1.ListUPSRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Ups) != 1 || resp.Ups[0].Name != "ups1" || resp.Ups[0].Description != "Rack UPS" || resp.Ups[0].Server != endpoint {
+		t.Fatalf("unexpected response %v", resp)
+	}
+}
+
+func TestGetVariables(t *testing.T) {
+	client, _, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	resp, err := client.GetVariables(ctx, &nutv1.GetVariablesRequest{Ups: "ups1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Variables) != 2 || resp.Variables[0].Name != "battery.charge" || resp.Variables[0].Value != "100" || resp.Variables[0].Unit != string(nut.UnitPercent) {
+		t.Fatalf("unexpected variables %v", resp.Variables)
+	}
+
+	resp, err = client.GetVariables(ctx, &nutv1.GetVariablesRequest{Ups: "ups1", Names: []string{"ups.status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Variables) != 1 || resp.Variables[0].Value != "OL" {
+		t.Fatalf("unexpected variables %v", resp.Variables)
+	}
+}
+
+func TestGetVariablesErrors(t *testing.T) {
+	client, _, _, _ := newTestService(t)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		req  *nutv1.GetVariablesRequest
+		want codes.Code
+	}{
+		{"missing ups", &nutv1.GetVariablesRequest{}, codes.InvalidArgument},
+		{"unknown server", &nutv1.GetVariablesRequest{Server: "other:3493", Ups: "ups1"}, codes.NotFound},
+		{"unknown ups", &nutv1.GetVariablesRequest{Ups: "missing"}, codes.NotFound},
+		{"unknown variable", &nutv1.GetVariablesRequest{Ups: "ups1", Names: []string{"input.voltage"}}, codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetVariables(ctx, tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code = %v, want %v (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	client, upsd, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	resp, err := client.SendCommand(ctx, &nutv1.SendCommandRequest{Ups: "ups1", Command: "beeper.disable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Ok {
+		t.Fatalf("command failed: %v", resp)
+	}
+	if issued := upsd.Commands("ups1"); len(issued) != 1 || issued[0] != "beeper.disable" {
+		t.Fatalf("server received %v", issued)
+	}
+
+	resp, err = client.SendCommand(ctx, &nutv1.SendCommandRequest{Ups: "ups1", Command: "test.battery.start"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Ok || resp.ErrorCode != string(nut.ErrCodeCmdNotSupported) {
+		t.Fatalf("unexpected response %v", resp)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	client, _, _, bus := newTestService(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamEvents(ctx, &nutv1.StreamEventsRequest{Ups: "ups1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subscription is made when the server handles the call; publish
+	// until the first event arrives
+	received := make(chan *nutv1.Event, 1)
+	go func() {
+		event, err := stream.Recv()
+		if err == nil {
+			received <- event
+		}
+	}()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		bus.Publish(nut.Event{Time: time.Now(), UPS: "other", Type: nut.EventVariableChanged})
+		bus.Publish(nut.Event{Time: time.Now(), UPS: "ups1", Type: nut.EventVariableChanged, Variable: "ups.status", OldValue: "OL", NewValue: "OB"})
+		select {
+		case event := <-received:
+			if event.Ups != "ups1" || event.Variable != "ups.status" || event.NewValue != "OB" || event.Type != string(nut.EventVariableChanged) {
+				t.Fatalf("unexpected event %v", event)
+			}
+			return
+		case <-ticker.C:
+		case <-ctx.Done():
+			t.Fatal("no event received")
+		}
+	}
+}
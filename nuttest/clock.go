@@ -0,0 +1,147 @@
+package nuttest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+// Clock is a fake nut.Clock for tests. Time stands still until Advance is
+// called, which fires the timers and tickers that have come due, so code
+// waiting for minutes of polling intervals can be tested without sleeping:
+//
+//	clock := nuttest.NewClock(time.Now())
+//	watcher := nut.NewWatcher(ups, nut.WithClock(clock), nut.WithWatchInterval(time.Minute))
+//	go watcher.Run(ctx)
+//	clock.BlockUntil(1) // The watcher is waiting for its next poll
+//	clock.Advance(time.Minute)
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*clockWaiter
+	added   chan struct{} // Closed and replaced whenever a waiter is added
+}
+
+// clockWaiter is a pending timer or ticker.
+type clockWaiter struct {
+	clock  *Clock
+	when   time.Time
+	period time.Duration // Zero for timers
+	ch     chan time.Time
+}
+
+// NewClock returns a fake clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now, added: make(chan struct{})}
+}
+
+// Now returns the fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer firing once the clock has advanced by d.
+func (c *Clock) NewTimer(d time.Duration) nut.Timer {
+	return fakeTimer{c.add(d, 0)}
+}
+
+// NewTicker returns a ticker firing every time the clock has advanced by d.
+// Like time.Ticker, it drops ticks the receiver is not ready for.
+func (c *Clock) NewTicker(d time.Duration) nut.Ticker {
+	if d <= 0 {
+		panic("nuttest: non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.add(d, d)}
+}
+
+func (c *Clock) add(d, period time.Duration) *clockWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &clockWaiter{clock: c, when: c.now.Add(d), period: period, ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	close(c.added)
+	c.added = make(chan struct{})
+	return w
+}
+
+// Advance moves the clock forward by d and fires the timers and tickers that
+// come due, in time order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].when.Before(c.waiters[j].when) })
+		if len(c.waiters) == 0 || c.waiters[0].when.After(end) {
+			break
+		}
+		w := c.waiters[0]
+		c.now = w.when
+		select {
+		case w.ch <- w.when:
+		default:
+		}
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = end
+}
+
+// Waiters returns the number of active timers and tickers.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil waits until at least n timers and tickers are active, e.g. until
+// the code under test is waiting for its next poll before calling Advance.
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		count, added := len(c.waiters), c.added
+		c.mu.Unlock()
+		if count >= n {
+			return
+		}
+		<-added
+	}
+}
+
+// C returns the channel receiving the fire times.
+func (w *clockWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// stop deactivates the timer or ticker and reports whether it was active.
+func (w *clockWaiter) stop() bool {
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct{ *clockWaiter }
+
+func (t fakeTimer) Stop() bool { return t.stop() }
+
+type fakeTicker struct{ *clockWaiter }
+
+func (t fakeTicker) Stop() { t.stop() }
@@ -0,0 +1,223 @@
+// Package nuttest provides helpers for testing applications built on go.nut.
+package nuttest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// garbledLine replaces response lines selected for garbling.
+const garbledLine = "\x00\xff#GARBLED#\xfe\x01\n"
+
+// FaultProxy is a TCP proxy that sits between a Client and a NUT server and
+// injects faults into server responses on demand: latency, truncated lines,
+// garbled lines and dropped connections. Faults are counted rather than random
+// so tests stay deterministic.
+type FaultProxy struct {
+	listener net.Listener
+	upstream string
+
+	mu       sync.Mutex
+	latency  time.Duration
+	truncate int // Number of upcoming response lines to truncate
+	garble   int // Number of upcoming response lines to garble
+	dropNext int // Number of upcoming response lines after which to drop the connection
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewFaultProxy starts a proxy listening on a random loopback port and
+// forwarding to upstream (host:port).
+func NewFaultProxy(upstream string) (*FaultProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &FaultProxy{
+		listener: listener,
+		upstream: upstream,
+		conns:    map[net.Conn]struct{}{},
+	}
+	p.wg.Add(1)
+	go p.serve()
+	return p, nil
+}
+
+// Addr returns the proxy's listening address as host:port.
+func (p *FaultProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// HostPort returns the proxy's listening host and port, in the form expected by
+// nut.ConnectWithOptionsAndConfig.
+func (p *FaultProxy) HostPort() (string, int) {
+	host, port, _ := net.SplitHostPort(p.Addr())
+	portNum, _ := strconv.Atoi(port)
+	return host, portNum
+}
+
+// SetLatency delays every response line by d. Zero disables the delay.
+func (p *FaultProxy) SetLatency(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = d
+}
+
+// TruncateNext cuts the next n response lines in half. Nothing can follow a
+// partial line, so each truncated line closes its connection and the
+// remaining count carries over to the connections made afterwards, e.g. by a
+// client reconnecting: TruncateNext(2) fails two connections in a row.
+func (p *FaultProxy) TruncateNext(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.truncate = n
+}
+
+// GarbleNext replaces the next n response lines with binary garbage.
+func (p *FaultProxy) GarbleNext(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.garble = n
+}
+
+// DropAfter closes the connection after forwarding n more response lines.
+// DropAfter(0) drops on the next response line.
+func (p *FaultProxy) DropAfter(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dropNext = n + 1
+}
+
+// DropConnections immediately closes all proxied connections.
+func (p *FaultProxy) DropConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
+
+// Reset clears all pending faults.
+func (p *FaultProxy) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = 0
+	p.truncate = 0
+	p.garble = 0
+	p.dropNext = 0
+}
+
+// Close stops the proxy and closes all proxied connections.
+func (p *FaultProxy) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	err := p.listener.Close()
+	p.DropConnections()
+	p.wg.Wait()
+	return err
+}
+
+func (p *FaultProxy) serve() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		p.wg.Add(1)
+		go p.handle(conn)
+	}
+}
+
+func (p *FaultProxy) handle(client net.Conn) {
+	defer p.wg.Done()
+
+	server, err := net.Dial("tcp", p.upstream)
+	if err != nil {
+		client.Close()
+		return
+	}
+	if !p.track(client, server) {
+		return
+	}
+	defer p.untrack(client, server)
+
+	// Requests are forwarded untouched
+	go func() {
+		io.Copy(server, client)
+		server.Close()
+	}()
+
+	reader := bufio.NewReader(server)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		out, drop := p.apply(line)
+		if _, err := io.WriteString(client, out); err != nil || drop {
+			return
+		}
+	}
+}
+
+// apply returns the bytes to forward for line and whether the connection
+// should be dropped afterwards.
+func (p *FaultProxy) apply(line string) (string, bool) {
+	p.mu.Lock()
+	latency := p.latency
+	out, drop := line, false
+	switch {
+	case p.truncate > 0:
+		p.truncate--
+		out, drop = line[:len(line)/2], true
+	case p.garble > 0:
+		p.garble--
+		out = garbledLine
+	}
+	if p.dropNext > 0 {
+		p.dropNext--
+		if p.dropNext == 0 {
+			out, drop = "", true
+		}
+	}
+	p.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return out, drop
+}
+
+func (p *FaultProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		for _, conn := range conns {
+			conn.Close()
+		}
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *FaultProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+		delete(p.conns, conn)
+	}
+}
@@ -0,0 +1,160 @@
+package nuttest
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// drainSteps is the number of updates DrainBattery spreads a drain over.
+const drainSteps = 50
+
+// DefaultVariables returns the variables of a healthy line-powered UPS, as
+// used by NewScenario.
+func DefaultVariables() map[string]string {
+	return map[string]string{
+		"ups.status":            "OL",
+		"ups.load":              "25",
+		"ups.realpower.nominal": "900",
+		"input.voltage":         "230",
+		"input.voltage.nominal": "230",
+		"battery.charge":        "100",
+		"battery.charge.low":    "20",
+		"battery.runtime":       "1800",
+		"battery.runtime.low":   "300",
+	}
+}
+
+// Scenario scripts power events on a UPS served by a Server, so tests of
+// applications built on go.nut can observe the resulting protocol behavior
+// through a Client or Monitor:
+//
+//	scenario.PowerFail()
+//	scenario.DrainBattery(ctx, 30*time.Second)
+//	scenario.Restore()
+//
+// Battery charge and runtime move together: runtime is the full runtime scaled
+// by the charge.
+type Scenario struct {
+	server *Server
+	ups    string
+
+	mu          sync.Mutex
+	fullRuntime float64
+	onBattery   bool
+	fsd         bool
+}
+
+// NewScenario serves a UPS named ups with DefaultVariables on server and
+// returns a Scenario driving it.
+func NewScenario(server *Server, ups string) *Scenario {
+	vars := DefaultVariables()
+	server.AddUPS(ups, "nuttest scenario", vars, "test.battery.start.quick", "shutdown.return")
+	fullRuntime, _ := strconv.ParseFloat(vars["battery.runtime"], 64)
+	return &Scenario{server: server, ups: ups, fullRuntime: fullRuntime}
+}
+
+// PowerFail switches the UPS to battery: status OB DISCHRG and no input
+// voltage. LB is raised if the charge is already at or below
+// battery.charge.low.
+func (s *Scenario) PowerFail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onBattery = true
+	s.server.SetVar(s.ups, "input.voltage", "0")
+	s.updateStatus()
+}
+
+// DrainBattery discharges the battery to zero over d, in small steps, and
+// blocks until done or until ctx is canceled. LB is raised once the charge
+// reaches battery.charge.low. Call PowerFail first for a realistic scenario.
+// A d too short to be divided into steps drains the battery at once.
+func (s *Scenario) DrainBattery(ctx context.Context, d time.Duration) error {
+	start := s.charge()
+	step := d / drainSteps
+	if step <= 0 {
+		s.SetCharge(0)
+		return nil
+	}
+	ticker := time.NewTicker(step)
+	defer ticker.Stop()
+
+	for i := 1; i <= drainSteps; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		s.SetCharge(start * float64(drainSteps-i) / drainSteps)
+	}
+	return nil
+}
+
+// SetCharge sets the battery charge, and the runtime matching it, immediately.
+func (s *Scenario) SetCharge(charge float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.server.SetVar(s.ups, "battery.charge", strconv.FormatFloat(charge, 'f', 0, 64))
+	s.server.SetVar(s.ups, "battery.runtime", strconv.FormatFloat(s.fullRuntime*charge/100, 'f', 0, 64))
+	s.updateStatus()
+}
+
+// Restore returns the UPS to line power, charging if the battery is not full,
+// and clears FSD.
+func (s *Scenario) Restore() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onBattery, s.fsd = false, false
+	s.server.SetVar(s.ups, "input.voltage", s.server.Var(s.ups, "input.voltage.nominal"))
+	s.updateStatus()
+}
+
+// ForceShutdown adds the FSD flag, as upsmon does on the primary before
+// shutting down.
+func (s *Scenario) ForceShutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fsd = true
+	s.updateStatus()
+}
+
+// Overload sets the load in percent; above 100 the OVER flag is raised.
+func (s *Scenario) Overload(load float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.server.SetVar(s.ups, "ups.load", strconv.FormatFloat(load, 'f', 0, 64))
+	s.updateStatus()
+}
+
+// updateStatus derives ups.status from the power source, battery charge and
+// load.
+func (s *Scenario) updateStatus() {
+	charge := s.charge()
+	var flags []string
+	if s.fsd {
+		flags = append(flags, "FSD")
+	}
+	if s.onBattery {
+		flags = append(flags, "OB", "DISCHRG")
+		low, _ := strconv.ParseFloat(s.server.Var(s.ups, "battery.charge.low"), 64)
+		if charge <= low {
+			flags = append(flags, "LB")
+		}
+	} else {
+		flags = append(flags, "OL")
+		if charge < 100 {
+			flags = append(flags, "CHRG")
+		}
+	}
+	if load, _ := strconv.ParseFloat(s.server.Var(s.ups, "ups.load"), 64); load > 100 {
+		flags = append(flags, "OVER")
+	}
+	s.server.SetVar(s.ups, "ups.status", strings.Join(flags, " "))
+}
+
+func (s *Scenario) charge() float64 {
+	charge, _ := strconv.ParseFloat(s.server.Var(s.ups, "battery.charge"), 64)
+	return charge
+}
@@ -0,0 +1,402 @@
+package nuttest
+
+import (
+	"bufio"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fakeUPS is the state of a UPS served by a Server.
+type fakeUPS struct {
+	description string
+	vars        map[string]string
+	commands    []string
+	issued      []string // Instant commands received, in order
+	err         string   // Error code answered to LIST and GET, if set
+}
+
+// Server is an in-process upsd speaking the NUT text protocol on a loopback
+// port. It serves LIST UPS/VAR/RW/CMD/CLIENT, GET VAR/UPSDESC/NUMLOGINS/TYPE/
+// DESC/CMDDESC, SET VAR, INSTCMD, USERNAME, PASSWORD, LOGIN, LOGOUT, PRIMARY,
+// MASTER, VER, NETVER and HELP; any username and password are accepted and
+// grant primary rights. Variable values can be changed at any time, e.g. by a
+// Scenario.
+type Server struct {
+	listener net.Listener
+
+	mu     sync.Mutex
+	ups    map[string]*fakeUPS
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer starts a server listening on a random loopback port, serving no
+// UPSes until AddUPS is called.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		ups:      map[string]*fakeUPS{},
+		conns:    map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the server's listening address as host:port.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// HostPort returns the server's listening host and port, in the form expected
+// by nut.ConnectWithOptionsAndConfig.
+func (s *Server) HostPort() (string, int) {
+	host, port, _ := net.SplitHostPort(s.Addr())
+	portNum, _ := strconv.Atoi(port)
+	return host, portNum
+}
+
+// AddUPS serves a UPS with the given variables and instant commands, replacing
+// any UPS of the same name.
+func (s *Server) AddUPS(name, description string, vars map[string]string, commands ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := make(map[string]string, len(vars))
+	for k, v := range vars {
+		copied[k] = v
+	}
+	s.ups[name] = &fakeUPS{description: description, vars: copied, commands: commands}
+}
+
+// SetVar sets a variable of a UPS; an empty value removes it.
+func (s *Server) SetVar(ups, name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.ups[ups]
+	if !ok {
+		return
+	}
+	if value == "" {
+		delete(u.vars, name)
+		return
+	}
+	u.vars[name] = value
+}
+
+// SetError makes LIST and GET requests for a UPS answer ERR code, as upsd does
+// e.g. for DATA-STALE; an empty code restores normal answers.
+func (s *Server) SetError(ups, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.ups[ups]; ok {
+		u.err = code
+	}
+}
+
+// Var returns the current value of a variable of a UPS.
+func (s *Server) Var(ups, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.ups[ups]; ok {
+		return u.vars[name]
+	}
+	return ""
+}
+
+// Commands returns the instant commands a UPS received, in order.
+func (s *Server) Commands(ups string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.ups[ups]; ok {
+		return append([]string(nil), u.issued...)
+	}
+	return nil
+}
+
+// DropConnections immediately closes all client connections.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the server and closes all client connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := splitArgs(strings.TrimRight(line, "\r\n"))
+		if len(args) == 0 {
+			continue
+		}
+		response, quit := s.respond(args)
+		if _, err := conn.Write([]byte(response)); err != nil || quit {
+			return
+		}
+	}
+}
+
+// respond returns the response to a command and whether to close the
+// connection afterwards.
+func (s *Server) respond(args []string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	command := strings.ToUpper(args[0])
+	switch command {
+	case "VER":
+		return "Network UPS Tools upsd 2.8.1 - nuttest\n", false
+	case "NETVER":
+		return "1.3\n", false
+	case "HELP":
+		return "Commands: HELP VER GET LIST SET INSTCMD LOGIN LOGOUT USERNAME PASSWORD PRIMARY MASTER\n", false
+	case "USERNAME", "PASSWORD":
+		return "OK\n", false
+	case "LOGOUT":
+		return "OK Goodbye\n", true
+	case "PRIMARY", "MASTER":
+		if len(args) < 2 {
+			return "ERR INVALID-ARGUMENT\n", false
+		}
+		if _, ok := s.ups[args[1]]; !ok {
+			return "ERR UNKNOWN-UPS\n", false
+		}
+		return "OK " + command + "-GRANTED\n", false
+	case "STARTTLS":
+		return "ERR FEATURE-NOT-CONFIGURED\n", false
+	case "LOGIN":
+		if len(args) < 2 {
+			return "ERR INVALID-ARGUMENT\n", false
+		}
+		if _, ok := s.ups[args[1]]; !ok {
+			return "ERR UNKNOWN-UPS\n", false
+		}
+		return "OK\n", false
+	case "LIST":
+		return s.list(args[1:]), false
+	case "GET":
+		return s.get(args[1:]), false
+	case "SET":
+		if len(args) != 5 || strings.ToUpper(args[1]) != "VAR" {
+			return "ERR INVALID-ARGUMENT\n", false
+		}
+		u, ok := s.ups[args[2]]
+		if !ok {
+			return "ERR UNKNOWN-UPS\n", false
+		}
+		if _, ok := u.vars[args[3]]; !ok {
+			return "ERR VAR-NOT-SUPPORTED\n", false
+		}
+		u.vars[args[3]] = args[4]
+		return "OK\n", false
+	case "INSTCMD":
+		if len(args) < 3 {
+			return "ERR INVALID-ARGUMENT\n", false
+		}
+		u, ok := s.ups[args[1]]
+		if !ok {
+			return "ERR UNKNOWN-UPS\n", false
+		}
+		for _, cmd := range u.commands {
+			if cmd == args[2] {
+				u.issued = append(u.issued, cmd)
+				return "OK\n", false
+			}
+		}
+		return "ERR CMD-NOT-SUPPORTED\n", false
+	default:
+		return "ERR UNKNOWN-COMMAND\n", false
+	}
+}
+
+func (s *Server) list(args []string) string {
+	if len(args) == 0 {
+		return "ERR INVALID-ARGUMENT\n"
+	}
+	var b strings.Builder
+	kind := strings.ToUpper(args[0])
+	if kind == "UPS" {
+		b.WriteString("BEGIN LIST UPS\n")
+		for _, name := range sortedKeys(s.ups) {
+			b.WriteString("UPS " + name + " " + quote(s.ups[name].description) + "\n")
+		}
+		b.WriteString("END LIST UPS\n")
+		return b.String()
+	}
+	if len(args) < 2 {
+		return "ERR INVALID-ARGUMENT\n"
+	}
+	u, ok := s.ups[args[1]]
+	if !ok {
+		return "ERR UNKNOWN-UPS\n"
+	}
+	if u.err != "" {
+		return "ERR " + u.err + "\n"
+	}
+
+	header := kind + " " + args[1]
+	b.WriteString("BEGIN LIST " + header + "\n")
+	switch kind {
+	case "VAR", "RW":
+		if kind == "VAR" {
+			for _, name := range sortedKeys(u.vars) {
+				b.WriteString("VAR " + args[1] + " " + name + " " + quote(u.vars[name]) + "\n")
+			}
+		}
+	case "CMD":
+		for _, cmd := range u.commands {
+			b.WriteString("CMD " + args[1] + " " + cmd + "\n")
+		}
+	case "CLIENT":
+	default:
+		return "ERR INVALID-ARGUMENT\n"
+	}
+	b.WriteString("END LIST " + header + "\n")
+	return b.String()
+}
+
+func (s *Server) get(args []string) string {
+	if len(args) < 2 {
+		return "ERR INVALID-ARGUMENT\n"
+	}
+	kind := strings.ToUpper(args[0])
+	u, ok := s.ups[args[1]]
+	if !ok {
+		return "ERR UNKNOWN-UPS\n"
+	}
+	if u.err != "" {
+		return "ERR " + u.err + "\n"
+	}
+	switch kind {
+	case "UPSDESC":
+		return "UPSDESC " + args[1] + " " + quote(u.description) + "\n"
+	case "NUMLOGINS":
+		return "NUMLOGINS " + args[1] + " 0\n"
+	}
+	if len(args) < 3 {
+		return "ERR INVALID-ARGUMENT\n"
+	}
+	switch kind {
+	case "VAR", "TYPE", "DESC":
+		value, ok := u.vars[args[2]]
+		if !ok {
+			return "ERR VAR-NOT-SUPPORTED\n"
+		}
+		switch kind {
+		case "VAR":
+			return "VAR " + args[1] + " " + args[2] + " " + quote(value) + "\n"
+		case "TYPE":
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return "TYPE " + args[1] + " " + args[2] + " NUMBER\n"
+			}
+			return "TYPE " + args[1] + " " + args[2] + " STRING:" + strconv.Itoa(len(value)) + "\n"
+		default:
+			return "DESC " + args[1] + " " + args[2] + " \"Description unavailable\"\n"
+		}
+	case "CMDDESC":
+		return "CMDDESC " + args[1] + " " + args[2] + " \"Description unavailable\"\n"
+	}
+	return "ERR INVALID-ARGUMENT\n"
+}
+
+// splitArgs splits a command line into words, honoring double quotes and
+// backslash escapes.
+func splitArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes, inWord := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+			inWord = true
+		case c == '"':
+			inQuotes = !inQuotes
+			inWord = true
+		case c == ' ' && !inQuotes:
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// quote returns s as a quoted protocol string.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
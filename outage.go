@@ -0,0 +1,66 @@
+package nut
+
+import (
+	"strconv"
+	"time"
+)
+
+// OutageStats counts the time a UPS spent on battery, as observed by a
+// Monitor's polls since it started monitoring the UPS. upsd does not keep
+// these counters, so they reset when the Monitor is recreated.
+type OutageStats struct {
+	Transfers int           // Number of transfers to battery
+	OnBattery time.Duration // Total time on battery, including an ongoing outage
+	Longest   time.Duration // Longest outage, including an ongoing one
+	LastStart time.Time     // Start of the last or ongoing outage; zero if none
+	LastEnd   time.Time     // End of the last outage; zero while it is ongoing or if none
+	Ongoing   bool          // The UPS is on battery
+}
+
+// derived returns the counters as derived variables, see DerivedVariables.
+func (s OutageStats) derived() map[string]string {
+	seconds := func(d time.Duration) string { return strconv.FormatInt(int64(d/time.Second), 10) }
+	return map[string]string{
+		DerivedPrefix + "outage.count":            strconv.Itoa(s.Transfers),
+		DerivedPrefix + "outage.duration.total":   seconds(s.OnBattery),
+		DerivedPrefix + "outage.duration.longest": seconds(s.Longest),
+	}
+}
+
+// outageTracker accumulates OutageStats from successive status observations.
+type outageTracker struct {
+	stats OutageStats // Completed outages, and LastStart of an ongoing one
+}
+
+// observe records the status of the UPS at now. Outages are timed from the
+// first poll that sees the UPS on battery to the first that does not.
+func (t *outageTracker) observe(status Status, now time.Time) {
+	onBattery := status.Has(StatusOnBattery)
+	switch {
+	case onBattery && !t.stats.Ongoing:
+		t.stats.Transfers++
+		t.stats.LastStart, t.stats.LastEnd = now, time.Time{}
+		t.stats.Ongoing = true
+	case !onBattery && t.stats.Ongoing:
+		duration := now.Sub(t.stats.LastStart)
+		t.stats.OnBattery += duration
+		if duration > t.stats.Longest {
+			t.stats.Longest = duration
+		}
+		t.stats.LastEnd = now
+		t.stats.Ongoing = false
+	}
+}
+
+// at returns the stats as of now, counting an ongoing outage up to now.
+func (t *outageTracker) at(now time.Time) OutageStats {
+	stats := t.stats
+	if stats.Ongoing {
+		ongoing := now.Sub(stats.LastStart)
+		stats.OnBattery += ongoing
+		if ongoing > stats.Longest {
+			stats.Longest = ongoing
+		}
+	}
+	return stats
+}
@@ -0,0 +1,170 @@
+package nut
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError describes a response line that does not match the NUT protocol.
+type ParseError struct {
+	Command string // Command whose response was being parsed
+	Line    string // Offending line, empty for missing lines
+	Reason  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Line == "" {
+		return fmt.Sprintf("malformed response to %q: %s", e.Command, e.Reason)
+	}
+	return fmt.Sprintf("malformed response to %q: %s: %q", e.Command, e.Reason, e.Line)
+}
+
+// WithStrictParsing makes the client fail with a *ParseError on malformed lines,
+// unexpected prefixes and missing BEGIN/END markers instead of skipping them.
+func WithStrictParsing() ClientOption {
+	return func(c *Client) {
+		c.strictParsing = true
+	}
+}
+
+// WithParseErrorHandler registers a callback invoked for every malformed
+// response line. In the default lenient mode such lines are skipped, and this
+// callback is the only way to observe them.
+func WithParseErrorHandler(handler func(*ParseError)) ClientOption {
+	return func(c *Client) {
+		c.parseErrorHandler = handler
+	}
+}
+
+// malformed reports a protocol violation. It returns the error in strict mode and
+// nil in lenient mode, after notifying the parse error handler and logger. In
+// strict mode the client is marked broken, as the rest of the response is left
+// unread; a skipped line in lenient mode leaves the connection in sync.
+func (c *Client) malformed(cmd, line, reason string) error {
+	perr := &ParseError{Command: cmd, Line: line, Reason: reason}
+	if c.strictParsing {
+		c.markBroken()
+	}
+	if c.parseErrorHandler != nil {
+		c.parseErrorHandler(perr)
+	}
+	if logger := c.loggerFor(LogWarn); logger != nil {
+		logger.Printf("Warning: %v", perr)
+	}
+	if c.strictParsing {
+		return perr
+	}
+	return nil
+}
+
+// trimPrefix removes prefix from line, reporting a line that lacks it. In lenient
+// mode the line is returned unchanged when the prefix is missing.
+func (c *Client) trimPrefix(cmd, line, prefix string) (string, error) {
+	if strings.HasPrefix(line, prefix) {
+		return strings.TrimPrefix(line, prefix), nil
+	}
+	if err := c.malformed(cmd, line, fmt.Sprintf("expected prefix %q", prefix)); err != nil {
+		return "", err
+	}
+	return line, nil
+}
+
+// listBody validates the BEGIN/END framing of a LIST response and returns the
+// body lines with prefix removed. Lines without the prefix are skipped in
+// lenient mode.
+func (c *Client) listBody(cmd string, resp []string, prefix string) ([]string, error) {
+	cmd = strings.TrimSpace(cmd)
+	body := []string{}
+	// Broken framing means the response may belong to another command
+	if len(resp) < 2 {
+		c.markBroken()
+		return body, c.malformed(cmd, "", "missing BEGIN/END markers")
+	}
+	if resp[0] != "BEGIN "+cmd {
+		c.markBroken()
+		if err := c.malformed(cmd, resp[0], "expected BEGIN marker"); err != nil {
+			return body, err
+		}
+	}
+	if resp[len(resp)-1] != "END "+cmd {
+		c.markBroken()
+		if err := c.malformed(cmd, resp[len(resp)-1], "expected END marker"); err != nil {
+			return body, err
+		}
+	}
+
+	for _, line := range resp[1 : len(resp)-1] {
+		if !strings.HasPrefix(line, prefix) {
+			if err := c.malformed(cmd, line, fmt.Sprintf("expected prefix %q", prefix)); err != nil {
+				return body, err
+			}
+			continue
+		}
+		body = append(body, strings.TrimPrefix(line, prefix))
+	}
+	return body, nil
+}
+
+// parseQuoted decodes a double-quoted protocol string at the start of s,
+// resolving backslash escapes, and returns the decoded value and the remainder
+// of s after the closing quote. ok is false if s does not start with a complete
+// quoted string.
+func parseQuoted(s string) (value, rest string, ok bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, false
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", s, false
+}
+
+// splitFields splits line into space-separated fields, unquoting double-quoted
+// fields. With comments, a field starting with # ends the line.
+func splitFields(line string, comments bool) ([]string, error) {
+	var fields []string
+	rest := strings.TrimSpace(line)
+	for rest != "" && !(comments && strings.HasPrefix(rest, "#")) {
+		var field string
+		if strings.HasPrefix(rest, `"`) {
+			value, after, ok := parseQuoted(rest)
+			if !ok {
+				// The line may hold a password; report the position only
+				return nil, fmt.Errorf("unterminated quoted string in field %d", len(fields)+1)
+			}
+			field, rest = value, after
+		} else if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			field, rest = rest[:i], rest[i:]
+		} else {
+			field, rest = rest, ""
+		}
+		fields = append(fields, field)
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	return fields, nil
+}
+
+// quotedValue decodes the quoted string that makes up the remainder of a
+// response line. In lenient mode an unquoted remainder is returned as-is.
+func (c *Client) quotedValue(cmd, s string) (string, error) {
+	value, _, ok := parseQuoted(s)
+	if ok {
+		return value, nil
+	}
+	if err := c.malformed(cmd, s, "expected quoted string"); err != nil {
+		return "", err
+	}
+	return s, nil
+}
@@ -0,0 +1,140 @@
+package nut_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+// newScriptedClient returns a client connected to a server that answers each
+// command in responses with the given lines, and any other command with an
+// error.
+func newScriptedClient(tb testing.TB, responses map[string][]string, opts ...nut.ClientOption) *nut.Client {
+	tb.Helper()
+	sequences := make(map[string][][]string, len(responses))
+	for cmd, lines := range responses {
+		sequences[cmd] = [][]string{lines}
+	}
+	return newSequencedClient(tb, sequences, opts...)
+}
+
+// newSequencedClient is like newScriptedClient, but answers successive
+// sends of a command with successive responses, repeating the last one.
+func newSequencedClient(tb testing.TB, responses map[string][][]string, opts ...nut.ClientOption) *nut.Client {
+	tb.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSuffix(line, "\n")
+			lines := []string{"ERR UNKNOWN-COMMAND"}
+			if sequence := responses[cmd]; len(sequence) > 0 {
+				lines = sequence[0]
+				if len(sequence) > 1 {
+					responses[cmd] = sequence[1:]
+				}
+			}
+			conn.Write([]byte(strings.Join(lines, "\n") + "\n"))
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	client, err := nut.NewClientFromConn(conn, append([]nut.ClientOption{nut.WithSkipHandshake()}, opts...)...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { client.Close() })
+	return client
+}
+
+var malformedVarList = map[string][]string{
+	"LIST VAR ups1": {
+		"BEGIN LIST VAR ups1",
+		`VAR ups1 battery.charge "100"`,
+		"garbage",
+		`VAR ups1 ups.status "OL"`,
+		"END LIST VAR ups1",
+	},
+}
+
+func TestLenientParsingKeepsConnection(t *testing.T) {
+	var reported []*nut.ParseError
+	client := newScriptedClient(t, malformedVarList, nut.WithParseErrorHandler(func(err *nut.ParseError) { reported = append(reported, err) }))
+	vars, err := nut.NewNUTBackend(client).Variables(context.Background(), "ups1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 2 || len(reported) != 1 || reported[0].Line != "garbage" {
+		t.Fatalf("vars = %+v, reported = %v", vars, reported)
+	}
+	if client.Broken() {
+		t.Fatal("a skipped line marked the client broken")
+	}
+}
+
+func TestStrictParsingMarksBroken(t *testing.T) {
+	client := newScriptedClient(t, malformedVarList, nut.WithStrictParsing())
+	var perr *nut.ParseError
+	if _, err := nut.NewNUTBackend(client).Variables(context.Background(), "ups1"); !errors.As(err, &perr) {
+		t.Fatalf("err = %v, want a *ParseError", err)
+	}
+	if !client.Broken() {
+		t.Fatal("client not marked broken")
+	}
+}
+
+func TestBrokenFramingMarksBroken(t *testing.T) {
+	client := newScriptedClient(t, map[string][]string{
+		"LIST VAR ups1": {"BEGIN LIST VAR ups2", `VAR ups1 ups.status "OL"`, "END LIST VAR ups1"},
+	})
+	nut.NewNUTBackend(client).Variables(context.Background(), "ups1")
+	if !client.Broken() {
+		t.Fatal("client not marked broken")
+	}
+}
+
+func TestUPSListSkipsMalformedLines(t *testing.T) {
+	var reported []*nut.ParseError
+	client := newScriptedClient(t, map[string][]string{
+		"LIST UPS": {
+			"BEGIN LIST UPS",
+			`UPS ups1 "Rack A"`,
+			"UPS ups2",
+			`UPS ups3 "Rack C`,
+			`UPS ups4 "Rack D"`,
+			"END LIST UPS",
+		},
+	}, nut.WithParseErrorHandler(func(err *nut.ParseError) { reported = append(reported, err) }))
+	upsList, err := client.GetUPSList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upsList) != 2 || upsList[0].Name != "ups1" || upsList[1].Description != "Rack D" || len(reported) != 2 {
+		t.Fatalf("ups = %+v, reported = %v", upsList, reported)
+	}
+	listed, err := nut.NewNUTBackend(client).ListUPS(context.Background())
+	if err != nil || len(listed) != 2 {
+		t.Fatalf("listed = %+v, err = %v", listed, err)
+	}
+}
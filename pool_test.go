@@ -0,0 +1,77 @@
+package nut_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+func TestPoolPutAfterForcedClose(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+
+	var destroyed atomic.Int32
+	host, port := server.HostPort()
+	pool, err := nut.NewPool(nut.PoolConfig{
+		MaxSize:  2,
+		Hostname: host,
+		Port:     port,
+		Hooks:    nut.PoolHooks{OnDestroy: func(*nut.Client) { destroyed.Add(1) }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := pool.CloseWithContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("CloseWithContext: err = %v, want deadline exceeded", err)
+	}
+	if err := pool.Put(client); err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(client)
+
+	stats := pool.Metrics()
+	if stats.Active != 0 || stats.Closed != 1 || destroyed.Load() != 1 {
+		t.Fatalf("active = %d, closed = %d, destroyed = %d; want 0, 1, 1", stats.Active, stats.Closed, destroyed.Load())
+	}
+}
+
+func TestPoolPutTwice(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+
+	host, port := server.HostPort()
+	pool, err := nut.NewPool(nut.PoolConfig{MaxSize: 2, Hostname: host, Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	client, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(client)
+	pool.Put(client)
+	if idle, active := pool.Stats(); idle != 1 || active != 1 {
+		t.Fatalf("idle = %d, active = %d; want 1, 1", idle, active)
+	}
+}
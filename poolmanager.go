@@ -0,0 +1,154 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// PoolManager maintains an independent Pool per NUT endpoint (host:port),
+// created on first use, so applications talking to many servers don't have to
+// manage one Pool per server by hand.
+type PoolManager struct {
+	template PoolConfig
+
+	mu     sync.Mutex
+	pools  map[string]*Pool
+	owners map[*Client]*Pool // Pool each checked-out client belongs to
+	closed bool
+}
+
+// NewPoolManager returns a PoolManager creating sub-pools from template. The
+// Hostname and Port of template are ignored; they are taken from the endpoint
+// passed to GetFor.
+func NewPoolManager(template PoolConfig) *PoolManager {
+	return &PoolManager{
+		template: template,
+		pools:    map[string]*Pool{},
+		owners:   map[*Client]*Pool{},
+	}
+}
+
+// GetFor retrieves a client for endpoint ("host:port", or "host" for the
+// default port) from its sub-pool, creating the sub-pool if needed.
+func (m *PoolManager) GetFor(ctx context.Context, endpoint string) (*Client, error) {
+	pool, err := m.poolFor(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.owners[client] = pool
+	m.mu.Unlock()
+	return client, nil
+}
+
+// Put returns a client obtained from GetFor to its sub-pool.
+func (m *PoolManager) Put(client *Client) error {
+	if client == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	pool, ok := m.owners[client]
+	delete(m.owners, client)
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("client does not belong to this pool manager")
+	}
+	return pool.Put(client)
+}
+
+// Pool returns the sub-pool for endpoint, or nil if none was created yet.
+func (m *PoolManager) Pool(endpoint string) *Pool {
+	key, _, _, err := normalizeEndpoint(endpoint)
+	if err != nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pools[key]
+}
+
+// Endpoints returns the endpoints that currently have a sub-pool, sorted.
+func (m *PoolManager) Endpoints() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoints := make([]string, 0, len(m.pools))
+	for endpoint := range m.pools {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// Close closes all sub-pools and prevents new ones from being created.
+func (m *PoolManager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	pools := m.pools
+	m.pools = map[string]*Pool{}
+	m.mu.Unlock()
+
+	var lastErr error
+	for _, pool := range pools {
+		if err := pool.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (m *PoolManager) poolFor(endpoint string) (*Pool, error) {
+	key, host, port, err := normalizeEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, fmt.Errorf("pool manager is closed")
+	}
+	if pool, ok := m.pools[key]; ok {
+		return pool, nil
+	}
+
+	config := m.template
+	config.Hostname = host
+	config.Port = port
+	pool, err := NewPool(config)
+	if err != nil {
+		return nil, err
+	}
+	m.pools[key] = pool
+	return pool, nil
+}
+
+// normalizeEndpoint splits endpoint into host and port, defaulting to port 3493,
+// and returns the canonical host:port key.
+func normalizeEndpoint(endpoint string) (key, host string, port int, err error) {
+	host, portStr, splitErr := net.SplitHostPort(endpoint)
+	if splitErr != nil {
+		host, portStr = endpoint, "3493"
+	}
+	if host == "" {
+		return "", "", 0, fmt.Errorf("invalid endpoint %q: hostname is required", endpoint)
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), host, port, nil
+}
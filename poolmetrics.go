@@ -0,0 +1,145 @@
+package nut
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// poolCounters are the cumulative counters of a Pool.
+type poolCounters struct {
+	checkouts           uint64
+	waits               uint64
+	waitDuration        time.Duration
+	timeouts            uint64
+	created             uint64
+	createFailures      uint64
+	closed              uint64
+	healthCheckFailures uint64
+}
+
+// PoolStats is a point-in-time view of the usage of a Pool, for capacity
+// tuning: frequent waits or timeouts mean MaxSize is too small, while a high
+// rate of created and closed connections means clients are not returned in
+// time or MaxSize is too small to keep them idle. Counters are cumulative
+// since the pool was created.
+type PoolStats struct {
+	Endpoint            string        // host:port of the NUT server
+	Idle                int           // Connections waiting in the pool
+	Active              int           // Idle and checked-out connections
+	MaxSize             int           // Maximum number of connections
+	Checkouts           uint64        // Clients handed out by Get
+	Waits               uint64        // Calls to Get that waited because the pool was exhausted
+	WaitDuration        time.Duration // Total time spent in those waits
+	Timeouts            uint64        // Waits that ended because the Get context was done
+	Created             uint64        // Connections established
+	CreateFailures      uint64        // Connection attempts that failed
+	Closed              uint64        // Connections closed by the pool
+	HealthCheckFailures uint64        // Connections found unusable when checked out or returned
+}
+
+// Metrics returns the current statistics of the pool.
+func (p *Pool) Metrics() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := p.counters
+	return PoolStats{
+		Endpoint:            net.JoinHostPort(p.hostname, strconv.Itoa(p.port)),
+		Idle:                len(p.clients),
+		Active:              p.activeClients,
+		MaxSize:             p.maxSize,
+		Checkouts:           c.checkouts,
+		Waits:               c.waits,
+		WaitDuration:        c.waitDuration,
+		Timeouts:            c.timeouts,
+		Created:             c.created,
+		CreateFailures:      c.createFailures,
+		Closed:              c.closed,
+		HealthCheckFailures: c.healthCheckFailures,
+	}
+}
+
+// recordWait accounts for a wait of Get for an available client.
+func (p *Pool) recordWait(start time.Time, timedOut bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counters.waitDuration += time.Since(start)
+	if timedOut {
+		p.counters.timeouts++
+	}
+}
+
+// Metrics returns the statistics of every sub-pool, sorted by endpoint.
+func (m *PoolManager) Metrics() []PoolStats {
+	m.mu.Lock()
+	pools := make([]*Pool, 0, len(m.pools))
+	for _, pool := range m.pools {
+		pools = append(pools, pool)
+	}
+	m.mu.Unlock()
+
+	stats := make([]PoolStats, len(pools))
+	for i, pool := range pools {
+		stats[i] = pool.Metrics()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Endpoint < stats[j].Endpoint })
+	return stats
+}
+
+// poolMetric describes one metric of the Prometheus exposition.
+type poolMetric struct {
+	name, kind, help string
+	value            func(PoolStats) float64
+}
+
+var poolMetrics = []poolMetric{
+	{"nut_pool_idle_connections", "gauge", "Connections waiting in the pool.", func(s PoolStats) float64 { return float64(s.Idle) }},
+	{"nut_pool_active_connections", "gauge", "Idle and checked-out connections.", func(s PoolStats) float64 { return float64(s.Active) }},
+	{"nut_pool_max_connections", "gauge", "Maximum number of connections.", func(s PoolStats) float64 { return float64(s.MaxSize) }},
+	{"nut_pool_checkouts_total", "counter", "Clients handed out by the pool.", func(s PoolStats) float64 { return float64(s.Checkouts) }},
+	{"nut_pool_waits_total", "counter", "Checkouts that waited because the pool was exhausted.", func(s PoolStats) float64 { return float64(s.Waits) }},
+	{"nut_pool_wait_seconds_total", "counter", "Total time spent waiting for a connection.", func(s PoolStats) float64 { return s.WaitDuration.Seconds() }},
+	{"nut_pool_checkout_timeouts_total", "counter", "Waits that ended because the context was done.", func(s PoolStats) float64 { return float64(s.Timeouts) }},
+	{"nut_pool_connections_created_total", "counter", "Connections established.", func(s PoolStats) float64 { return float64(s.Created) }},
+	{"nut_pool_connection_failures_total", "counter", "Connection attempts that failed.", func(s PoolStats) float64 { return float64(s.CreateFailures) }},
+	{"nut_pool_connections_closed_total", "counter", "Connections closed by the pool.", func(s PoolStats) float64 { return float64(s.Closed) }},
+	{"nut_pool_health_check_failures_total", "counter", "Connections found unusable.", func(s PoolStats) float64 { return float64(s.HealthCheckFailures) }},
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePoolMetrics writes the statistics in the Prometheus text exposition
+// format, with an endpoint label per pool.
+func WritePoolMetrics(w io.Writer, stats ...PoolStats) error {
+	bw := bufio.NewWriter(w)
+	for _, metric := range poolMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, s := range stats {
+			value := strconv.FormatFloat(metric.value(s), 'g', -1, 64)
+			fmt.Fprintf(bw, "%s{endpoint=\"%s\"} %s\n", metric.name, labelEscaper.Replace(s.Endpoint), value)
+		}
+	}
+	return bw.Flush()
+}
+
+// PoolMetricsHandler returns an http.Handler serving the statistics returned
+// by stats in the Prometheus text exposition format, to be scraped directly
+// or mounted next to an existing /metrics endpoint:
+//
+//	http.Handle("/metrics/pool", nut.PoolMetricsHandler(manager.Metrics))
+//	http.Handle("/metrics/pool", nut.PoolMetricsHandler(func() []nut.PoolStats {
+//		return []nut.PoolStats{pool.Metrics()}
+//	}))
+func PoolMetricsHandler(stats func() []PoolStats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = WritePoolMetrics(w, stats()...)
+	})
+}
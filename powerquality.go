@@ -0,0 +1,199 @@
+package nut
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultVoltageTolerance is the deviation from the nominal input voltage, in
+// percent, beyond which a sample is out of tolerance.
+const defaultVoltageTolerance = 10
+
+// PowerQualityWindow summarizes the utility power seen by a UPS within one
+// window.
+type PowerQualityWindow struct {
+	Start          time.Time
+	Samples        int
+	Voltage        SeriesPoint // input.voltage; Count is zero without values
+	Frequency      SeriesPoint // input.frequency; Count is zero without values
+	OutOfTolerance int         // Samples with input.voltage too far from nominal
+	Transfers      int         // Switches to battery (upsmon ONBATT)
+}
+
+// PowerQualityReport summarizes the utility power seen by one UPS, e.g. to
+// show the facilities team or the utility that voltage is chronically low at
+// certain hours, from the samples and events of a HistoryStore.
+type PowerQualityReport struct {
+	Server         string
+	UPS            string
+	Window         time.Duration
+	NominalVoltage float64              // Latest input.voltage.nominal, zero if not reported
+	Tolerance      float64              // Allowed deviation from nominal, in percent
+	Total          PowerQualityWindow   // Whole period; Start is that of the first window
+	Windows        []PowerQualityWindow // In time order; windows without data are omitted
+}
+
+// QueryPowerQuality reads the samples and events matching query from store
+// and returns a report per UPS, in order of first appearance, aggregated into
+// windows of the given length (e.g. an hour or a day), aligned as by
+// time.Time.Truncate. A sample is out of tolerance when input.voltage deviates
+// from input.voltage.nominal by more than tolerance percent (default 10);
+// samples of a UPS that does not report its nominal voltage are not checked.
+// Samples downsampled by a RetentionPolicy keep their minimum and maximum
+// voltage; one out of tolerance counts once, so OutOfTolerance is a lower
+// bound for compacted history.
+func QueryPowerQuality(ctx context.Context, store HistoryStore, query HistoryQuery, window time.Duration, tolerance float64) ([]PowerQualityReport, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	if tolerance <= 0 {
+		tolerance = defaultVoltageTolerance
+	}
+	samples, err := store.Samples(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	events, err := store.Events(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*powerQualityBuilder
+	index := map[string]*powerQualityBuilder{}
+	builder := func(server, ups string) *powerQualityBuilder {
+		key := energyKey(server, ups)
+		b, ok := index[key]
+		if !ok {
+			b = &powerQualityBuilder{
+				report:  PowerQualityReport{Server: server, UPS: ups, Window: window, Tolerance: tolerance},
+				windows: map[time.Time]*PowerQualityWindow{},
+			}
+			index[key] = b
+			reports = append(reports, b)
+		}
+		return b
+	}
+
+	for _, sample := range samples {
+		b := builder(sample.Server, sample.UPS)
+		if nominal, ok := numericVariable(sample.Variables, "input.voltage.nominal"); ok && nominal > 0 {
+			b.report.NominalVoltage = nominal
+		}
+		b.addSample(sample)
+	}
+	for _, event := range events {
+		if notifyType, _ := upsmonNotification(event); event.Type == EventVariableChanged && notifyType == "ONBATT" {
+			builder(event.Server, event.UPS).addTransfer(event.Time)
+		}
+	}
+
+	result := make([]PowerQualityReport, len(reports))
+	for i, b := range reports {
+		result[i] = b.build()
+	}
+	return result, nil
+}
+
+// powerQualityBuilder accumulates the report of one UPS.
+type powerQualityBuilder struct {
+	report  PowerQualityReport
+	windows map[time.Time]*PowerQualityWindow
+}
+
+func (b *powerQualityBuilder) window(t time.Time) *PowerQualityWindow {
+	start := t.Truncate(b.report.Window)
+	w, ok := b.windows[start]
+	if !ok {
+		w = &PowerQualityWindow{Start: start, Voltage: SeriesPoint{Time: start}, Frequency: SeriesPoint{Time: start}}
+		b.windows[start] = w
+	}
+	return w
+}
+
+// addSample adds a sample, or the samples merged into a downsampled one.
+func (b *powerQualityBuilder) addSample(sample Snapshot) {
+	w := b.window(sample.Time)
+	samples := 1
+	if voltage, ok := variableStats(sample.Variables, "input.voltage"); ok {
+		w.Voltage.merge(voltage)
+		samples = voltage.Count
+		nominal := b.report.NominalVoltage
+		limit := nominal * b.report.Tolerance / 100
+		if nominal > 0 && (math.Abs(voltage.Min-nominal) > limit || math.Abs(voltage.Max-nominal) > limit) {
+			w.OutOfTolerance++
+		}
+	}
+	if frequency, ok := variableStats(sample.Variables, "input.frequency"); ok {
+		w.Frequency.merge(frequency)
+		samples = max(samples, frequency.Count)
+	}
+	w.Samples += samples
+}
+
+func (b *powerQualityBuilder) addTransfer(t time.Time) {
+	b.window(t).Transfers++
+}
+
+// build sorts the windows and computes the totals.
+func (b *powerQualityBuilder) build() PowerQualityReport {
+	report := b.report
+	for _, w := range b.windows {
+		report.Windows = append(report.Windows, *w)
+	}
+	sort.Slice(report.Windows, func(i, j int) bool { return report.Windows[i].Start.Before(report.Windows[j].Start) })
+
+	total := &report.Total
+	for i, w := range report.Windows {
+		if i == 0 {
+			total.Start = w.Start
+			total.Voltage.Time, total.Frequency.Time = w.Start, w.Start
+		}
+		total.Samples += w.Samples
+		total.OutOfTolerance += w.OutOfTolerance
+		total.Transfers += w.Transfers
+		total.Voltage.merge(w.Voltage)
+		total.Frequency.merge(w.Frequency)
+	}
+	return report
+}
+
+// numericVariable returns the value of a numeric variable.
+func numericVariable(values map[string]string, name string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(values[name]), 64)
+	return f, err == nil
+}
+
+// WriteCSV writes the windows of the report as CSV with a header row, for
+// spreadsheets. Statistics of windows without values are left empty.
+func (r PowerQualityReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"start", "samples",
+		"voltage_min", "voltage_avg", "voltage_max",
+		"frequency_min", "frequency_avg", "frequency_max",
+		"out_of_tolerance", "transfers",
+	})
+	stats := func(p SeriesPoint, decimals int) []string {
+		if p.Count == 0 {
+			return []string{"", "", ""}
+		}
+		format := func(f float64) string { return strconv.FormatFloat(f, 'f', decimals, 64) }
+		return []string{format(p.Min), format(p.Avg), format(p.Max)}
+	}
+	for _, window := range r.Windows {
+		record := []string{window.Start.Format(time.RFC3339), strconv.Itoa(window.Samples)}
+		record = append(record, stats(window.Voltage, 1)...)
+		record = append(record, stats(window.Frequency, 2)...)
+		record = append(record, strconv.Itoa(window.OutOfTolerance), strconv.Itoa(window.Transfers))
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
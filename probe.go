@@ -0,0 +1,121 @@
+package nut
+
+import "fmt"
+
+// Permission is the outcome of probing whether the session may perform an action.
+type Permission int
+
+const (
+	// PermissionUnknown means the probe could not determine the permission
+	// without actually performing the action.
+	PermissionUnknown Permission = iota
+	// PermissionDenied means the action would be rejected by upsd.
+	PermissionDenied
+	// PermissionGranted means the action is allowed for this session.
+	PermissionGranted
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionDenied:
+		return "denied"
+	case PermissionGranted:
+		return "granted"
+	default:
+		return "unknown"
+	}
+}
+
+// Permissions describes the capabilities of the current session for a UPS.
+type Permissions struct {
+	Authenticated bool       // USERNAME and PASSWORD were accepted
+	Primary       bool       // PRIMARY (or MASTER on older servers) was accepted
+	InstCmd       Permission // INSTCMD is allowed
+	Set           Permission // SET VAR is allowed
+	FSD           Permission // FSD is allowed
+}
+
+// probeName is a command and variable name no driver provides, used to exercise
+// upsd's authentication checks without changing any state.
+const probeName = "go.nut.probe"
+
+// Probe determines which state-changing operations the current session may
+// perform on the UPS, so callers can hide actions that would fail. It never
+// sends a command that changes UPS state: INSTCMD and SET are probed with a name
+// no driver supports. upsd checks the SET action before looking up the
+// variable, so Set is definite. It checks INSTCMD against the user's list of
+// commands first, so an authenticated session that is not allowed all commands
+// reports PermissionUnknown for InstCmd: it may still run the ones it is
+// granted.
+func (u *UPS) Probe() (Permissions, error) {
+	perms := Permissions{
+		Authenticated: u.nutClient.username != "",
+	}
+
+	primary, err := u.probePrimary()
+	if err != nil {
+		return perms, err
+	}
+	perms.Primary = primary
+
+	perms.InstCmd, err = u.probeAction(fmt.Sprintf("INSTCMD %s %s", quoteName(u.Name), probeName), ErrCodeCmdNotSupported)
+	if err != nil {
+		return perms, err
+	}
+	if perms.InstCmd == PermissionDenied && perms.Authenticated {
+		// Denied for the probe name only, per-command ACLs may allow others
+		perms.InstCmd = PermissionUnknown
+	}
+	perms.Set, err = u.probeAction(fmt.Sprintf(`SET VAR %s %s "0"`, quoteName(u.Name), probeName), ErrCodeVarNotSupported)
+	if err != nil {
+		return perms, err
+	}
+
+	// FSD is granted to primary sessions ("upsmon primary" in upsd.users)
+	switch {
+	case perms.Primary:
+		perms.FSD = PermissionGranted
+	case !perms.Authenticated:
+		perms.FSD = PermissionDenied
+	default:
+		perms.FSD = PermissionUnknown
+	}
+
+	return perms, nil
+}
+
+// probePrimary checks for primary rights using PRIMARY, falling back to the
+// MASTER command understood by servers older than NUT 2.8.
+func (u *UPS) probePrimary() (bool, error) {
+	_, err := u.nutClient.SendCommand(fmt.Sprintf("PRIMARY %s", quoteName(u.Name)))
+	if hasErrorCode(err, ErrCodeUnknownCommand) || hasErrorCode(err, ErrCodeInvalidArgument) {
+		_, err = u.nutClient.SendCommand(fmt.Sprintf("MASTER %s", quoteName(u.Name)))
+	}
+	switch {
+	case err == nil:
+		u.setCached(func() { u.Master = true })
+		return true, nil
+	case hasErrorCode(err, ErrCodeAccessDenied), hasErrorCode(err, ErrCodeUsernameRequired), hasErrorCode(err, ErrCodePasswordRequired):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// probeAction sends a harmless variant of a state-changing command and maps the
+// resulting error code to a Permission. notSupported is the error upsd returns
+// once the action passed its access checks and the probe name was looked up.
+func (u *UPS) probeAction(cmd string, notSupported ErrorCode) (Permission, error) {
+	_, err := u.nutClient.SendCommand(cmd)
+	switch {
+	case err == nil:
+		// Should not happen for an unsupported name; treat as allowed
+		return PermissionGranted, nil
+	case hasErrorCode(err, notSupported):
+		return PermissionGranted, nil
+	case hasErrorCode(err, ErrCodeUsernameRequired), hasErrorCode(err, ErrCodePasswordRequired), hasErrorCode(err, ErrCodeAccessDenied):
+		return PermissionDenied, nil
+	default:
+		return PermissionUnknown, err
+	}
+}
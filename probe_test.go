@@ -0,0 +1,45 @@
+package nut_test
+
+import (
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		instcmd     string
+		set         string
+		wantInstCmd nut.Permission
+		wantSet     nut.Permission
+		wantErr     bool
+	}{
+		{"all allowed", "ERR CMD-NOT-SUPPORTED", "ERR VAR-NOT-SUPPORTED", nut.PermissionGranted, nut.PermissionGranted, false},
+		{"per-command ACL", "ERR ACCESS-DENIED", "ERR ACCESS-DENIED", nut.PermissionUnknown, nut.PermissionDenied, false},
+		{"unexpected error", "ERR CMD-NOT-SUPPORTED", "ERR DRIVER-NOT-CONNECTED", nut.PermissionGranted, nut.PermissionUnknown, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newScriptedClient(t, map[string][]string{
+				"USERNAME admin":                {"OK"},
+				"PASSWORD secret":               {"OK"},
+				"PRIMARY ups1":                  {"ERR ACCESS-DENIED"},
+				"MASTER ups1":                   {"ERR ACCESS-DENIED"},
+				"INSTCMD ups1 go.nut.probe":     {tt.instcmd},
+				`SET VAR ups1 go.nut.probe "0"`: {tt.set},
+			})
+			if _, err := client.Authenticate("admin", "secret"); err != nil {
+				t.Fatal(err)
+			}
+			ups, _ := nut.NewUPS("ups1", client)
+			perms, err := ups.Probe()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if !perms.Authenticated || perms.Primary || perms.InstCmd != tt.wantInstCmd || perms.Set != tt.wantSet {
+				t.Fatalf("perms = %+v", perms)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package nut
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WithHTTPProxy connects to upsd through the HTTP proxy at proxyURL using the
+// CONNECT method. User information in the URL is sent as basic proxy
+// authentication. "https" proxies are reached over TLS.
+func WithHTTPProxy(proxyURL *url.URL) ClientOption {
+	return func(c *Client) {
+		c.proxy = func(string, int) (*url.URL, error) {
+			return proxyURL, nil
+		}
+	}
+}
+
+// WithProxyFromEnvironment connects through the proxy named by the HTTPS_PROXY
+// (or https_proxy) environment variable, unless the server matches NO_PROXY.
+func WithProxyFromEnvironment() ClientOption {
+	return func(c *Client) {
+		c.proxy = func(host string, port int) (*url.URL, error) {
+			target := &url.URL{Scheme: "https", Host: net.JoinHostPort(host, strconv.Itoa(port))}
+			return http.ProxyFromEnvironment(&http.Request{URL: target})
+		}
+	}
+}
+
+// dialProxy opens a tunnel to hostname:port through the HTTP proxy at proxyURL.
+func (c *Client) dialProxy(ctx context.Context, proxyURL *url.URL, hostname string, port int) (net.Conn, error) {
+	proxyPort := proxyURL.Port()
+	if proxyPort == "" {
+		proxyPort = "80"
+		if proxyURL.Scheme == "https" {
+			proxyPort = "443"
+		}
+	}
+	portNum, err := strconv.Atoi(proxyPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy port %q", proxyPort)
+	}
+
+	conn, err := c.dialDirect(ctx, proxyURL.Hostname(), portNum)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to proxy %s: %w", proxyURL.Host, err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy %s: %w", proxyURL.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	target := net.JoinHostPort(hostname, strconv.Itoa(port))
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: target},
+		Host:   target,
+		Header: http.Header{},
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if c.ConnectTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.ConnectTimeout))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("sending CONNECT to proxy %s: %w", proxyURL.Host, err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading CONNECT response from proxy %s: %w", proxyURL.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", proxyURL.Host, target, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were already read into reader.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
@@ -0,0 +1,47 @@
+package nut_test
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+func TestFaultProxyTruncateNext(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+	proxy, err := nuttest.NewFaultProxy(server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	// get sends a command on a fresh connection and returns the reply, or
+	// what arrived of it before the connection closed
+	get := func() (string, bool) {
+		conn, err := net.Dial("tcp", proxy.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET VAR ups1 ups.status\n"))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		return line, err == nil
+	}
+
+	proxy.TruncateNext(2)
+	for i := 0; i < 2; i++ {
+		if line, complete := get(); complete || !strings.HasPrefix(`VAR ups1 ups.status "OL"`, line) {
+			t.Fatalf("connection %d: got %q, complete %v; want a truncated line", i, line, complete)
+		}
+	}
+	if line, complete := get(); !complete || line != "VAR ups1 ups.status \"OL\"\n" {
+		t.Fatalf("got %q, complete %v after the truncated lines", line, complete)
+	}
+}
@@ -0,0 +1,181 @@
+package nut
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// commandPriority orders waiting commands when the connection is busy.
+type commandPriority int
+
+const (
+	// priorityControl is used for state-changing commands (INSTCMD, SET, FSD, ...)
+	priorityControl commandPriority = iota
+	// priorityStatus is used for single-line queries such as GET
+	priorityStatus
+	// priorityBulk is used for multi-line LIST commands
+	priorityBulk
+
+	numPriorities
+)
+
+type priorityKey struct{}
+
+// withPriority overrides the priority derived from the command verb for
+// commands sent with the returned context.
+func withPriority(ctx context.Context, p commandPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// priorityFor returns the priority for cmd, honouring an override set on ctx.
+func priorityFor(ctx context.Context, cmd string) commandPriority {
+	if p, ok := ctx.Value(priorityKey{}).(commandPriority); ok {
+		return p
+	}
+
+	verb := strings.ToUpper(strings.Fields(cmd + " ")[0])
+	switch verb {
+	case "INSTCMD", "SET", "FSD", "LOGOUT", "STARTTLS", "USERNAME", "PASSWORD", "LOGIN", "MASTER", "PRIMARY":
+		return priorityControl
+	case "LIST":
+		return priorityBulk
+	default:
+		return priorityStatus
+	}
+}
+
+// upsFor returns the UPS name a command operates on, or an empty string for
+// commands that are not bound to a UPS.
+func upsFor(cmd string) string {
+	fields := strings.Fields(cmd)
+	if len(fields) < 2 {
+		return ""
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "INSTCMD", "FSD", "LOGIN", "MASTER", "PRIMARY":
+		return fields[1]
+	case "LIST", "GET", "SET":
+		if len(fields) > 2 {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// fairQueue holds the waiters of one priority level, grouped by UPS. Waiters
+// for the same UPS are served in arrival order, and UPSes take turns so that a
+// burst of commands for one UPS cannot starve the others.
+type fairQueue struct {
+	order []string                   // UPSes with waiters, in turn order
+	byUPS map[string][]chan struct{} // Waiters per UPS in arrival order
+}
+
+func (f *fairQueue) push(ups string, ready chan struct{}) {
+	if f.byUPS == nil {
+		f.byUPS = map[string][]chan struct{}{}
+	}
+	if len(f.byUPS[ups]) == 0 {
+		f.order = append(f.order, ups)
+	}
+	f.byUPS[ups] = append(f.byUPS[ups], ready)
+}
+
+// pop removes the next waiter, rotating the UPS it belongs to to the back.
+func (f *fairQueue) pop() (chan struct{}, bool) {
+	if len(f.order) == 0 {
+		return nil, false
+	}
+	ups := f.order[0]
+	f.order = f.order[1:]
+	waiters := f.byUPS[ups]
+	next := waiters[0]
+	if len(waiters) > 1 {
+		f.byUPS[ups] = waiters[1:]
+		f.order = append(f.order, ups)
+	} else {
+		delete(f.byUPS, ups)
+	}
+	return next, true
+}
+
+// remove drops ready from the queue and reports whether it was found.
+func (f *fairQueue) remove(ups string, ready chan struct{}) bool {
+	waiters := f.byUPS[ups]
+	for i, w := range waiters {
+		if w != ready {
+			continue
+		}
+		waiters = append(waiters[:i], waiters[i+1:]...)
+		if len(waiters) > 0 {
+			f.byUPS[ups] = waiters
+			return true
+		}
+		delete(f.byUPS, ups)
+		for j, name := range f.order {
+			if name == ups {
+				f.order = append(f.order[:j], f.order[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// commandQueue serializes access to the connection. When several goroutines
+// wait, the connection is handed to the highest priority waiter first. Within a
+// priority, UPSes are served round-robin and commands for the same UPS in
+// arrival order.
+type commandQueue struct {
+	mu      sync.Mutex
+	busy    bool
+	waiters [numPriorities]fairQueue
+}
+
+// acquire blocks until the caller owns the connection or ctx is done. ups
+// identifies the UPS the command is for, see upsFor.
+func (q *commandQueue) acquire(ctx context.Context, p commandPriority, ups string) error {
+	q.mu.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	q.waiters[p].push(ups, ready)
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		removed := q.waiters[p].remove(ups, ready)
+		q.mu.Unlock()
+		if !removed {
+			// Ownership was handed over concurrently with the cancellation
+			q.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// lock acquires the connection with control priority, ignoring cancellation.
+func (q *commandQueue) lock() {
+	_ = q.acquire(context.Background(), priorityControl, "")
+}
+
+// release hands the connection to the next waiter, if any.
+func (q *commandQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for p := range q.waiters {
+		if next, ok := q.waiters[p].pop(); ok {
+			close(next)
+			return
+		}
+	}
+	q.busy = false
+}
@@ -0,0 +1,191 @@
+package nut
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateDirection selects which changes a RateRule detects.
+type RateDirection int
+
+const (
+	RateFalling RateDirection = iota // Value dropped by Delta
+	RateRising                       // Value rose by Delta
+	RateEither                       // Value dropped or rose by Delta
+)
+
+var rateVerbs = []string{"drops", "rises", "changes"}
+
+// RateRule raises an alert while a numeric variable has changed by at least
+// Delta within the last Window, e.g. input voltage sagging before the UPS
+// switches to battery, or battery charge falling faster than expected before
+// the UPS reports LB. The alert clears once the change no longer falls within
+// the window. Window should span several samples, so it must be well above
+// the polling interval.
+type RateRule struct {
+	Name      string // Alert name; defaults to the rule in ParseRateRule notation
+	Variable  string
+	Direction RateDirection
+	Delta     float64 // Minimum change, always positive
+	Window    time.Duration
+}
+
+// VoltageSagRule detects input.voltage dropping by drop volts within window.
+func VoltageSagRule(drop float64, window time.Duration) RateRule {
+	return RateRule{Name: "input.voltage.sag", Variable: "input.voltage", Direction: RateFalling, Delta: drop, Window: window}
+}
+
+// VoltageSwellRule detects input.voltage rising by rise volts within window.
+func VoltageSwellRule(rise float64, window time.Duration) RateRule {
+	return RateRule{Name: "input.voltage.swell", Variable: "input.voltage", Direction: RateRising, Delta: rise, Window: window}
+}
+
+// FastDischargeRule detects battery.charge dropping by drop percent within
+// window.
+func FastDischargeRule(drop float64, window time.Duration) RateRule {
+	return RateRule{Name: "battery.discharge", Variable: "battery.charge", Direction: RateFalling, Delta: drop, Window: window}
+}
+
+// ParseRateRule parses a rule such as "input.voltage drops 20 within 10s",
+// "input.voltage rises 15 within 10s" or "battery.charge changes 5 within 1m".
+func ParseRateRule(rule string) (RateRule, error) {
+	fields := strings.Fields(rule)
+	if len(fields) != 5 || fields[3] != "within" {
+		return RateRule{}, fmt.Errorf("invalid rate rule %q: expected \"<variable> drops|rises|changes <delta> within <duration>\"", rule)
+	}
+	parsed := RateRule{Variable: fields[0], Direction: -1}
+	for i, verb := range rateVerbs {
+		if fields[1] == verb {
+			parsed.Direction = RateDirection(i)
+		}
+	}
+	if parsed.Direction < 0 {
+		return RateRule{}, fmt.Errorf("invalid rate rule %q: unknown change %q", rule, fields[1])
+	}
+	var err error
+	if parsed.Delta, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return RateRule{}, fmt.Errorf("invalid rate rule %q: %w", rule, err)
+	}
+	if parsed.Window, err = time.ParseDuration(fields[4]); err != nil {
+		return RateRule{}, fmt.Errorf("invalid rate rule %q: %w", rule, err)
+	}
+	return parsed, nil
+}
+
+// String returns the rule in ParseRateRule notation.
+func (r RateRule) String() string {
+	verb := "changes"
+	if r.Direction >= 0 && int(r.Direction) < len(rateVerbs) {
+		verb = rateVerbs[r.Direction]
+	}
+	return fmt.Sprintf("%s %s %s within %s", r.Variable, verb, strconv.FormatFloat(r.Delta, 'g', -1, 64), r.Window)
+}
+
+func (r RateRule) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.String()
+}
+
+func (r RateRule) validate() error {
+	if r.Variable == "" {
+		return fmt.Errorf("rate rule %q has no variable", r.name())
+	}
+	if r.Direction < RateFalling || r.Direction > RateEither {
+		return fmt.Errorf("rate rule %q has an invalid direction", r.name())
+	}
+	if r.Delta <= 0 {
+		return fmt.Errorf("rate rule %q must have a positive delta", r.name())
+	}
+	if r.Window <= 0 {
+		return fmt.Errorf("rate rule %q must have a positive window", r.name())
+	}
+	return nil
+}
+
+// rateSample is a value of the watched variable.
+type rateSample struct {
+	time  time.Time
+	value float64
+}
+
+// rateDetector evaluates a RateRule, keeping the samples within the window
+// per UPS. It is only used under the AlertRules lock.
+type rateDetector struct {
+	RateRule
+	samples map[alertKey][]rateSample
+}
+
+// evaluate records the sample and reports whether the variable changed by
+// Delta within the window ending at the sample.
+func (d *rateDetector) evaluate(sample Snapshot, active bool) (raised, ok bool) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(sample.Variables[d.Variable]), 64)
+	if err != nil {
+		return false, false
+	}
+	now := sample.Time
+
+	key := alertKey{server: sample.Server, ups: sample.UPS}
+	history := d.samples[key]
+	if n := len(history); n > 0 && !now.After(history[n-1].time) {
+		history = history[:n-1] // Same sample evaluated again
+	}
+	cutoff := now.Add(-d.Window)
+	start := 0
+	for start < len(history) && history[start].time.Before(cutoff) {
+		start++
+	}
+	history = append(history[start:], rateSample{time: now, value: value})
+	d.samples[key] = history
+
+	low, high := rateBounds(history)
+	fell, rose := high-value >= d.Delta, value-low >= d.Delta
+	switch d.Direction {
+	case RateFalling:
+		return fell, true
+	case RateRising:
+		return rose, true
+	default:
+		return fell || rose, true
+	}
+}
+
+// event reports the current value and, as OldValue, the value it changed
+// from: the highest value in the window for a drop and the lowest for a rise.
+func (d *rateDetector) event(sample Snapshot) Event {
+	event := Event{Variable: d.Variable, NewValue: sample.Variables[d.Variable]}
+	history := d.samples[alertKey{server: sample.Server, ups: sample.UPS}]
+	if len(history) == 0 {
+		return event
+	}
+	current := history[len(history)-1].value
+	low, high := rateBounds(history)
+	reference := low
+	if d.Direction == RateFalling || (d.Direction == RateEither && high-current >= current-low) {
+		reference = high
+	}
+	event.OldValue = strconv.FormatFloat(reference, 'f', -1, 64)
+	return event
+}
+
+// AddRate registers a rate-of-change rule. AlertRules.Evaluate must then be
+// called with every sample, e.g. after each poll, as the rule compares each
+// sample with those before it.
+func (a *AlertRules) AddRate(rule RateRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	return a.add(&rateDetector{RateRule: rule, samples: map[alertKey][]rateSample{}})
+}
+
+// rateBounds returns the lowest and highest value of the samples.
+func rateBounds(history []rateSample) (low, high float64) {
+	low, high = history[0].value, history[0].value
+	for _, s := range history[1:] {
+		low, high = min(low, s.value), max(high, s.value)
+	}
+	return low, high
+}
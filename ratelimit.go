@@ -0,0 +1,92 @@
+package nut
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket limiting the rate of commands sent to upsd.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(opsPerSec float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   opsPerSec,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long the
+// caller has to wait for the next token.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// wait blocks until a token is available or ctx is done. With failFast set it
+// returns ErrRateLimited instead of blocking.
+func (l *rateLimiter) wait(ctx context.Context, failFast bool) error {
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		if failFast {
+			return ErrRateLimited
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WithRateLimit limits the client to opsPerSec commands per second, allowing
+// bursts of up to burst commands. Commands exceeding the limit block until
+// allowed or their context is cancelled.
+func WithRateLimit(opsPerSec float64, burst int) ClientOption {
+	return func(c *Client) {
+		if opsPerSec <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newRateLimiter(opsPerSec, burst)
+	}
+}
+
+// WithRateLimitFailFast makes commands exceeding the rate limit return
+// ErrRateLimited immediately instead of blocking. It has no effect unless
+// WithRateLimit is also given.
+func WithRateLimitFailFast() ClientOption {
+	return func(c *Client) {
+		c.rateLimitFailFast = true
+	}
+}
@@ -0,0 +1,195 @@
+package nut
+
+import (
+	"context"
+	"time"
+)
+
+// ReadOnlyClient exposes only the queries of a Client: it cannot send raw
+// commands, authenticate, change variables, run instant commands or close the
+// connection. It is meant to be handed to plugins, templates and other code
+// that should observe UPSes but not control them.
+type ReadOnlyClient struct {
+	client *Client
+}
+
+// ReadOnly returns a read-only view of the client.
+func (c *Client) ReadOnly() ReadOnlyClient {
+	return ReadOnlyClient{client: c}
+}
+
+// UPS returns a read-only handle for the named UPS. The UPS is not checked to
+// exist on the server.
+func (c ReadOnlyClient) UPS(name string) ReadOnlyUPS {
+	return ReadOnlyUPS{ups: &UPS{Name: name, nutClient: c.client}}
+}
+
+// GetUPSList returns the UPSes provided by the server; see Client.GetUPSList.
+func (c ReadOnlyClient) GetUPSList() ([]ReadOnlyUPS, error) {
+	list, err := c.client.GetUPSList()
+	if err != nil {
+		return nil, err
+	}
+	upsList := make([]ReadOnlyUPS, len(list))
+	for i := range list {
+		upsList[i] = ReadOnlyUPS{ups: &list[i]}
+	}
+	return upsList, nil
+}
+
+// Help returns the commands supported by the server; see Client.Help.
+func (c ReadOnlyClient) Help() (string, error) {
+	return c.client.Help()
+}
+
+// GetVersion returns the server version; see Client.GetVersion.
+func (c ReadOnlyClient) GetVersion() (string, error) {
+	return c.client.GetVersion()
+}
+
+// GetNetworkProtocolVersion returns the protocol version; see
+// Client.GetNetworkProtocolVersion.
+func (c ReadOnlyClient) GetNetworkProtocolVersion() (string, error) {
+	return c.client.GetNetworkProtocolVersion()
+}
+
+// Capabilities returns the features of the server; see Client.Capabilities.
+func (c ReadOnlyClient) Capabilities() (Capabilities, error) {
+	return c.client.Capabilities()
+}
+
+// State returns the connection state; see Client.State.
+func (c ReadOnlyClient) State() ConnState {
+	return c.client.State()
+}
+
+// IsConnected reports whether the client can send commands; see
+// Client.IsConnected.
+func (c ReadOnlyClient) IsConnected() bool {
+	return c.client.IsConnected()
+}
+
+// Health returns the health of the connection; see Client.Health.
+func (c ReadOnlyClient) Health() ClientHealth {
+	return c.client.Health()
+}
+
+// GetMetrics returns the client's statistics; see Client.GetMetrics.
+func (c ReadOnlyClient) GetMetrics() ClientMetrics {
+	return c.client.GetMetrics()
+}
+
+// ReadOnlyUPS exposes only the queries of a UPS: no SetVariable, SendCommand,
+// ForceShutdown, Login or other state-changing operations.
+type ReadOnlyUPS struct {
+	ups *UPS
+}
+
+// ReadOnly returns a read-only view of the UPS.
+func (u *UPS) ReadOnly() ReadOnlyUPS {
+	return ReadOnlyUPS{ups: u}
+}
+
+// Name returns the UPS name.
+func (u ReadOnlyUPS) Name() string {
+	return u.ups.Name
+}
+
+// Description returns the description loaded with the UPS, if any; see
+// GetDescription to query the server.
+func (u ReadOnlyUPS) Description() string {
+	return u.ups.Cached().Description
+}
+
+// GetDescription returns the UPS description; see UPS.GetDescription.
+func (u ReadOnlyUPS) GetDescription() (string, error) {
+	return u.ups.GetDescription()
+}
+
+// GetNumberOfLogins returns the number of clients logged in to the UPS; see
+// UPS.GetNumberOfLogins.
+func (u ReadOnlyUPS) GetNumberOfLogins() (int, error) {
+	return u.ups.GetNumberOfLogins()
+}
+
+// GetClients returns the clients logged in to the UPS; see UPS.GetClients.
+func (u ReadOnlyUPS) GetClients() ([]string, error) {
+	return u.ups.GetClients()
+}
+
+// GetVariables returns the UPS variables; see UPS.GetVariables.
+func (u ReadOnlyUPS) GetVariables() ([]Variable, error) {
+	return u.ups.GetVariables()
+}
+
+// GetVariableValue returns the value of a variable; see UPS.GetVariableValue.
+func (u ReadOnlyUPS) GetVariableValue(variableName string) (string, error) {
+	return u.ups.GetVariableValue(variableName)
+}
+
+// GetVariableRanges returns the ranges accepted by a variable; see
+// UPS.GetVariableRanges.
+func (u ReadOnlyUPS) GetVariableRanges(variableName string) ([]Range, error) {
+	return u.ups.GetVariableRanges(variableName)
+}
+
+// GetVariableDescription returns the description of a variable; see
+// UPS.GetVariableDescription.
+func (u ReadOnlyUPS) GetVariableDescription(variableName string) (string, error) {
+	return u.ups.GetVariableDescription(variableName)
+}
+
+// GetVariableServerType returns the type of a variable; see
+// UPS.GetVariableServerType.
+func (u ReadOnlyUPS) GetVariableServerType(variableName string) (ServerType, error) {
+	return u.ups.GetVariableServerType(variableName)
+}
+
+// GetVariableEnum returns the values accepted by an enumerated variable; see
+// UPS.GetVariableEnum.
+func (u ReadOnlyUPS) GetVariableEnum(variableName string) ([]string, error) {
+	return u.ups.GetVariableEnum(variableName)
+}
+
+// GetCommands returns the instant commands of the UPS, without running them;
+// see UPS.GetCommands.
+func (u ReadOnlyUPS) GetCommands() ([]Command, error) {
+	return u.ups.GetCommands()
+}
+
+// GetCommandDescription returns the description of an instant command; see
+// UPS.GetCommandDescription.
+func (u ReadOnlyUPS) GetCommandDescription(commandName string) (string, error) {
+	return u.ups.GetCommandDescription(commandName)
+}
+
+// GetStatus returns the parsed ups.status; see UPS.GetStatus.
+func (u ReadOnlyUPS) GetStatus() (Status, error) {
+	return u.ups.GetStatus()
+}
+
+// PollStatus reads ups.status on the fast path; see UPS.PollStatus.
+func (u ReadOnlyUPS) PollStatus(ctx context.Context) (Status, error) {
+	return u.ups.PollStatus(ctx)
+}
+
+// SubscribeStatus sends status changes; see UPS.SubscribeStatus.
+func (u ReadOnlyUPS) SubscribeStatus(ctx context.Context, interval time.Duration, opts ...StatusOption) (<-chan Status, error) {
+	return u.ups.SubscribeStatus(ctx, interval, opts...)
+}
+
+// WatchVariable sends changes of a variable; see UPS.WatchVariable.
+func (u ReadOnlyUPS) WatchVariable(ctx context.Context, variableName string, interval time.Duration, opts ...WatcherOption) (<-chan VariableUpdate, error) {
+	return u.ups.WatchVariable(ctx, variableName, interval, opts...)
+}
+
+// GetDelays reads the shutdown and start delays; see UPS.GetDelays.
+func (u ReadOnlyUPS) GetDelays(ctx context.Context) (Delays, error) {
+	return u.ups.GetDelays(ctx)
+}
+
+// IsCalibrating reports whether a runtime calibration is in progress; see
+// UPS.IsCalibrating.
+func (u ReadOnlyUPS) IsCalibrating(ctx context.Context) (bool, error) {
+	return u.ups.IsCalibrating(ctx)
+}
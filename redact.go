@@ -0,0 +1,44 @@
+package nut
+
+import "strings"
+
+// redactedValue replaces secrets in logged commands.
+const redactedValue = "********"
+
+// WithRedactUsernames additionally redacts the argument of USERNAME commands in
+// log output. PASSWORD arguments are always redacted unless WithoutRedaction is set.
+func WithRedactUsernames() ClientOption {
+	return func(c *Client) {
+		c.redactUsernames = true
+	}
+}
+
+// WithoutRedaction disables redaction of credentials in log output. Only use this
+// when debugging against test servers.
+func WithoutRedaction() ClientOption {
+	return func(c *Client) {
+		c.noRedaction = true
+	}
+}
+
+// redact returns cmd with credentials replaced, suitable for logging.
+func (c *Client) redact(cmd string) string {
+	if c.noRedaction {
+		return cmd
+	}
+
+	trimmed := strings.TrimSpace(cmd)
+	verb, _, hasArg := strings.Cut(trimmed, " ")
+	if !hasArg {
+		return cmd
+	}
+	switch strings.ToUpper(verb) {
+	case "PASSWORD":
+		return verb + " " + redactedValue
+	case "USERNAME":
+		if c.redactUsernames {
+			return verb + " " + redactedValue
+		}
+	}
+	return cmd
+}
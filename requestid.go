@@ -0,0 +1,87 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context that makes commands sent with it use id as
+// their request ID instead of the client's sequence number.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set by WithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// requestID returns the ID for a command sent with ctx: the caller-provided
+// one, or the next number of the client's sequence.
+func (c *Client) requestID(ctx context.Context) string {
+	return c.reserveRequestID(ctx).String()
+}
+
+// pendingRequestID is a request ID that is only formatted when it is needed,
+// so that successful commands on allocation-free paths do not allocate for it.
+type pendingRequestID struct {
+	id  string
+	seq uint64
+}
+
+// reserveRequestID is requestID without formatting the sequence number.
+func (c *Client) reserveRequestID(ctx context.Context) pendingRequestID {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return pendingRequestID{id: id}
+	}
+	return pendingRequestID{seq: atomic.AddUint64(&c.requestSeq, 1)}
+}
+
+func (r pendingRequestID) String() string {
+	if r.id != "" {
+		return r.id
+	}
+	return strconv.FormatUint(r.seq, 10)
+}
+
+// CommandError is returned by SendCommandWithContext when a command fails
+// after being sent. It identifies the command and its request ID, which also
+// appears in log lines and command traces; the cause is available through
+// errors.Is and errors.As.
+type CommandError struct {
+	RequestID string
+	Command   string // The command, with credentials redacted
+	Err       error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("request %s (%s): %v", e.RequestID, e.Command, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// CommandTrace describes a command sent by the client, for WithCommandTrace.
+type CommandTrace struct {
+	RequestID string
+	Command   string // The command, with credentials redacted
+	Start     time.Time
+	Duration  time.Duration
+	Err       error
+}
+
+// WithCommandTrace registers fn to be called after every command sent with
+// SendCommandWithContext, whether it succeeded or not. fn is called while the
+// connection is held, so it must not block or call methods of the client.
+func WithCommandTrace(fn func(CommandTrace)) ClientOption {
+	return func(c *Client) {
+		c.commandTrace = fn
+	}
+}
@@ -0,0 +1,27 @@
+package nut_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+func TestScenarioDrainTooShortToStep(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	scenario := nuttest.NewScenario(server, "ups1")
+	scenario.PowerFail()
+	for _, d := range []time.Duration{0, 10 * time.Nanosecond} {
+		if err := scenario.DrainBattery(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+		if charge := server.Var("ups1", "battery.charge"); charge != "0" {
+			t.Fatalf("DrainBattery(%v) left the charge at %s", d, charge)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultSelfTestTimeout bounds a single scheduled battery test.
+const defaultSelfTestTimeout = 10 * time.Minute
+
+// SelfTestConfig configures a SelfTestScheduler.
+type SelfTestConfig struct {
+	Schedule  Schedule        // When to test, e.g. ParseCron("0 3 * * 0")
+	Type      BatteryTestType // Kind of test to run
+	Stagger   time.Duration   // Delay between the starts of successive UPSes' tests
+	Timeout   time.Duration   // Maximum duration of one test (default 10m)
+	History   HistoryStore    // Optional store recording every result as an event
+	Notifiers []Notifier      // Notified of failed tests
+	Clock     Clock           // Clock timing the schedule and results (default the system clock)
+}
+
+// SelfTestResult is the outcome of a scheduled battery test of one UPS.
+type SelfTestResult struct {
+	Server string
+	UPS    string
+	Result BatteryTestResult
+	Err    error // Error starting or following the test
+}
+
+// Passed reports whether the test completed and passed.
+func (r SelfTestResult) Passed() bool {
+	return r.Err == nil && r.Result.Passed
+}
+
+// event returns the history and notification event for the result, at the
+// test's start or else at now.
+func (r SelfTestResult) event(now time.Time) Event {
+	event := Event{
+		Time:     r.Result.Started,
+		Server:   r.Server,
+		UPS:      r.UPS,
+		Type:     EventSelfTestFailed,
+		Variable: "ups.test.result",
+		NewValue: r.Result.Result,
+		Err:      r.Err,
+	}
+	if event.Time.IsZero() {
+		event.Time = now
+	}
+	if r.Passed() {
+		event.Type = EventSelfTestPassed
+	}
+	return event
+}
+
+// SelfTestScheduler runs battery self-tests on a set of UPSes on a recurring
+// schedule. Tests of different UPSes are staggered so that they do not all run
+// on battery at the same time.
+type SelfTestScheduler struct {
+	config SelfTestConfig
+
+	mu   sync.Mutex
+	ups  []*UPS
+	last []SelfTestResult
+}
+
+// NewSelfTestScheduler validates config and returns a scheduler testing ups.
+// Call Run to start it.
+func NewSelfTestScheduler(config SelfTestConfig, ups ...*UPS) (*SelfTestScheduler, error) {
+	if config.Schedule == nil {
+		return nil, fmt.Errorf("self-test schedule is required")
+	}
+	if config.Stagger < 0 {
+		return nil, fmt.Errorf("self-test stagger must not be negative")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSelfTestTimeout
+	}
+	config.Clock = clockOrSystem(config.Clock)
+	return &SelfTestScheduler{config: config, ups: ups}, nil
+}
+
+// Run tests the UPSes every time the schedule fires until ctx is done. Errors
+// recording or notifying results do not stop it; use RunOnce to observe them.
+func (s *SelfTestScheduler) Run(ctx context.Context) error {
+	for {
+		now := s.config.Clock.Now()
+		next := s.config.Schedule.Next(now)
+		if next.IsZero() {
+			return fmt.Errorf("self-test schedule has no further runs")
+		}
+		timer := s.config.Clock.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+		_, _ = s.RunOnce(ctx)
+	}
+}
+
+// RunOnce tests every UPS now, staggered, records and notifies the results,
+// and returns them in the order of the UPSes. The error reports failures to
+// record or notify results, not failed tests.
+func (s *SelfTestScheduler) RunOnce(ctx context.Context) ([]SelfTestResult, error) {
+	s.mu.Lock()
+	targets := append([]*UPS(nil), s.ups...)
+	s.mu.Unlock()
+
+	results := make([]SelfTestResult, len(targets))
+	var wg sync.WaitGroup
+	for i, ups := range targets {
+		if i > 0 && s.config.Stagger > 0 {
+			timer := s.config.Clock.NewTimer(s.config.Stagger)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C():
+			}
+		}
+		results[i] = SelfTestResult{Server: upsServer(ups), UPS: ups.Name}
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, ups *UPS) {
+			defer wg.Done()
+			testCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+			defer cancel()
+			started := s.config.Clock.Now()
+			results[i].Result, results[i].Err = ups.RunBatteryTest(testCtx, s.config.Type)
+			if !results[i].Result.Started.IsZero() {
+				results[i].Result.Started = started
+			}
+		}(i, ups)
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	s.last = results
+	s.mu.Unlock()
+	return results, s.report(ctx, results)
+}
+
+// report records the results in the history store and notifies failures.
+func (s *SelfTestScheduler) report(ctx context.Context, results []SelfTestResult) error {
+	events := make([]Event, 0, len(results))
+	for _, result := range results {
+		events = append(events, result.event(s.config.Clock.Now()))
+	}
+
+	var errs []error
+	if s.config.History != nil {
+		if err := s.config.History.AppendEvents(ctx, events); err != nil {
+			errs = append(errs, fmt.Errorf("recording self-test results: %w", err))
+		}
+	}
+	for _, event := range events {
+		if event.Type != EventSelfTestFailed || errors.Is(event.Err, context.Canceled) {
+			continue
+		}
+		for _, notifier := range s.config.Notifiers {
+			if err := notifier.Notify(ctx, event); err != nil {
+				errs = append(errs, fmt.Errorf("notifying %s for %s: %w", event.Type, event.UPS, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetUPS replaces the UPSes tested from the next run on.
+func (s *SelfTestScheduler) SetUPS(ups ...*UPS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ups = ups
+}
+
+// LastResults returns the results of the most recent run, or nil.
+func (s *SelfTestScheduler) LastResults() []SelfTestResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SelfTestResult(nil), s.last...)
+}
+
+// upsServer returns the endpoint (host:port) of the UPS's client, or an empty
+// string for clients created from a connection.
+func upsServer(ups *UPS) string {
+	if ups.nutClient == nil || ups.nutClient.host == "" {
+		return ""
+	}
+	return net.JoinHostPort(ups.nutClient.host, strconv.Itoa(ups.nutClient.port))
+}
@@ -0,0 +1,60 @@
+package nut
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Session describes the state of the client's session with upsd.
+type Session struct {
+	Username        string        // Username accepted by Authenticate, empty if not authenticated
+	Authenticated   bool          // USERNAME and PASSWORD were accepted
+	LoggedIn        bool          // LOGIN was issued for a UPS
+	LoginUPS        string        // UPS named in the LOGIN command
+	TLS             bool          // Connection was upgraded with STARTTLS
+	ServerVersion   string        // Banner returned by VER
+	ProtocolVersion string        // Network protocol version returned by NETVER
+	RemoteAddr      net.Addr      // Address of the NUT server
+	ConnectedAt     time.Time     // Time the connection was established
+	Uptime          time.Duration // Time since the connection was established
+}
+
+// Session returns the current state of the client's session.
+func (c *Client) Session() Session {
+	c.sessionMu.Lock()
+	s := Session{
+		Username:      c.username,
+		Authenticated: c.username != "",
+		LoggedIn:      c.loginUPS != "",
+		LoginUPS:      c.loginUPS,
+		ConnectedAt:   c.connectedAt,
+	}
+	c.sessionMu.Unlock()
+
+	s.TLS = c.UseTLS
+	s.ServerVersion = c.Version
+	s.ProtocolVersion = c.ProtocolVersion
+	s.RemoteAddr = c.Hostname
+	if !s.ConnectedAt.IsZero() {
+		s.Uptime = time.Since(s.ConnectedAt)
+	}
+	return s
+}
+
+// Login registers the session as a client of the UPS with LOGIN, which makes it
+// count towards NUMLOGINS. upsd allows one LOGIN per connection and requires
+// prior authentication.
+func (u *UPS) Login() (bool, error) {
+	resp, err := u.nutClient.SendCommand(fmt.Sprintf("LOGIN %s", quoteName(u.Name)))
+	if err != nil {
+		return false, err
+	}
+	if len(resp) > 0 && resp[0] == "OK" {
+		u.nutClient.sessionMu.Lock()
+		u.nutClient.loginUPS = u.Name
+		u.nutClient.sessionMu.Unlock()
+		return true, nil
+	}
+	return false, nil
+}
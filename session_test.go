@@ -0,0 +1,52 @@
+package nut_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+// TestSessionDuringAuthenticate reads the session while another goroutine
+// authenticates and logs in; run with -race.
+func TestSessionDuringAuthenticate(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, nil, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if _, err := client.Authenticate("upsmon", "secret"); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := ups.Login(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		client.Session()
+	}
+	wg.Wait()
+
+	if session := client.Session(); session.Username != "upsmon" || session.LoginUPS != "ups1" {
+		t.Fatalf("session %+v", session)
+	}
+}
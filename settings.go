@@ -0,0 +1,164 @@
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// rollbackTimeout bounds restoring previous values in ApplySettings, which runs
+// even if the caller's ctx is done.
+const rollbackTimeout = 30 * time.Second
+
+// SettingResult is the outcome of applying one variable in ApplySettings.
+type SettingResult struct {
+	Variable   string
+	Value      string // Requested value
+	Previous   string // Value read before applying
+	Applied    bool   // SET was accepted by upsd
+	RolledBack bool   // Previous value was restored after a later failure
+	Err        error  // Validation, SET or rollback error
+}
+
+// ApplySettings sets several variables as a unit. All values are validated
+// against the variables' writability, type, enumeration and ranges before any
+// is set. Variables are then applied in name order; if one fails, those already
+// applied are restored to their previous values in reverse order, even if the
+// failure is ctx being canceled. So is the failing variable unless upsd
+// rejected it with an ERR, as a SET that failed e.g. on a timeout may still
+// have been applied. A result is returned per variable, together with an
+// error describing the first failure.
+func (u *UPS) ApplySettings(ctx context.Context, settings map[string]string) ([]SettingResult, error) {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]SettingResult, len(names))
+	var firstErr error
+	for i, name := range names {
+		results[i] = SettingResult{Variable: name, Value: settings[name]}
+		if err := u.validateSetting(ctx, name, settings[name]); err != nil {
+			results[i].Err = err
+			if firstErr == nil {
+				firstErr = fmt.Errorf("validating %s: %w", name, err)
+			}
+		}
+	}
+	if firstErr != nil {
+		return results, firstErr
+	}
+
+	for i := range results {
+		previous, err := u.getVariableValue(ctx, results[i].Variable)
+		if err != nil {
+			results[i].Err = err
+			return results, fmt.Errorf("reading %s: %w", results[i].Variable, err)
+		}
+		results[i].Previous = previous
+	}
+
+	for i := range results {
+		result := &results[i]
+		ok, err := u.setVariable(ctx, result.Variable, result.Value)
+		if errors.Is(err, ErrDryRun) {
+			continue
+		}
+		if err == nil && !ok {
+			err = fmt.Errorf("unexpected response")
+		}
+		if err != nil {
+			result.Err = err
+			applied := results[:i]
+			var perr *ProtocolError
+			if !errors.As(err, &perr) {
+				// The SET may have reached upsd before the failure, e.g. a timeout
+				applied = results[:i+1]
+			}
+			u.rollbackSettings(ctx, applied)
+			return results, fmt.Errorf("setting %s: %w", result.Variable, err)
+		}
+		result.Applied = true
+	}
+	return results, nil
+}
+
+// rollbackSettings restores the previous values of applied settings, and of a
+// failed one whose SET may have been applied, in reverse order. The failure
+// that triggers it may be ctx ending, so it runs detached from ctx's
+// cancellation, with its own timeout.
+func (u *UPS) rollbackSettings(ctx context.Context, results []SettingResult) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	for i := len(results) - 1; i >= 0; i-- {
+		result := &results[i]
+		if (!result.Applied && result.Err == nil) || result.Previous == result.Value {
+			continue
+		}
+		ok, err := u.setVariable(ctx, result.Variable, result.Previous)
+		if err == nil && !ok {
+			err = fmt.Errorf("unexpected response")
+		}
+		if err != nil {
+			result.Err = errors.Join(result.Err, fmt.Errorf("rolling back to %q: %w", result.Previous, err))
+			continue
+		}
+		result.RolledBack = true
+	}
+}
+
+// validateSetting checks value against the type information upsd reports for
+// variableName.
+func (u *UPS) validateSetting(ctx context.Context, variableName, value string) error {
+	serverType, err := u.getVariableServerType(ctx, variableName)
+	if err != nil {
+		return err
+	}
+	if !serverType.Writable {
+		return errorForMessage(ErrCodeReadOnly)
+	}
+	if serverType.String && serverType.MaxLength > 0 && len(value) > serverType.MaxLength {
+		return fmt.Errorf("value longer than %d characters: %w", serverType.MaxLength, errorForMessage(ErrCodeTooLong))
+	}
+
+	if serverType.Enum {
+		values, err := u.getVariableEnum(ctx, variableName)
+		if err != nil {
+			return err
+		}
+		for _, allowed := range values {
+			if allowed == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %q: %w", value, values, errorForMessage(ErrCodeInvalidValue))
+	}
+
+	if !serverType.Number && !serverType.Range {
+		return nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number: %w", value, errorForMessage(ErrCodeInvalidValue))
+	}
+	if !serverType.Range {
+		return nil
+	}
+	ranges, err := u.getVariableRanges(ctx, variableName)
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	for _, r := range ranges {
+		if r.Contains(number) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%v is outside the accepted ranges: %w", number, errorForMessage(ErrCodeInvalidValue))
+}
@@ -0,0 +1,77 @@
+package nut_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+var settingsResponses = map[string][][]string{
+	"GET TYPE ups1 ups.delay.shutdown":     {{"TYPE ups1 ups.delay.shutdown RW NUMBER"}},
+	"GET TYPE ups1 ups.delay.start":        {{"TYPE ups1 ups.delay.start RW NUMBER"}},
+	"GET VAR ups1 ups.delay.shutdown":      {{`VAR ups1 ups.delay.shutdown "20"`}},
+	"GET VAR ups1 ups.delay.start":         {{`VAR ups1 ups.delay.start "30"`}},
+	`SET VAR ups1 ups.delay.shutdown "60"`: {{"OK"}},
+	`SET VAR ups1 ups.delay.shutdown "20"`: {{"OK"}},
+	`SET VAR ups1 ups.delay.start "30"`:    {{"OK"}},
+}
+
+func TestApplySettingsRollsBackInterruptedSet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Cancel once the first SET is done, so the second fails without an ERR
+	trace := nut.WithCommandTrace(func(trace nut.CommandTrace) {
+		if trace.Command == `SET VAR ups1 ups.delay.shutdown "60"` {
+			cancel()
+		}
+	})
+	client := newSequencedClient(t, settingsResponses, trace)
+	ups, _ := nut.NewUPS("ups1", client)
+
+	results, err := ups.ApplySettings(ctx, map[string]string{"ups.delay.shutdown": "60", "ups.delay.start": "120"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	// The client cannot tell whether the interrupted SET reached upsd, so it
+	// is restored too
+	for _, result := range results {
+		if !result.RolledBack {
+			t.Errorf("%s not rolled back: %+v", result.Variable, result)
+		}
+	}
+	if results[1].Applied || !errors.Is(results[1].Err, context.Canceled) {
+		t.Errorf("interrupted setting %+v", results[1])
+	}
+}
+
+func TestApplySettingsRejectedSetNotRolledBack(t *testing.T) {
+	responses := map[string][][]string{`SET VAR ups1 ups.delay.start "120"`: {{"ERR INVALID-VALUE"}}}
+	for cmd, sequence := range settingsResponses {
+		responses[cmd] = sequence
+	}
+	client := newSequencedClient(t, responses)
+	ups, _ := nut.NewUPS("ups1", client)
+
+	results, err := ups.ApplySettings(context.Background(), map[string]string{"ups.delay.shutdown": "60", "ups.delay.start": "120"})
+	if err == nil {
+		t.Fatal("no error for a rejected SET")
+	}
+	if !results[0].RolledBack || results[1].RolledBack {
+		t.Fatalf("results %+v", results)
+	}
+}
+
+func TestApplySettingsValidationUsesContext(t *testing.T) {
+	client := newSequencedClient(t, settingsResponses)
+	ups, _ := nut.NewUPS("ups1", client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := ups.ApplySettings(ctx, map[string]string{"ups.delay.shutdown": "60"})
+	if !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "validating") || !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("err = %v, results %+v", err, results)
+	}
+}
@@ -0,0 +1,100 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+)
+
+// ShutdownMode selects how the UPS should behave after shutting down its load.
+type ShutdownMode int
+
+const (
+	// ShutdownReturn turns off the load and restores it when line power returns.
+	ShutdownReturn ShutdownMode = iota
+	// ShutdownStayOff turns off the load and keeps it off until manually restarted.
+	ShutdownStayOff
+	// ShutdownReboot power-cycles the load.
+	ShutdownReboot
+)
+
+func (m ShutdownMode) String() string {
+	switch m {
+	case ShutdownStayOff:
+		return "stayoff"
+	case ShutdownReboot:
+		return "reboot"
+	default:
+		return "return"
+	}
+}
+
+// shutdownCommands lists the instant commands implementing each mode, in order
+// of preference. load.off.delay does not restore the load when power returns,
+// so it is no substitute for shutdown.return.
+var shutdownCommands = map[ShutdownMode][]string{
+	ShutdownReturn:  {"shutdown.return"},
+	ShutdownStayOff: {"shutdown.stayoff", "load.off.delay"},
+	ShutdownReboot:  {"shutdown.reboot", "shutdown.reboot.graceful"},
+}
+
+type shutdownOptions struct {
+	confirmed bool
+}
+
+// ShutdownOption configures UPS.Shutdown.
+type ShutdownOption func(*shutdownOptions)
+
+// ConfirmShutdown acknowledges that Shutdown will cut power to the load. Shutdown
+// refuses to send any command without it.
+func ConfirmShutdown() ShutdownOption {
+	return func(o *shutdownOptions) {
+		o.confirmed = true
+	}
+}
+
+// Shutdown turns off the UPS load using the instant command matching mode among
+// those the device advertises, and returns the name of the command sent.
+// Because this cuts power, ConfirmShutdown must be passed; otherwise
+// ErrShutdownNotConfirmed is returned and nothing is sent. ConfirmShutdown
+// takes the place of WithAllowDestructive.
+func (u *UPS) Shutdown(ctx context.Context, mode ShutdownMode, opts ...ShutdownOption) (string, error) {
+	options := shutdownOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if !options.confirmed {
+		return "", ErrShutdownNotConfirmed
+	}
+
+	candidates, ok := shutdownCommands[mode]
+	if !ok {
+		return "", fmt.Errorf("unknown shutdown mode %d", mode)
+	}
+
+	names, err := u.commandNames()
+	if err != nil {
+		return "", err
+	}
+	supported := make(map[string]bool, len(names))
+	for _, name := range names {
+		supported[name] = true
+	}
+
+	for _, candidate := range candidates {
+		if !supported[candidate] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if logger := u.nutClient.loggerFor(LogInfo); logger != nil {
+			logger.Printf("Shutting down %s (%s) with %s", u.Name, mode, candidate)
+		}
+		if _, err := u.instCmd(ctx, candidate, true); err != nil {
+			return candidate, err
+		}
+		return candidate, nil
+	}
+
+	return "", fmt.Errorf("UPS %s advertises no command for shutdown mode %s: %w", u.Name, mode, errorForMessage(ErrCodeCmdNotSupported))
+}
@@ -0,0 +1,103 @@
+package nut
+
+import (
+	"sort"
+	"time"
+)
+
+// Snapshot is a point-in-time copy of a UPS's state that does not reference the
+// connection it was read from.
+type Snapshot struct {
+	Time        time.Time
+	Server      string // Endpoint (host:port) the UPS was read from
+	UPS         string
+	Description string
+	Status      Status
+	Variables   map[string]string // Raw variable values keyed by name
+	Derived     map[string]string // Computed values keyed by name, see DerivedVariables
+	Commands    []string          // Instant commands, if read
+	Clients     []string          // Clients logged in to the UPS, if read
+}
+
+// Snapshot returns a copy of the state cached by the UPS that remains valid
+// after the client disconnects, e.g. to pass it to another goroutine, export
+// it or compare it with a later one. It does not query the server: variables,
+// commands and clients are those of the last GetVariables, GetCommands and
+// GetClients. Time is when the variables were read, or now if they were not.
+func (u *UPS) Snapshot() Snapshot {
+	info := u.Cached()
+	snapshot := Snapshot{
+		Time:        time.Now(),
+		Server:      upsServer(u),
+		UPS:         info.Name,
+		Description: info.Description,
+		Variables:   make(map[string]string, len(info.Variables)),
+		Clients:     info.Clients,
+	}
+	for i, v := range info.Variables {
+		if i == 0 || v.SampledAt.After(snapshot.Time) {
+			snapshot.Time = v.SampledAt
+		}
+		snapshot.Variables[v.Name] = v.Raw
+	}
+	for _, command := range info.Commands {
+		snapshot.Commands = append(snapshot.Commands, command.Name)
+	}
+	snapshot.Status = ParseStatus(snapshot.Variables["ups.status"])
+	snapshot.Derived = DerivedVariables(snapshot.Variables, snapshot.Time)
+	return snapshot
+}
+
+// Equal reports whether two snapshots describe the same state of the same
+// UPS. Time and derived values are not compared.
+func (s Snapshot) Equal(other Snapshot) bool {
+	return s.Server == other.Server && s.UPS == other.UPS &&
+		s.Description == other.Description && s.Status == other.Status &&
+		equalStringMaps(s.Variables, other.Variables) &&
+		equalStrings(s.Commands, other.Commands) && equalStrings(s.Clients, other.Clients)
+}
+
+// Changes returns the variables that differ in newer, in name order, timed
+// at newer.Time. A variable missing from newer has an empty NewValue.
+func (s Snapshot) Changes(newer Snapshot) []VariableUpdate {
+	names := map[string]bool{}
+	for name := range s.Variables {
+		names[name] = true
+	}
+	for name := range newer.Variables {
+		names[name] = true
+	}
+	var changes []VariableUpdate
+	for name := range names {
+		oldValue, newValue := s.Variables[name], newer.Variables[name]
+		if oldValue != newValue {
+			changes = append(changes, VariableUpdate{Time: newer.Time, Variable: name, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Variable < changes[j].Variable })
+	return changes
+}
+
+func equalStringMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
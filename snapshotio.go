@@ -0,0 +1,201 @@
+package nut
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is a file format for snapshots and configuration files.
+type Format string
+
+// File formats.
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// snapshotFile is the JSON representation of a Snapshot, and the layout of
+// the YAML and TOML documents.
+type snapshotFile struct {
+	Time        time.Time         `json:"time"`
+	Server      string            `json:"server,omitempty"`
+	UPS         string            `json:"ups"`
+	Description string            `json:"description,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	Variables   map[string]string `json:"variables"`
+	Derived     map[string]string `json:"derived,omitempty"`
+	Commands    []string          `json:"commands,omitempty"`
+	Clients     []string          `json:"clients,omitempty"`
+}
+
+// Export writes the snapshot to w in the given format, e.g. to archive it,
+// attach it to a support ticket or diff it against another day's. Variables
+// are written in name order so that exports of similar states diff cleanly.
+// (The method is not named WriteTo, which would clash with io.WriterTo.)
+func (s Snapshot) Export(w io.Writer, format Format) error {
+	file := snapshotFile{
+		Time:        s.Time,
+		Server:      s.Server,
+		UPS:         s.UPS,
+		Description: s.Description,
+		Status:      s.Status.String(),
+		Variables:   s.Variables,
+		Derived:     s.Derived,
+		Commands:    s.Commands,
+		Clients:     s.Clients,
+	}
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(file)
+	case FormatYAML:
+		return file.writeYAML(w)
+	case FormatTOML:
+		return file.writeTOML(w)
+	default:
+		return fmt.Errorf("unsupported snapshot format %q", format)
+	}
+}
+
+// ReadSnapshot reads a snapshot written by Snapshot.Export. YAML and TOML
+// documents are read with the parsers of configuration files (see
+// ParseConfig), so hand-edited snapshots may use comments, plain or quoted
+// values and block mappings. Status is taken from the ups.status variable if
+// present.
+//
+// A snapshot can serve as a fixture for the nuttest fake server:
+//
+//	server.AddUPS(snapshot.UPS, snapshot.Description, snapshot.Variables)
+func ReadSnapshot(r io.Reader, format Format) (Snapshot, error) {
+	var file snapshotFile
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.NewDecoder(r).Decode(&file)
+	case FormatYAML, FormatTOML:
+		var data []byte
+		if data, err = io.ReadAll(r); err != nil {
+			break
+		}
+		var document interface{}
+		if document, err = parseDocument(string(data), format); err == nil {
+			err = decodeDocument(document, &file)
+		}
+	default:
+		return Snapshot{}, fmt.Errorf("unsupported snapshot format %q", format)
+	}
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("reading %s snapshot: %w", format, err)
+	}
+	if file.Variables == nil {
+		file.Variables = map[string]string{}
+	}
+
+	status := file.Status
+	if value, ok := file.Variables["ups.status"]; ok {
+		status = value
+	}
+	return Snapshot{
+		Time:        file.Time,
+		Server:      file.Server,
+		UPS:         file.UPS,
+		Description: file.Description,
+		Status:      ParseStatus(status),
+		Variables:   file.Variables,
+		Derived:     file.Derived,
+		Commands:    file.Commands,
+		Clients:     file.Clients,
+	}, nil
+}
+
+// fields returns the scalar and list fields in document order. Lists are
+// written as flow sequences, which are also TOML arrays.
+func (f snapshotFile) fields() [][2]string {
+	fields := [][2]string{
+		{"time", f.Time.Format(time.RFC3339Nano)},
+		{"server", quoteDocumentString(f.Server)},
+		{"ups", quoteDocumentString(f.UPS)},
+		{"description", quoteDocumentString(f.Description)},
+		{"status", quoteDocumentString(f.Status)},
+	}
+	for _, list := range []struct {
+		name   string
+		values []string
+	}{{"commands", f.Commands}, {"clients", f.Clients}} {
+		if len(list.values) == 0 {
+			continue
+		}
+		quoted := make([]string, len(list.values))
+		for i, value := range list.values {
+			quoted[i] = quoteDocumentString(value)
+		}
+		fields = append(fields, [2]string{list.name, "[" + strings.Join(quoted, ", ") + "]"})
+	}
+	return fields
+}
+
+func (f snapshotFile) writeYAML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, field := range f.fields() {
+		fmt.Fprintf(bw, "%s: %s\n", field[0], field[1])
+	}
+	for _, table := range []struct {
+		name   string
+		values map[string]string
+	}{{"variables", f.Variables}, {"derived", f.Derived}} {
+		if len(table.values) == 0 {
+			fmt.Fprintf(bw, "%s: {}\n", table.name)
+			continue
+		}
+		fmt.Fprintf(bw, "%s:\n", table.name)
+		for _, name := range sortedKeys(table.values) {
+			fmt.Fprintf(bw, "  %s: %s\n", quoteDocumentString(name), quoteDocumentString(table.values[name]))
+		}
+	}
+	return bw.Flush()
+}
+
+func (f snapshotFile) writeTOML(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, field := range f.fields() {
+		fmt.Fprintf(bw, "%s = %s\n", field[0], field[1])
+	}
+	for _, table := range []struct {
+		name   string
+		values map[string]string
+	}{{"variables", f.Variables}, {"derived", f.Derived}} {
+		fmt.Fprintf(bw, "\n[%s]\n", table.name)
+		for _, name := range sortedKeys(table.values) {
+			fmt.Fprintf(bw, "%s = %s\n", quoteDocumentString(name), quoteDocumentString(table.values[name]))
+		}
+	}
+	return bw.Flush()
+}
+
+// quoteDocumentString quotes s as a JSON string, which is also a valid YAML
+// double-quoted and TOML basic string.
+func quoteDocumentString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
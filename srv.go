@@ -0,0 +1,73 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SRVTarget describes a NUT server discovered through a DNS SRV record.
+type SRVTarget struct {
+	Host     string
+	Port     int
+	Priority uint16
+	Weight   uint16
+}
+
+// LookupSRV resolves the _nut._tcp.<domain> SRV records for domain. Targets are
+// returned in failover order: sorted by priority and randomized by weight within
+// each priority, as described in RFC 2782.
+func LookupSRV(ctx context.Context, domain string) ([]SRVTarget, error) {
+	return lookupSRV(ctx, net.DefaultResolver, domain)
+}
+
+func lookupSRV(ctx context.Context, resolver *net.Resolver, domain string) ([]SRVTarget, error) {
+	_, records, err := resolver.LookupSRV(ctx, "nut", "tcp", domain)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]SRVTarget, 0, len(records))
+	for _, srv := range records {
+		// A target of "." means the service is decidedly not available
+		if srv.Target == "." {
+			continue
+		}
+		targets = append(targets, SRVTarget{
+			Host:     strings.TrimSuffix(srv.Target, "."),
+			Port:     int(srv.Port),
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+		})
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no NUT servers advertised for %s", domain)
+	}
+	return targets, nil
+}
+
+// ConnectSRV discovers NUT servers for domain via DNS SRV records and connects to
+// the first reachable target in failover order.
+func ConnectSRV(ctx context.Context, domain string, opts ...ClientOption) (*Client, error) {
+	targets, err := lookupSRV(ctx, newClient(opts).lookupResolver(), domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, target := range targets {
+		client, err := ConnectWithOptionsAndConfig(ctx, target.Host, opts, target.Port)
+		if err == nil {
+			return client, nil
+		}
+		lastErr = err
+
+		// Stop trying further targets once the caller gave up
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, fmt.Errorf("all SRV targets for %s failed: %w", domain, lastErr)
+}
@@ -0,0 +1,12 @@
+module github.com/bearx3f/go.nut/sshtunnel
+
+go 1.21
+
+require (
+	github.com/bearx3f/go.nut v0.0.0
+	golang.org/x/crypto v0.31.0
+)
+
+require golang.org/x/sys v0.28.0 // indirect
+
+replace github.com/bearx3f/go.nut => ../
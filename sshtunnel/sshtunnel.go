@@ -0,0 +1,246 @@
+// Package sshtunnel connects to NUT servers that are only reachable through an
+// SSH jump host, so applications don't need to shell out to `ssh -L`:
+//
+//	client, err := sshtunnel.Connect(ctx, sshtunnel.Config{
+//		Address: "jump.example.com",
+//		User:    "monitor",
+//	}, "ups-server.internal:3493")
+//
+// It is a separate module so that the main module keeps depending only on the
+// standard library.
+package sshtunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultSSHPort = 22
+	defaultNUTPort = 3493
+	defaultTimeout = 10 * time.Second
+)
+
+// Config describes the SSH jump host.
+type Config struct {
+	Address         string              // host[:port] of the SSH server; port 22 by default
+	User            string              // SSH user name
+	Auth            []ssh.AuthMethod    // Defaults to the keys of the SSH agent at $SSH_AUTH_SOCK
+	HostKeyCallback ssh.HostKeyCallback // Defaults to ~/.ssh/known_hosts
+	Timeout         time.Duration       // Connection and handshake timeout, default 10s
+}
+
+// Tunnel is an SSH connection to a jump host through which NUT servers are
+// dialed. A Tunnel can carry any number of clients; closing it closes them.
+type Tunnel struct {
+	ssh *ssh.Client
+}
+
+// Dial connects and authenticates to the SSH server. The connection is
+// bounded by ctx and config.Timeout.
+func Dial(ctx context.Context, config Config) (*Tunnel, error) {
+	if config.User == "" {
+		return nil, fmt.Errorf("ssh user is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.HostKeyCallback == nil {
+		callback, err := KnownHosts()
+		if err != nil {
+			return nil, err
+		}
+		config.HostKeyCallback = callback
+	}
+	if config.Auth == nil {
+		auth, agentConn, err := agentAuth()
+		if err != nil {
+			return nil, err
+		}
+		// The agent is only needed to sign during the handshake
+		defer agentConn.Close()
+		config.Auth = []ssh.AuthMethod{auth}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	address := withDefaultPort(config.Address, defaultSSHPort)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh server %s: %w", address, err)
+	}
+
+	// ssh.NewClientConn does not take a context; bound it with a deadline and
+	// close the connection if ctx is canceled first
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:            config.User,
+		Auth:            config.Auth,
+		HostKeyCallback: config.HostKeyCallback,
+		Timeout:         config.Timeout,
+	})
+	if !stop() {
+		if err == nil {
+			sshConn.Close()
+		}
+		return nil, fmt.Errorf("ssh handshake with %s: %w", address, ctx.Err())
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s: %w", address, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return &Tunnel{ssh: ssh.NewClient(sshConn, chans, reqs)}, nil
+}
+
+// Connect dials upsd ("host[:port]", port 3493 by default, as seen from the
+// jump host) through the tunnel and returns a client that completed the
+// version handshake. The client cannot Reconnect; call Connect again instead.
+func (t *Tunnel) Connect(ctx context.Context, upsd string, opts ...nut.ClientOption) (*nut.Client, error) {
+	conn, err := t.DialContext(ctx, "tcp", withDefaultPort(upsd, defaultNUTPort))
+	if err != nil {
+		return nil, err
+	}
+	return nut.NewClientFromConn(conn, opts...)
+}
+
+// DialContext opens a connection to addr through the tunnel. Unlike a raw
+// SSH channel, the connection supports deadlines, which the client needs.
+func (t *Tunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	channel, err := t.ssh.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s through ssh: %w", addr, err)
+	}
+	return bridge(channel), nil
+}
+
+// bridgedConn is the local end of a net.Pipe copied to and from an SSH
+// channel, reporting the channel's addresses.
+type bridgedConn struct {
+	net.Conn
+	channel net.Conn
+}
+
+// bridge connects channel to a net.Pipe, as SSH channels do not support
+// deadlines. Closing the returned connection closes the channel.
+func bridge(channel net.Conn) net.Conn {
+	local, remote := net.Pipe()
+	go func() {
+		io.Copy(remote, channel)
+		remote.Close()
+	}()
+	go func() {
+		io.Copy(channel, remote)
+		channel.Close()
+	}()
+	return &bridgedConn{Conn: local, channel: channel}
+}
+
+func (c *bridgedConn) LocalAddr() net.Addr  { return c.channel.LocalAddr() }
+func (c *bridgedConn) RemoteAddr() net.Addr { return c.channel.RemoteAddr() }
+
+// Close closes the SSH connection and all clients using it.
+func (t *Tunnel) Close() error {
+	return t.ssh.Close()
+}
+
+// Connect establishes a tunnel for a single client: the SSH connection is
+// closed when the client's connection is.
+func Connect(ctx context.Context, config Config, upsd string, opts ...nut.ClientOption) (*nut.Client, error) {
+	tunnel, err := Dial(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := tunnel.DialContext(ctx, "tcp", withDefaultPort(upsd, defaultNUTPort))
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return nut.NewClientFromConn(&tunnelConn{Conn: conn, tunnel: tunnel}, opts...)
+}
+
+// tunnelConn is a connection that owns its tunnel.
+type tunnelConn struct {
+	net.Conn
+	tunnel *Tunnel
+	once   sync.Once
+}
+
+func (c *tunnelConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.tunnel.Close() })
+	return err
+}
+
+// PrivateKeyFile returns key authentication with the private key at path,
+// decrypted with passphrase if it is not empty.
+func PrivateKeyFile(path string, passphrase []byte) (ssh.AuthMethod, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var signer ssh.Signer
+	if len(passphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, passphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+	return ssh.PublicKeys(signer), nil
+}
+
+// KnownHosts returns a host key callback checking the given known_hosts
+// files, ~/.ssh/known_hosts if none are given.
+func KnownHosts(files ...string) (ssh.HostKeyCallback, error) {
+	if len(files) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("no known_hosts file: %w", err)
+		}
+		files = []string{filepath.Join(home, ".ssh", "known_hosts")}
+	}
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// agentAuth returns authentication with the keys of the SSH agent and the
+// agent connection, which must stay open until the handshake is done.
+func agentAuth() (ssh.AuthMethod, net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, fmt.Errorf("no ssh auth method given and SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ssh agent: %w", err)
+	}
+	return ssh.PublicKeysCallback(agent.NewClient(conn).Signers), conn, nil
+}
+
+// withDefaultPort appends port to address if it has none.
+func withDefaultPort(address string, port int) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
+}
@@ -0,0 +1,228 @@
+package sshtunnel
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/bearx3f/go.nut/nuttest"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// newSigner returns a signer for a fresh ed25519 key.
+func newSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	return signerFor(t, newKey(t))
+}
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func signerFor(t *testing.T, key ed25519.PrivateKey) ssh.Signer {
+	t.Helper()
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// startJumpHost runs an SSH server accepting clientKey for user "monitor" and
+// forwarding direct-tcpip channels, and returns its address and host key.
+func startJumpHost(t *testing.T, clientKey ssh.PublicKey) (string, ssh.PublicKey) {
+	t.Helper()
+	hostKey := newSigner(t)
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "monitor" && string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveJumpHost(conn, config)
+		}
+	}()
+	return listener.Addr().String(), hostKey.PublicKey()
+}
+
+func serveJumpHost(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		var target struct {
+			Host       string
+			Port       uint32
+			OriginHost string
+			OriginPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.FormatUint(uint64(target.Port), 10)))
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			upstream.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			io.Copy(channel, upstream)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(upstream, channel)
+			upstream.Close()
+		}()
+	}
+}
+
+func startUPSServer(t *testing.T) string {
+	t.Helper()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+	return server.Addr()
+}
+
+func TestConnectWithKey(t *testing.T) {
+	clientKey := newSigner(t)
+	jumpHost, hostKey := startJumpHost(t, clientKey.PublicKey())
+	upsd := startUPSServer(t)
+
+	client, err := Connect(context.Background(), Config{
+		Address:         jumpHost,
+		User:            "monitor",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(clientKey)},
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+	}, upsd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	list, err := client.GetUPSList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "ups1" {
+		t.Fatalf("unexpected UPS list %+v", list)
+	}
+}
+
+func TestConnectWithAgent(t *testing.T) {
+	clientKey := newKey(t)
+	jumpHost, hostKey := startJumpHost(t, signerFor(t, clientKey).PublicKey())
+	upsd := startUPSServer(t)
+
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: clientKey}); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+
+	tunnel, err := Dial(context.Background(), Config{
+		Address:         jumpHost,
+		User:            "monitor",
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+	client, err := tunnel.Connect(context.Background(), upsd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetVersion(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDialRejectsUnknownHostKey(t *testing.T) {
+	clientKey := newSigner(t)
+	jumpHost, _ := startJumpHost(t, clientKey.PublicKey())
+
+	_, err := Dial(context.Background(), Config{
+		Address:         jumpHost,
+		User:            "monitor",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(clientKey)},
+		HostKeyCallback: ssh.FixedHostKey(newSigner(t).PublicKey()),
+	})
+	if err == nil {
+		t.Fatal("expected host key mismatch")
+	}
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	tests := []struct {
+		address string
+		port    int
+		want    string
+	}{
+		{"jump.example.com", 22, "jump.example.com:22"},
+		{"jump.example.com:2222", 22, "jump.example.com:2222"},
+		{"::1", 3493, "[::1]:3493"},
+		{"[::1]:3494", 3493, "[::1]:3494"},
+	}
+	for _, tt := range tests {
+		if got := withDefaultPort(tt.address, tt.port); got != tt.want {
+			t.Errorf("withDefaultPort(%q, %d) = %q, want %q", tt.address, tt.port, got, tt.want)
+		}
+	}
+}
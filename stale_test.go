@@ -0,0 +1,43 @@
+package nut_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestStaleFallback(t *testing.T) {
+	stale := []string{"ERR DATA-STALE"}
+	client := newSequencedClient(t, map[string][][]string{
+		"LIST VAR ups1": {{
+			"BEGIN LIST VAR ups1",
+			`VAR ups1 battery.charge "100"`,
+			`VAR ups1 ups.status "OL"`,
+			"END LIST VAR ups1",
+		}, stale},
+		"GET VAR ups1 battery.charge": {{`VAR ups1 battery.charge "99"`}, stale},
+		"GET VAR ups1 input.voltage":  {stale},
+	}, nut.WithStaleFallback())
+	ctx := context.Background()
+	backend := nut.NewNUTBackend(client)
+
+	want := map[string]string{"battery.charge": "100", "ups.status": "OL"}
+	for i := 0; i < 2; i++ {
+		values, err := backend.Variables(ctx, "ups1")
+		if err != nil || !reflect.DeepEqual(values, want) {
+			t.Fatalf("read %d: values = %v, err = %v", i, values, err)
+		}
+	}
+
+	ups, _ := nut.NewUPS("ups1", client)
+	for i := 0; i < 2; i++ {
+		if value, err := ups.GetVariableValue("battery.charge"); err != nil || value != "99" {
+			t.Fatalf("read %d: value = %q, err = %v", i, value, err)
+		}
+	}
+	if _, err := ups.GetVariableValue("input.voltage"); err == nil {
+		t.Fatal("expected DATA-STALE for a variable never read")
+	}
+}
@@ -0,0 +1,180 @@
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ConnState is the lifecycle state of a Client's connection.
+type ConnState int32
+
+// Connection states reported by Client.State.
+const (
+	StateConnecting    ConnState = iota // Dialing and exchanging versions
+	StateConnected                      // Ready for commands
+	StateTLS                            // Upgraded with STARTTLS, not authenticated
+	StateAuthenticated                  // USERNAME and PASSWORD accepted
+	StateReconnecting                   // Re-establishing the connection in Reconnect
+	StateClosed                         // Closed by Close, Disconnect or a fatal error
+)
+
+// String returns the state name.
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateTLS:
+		return "tls"
+	case StateAuthenticated:
+		return "authenticated"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// WithOnStateChange registers fn to be called on every state transition of the
+// client. fn is called synchronously, possibly while the connection is held,
+// so it must not block or call methods of the client.
+func WithOnStateChange(fn func(old, new ConnState)) ClientOption {
+	return func(c *Client) {
+		c.onStateChange = fn
+	}
+}
+
+// State returns the current connection state.
+func (c *Client) State() ConnState {
+	return ConnState(atomic.LoadInt32(&c.state))
+}
+
+// IsConnected reports whether the client can send commands, i.e. it is
+// connected and not being closed or re-established.
+func (c *Client) IsConnected() bool {
+	switch c.State() {
+	case StateConnected, StateTLS, StateAuthenticated:
+		return true
+	default:
+		return false
+	}
+}
+
+// Broken reports whether a command failed in a way that may leave the
+// connection out of sync with the server: an I/O error, a timeout or
+// cancellation while reading a response, a LIST response with broken BEGIN/END
+// framing, or a malformed response in strict mode (see WithStrictParsing). A
+// broken client should be closed or reconnected rather than reused; Pool does
+// so automatically.
+func (c *Client) Broken() bool {
+	return atomic.LoadInt32(&c.broken) != 0
+}
+
+func (c *Client) markBroken() {
+	atomic.StoreInt32(&c.broken, 1)
+}
+
+// ClientHealth is a point-in-time view of a client's connection health.
+type ClientHealth struct {
+	State               ConnState
+	Broken              bool      // See Client.Broken
+	LastSuccess         time.Time // Time of the last command answered by the server
+	LastError           error     // Error of the last failed command, including ERR responses
+	ConsecutiveFailures int       // Commands failed without a server response since LastSuccess
+}
+
+// Health returns the current health of the client. An ERR response counts as
+// a response: it is reported as LastError but does not count as a failure, as
+// the connection is still usable.
+func (c *Client) Health() ClientHealth {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	return ClientHealth{
+		State:               c.State(),
+		Broken:              c.Broken(),
+		LastSuccess:         c.lastSuccess,
+		LastError:           c.lastError,
+		ConsecutiveFailures: c.consecutiveFailures,
+	}
+}
+
+// LastError returns the error of the last failed command, or nil.
+func (c *Client) LastError() error {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	return c.lastError
+}
+
+// recordResult updates the health counters with the outcome of a command.
+func (c *Client) recordResult(err error) {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	if err != nil {
+		c.lastError = err
+	}
+	if err == nil || isProtocolError(err) {
+		c.lastSuccess = time.Now()
+		c.consecutiveFailures = 0
+		return
+	}
+	c.consecutiveFailures++
+}
+
+// isProtocolError reports whether err is a *ProtocolError, i.e. the server
+// answered.
+func isProtocolError(err error) bool {
+	var protoErr *ProtocolError
+	return errors.As(err, &protoErr)
+}
+
+func (c *Client) setState(state ConnState) {
+	old := ConnState(atomic.SwapInt32(&c.state, int32(state)))
+	if old != state && c.onStateChange != nil {
+		c.onStateChange(old, state)
+	}
+}
+
+// Reconnect closes the current connection, if any, and connects again to the
+// same server, repeating STARTTLS if it was in use. Authentication and LOGIN
+// are not repeated; call Authenticate and UPS.Login again as needed.
+func (c *Client) Reconnect(ctx context.Context) error {
+	if c.host == "" {
+		return fmt.Errorf("cannot reconnect a client created from a connection")
+	}
+
+	c.queue.lock()
+	useTLS := c.UseTLS
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.reader = nil
+	c.UseTLS = false
+	c.sessionMu.Lock()
+	c.username = ""
+	c.loginUPS = ""
+	c.sessionMu.Unlock()
+	c.setState(StateReconnecting)
+	c.queue.release()
+
+	c.capsMu.Lock()
+	c.capabilities = nil
+	c.capsMu.Unlock()
+
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	if useTLS {
+		if err := c.StartTLS(); err != nil {
+			c.Close()
+			return err
+		}
+	}
+	return nil
+}
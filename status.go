@@ -0,0 +1,356 @@
+package nut
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status is a bitmask of the flags reported in the ups.status variable.
+type Status uint32
+
+// Flags reported in ups.status.
+const (
+	StatusOnline         Status = 1 << iota // OL
+	StatusOnBattery                         // OB
+	StatusLowBattery                        // LB
+	StatusHighBattery                       // HB
+	StatusReplaceBattery                    // RB
+	StatusCharging                          // CHRG
+	StatusDischarging                       // DISCHRG
+	StatusBypass                            // BYPASS
+	StatusCalibrating                       // CAL
+	StatusOff                               // OFF
+	StatusOverload                          // OVER
+	StatusTrim                              // TRIM
+	StatusBoost                             // BOOST
+	StatusForcedShutdown                    // FSD
+	StatusAlarm                             // ALARM
+	StatusTest                              // TEST
+)
+
+var statusTokens = []struct {
+	flag  Status
+	token string
+}{
+	{StatusOnline, "OL"},
+	{StatusOnBattery, "OB"},
+	{StatusLowBattery, "LB"},
+	{StatusHighBattery, "HB"},
+	{StatusReplaceBattery, "RB"},
+	{StatusCharging, "CHRG"},
+	{StatusDischarging, "DISCHRG"},
+	{StatusBypass, "BYPASS"},
+	{StatusCalibrating, "CAL"},
+	{StatusOff, "OFF"},
+	{StatusOverload, "OVER"},
+	{StatusTrim, "TRIM"},
+	{StatusBoost, "BOOST"},
+	{StatusForcedShutdown, "FSD"},
+	{StatusAlarm, "ALARM"},
+	{StatusTest, "TEST"},
+}
+
+// ParseStatus parses a ups.status value such as "OL CHRG" into a Status.
+// Unknown tokens are ignored.
+func ParseStatus(value string) Status {
+	var status Status
+	for _, field := range strings.Fields(value) {
+		for _, t := range statusTokens {
+			if field == t.token {
+				status |= t.flag
+				break
+			}
+		}
+	}
+	return status
+}
+
+// parseStatusBytes is ParseStatus for a byte slice, without allocating.
+func parseStatusBytes(value []byte) Status {
+	var status Status
+	for len(value) > 0 {
+		i := bytes.IndexByte(value, ' ')
+		if i < 0 {
+			i = len(value)
+		}
+		field := value[:i]
+		for _, t := range statusTokens {
+			if string(field) == t.token {
+				status |= t.flag
+				break
+			}
+		}
+		value = bytes.TrimLeft(value[i:], " ")
+	}
+	return status
+}
+
+// Has reports whether all of the given flags are set.
+func (s Status) Has(flags Status) bool {
+	return s&flags == flags
+}
+
+// String returns the flags in ups.status notation, e.g. "OB LB".
+func (s Status) String() string {
+	tokens := []string{}
+	for _, t := range statusTokens {
+		if s&t.flag != 0 {
+			tokens = append(tokens, t.token)
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// GetStatus reads and parses the ups.status variable.
+func (u *UPS) GetStatus() (Status, error) {
+	return u.getStatus(context.Background())
+}
+
+func (u *UPS) getStatus(ctx context.Context) (Status, error) {
+	value, err := u.getVariableValue(ctx, "ups.status")
+	if err != nil {
+		return 0, err
+	}
+	return ParseStatus(value), nil
+}
+
+// PollStatus reads ups.status like GetStatus, but is optimized for tight polling
+// loops over many UPSes: the command is pre-built, the response is parsed in
+// place from the read buffer and a successful poll does not allocate. Metrics,
+// request IDs, logging, command traces and WithOperationBudget work as for
+// SendCommandWithContext; a budget costs an allocation per poll. Unlike other
+// commands, an in-flight exchange is bounded by the context deadline and
+// ReadTimeout but not interrupted by cancellation.
+func (u *UPS) PollStatus(ctx context.Context) (status Status, err error) {
+	c := u.nutClient
+	ctx, cancel := c.withBudget(ctx)
+	defer cancel()
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx, c.rateLimitFailFast); err != nil {
+			return 0, err
+		}
+	}
+	if err := c.queue.acquire(ctx, priorityStatus, u.Name); err != nil {
+		return 0, err
+	}
+	defer c.queue.release()
+
+	if c.conn == nil || c.State() == StateClosed {
+		return 0, ErrClosed
+	}
+	statusCmd, statusPrefix := u.statusCommand()
+	id, start := c.reserveRequestID(ctx), time.Now()
+	defer func() {
+		c.recordResult(err)
+		if err == nil {
+			return
+		}
+		c.countFailed()
+		command := string(statusCmd[:len(statusCmd)-1])
+		if logger := c.loggerFor(LogWarn); logger != nil {
+			logger.Printf("[%s] Status poll failed: %v", id, err)
+		}
+		if c.commandTrace != nil {
+			c.commandTrace(CommandTrace{RequestID: id.String(), Command: command, Start: start, Duration: time.Since(start), Err: err})
+		}
+		err = &CommandError{RequestID: id.String(), Command: command, Err: err}
+	}()
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	deadline := start.Add(c.ReadTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if logger := c.loggerFor(LogDebug); logger != nil {
+		logger.Printf("[%s] Sending command: %s", id, statusCmd[:len(statusCmd)-1])
+	}
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return 0, err
+	}
+	n, err := c.conn.Write(statusCmd)
+	c.conn.SetWriteDeadline(time.Time{})
+	if err != nil {
+		c.markBroken()
+		return 0, fmt.Errorf("failed to send command: %w", err)
+	}
+	c.countSent(n)
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+	line, err := c.reader.ReadSlice('\n')
+	if err != nil {
+		c.markBroken()
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	c.countReceived(len(line))
+
+	if bytes.HasPrefix(line, []byte("ERR ")) {
+		return 0, errorForResponse(string(line))
+	}
+	if !bytes.HasPrefix(line, statusPrefix) || !bytes.HasSuffix(line, []byte(`"`)) {
+		c.markBroken()
+		return 0, &ParseError{Command: string(statusCmd[:len(statusCmd)-1]), Line: string(line), Reason: "unexpected response"}
+	}
+	status = parseStatusBytes(line[len(statusPrefix) : len(line)-1])
+	if c.commandTrace != nil {
+		c.commandTrace(CommandTrace{RequestID: id.String(), Command: string(statusCmd[:len(statusCmd)-1]), Start: start, Duration: time.Since(start)})
+	}
+	return status, nil
+}
+
+// statusCommand returns the pre-built PollStatus command and response prefix,
+// building them on first use.
+func (u *UPS) statusCommand() (cmd, prefix []byte) {
+	mu := u.lock()
+	mu.RLock()
+	cmd, prefix = u.statusCmd, u.statusPrefix
+	mu.RUnlock()
+	if cmd != nil {
+		return cmd, prefix
+	}
+	cmd = []byte(fmt.Sprintf("GET VAR %s ups.status\n", quoteName(u.Name)))
+	prefix = []byte(fmt.Sprintf("VAR %s ups.status \"", u.Name))
+	u.setCached(func() { u.statusCmd, u.statusPrefix = cmd, prefix })
+	return cmd, prefix
+}
+
+// WaitForStatus polls ups.status every pollInterval (default 5s) until
+// condition is satisfied and returns the matching status, e.g.
+//
+//	ups.WaitForStatus(ctx, func(s Status) bool { return s.Has(StatusOnline) && !s.Has(StatusOnBattery) }, time.Second)
+//
+// DRIVER-NOT-CONNECTED and DATA-STALE errors, as while a driver restarts, are
+// waited out; other errors are returned immediately, as is ctx's error once it
+// is done. Of the options, only WithStatusClock applies.
+func (u *UPS) WaitForStatus(ctx context.Context, condition func(Status) bool, pollInterval time.Duration, opts ...StatusOption) (Status, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultWatchInterval
+	}
+	var options statusSubscription
+	for _, opt := range opts {
+		opt(&options)
+	}
+	clock := clockOrSystem(options.clock)
+	for {
+		status, err := u.PollStatus(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
+		if err != nil && !driverNotReady(err) {
+			return 0, err
+		}
+		if err == nil && condition(status) {
+			return status, nil
+		}
+
+		timer := clock.NewTimer(pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// StatusOption configures SubscribeStatus and WaitForStatus.
+type StatusOption func(*statusSubscription)
+
+type statusSubscription struct {
+	debounce time.Duration
+	clock    Clock
+}
+
+// WithStatusDebounce suppresses status changes that do not persist for at least
+// d, e.g. a brief OB blip during a transfer test.
+func WithStatusDebounce(d time.Duration) StatusOption {
+	return func(s *statusSubscription) {
+		s.debounce = d
+	}
+}
+
+// WithStatusClock sets the clock timing polls and debouncing (default the
+// system clock), e.g. a fake clock in tests.
+func WithStatusClock(clock Clock) StatusOption {
+	return func(s *statusSubscription) {
+		s.clock = clock
+	}
+}
+
+// SubscribeStatus polls ups.status every interval and sends the parsed status
+// once initially and then whenever it changes. Polling errors are logged and
+// skipped. The channel is closed once ctx is done. An error is returned if the
+// initial status cannot be read.
+func (u *UPS) SubscribeStatus(ctx context.Context, interval time.Duration, opts ...StatusOption) (<-chan Status, error) {
+	sub := statusSubscription{}
+	for _, opt := range opts {
+		opt(&sub)
+	}
+	sub.clock = clockOrSystem(sub.clock)
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	status, err := u.getStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan Status, 1)
+	updates <- status
+
+	go func() {
+		defer close(updates)
+
+		ticker := sub.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := status
+		var pending Status
+		var pendingSince time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+
+			current, err := u.getStatus(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if logger := u.nutClient.loggerFor(LogWarn); logger != nil {
+					logger.Printf("Warning: failed to poll status of %s: %v", u.Name, err)
+				}
+				continue
+			}
+			if current == last {
+				pendingSince = time.Time{}
+				continue
+			}
+
+			// Wait until the new status has been stable for the debounce period
+			now := sub.clock.Now()
+			if pendingSince.IsZero() || current != pending {
+				pending, pendingSince = current, now
+			}
+			if now.Sub(pendingSince) < sub.debounce {
+				continue
+			}
+			last, pendingSince = current, time.Time{}
+
+			select {
+			case updates <- current:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
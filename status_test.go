@@ -0,0 +1,203 @@
+package nut_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+// newStatusUPS serves a UPS with the given ups.status and returns a client
+// connected to it and its handle.
+func newStatusUPS(tb testing.TB, status string, opts ...nut.ClientOption) (*nut.Client, nut.UPS) {
+	tb.Helper()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { server.Close() })
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": status})
+
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, opts, port)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { client.Disconnect() })
+	ups, err := nut.NewUPS("ups1", client)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return client, ups
+}
+
+// newStatusResponder returns a client connected to a minimal server that
+// answers GET VAR ups1 ups.status with status and any other command with an
+// error, without allocating, so allocations of the client can be measured.
+func newStatusResponder(tb testing.TB, status string) *nut.Client {
+	tb.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		query := []byte("GET VAR ups1 ups.status\n")
+		answer := []byte(`VAR ups1 ups.status "` + status + "\"\n")
+		unknown := []byte("ERR UNKNOWN-COMMAND\n")
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadSlice('\n')
+			if err != nil {
+				return
+			}
+			if bytes.Equal(line, query) {
+				conn.Write(answer)
+			} else {
+				conn.Write(unknown)
+			}
+		}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	client, err := nut.NewClientFromConn(conn, nut.WithSkipHandshake())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestPollStatus(t *testing.T) {
+	var traces []nut.CommandTrace
+	client, ups := newStatusUPS(t, "OB LB", nut.WithCommandTrace(func(trace nut.CommandTrace) { traces = append(traces, trace) }))
+
+	status, err := ups.PollStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := nut.StatusOnBattery | nut.StatusLowBattery; status != want {
+		t.Errorf("status = %v, want %v", status, want)
+	}
+
+	metrics := client.GetMetrics()
+	if metrics.CommandsSent == 0 || metrics.BytesSent == 0 || metrics.BytesReceived == 0 {
+		t.Errorf("metrics not updated: %+v", metrics)
+	}
+	last := traces[len(traces)-1]
+	if last.Command != "GET VAR ups1 ups.status" || last.RequestID == "" || last.Err != nil {
+		t.Errorf("unexpected trace %+v", last)
+	}
+}
+
+func TestPollStatusUnknownUPS(t *testing.T) {
+	client, _ := newStatusUPS(t, "OL")
+	other, err := nut.NewUPS("missing", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := client.GetMetrics().CommandsFailed
+	_, err = other.PollStatus(context.Background())
+	var cmdErr *nut.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("err = %v, want a *CommandError", err)
+	}
+	if failed := client.GetMetrics().CommandsFailed - before; failed != 1 {
+		t.Errorf("CommandsFailed increased by %d, want 1", failed)
+	}
+}
+
+func TestPollStatusOperationBudget(t *testing.T) {
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"})
+	proxy, err := nuttest.NewFaultProxy(server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	host, port := proxy.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, []nut.ClientOption{nut.WithOperationBudget(100 * time.Millisecond)}, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ups, _ := nut.NewUPS("ups1", client)
+
+	proxy.SetLatency(time.Second)
+	start := time.Now()
+	if _, err := ups.PollStatus(context.Background()); err == nil {
+		t.Fatal("PollStatus succeeded past the budget")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("PollStatus took %v with a 100ms budget", elapsed)
+	}
+	if !client.Broken() {
+		t.Error("client not broken after the budget ran out")
+	}
+}
+
+func TestPollStatusDoesNotAllocate(t *testing.T) {
+	ups, err := nut.NewUPS("ups1", newStatusResponder(t, "OL CHRG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := ups.PollStatus(ctx); err != nil { // Builds the cached command
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := ups.PollStatus(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("PollStatus allocates %v times per call, want 0", allocs)
+	}
+}
+
+func BenchmarkPollStatus(b *testing.B) {
+	ups, err := nut.NewUPS("ups1", newStatusResponder(b, "OL CHRG"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ups.PollStatus(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetStatus(b *testing.B) {
+	ups, err := nut.NewUPS("ups1", newStatusResponder(b, "OL CHRG"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ups.GetStatus(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogSeverity is a syslog severity level (RFC 5424 section 6.2.1).
+type SyslogSeverity int
+
+// Syslog severities.
+const (
+	SyslogEmergency SyslogSeverity = iota
+	SyslogAlert
+	SyslogCritical
+	SyslogError
+	SyslogWarning
+	SyslogNotice
+	SyslogInfo
+	SyslogDebug
+)
+
+// SyslogFacility is a syslog facility code (RFC 5424 section 6.2.1).
+type SyslogFacility int
+
+// Common syslog facilities.
+const (
+	SyslogFacilityUser   SyslogFacility = 1
+	SyslogFacilityDaemon SyslogFacility = 3
+	SyslogFacilityLocal0 SyslogFacility = 16
+	SyslogFacilityLocal1 SyslogFacility = 17
+	SyslogFacilityLocal2 SyslogFacility = 18
+	SyslogFacilityLocal3 SyslogFacility = 19
+	SyslogFacilityLocal4 SyslogFacility = 20
+	SyslogFacilityLocal5 SyslogFacility = 21
+	SyslogFacilityLocal6 SyslogFacility = 22
+	SyslogFacilityLocal7 SyslogFacility = 23
+)
+
+// defaultSyslogSeverities maps upsmon NOTIFYTYPEs and upper-cased event types
+// to severities; unlisted events are logged as SyslogNotice.
+var defaultSyslogSeverities = map[string]SyslogSeverity{
+	"ONLINE":              SyslogNotice,
+	"ONBATT":              SyslogWarning,
+	"LOWBATT":             SyslogAlert,
+	"FSD":                 SyslogAlert,
+	"REPLBATT":            SyslogWarning,
+	"COMMOK":              SyslogNotice,
+	"COMMBAD":             SyslogError,
+	"VARIABLE_CHANGED":    SyslogInfo,
+	"CLIENT_CONNECTED":    SyslogInfo,
+	"CLIENT_DISCONNECTED": SyslogInfo,
+	"ERROR":               SyslogWarning,
+	"ALERT_RAISED":        SyslogWarning,
+	"ALERT_CLEARED":       SyslogNotice,
+	"SELFTEST_PASSED":     SyslogInfo,
+	"SELFTEST_FAILED":     SyslogError,
+}
+
+// localSyslogSockets are tried in order when SyslogConfig.Address is empty.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig configures a SyslogNotifier.
+type SyslogConfig struct {
+	// Network and Address of the syslog server: "udp", "tcp", "unixgram" or "unix"
+	// and e.g. "logs.example.com:514". An empty Address uses the local
+	// syslog socket.
+	Network string
+	Address string
+
+	Facility SyslogFacility // Default SyslogFacilityDaemon
+	AppName  string         // APP-NAME field (default "nut")
+	Hostname string         // HOSTNAME field (default os.Hostname)
+	Timeout  time.Duration  // Timeout for connecting and writing (default 5s)
+
+	// Severities overrides the severity of events, keyed by upsmon NOTIFYTYPE
+	// (ONLINE, ONBATT, LOWBATT, FSD, REPLBATT, COMMOK, COMMBAD) or upper-cased
+	// event type (e.g. ALERT_RAISED). By default power failures are warnings,
+	// low battery and forced shutdowns alerts, and lost communication errors.
+	Severities map[string]SyslogSeverity
+}
+
+// SyslogNotifier is a Notifier sending events as RFC 5424 syslog messages to a
+// local or remote syslog daemon. The MSGID field holds the upsmon NOTIFYTYPE
+// or upper-cased event type, and the message is as in ExecNotifier. Messages
+// over TCP are framed by octet counting (RFC 6587) and messages over unix
+// stream sockets end with a newline.
+type SyslogNotifier struct {
+	config SyslogConfig
+
+	mu      sync.Mutex
+	conn    net.Conn
+	network string // Network of conn
+}
+
+// NewSyslogNotifier validates config and returns a SyslogNotifier. The
+// connection is established on the first event and re-established after write
+// errors.
+func NewSyslogNotifier(config SyslogConfig) (*SyslogNotifier, error) {
+	if config.Address != "" && config.Network == "" {
+		return nil, fmt.Errorf("syslog network is required with an address")
+	}
+	switch config.Network {
+	case "", "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q", config.Network)
+	}
+	if config.Facility < 0 || config.Facility > SyslogFacilityLocal7 {
+		return nil, fmt.Errorf("invalid syslog facility %d", config.Facility)
+	}
+	if config.Facility == 0 {
+		config.Facility = SyslogFacilityDaemon
+	}
+	if config.AppName == "" {
+		config.AppName = "nut"
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	return &SyslogNotifier{config: config}, nil
+}
+
+// Notify sends event to the syslog daemon.
+func (n *SyslogNotifier) Notify(ctx context.Context, event Event) error {
+	msg := n.format(event)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	// Retry once on a fresh connection, e.g. after the daemon restarted
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if n.conn == nil {
+			if n.conn, n.network, err = n.dial(ctx); err != nil {
+				return fmt.Errorf("connecting to syslog: %w", err)
+			}
+		}
+		n.conn.SetWriteDeadline(time.Now().Add(n.config.Timeout))
+		if _, err = n.conn.Write(frameSyslog(n.network, msg)); err == nil {
+			return nil
+		}
+		n.conn.Close()
+		n.conn = nil
+	}
+	return fmt.Errorf("writing to syslog: %w", err)
+}
+
+// Close closes the connection to the syslog daemon.
+func (n *SyslogNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn = nil
+	return err
+}
+
+// dial connects to the syslog daemon and returns the connection and its
+// network.
+func (n *SyslogNotifier) dial(ctx context.Context) (net.Conn, string, error) {
+	dialer := net.Dialer{Timeout: n.config.Timeout}
+	if n.config.Address != "" {
+		conn, err := dialer.DialContext(ctx, n.config.Network, n.config.Address)
+		return conn, n.config.Network, err
+	}
+	var lastErr error
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := dialer.DialContext(ctx, network, path)
+			if err == nil {
+				return conn, network, nil
+			}
+			lastErr = err
+		}
+	}
+	return nil, "", lastErr
+}
+
+// frameSyslog frames msg for a stream transport: by octet counting over TCP
+// and with a trailing newline over unix stream sockets, as syslog daemons
+// reading /dev/log expect. Datagrams are sent as they are.
+func frameSyslog(network, msg string) []byte {
+	switch {
+	case strings.HasPrefix(network, "tcp"):
+		return []byte(fmt.Sprintf("%d %s", len(msg), msg))
+	case network == "unix":
+		return []byte(msg + "\n")
+	default:
+		return []byte(msg)
+	}
+}
+
+// syslogTimestamp is RFC 3339 with microseconds, the most precision RFC 5424
+// allows in TIMESTAMP.
+const syslogTimestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+// format returns the RFC 5424 message for event.
+func (n *SyslogNotifier) format(event Event) string {
+	notifyType, message := upsmonNotification(event)
+	severity, ok := n.config.Severities[notifyType]
+	if !ok {
+		if severity, ok = defaultSyslogSeverities[notifyType]; !ok {
+			severity = SyslogNotice
+		}
+	}
+	timestamp := event.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		int(n.config.Facility)*8+int(severity),
+		timestamp.Format(syslogTimestamp),
+		syslogField(n.config.Hostname),
+		syslogField(n.config.AppName),
+		os.Getpid(),
+		syslogField(notifyType),
+		message)
+}
+
+// syslogField returns s as a header field: printable ASCII without spaces, or
+// "-" if empty.
+func syslogField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
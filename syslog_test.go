@@ -0,0 +1,83 @@
+package nut_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestSyslogFraming(t *testing.T) {
+	dir, err := os.MkdirTemp("", "syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		network, address string
+		read             func(r *bufio.Reader) (string, error)
+	}{
+		{"unix", filepath.Join(dir, "log"), func(r *bufio.Reader) (string, error) {
+			line, err := r.ReadString('\n')
+			return strings.TrimSuffix(line, "\n"), err
+		}},
+		{"tcp", "127.0.0.1:0", func(r *bufio.Reader) (string, error) {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return "", err
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+			if err != nil {
+				return "", err
+			}
+			msg := make([]byte, n)
+			_, err = io.ReadFull(r, msg)
+			return string(msg), err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			listener, err := net.Listen(tt.network, tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+			notifier, err := nut.NewSyslogNotifier(nut.SyslogConfig{Network: tt.network, Address: listener.Addr().String(), Hostname: "host1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer notifier.Close()
+
+			event := nut.Event{Time: time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC), UPS: "ups1", Type: nut.EventServerDown}
+			for i := 0; i < 2; i++ {
+				if err := notifier.Notify(context.Background(), event); err != nil {
+					t.Fatal(err)
+				}
+			}
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for i := 0; i < 2; i++ {
+				msg, err := tt.read(reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(msg, " 2026-03-01T12:00:00.123456Z host1 nut ") {
+					t.Fatalf("message %d = %q", i, msg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package nut
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+)
+
+// NotificationData is the data passed to notification templates. Event fields
+// are promoted, so {{.Type}} and {{.UPS}} refer to the event.
+type NotificationData struct {
+	Event
+	Snapshot *Snapshot  // Latest snapshot of the event's UPS, nil if unknown
+	Fleet    []Snapshot // Latest snapshots of every UPS known to the source
+}
+
+// SnapshotSource returns the latest UPS snapshots, e.g. Fleet.AllUPS or
+// Monitor.Snapshots, to give notification templates access to UPS state.
+type SnapshotSource func() []Snapshot
+
+// templateFuncs are available in notification templates in addition to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"var": func(snapshot *Snapshot, name string) string {
+		if snapshot == nil {
+			return ""
+		}
+		return snapshot.Variables[name]
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// MessageTemplate renders notification text from a text/template executed with
+// NotificationData. Besides the builtins, templates can use upper, lower, join,
+// default ({{default "n/a" .Variable}}), var ({{var .Snapshot "battery.charge"}})
+// and json, which encodes a value as JSON, quotes included, for JSON bodies
+// ({"text": {{json .UPS}}}).
+type MessageTemplate struct {
+	tmpl   *template.Template
+	source SnapshotSource
+}
+
+// NewMessageTemplate parses text. source may be nil, in which case Snapshot and
+// Fleet are empty when rendering.
+func NewMessageTemplate(text string, source SnapshotSource) (*MessageTemplate, error) {
+	tmpl, err := template.New("notification").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageTemplate{tmpl: tmpl, source: source}, nil
+}
+
+// Render executes the template for event.
+func (t *MessageTemplate) Render(event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, t.data(event)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (t *MessageTemplate) data(event Event) NotificationData {
+	data := NotificationData{Event: event}
+	if t.source == nil {
+		return data
+	}
+	data.Fleet = t.source()
+	for i := range data.Fleet {
+		snapshot := &data.Fleet[i]
+		if snapshot.UPS == event.UPS && (event.Server == "" || snapshot.Server == event.Server) {
+			data.Snapshot = snapshot
+			break
+		}
+	}
+	return data
+}
@@ -0,0 +1,29 @@
+package nut_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestMessageTemplateJSON(t *testing.T) {
+	source := func() []nut.Snapshot {
+		return []nut.Snapshot{{UPS: `rack "A"`, Variables: map[string]string{"battery.charge": "40"}}}
+	}
+	tmpl, err := nut.NewMessageTemplate(`{"text": {{json (printf "%s on %s" .Type .UPS)}}, "charge": {{json (var .Snapshot "battery.charge")}}}`, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := tmpl.Render(nut.Event{Type: nut.EventServerDown, UPS: `rack "A"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]string
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("body %s is not JSON: %v", body, err)
+	}
+	if decoded["text"] != string(nut.EventServerDown)+` on rack "A"` || decoded["charge"] != "40" {
+		t.Fatalf("decoded %v", decoded)
+	}
+}
@@ -0,0 +1,79 @@
+package nut_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+// newSlowListClient returns a client connected to a server that answers LIST
+// VAR with a line every interval.
+func newSlowListClient(t *testing.T, interval time.Duration, opts ...nut.ClientOption) *nut.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+			for _, line := range []string{"BEGIN LIST VAR ups1", `VAR ups1 battery.charge "100"`, `VAR ups1 ups.load "20"`, `VAR ups1 ups.status "OL"`, "END LIST VAR ups1"} {
+				time.Sleep(interval)
+				conn.Write([]byte(line + "\n"))
+			}
+		}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := nut.NewClientFromConn(conn, append([]nut.ClientOption{nut.WithSkipHandshake()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestReadTimeoutBoundsWholeResponse(t *testing.T) {
+	client := newSlowListClient(t, 60*time.Millisecond, nut.WithReadTimeout(200*time.Millisecond))
+	if _, err := nut.NewNUTBackend(client).Variables(context.Background(), "ups1"); err == nil {
+		t.Fatal("a response slower than ReadTimeout succeeded")
+	}
+}
+
+func TestListTimeoutAppliesPerLine(t *testing.T) {
+	client := newSlowListClient(t, 60*time.Millisecond, nut.WithReadTimeout(200*time.Millisecond), nut.WithListTimeout(200*time.Millisecond))
+	values, err := nut.NewNUTBackend(client).Variables(context.Background(), "ups1")
+	if err != nil || len(values) != 3 {
+		t.Fatalf("values = %v, err = %v", values, err)
+	}
+}
+
+func TestRejectedListDoesNotWaitForEnd(t *testing.T) {
+	client := newScriptedClient(t, map[string][]string{"LIST VAR ups9": {"ERR UNKNOWN-UPS"}}, nut.WithReadTimeout(5*time.Second))
+	start := time.Now()
+	_, err := nut.NewNUTBackend(client).Variables(context.Background(), "ups9")
+	if code, _ := nut.ErrorCodeOf(err); code != nut.ErrCodeUnknownUPS {
+		t.Fatalf("err = %v, want UNKNOWN-UPS", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("rejected LIST took %v", elapsed)
+	}
+	if client.Broken() {
+		t.Fatal("a rejected LIST marked the client broken")
+	}
+}
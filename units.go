@@ -0,0 +1,74 @@
+package nut
+
+import "strings"
+
+// Unit is the physical unit of a variable's value.
+type Unit string
+
+// Units of well-known NUT variables.
+const (
+	UnitNone        Unit = ""
+	UnitPercent     Unit = "percent"
+	UnitVolts       Unit = "volts"
+	UnitAmperes     Unit = "amperes"
+	UnitWatts       Unit = "watts"
+	UnitVoltAmperes Unit = "volt_amperes"
+	UnitHertz       Unit = "hertz"
+	UnitSeconds     Unit = "seconds"
+	UnitCelsius     Unit = "celsius"
+	UnitWattHours   Unit = "watt_hours"
+)
+
+// unitsBySegment maps a name segment of the NUT variable catalog to its unit,
+// e.g. "voltage" in input.voltage.nominal. Earlier entries take precedence.
+var unitsBySegment = []struct {
+	segment string
+	unit    Unit
+}{
+	{"energy", UnitWattHours},
+	{"realpower", UnitWatts},
+	{"power", UnitVoltAmperes},
+	{"runtime", UnitSeconds},
+	{"delay", UnitSeconds},
+	{"timer", UnitSeconds},
+	{"interval", UnitSeconds},
+	{"age", UnitSeconds},
+	{"duration", UnitSeconds},
+	{"voltage", UnitVolts},
+	{"transfer", UnitVolts}, // input.transfer.low/high
+	{"current", UnitAmperes},
+	{"frequency", UnitHertz},
+	{"temperature", UnitCelsius},
+	{"charge", UnitPercent},
+	{"load", UnitPercent},
+	{"efficiency", UnitPercent},
+	{"humidity", UnitPercent},
+}
+
+// unitlessSuffixes are final name segments of variables holding states or
+// descriptions rather than measurements, such as input.voltage.status.
+var unitlessSuffixes = map[string]bool{
+	"status":   true,
+	"alarm":    true,
+	"reason":   true,
+	"extended": true,
+	"type":     true,
+	"mode":     true,
+}
+
+// UnitFor returns the unit of a variable from the NUT variable catalog, based
+// on its name, or UnitNone for unitless and unknown variables.
+func UnitFor(name string) Unit {
+	segments := strings.Split(name, ".")
+	if unitlessSuffixes[segments[len(segments)-1]] {
+		return UnitNone
+	}
+	for _, candidate := range unitsBySegment {
+		for _, segment := range segments {
+			if segment == candidate.segment {
+				return candidate.unit
+			}
+		}
+	}
+	return UnitNone
+}
@@ -1,10 +1,13 @@
 package nut
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var numericRegex = regexp.MustCompile(`^-?\d+(?:\.\d+)?$`)
@@ -21,6 +24,12 @@ func quoteName(name string) string {
 }
 
 // UPS contains information about a specific UPS provided by the NUT instance.
+//
+// A UPS is safe for concurrent use: its methods may be called from several
+// goroutines, e.g. a Watcher and an HTTP handler. The exported fields cache the
+// results of GetDescription, GetVariables and similar methods and are updated
+// under an internal lock, so read them with Cached when other goroutines may
+// be calling methods on the same UPS. Copies of a UPS share that lock.
 type UPS struct {
 	Name           string
 	Description    string
@@ -30,17 +39,89 @@ type UPS struct {
 	Variables      []Variable
 	Commands       []Command
 	nutClient      *Client
+
+	// Pre-built GET VAR ups.status command and response prefix for PollStatus
+	statusCmd    []byte
+	statusPrefix []byte
+
+	mu *sync.RWMutex // Guards the cached fields; see lock
+}
+
+// UPSInfo is a consistent copy of the fields cached by a UPS.
+type UPSInfo struct {
+	Name           string
+	Description    string
+	Master         bool
+	NumberOfLogins int
+	Clients        []string
+	Variables      []Variable
+	Commands       []Command
+}
+
+// detachedUPSLock guards UPS values not created by this package.
+var detachedUPSLock sync.RWMutex
+
+// newUPSValue returns a UPS with its lock.
+func newUPSValue(name, description string, client *Client) UPS {
+	return UPS{Name: name, Description: description, nutClient: client, mu: new(sync.RWMutex)}
+}
+
+// lock returns the lock guarding the cached fields.
+func (u *UPS) lock() *sync.RWMutex {
+	if u.mu == nil {
+		return &detachedUPSLock
+	}
+	return u.mu
+}
+
+// setCached updates cached fields under the lock.
+func (u *UPS) setCached(update func()) {
+	mu := u.lock()
+	mu.Lock()
+	update()
+	mu.Unlock()
+}
+
+// Cached returns a copy of the cached fields, safe to read while other
+// goroutines call methods on the UPS. It does not query the server.
+func (u *UPS) Cached() UPSInfo {
+	mu := u.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+	return UPSInfo{
+		Name:           u.Name,
+		Description:    u.Description,
+		Master:         u.Master,
+		NumberOfLogins: u.NumberOfLogins,
+		Clients:        append([]string(nil), u.Clients...),
+		Variables:      append([]Variable(nil), u.Variables...),
+		Commands:       append([]Command(nil), u.Commands...),
+	}
 }
 
 // Variable describes a single variable related to a UPS.
 type Variable struct {
-	Name          string
-	Value         interface{}
-	Type          string
-	Description   string
-	Writeable     bool
+	Name        string
+	Value       interface{} // Decoded value; see Kind
+	Raw         string      // Value as reported by upsd
+	Kind        ValueKind   // Go type of Value
+	ServerType  ServerType  // Type as reported by upsd
+	Enum        []string    // Accepted values of an ENUM variable
+	Ranges      []Range     // Accepted intervals of a RANGE variable
+	Unit        Unit        // Unit from the NUT variable catalog, see UnitFor
+	Description string
+	SampledAt   time.Time // Time the value was read from upsd
+	Stale       bool      // Last known value served because upsd reported DATA-STALE; see WithStaleFallback
+
+	// Deprecated: use Kind. Type is Kind.String().
+	Type string
+	// Deprecated: use ServerType.Writable.
+	Writeable bool
+	// Deprecated: use ServerType.MaxLength.
 	MaximumLength int
-	OriginalType  string
+	// Deprecated: use ServerType. OriginalType is the first type flag reported
+	// by upsd, e.g. "STRING" or "ENUM".
+	OriginalType string
 }
 
 // Command describes an available command for a UPS.
@@ -51,27 +132,17 @@ type Command struct {
 
 // NewUPS takes a UPS name and NUT client and returns an instantiated UPS struct.
 func NewUPS(name string, client *Client) (UPS, error) {
-	newUPS := UPS{
-		Name:      name,
-		nutClient: client,
-	}
+	newUPS := newUPSValue(name, "", client)
 
 	// Only fetch basic info, defer variable/command details to lazy loading
 	_, err := newUPS.GetDescription()
 	if err != nil {
 		// Non-fatal, just log
-		if client.Logger != nil {
-			client.Logger.Printf("Warning: failed to get description for %s: %v", name, err)
-		}
-	}
-
-	_, err = newUPS.GetNumberOfLogins()
-	if err != nil {
-		// Non-fatal, just log
-		if client.Logger != nil {
-			client.Logger.Printf("Warning: failed to get number of logins for %s: %v", name, err)
+		if logger := client.loggerFor(LogWarn); logger != nil {
+			logger.Printf("Warning: failed to get description for %s: %v", name, err)
 		}
 	}
+	newUPS.loadLogins()
 
 	// Don't fetch clients/variables/commands during init - too slow and error-prone
 	// Users can call GetClients(), GetVariables() or GetCommands() when needed
@@ -79,50 +150,64 @@ func NewUPS(name string, client *Client) (UPS, error) {
 	return newUPS, nil
 }
 
+// loadLogins fetches the number of logins. Failures are not fatal when
+// instantiating a UPS and are only logged.
+func (u *UPS) loadLogins() {
+	if _, err := u.GetNumberOfLogins(); err != nil {
+		u.nutClient.logf(LogWarn, "Warning: failed to get number of logins for %s: %v", u.Name, err)
+	}
+}
+
 // GetNumberOfLogins returns the number of clients which have done LOGIN for this UPS.
 func (u *UPS) GetNumberOfLogins() (int, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("GET NUMLOGINS %s", quoteName(u.Name)))
+	cmd := fmt.Sprintf("GET NUMLOGINS %s", quoteName(u.Name))
+	resp, err := u.nutClient.SendCommand(cmd)
 	if err != nil {
 		return 0, err
 	}
 	if len(resp) < 1 {
 		return 0, fmt.Errorf("empty response from GET NUMLOGINS")
 	}
-	atoi, err := strconv.Atoi(strings.TrimPrefix(resp[0], fmt.Sprintf("NUMLOGINS %s ", u.Name)))
+	value, err := u.nutClient.trimPrefix(cmd, resp[0], fmt.Sprintf("NUMLOGINS %s ", u.Name))
+	if err != nil {
+		return 0, err
+	}
+	atoi, err := strconv.Atoi(value)
 	if err != nil {
 		return 0, err
 	}
-	u.NumberOfLogins = atoi
+	u.setCached(func() { u.NumberOfLogins = atoi })
 	return atoi, nil
 }
 
 // GetClients returns a list of NUT clients.
 func (u *UPS) GetClients() ([]string, error) {
-	clientsList := []string{}
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("LIST CLIENT %s", quoteName(u.Name)))
+	clientsList, err := u.getClients(context.Background())
 	if err != nil {
 		return clientsList, err
 	}
-	// Check if response has enough elements to slice safely
-	if len(resp) < 2 {
-		return clientsList, nil
-	}
-	linePrefix := fmt.Sprintf("CLIENT %s ", u.Name)
-	for _, line := range resp[1 : len(resp)-1] {
-		clientsList = append(clientsList, strings.TrimPrefix(line, linePrefix))
-	}
-	u.Clients = clientsList
+	u.setCached(func() { u.Clients = clientsList })
 	return clientsList, nil
 }
 
+func (u *UPS) getClients(ctx context.Context) ([]string, error) {
+	cmd := fmt.Sprintf("LIST CLIENT %s", quoteName(u.Name))
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
+	if err != nil {
+		return []string{}, err
+	}
+	return u.nutClient.listBody(cmd, resp, fmt.Sprintf("CLIENT %s ", u.Name))
+}
+
 // CheckIfMaster returns true if the session is authenticated with the master permission set.
 func (u *UPS) CheckIfMaster() (bool, error) {
 	resp, err := u.nutClient.SendCommand(fmt.Sprintf("MASTER %s", quoteName(u.Name)))
 	if err != nil {
 		return false, err
 	}
-	if len(resp) > 0 && resp[0] == "OK" {
-		u.Master = true
+	// upsd answers "OK MASTER-GRANTED"; older servers a bare "OK"
+	if len(resp) > 0 && strings.HasPrefix(resp[0], "OK") {
+		u.setCached(func() { u.Master = true })
 		return true, nil
 	}
 	return false, nil
@@ -130,207 +215,308 @@ func (u *UPS) CheckIfMaster() (bool, error) {
 
 // GetDescription the value of "desc=" from ups.conf for this UPS. If it is not set, upsd will return "Unavailable".
 func (u *UPS) GetDescription() (string, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("GET UPSDESC %s", quoteName(u.Name)))
+	cmd := fmt.Sprintf("GET UPSDESC %s", quoteName(u.Name))
+	resp, err := u.nutClient.SendCommand(cmd)
 	if err != nil {
 		return "", err
 	}
 	if len(resp) < 1 {
 		return "", fmt.Errorf("empty response from GET UPSDESC")
 	}
-	description := strings.TrimPrefix(strings.ReplaceAll(resp[0], `"`, ""), fmt.Sprintf(`UPSDESC %s `, u.Name))
-	u.Description = description
+	trimmedLine, err := u.nutClient.trimPrefix(cmd, resp[0], fmt.Sprintf("UPSDESC %s ", u.Name))
+	if err != nil {
+		return "", err
+	}
+	description, err := u.nutClient.quotedValue(cmd, trimmedLine)
+	if err != nil {
+		return "", err
+	}
+	u.setCached(func() { u.Description = description })
 	return description, nil
 }
 
 // GetVariables returns a slice of Variable structs for the UPS.
 func (u *UPS) GetVariables() ([]Variable, error) {
 	vars := []Variable{}
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("LIST VAR %s", quoteName(u.Name)))
+	cmd := fmt.Sprintf("LIST VAR %s", quoteName(u.Name))
+	sampledAt := time.Now()
+	resp, err := u.nutClient.SendCommand(cmd)
 	if err != nil {
+		if u.nutClient.staleFallback && hasErrorCode(err, ErrCodeDataStale) {
+			if stale := u.staleVariables(); len(stale) > 0 {
+				return stale, nil
+			}
+		}
 		return vars, err
 	}
-	// Check if response has enough elements to slice safely
-	if len(resp) < 2 {
-		u.Variables = vars
-		return vars, nil
+	lines, err := u.nutClient.listBody(cmd, resp, fmt.Sprintf("VAR %s ", u.Name))
+	if err != nil {
+		return vars, err
 	}
-	offset := fmt.Sprintf("VAR %s ", u.Name)
-	for _, line := range resp[1 : len(resp)-1] {
+	for _, cleanedLine := range lines {
 		newVar := Variable{}
-		cleanedLine := strings.TrimPrefix(line, offset)
-		splitLine := strings.Split(cleanedLine, `"`)
+		name, quoted, found := strings.Cut(cleanedLine, " ")
+		value, _, ok := parseQuoted(quoted)
 
-		// Validate that we have enough parts after splitting
-		if len(splitLine) < 2 {
+		// Validate that the line holds a name and a quoted value
+		if !found || !ok {
+			if err := u.nutClient.malformed(cmd, cleanedLine, "expected quoted value"); err != nil {
+				return vars, err
+			}
 			continue // Skip malformed lines
 		}
 
-		splitLine[1] = strings.Trim(splitLine[1], " ")
-		newVar.Name = strings.TrimSuffix(splitLine[0], " ")
-		newVar.Value = splitLine[1]
+		newVar.Name = name
+		newVar.Raw = value
+		newVar.Unit = UnitFor(name)
+		newVar.SampledAt = sampledAt
 
 		description, err := u.GetVariableDescription(newVar.Name)
 		if err != nil {
 			return vars, err
 		}
 		newVar.Description = description
-		varType, writeable, maximumLength, err := u.GetVariableType(newVar.Name)
+		serverType, err := u.GetVariableServerType(newVar.Name)
 		if err != nil {
 			return vars, err
 		}
-		newVar.Type = varType
-		newVar.Writeable = writeable
-		newVar.MaximumLength = maximumLength
-
-		// Check for boolean values first
-		switch splitLine[1] {
-		case "enabled":
-			newVar.Value = true
-			newVar.Type = "BOOLEAN"
-			newVar.OriginalType = varType
-		case "disabled":
-			newVar.Value = false
-			newVar.Type = "BOOLEAN"
-			newVar.OriginalType = varType
-		default:
-			// Try numeric conversion
-			matched := numericRegex.MatchString(splitLine[1])
-			if matched {
-				// Try float first (handles both int and float strings)
-				if strings.Contains(splitLine[1], ".") {
-					converted, err := strconv.ParseFloat(splitLine[1], 64)
-					if err == nil {
-						newVar.Value = converted
-						newVar.Type = "FLOAT_64"
-						newVar.OriginalType = varType
-					}
-				} else {
-					converted, err := strconv.ParseInt(splitLine[1], 10, 64)
-					if err == nil {
-						newVar.Value = converted
-						newVar.Type = "INTEGER"
-						newVar.OriginalType = varType
-					}
-				}
+		newVar.ServerType = serverType
+		if u.nutClient.rawValues {
+			newVar.Value, newVar.Kind = value, ValueString
+		} else {
+			newVar.Value, newVar.Kind = serverType.decode(strings.Trim(value, " "))
+		}
+		if serverType.Enum {
+			if newVar.Enum, err = u.GetVariableEnum(newVar.Name); err != nil {
+				return vars, err
 			}
-
-			// If not boolean or numeric, keep as STRING
-			if newVar.Type == varType {
-				newVar.Type = "STRING"
-				newVar.OriginalType = varType
+		}
+		if serverType.Range {
+			if newVar.Ranges, err = u.GetVariableRanges(newVar.Name); err != nil {
+				return vars, err
 			}
 		}
 
+		// Compatibility fields
+		newVar.Type = newVar.Kind.String()
+		newVar.OriginalType = serverType.legacyType()
+		newVar.Writeable = serverType.Writable
+		newVar.MaximumLength = serverType.MaxLength
+
 		vars = append(vars, newVar)
 	}
-	u.Variables = vars
+	u.setCached(func() { u.Variables = vars })
+	if u.nutClient.staleFallback {
+		values := make(map[string]string, len(vars))
+		for _, v := range vars {
+			values[v.Name] = v.Raw
+		}
+		u.nutClient.rememberValues(u.Name, values)
+	}
 	return vars, nil
 }
 
-// GetVariableDescription returns a string that gives a brief explanation for the given variableName.
-// upsd may return "Unavailable" if the file which provides this description is not installed.
-func (u *UPS) GetVariableDescription(variableName string) (string, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("GET DESC %s %s", quoteName(u.Name), quoteName(variableName)))
+// staleVariables returns copies of the variables from the last successful
+// GetVariables, marked stale.
+func (u *UPS) staleVariables() []Variable {
+	mu := u.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+	vars := make([]Variable, len(u.Variables))
+	for i, v := range u.Variables {
+		v.Stale = true
+		vars[i] = v
+	}
+	return vars
+}
+
+// GetVariableValue returns the current raw value of a single variable using GET VAR.
+func (u *UPS) GetVariableValue(variableName string) (string, error) {
+	return u.getVariableValue(context.Background(), variableName)
+}
+
+func (u *UPS) getVariableValue(ctx context.Context, variableName string) (string, error) {
+	cmd := fmt.Sprintf("GET VAR %s %s", quoteName(u.Name), quoteName(variableName))
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
 	if err != nil {
+		if u.nutClient.staleFallback && hasErrorCode(err, ErrCodeDataStale) {
+			if value, ok := u.nutClient.lastValue(u.Name, variableName); ok {
+				return value, nil
+			}
+		}
 		return "", err
 	}
 	if len(resp) < 1 {
-		return "", fmt.Errorf("empty response from GET DESC")
+		return "", fmt.Errorf("empty response from GET VAR")
 	}
-	trimmedLine := strings.TrimPrefix(resp[0], fmt.Sprintf("DESC %s %s ", u.Name, variableName))
-	description := strings.ReplaceAll(trimmedLine, `"`, "")
-	return description, nil
+	trimmedLine, err := u.nutClient.trimPrefix(cmd, resp[0], fmt.Sprintf("VAR %s %s ", u.Name, variableName))
+	if err != nil {
+		return "", err
+	}
+	value, err := u.nutClient.quotedValue(cmd, trimmedLine)
+	if err == nil && u.nutClient.staleFallback {
+		u.nutClient.rememberValues(u.Name, map[string]string{variableName: value})
+	}
+	return value, err
 }
 
-// GetVariableType returns the variable type, writeability and maximum length for the given variableName.
-func (u *UPS) GetVariableType(variableName string) (string, bool, int, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("GET TYPE %s %s", quoteName(u.Name), quoteName(variableName)))
+// variableValues returns the raw values of all variables from a single LIST VAR,
+// without fetching descriptions or types.
+func (u *UPS) variableValues(ctx context.Context) (map[string]string, error) {
+	values := map[string]string{}
+	cmd := fmt.Sprintf("LIST VAR %s", quoteName(u.Name))
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
 	if err != nil {
-		return "UNKNOWN", false, -1, err
+		if u.nutClient.staleFallback && hasErrorCode(err, ErrCodeDataStale) {
+			if last := u.nutClient.lastVariableValues(u.Name); len(last) > 0 {
+				return last, nil
+			}
+		}
+		return values, err
 	}
-	if len(resp) < 1 {
-		return "UNKNOWN", false, -1, fmt.Errorf("empty response from GET TYPE")
+	lines, err := u.nutClient.listBody(cmd, resp, fmt.Sprintf("VAR %s ", u.Name))
+	if err != nil {
+		return values, err
 	}
-
-	// DEBUG: Log the raw response
-	if u.nutClient.Logger != nil {
-		u.nutClient.Logger.Printf("DEBUG GET TYPE response for %s: %#v", variableName, resp)
+	for _, line := range lines {
+		name, quoted, _ := strings.Cut(line, " ")
+		value, err := u.nutClient.quotedValue(cmd, quoted)
+		if err != nil {
+			return values, err
+		}
+		values[name] = value
 	}
+	if u.nutClient.staleFallback {
+		u.nutClient.rememberValues(u.Name, values)
+	}
+	return values, nil
+}
 
-	trimmedLine := strings.TrimPrefix(resp[0], fmt.Sprintf("TYPE %s %s ", u.Name, variableName))
-
-	// DEBUG: Log after trimming
-	if u.nutClient.Logger != nil {
-		u.nutClient.Logger.Printf("DEBUG trimmed line: %q", trimmedLine)
+// rememberValues records values read from upsd for WithStaleFallback.
+func (c *Client) rememberValues(ups string, values map[string]string) {
+	c.staleMu.Lock()
+	defer c.staleMu.Unlock()
+	if c.lastValues == nil {
+		c.lastValues = map[string]map[string]string{}
 	}
+	last := c.lastValues[ups]
+	if last == nil {
+		last = map[string]string{}
+		c.lastValues[ups] = last
+	}
+	for name, value := range values {
+		last[name] = value
+	}
+}
 
-	splitLine := strings.Split(trimmedLine, " ")
+// lastValue returns the last value of a variable read on the client.
+func (c *Client) lastValue(ups, name string) (string, bool) {
+	c.staleMu.Lock()
+	defer c.staleMu.Unlock()
+	value, ok := c.lastValues[ups][name]
+	return value, ok
+}
 
-	// DEBUG: Log split result
-	if u.nutClient.Logger != nil {
-		u.nutClient.Logger.Printf("DEBUG split line: %#v (len=%d)", splitLine, len(splitLine))
+// lastVariableValues returns a copy of the last values read for a UPS on the
+// client.
+func (c *Client) lastVariableValues(ups string) map[string]string {
+	c.staleMu.Lock()
+	defer c.staleMu.Unlock()
+	values := make(map[string]string, len(c.lastValues[ups]))
+	for name, value := range c.lastValues[ups] {
+		values[name] = value
 	}
+	return values
+}
 
-	if len(splitLine) < 1 {
-		return "UNKNOWN", false, -1, fmt.Errorf("invalid TYPE response format")
-	}
+// Range is an interval of values accepted by a writable variable.
+type Range struct {
+	Min float64
+	Max float64
+}
 
-	writeable := false
-	varType := "UNKNOWN"
-	maximumLength := 0
+// Contains reports whether value lies within the range, inclusive.
+func (r Range) Contains(value float64) bool {
+	return value >= r.Min && value <= r.Max
+}
 
-	// Check if response includes RW/RO flag (newer NUT versions)
-	// or just the type (older NUT versions)
-	if len(splitLine) >= 2 && (splitLine[0] == "RW" || splitLine[0] == "RO") {
-		// Format: "RW TYPE" or "RO TYPE"
-		writeable = (splitLine[0] == "RW")
-		varType = splitLine[1]
-	} else if len(splitLine) >= 1 {
-		// Format: "TYPE" only (older NUT servers don't send RW/RO)
-		// Assume read-only by default for safety
-		writeable = false
-		varType = splitLine[0]
+// GetVariableRanges returns the ranges of values accepted by variableName, as
+// reported by LIST RANGE. An empty slice means the variable has no range
+// constraint.
+func (u *UPS) GetVariableRanges(variableName string) ([]Range, error) {
+	return u.getVariableRanges(context.Background(), variableName)
+}
 
-		if u.nutClient.Logger != nil {
-			u.nutClient.Logger.Printf("Note: variable %s TYPE response has no RW/RO flag (old NUT version), assuming read-only", variableName)
+func (u *UPS) getVariableRanges(ctx context.Context, variableName string) ([]Range, error) {
+	ranges := []Range{}
+	cmd := fmt.Sprintf("LIST RANGE %s %s", quoteName(u.Name), quoteName(variableName))
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
+	if err != nil {
+		return ranges, err
+	}
+	lines, err := u.nutClient.listBody(cmd, resp, fmt.Sprintf("RANGE %s %s ", u.Name, variableName))
+	if err != nil {
+		return ranges, err
+	}
+	for _, line := range lines {
+		minValue, rest, okMin := parseQuoted(line)
+		maxValue, _, okMax := parseQuoted(strings.TrimPrefix(rest, " "))
+		if !okMin || !okMax {
+			if err := u.nutClient.malformed(cmd, line, "expected quoted minimum and maximum"); err != nil {
+				return ranges, err
+			}
+			continue
 		}
-	} else {
-		if u.nutClient.Logger != nil {
-			u.nutClient.Logger.Printf("Warning: variable %s has incomplete TYPE info: %q", variableName, trimmedLine)
+		lo, errMin := strconv.ParseFloat(minValue, 64)
+		hi, errMax := strconv.ParseFloat(maxValue, 64)
+		if errMin != nil || errMax != nil {
+			if err := u.nutClient.malformed(cmd, line, "non-numeric range"); err != nil {
+				return ranges, err
+			}
+			continue
 		}
-		return "UNKNOWN", writeable, -1, fmt.Errorf("invalid TYPE response format: got empty response after parsing")
+		ranges = append(ranges, Range{Min: lo, Max: hi})
 	}
+	return ranges, nil
+}
 
-	// Handle STRING:length format for both RW and RO
-	if strings.HasPrefix(varType, "STRING:") {
-		splitType := strings.Split(varType, ":")
-		if len(splitType) >= 2 {
-			varType = splitType[0]
-			maximumLength, err = strconv.Atoi(splitType[1])
-			if err != nil {
-				return varType, writeable, -1, err
-			}
-		}
+// GetVariableDescription returns a string that gives a brief explanation for the given variableName.
+// upsd may return "Unavailable" if the file which provides this description is not installed.
+func (u *UPS) GetVariableDescription(variableName string) (string, error) {
+	cmd := fmt.Sprintf("GET DESC %s %s", quoteName(u.Name), quoteName(variableName))
+	resp, err := u.nutClient.SendCommand(cmd)
+	if err != nil {
+		return "", err
+	}
+	if len(resp) < 1 {
+		return "", fmt.Errorf("empty response from GET DESC")
+	}
+	trimmedLine, err := u.nutClient.trimPrefix(cmd, resp[0], fmt.Sprintf("DESC %s %s ", u.Name, variableName))
+	if err != nil {
+		return "", err
 	}
+	return u.nutClient.quotedValue(cmd, trimmedLine)
+}
 
-	return varType, writeable, maximumLength, nil
+// GetVariableType returns the variable type, writeability and maximum length for the given variableName.
+//
+// Deprecated: use GetVariableServerType, which reports every type flag.
+func (u *UPS) GetVariableType(variableName string) (string, bool, int, error) {
+	serverType, err := u.GetVariableServerType(variableName)
+	if err != nil {
+		return "UNKNOWN", false, -1, err
+	}
+	return serverType.legacyType(), serverType.Writable, serverType.MaxLength, nil
 }
 
 // GetCommands returns a slice of Command structs for the UPS.
 func (u *UPS) GetCommands() ([]Command, error) {
 	commandsList := []Command{}
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("LIST CMD %s", quoteName(u.Name)))
+	names, err := u.commandNames()
 	if err != nil {
 		return commandsList, err
 	}
-	// Check if response has enough elements to slice safely
-	if len(resp) < 2 {
-		u.Commands = commandsList
-		return commandsList, nil
-	}
-	linePrefix := fmt.Sprintf("CMD %s ", u.Name)
-	for _, line := range resp[1 : len(resp)-1] {
-		cmdName := strings.TrimPrefix(line, linePrefix)
+	for _, cmdName := range names {
 		cmd := Command{
 			Name: cmdName,
 		}
@@ -341,31 +527,54 @@ func (u *UPS) GetCommands() ([]Command, error) {
 		cmd.Description = description
 		commandsList = append(commandsList, cmd)
 	}
-	u.Commands = commandsList
+	u.setCached(func() { u.Commands = commandsList })
 	return commandsList, nil
 }
 
 // GetCommandDescription returns a string that gives a brief explanation for the given commandName.
 func (u *UPS) GetCommandDescription(commandName string) (string, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("GET CMDDESC %s %s", quoteName(u.Name), quoteName(commandName)))
+	cmd := fmt.Sprintf("GET CMDDESC %s %s", quoteName(u.Name), quoteName(commandName))
+	resp, err := u.nutClient.SendCommand(cmd)
 	if err != nil {
 		return "", err
 	}
 	if len(resp) < 1 {
 		return "", fmt.Errorf("empty response from GET CMDDESC")
 	}
-	trimmedLine := strings.TrimPrefix(resp[0], fmt.Sprintf("CMDDESC %s %s ", u.Name, commandName))
-	description := strings.ReplaceAll(trimmedLine, `"`, "")
-	return description, nil
+	trimmedLine, err := u.nutClient.trimPrefix(cmd, resp[0], fmt.Sprintf("CMDDESC %s %s ", u.Name, commandName))
+	if err != nil {
+		return "", err
+	}
+	return u.nutClient.quotedValue(cmd, trimmedLine)
 }
 
 // SetVariable sets the given variableName to the given value on the UPS.
-func (u *UPS) SetVariable(variableName, value string) (bool, error) {
+func (u *UPS) SetVariable(variableName, value string) (ok bool, err error) {
+	return u.setVariable(context.Background(), variableName, value)
+}
+
+func (u *UPS) setVariable(ctx context.Context, variableName, value string) (ok bool, err error) {
+	defer func() { u.audit("SET", variableName, value, ok, err) }()
+
 	// Escape backslashes and quotes in the value
 	escapedValue := strings.ReplaceAll(value, `\`, `\\`)
 	escapedValue = strings.ReplaceAll(escapedValue, `"`, `\"`)
 
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf(`SET VAR %s %s "%s"`, quoteName(u.Name), quoteName(variableName), escapedValue))
+	cmd := fmt.Sprintf(`SET VAR %s %s "%s"`, quoteName(u.Name), quoteName(variableName), escapedValue)
+	if u.nutClient.dryRun {
+		return false, u.dryRun(cmd, func() error {
+			serverType, err := u.GetVariableServerType(variableName)
+			if err != nil {
+				return err
+			}
+			if !serverType.Writable {
+				return errorForMessage(ErrCodeReadOnly)
+			}
+			return nil
+		})
+	}
+
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
 	if err != nil {
 		return false, err
 	}
@@ -375,9 +584,133 @@ func (u *UPS) SetVariable(variableName, value string) (bool, error) {
 	return false, nil
 }
 
+// VerifyOption configures SetVariableVerified.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	delay time.Duration
+}
+
+// WithVerifyDelay waits d between the SET and the read-back, for drivers that
+// apply values asynchronously.
+func WithVerifyDelay(d time.Duration) VerifyOption {
+	return func(v *verifyConfig) {
+		v.delay = d
+	}
+}
+
+// SetVariableVerified sets variableName to value, then reads the variable back
+// and returns a *SetMismatchError if the driver did not apply the value. Numeric
+// values are compared numerically, so "30" matches "30.0".
+func (u *UPS) SetVariableVerified(ctx context.Context, variableName, value string, opts ...VerifyOption) error {
+	config := verifyConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	ok, err := u.setVariable(ctx, variableName, value)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("SET VAR %s: unexpected response", variableName)
+	}
+
+	if config.delay > 0 {
+		timer := time.NewTimer(config.delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	actual, err := u.getVariableValue(ctx, variableName)
+	if err != nil {
+		return fmt.Errorf("reading back %s: %w", variableName, err)
+	}
+	if !sameValue(actual, value) {
+		return &SetMismatchError{Variable: variableName, Expected: value, Actual: actual}
+	}
+	return nil
+}
+
+// sameValue reports whether two variable values are equal, ignoring surrounding
+// spaces and numeric formatting.
+func sameValue(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == b {
+		return true
+	}
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	return errA == nil && errB == nil && fa == fb
+}
+
+// commandNames returns the names of the instant commands supported by the UPS
+// without fetching their descriptions.
+func (u *UPS) commandNames() ([]string, error) {
+	cmd := fmt.Sprintf("LIST CMD %s", quoteName(u.Name))
+	resp, err := u.nutClient.SendCommand(cmd)
+	if err != nil {
+		return []string{}, err
+	}
+	return u.nutClient.listBody(cmd, resp, fmt.Sprintf("CMD %s ", u.Name))
+}
+
+// dryRun validates a state-changing command without sending it and returns the
+// *DryRunError describing it, or the validation error.
+func (u *UPS) dryRun(cmd string, validate func() error) error {
+	if err := validate(); err != nil {
+		if logger := u.nutClient.loggerFor(LogWarn); logger != nil {
+			logger.Printf("Dry run: validation failed for %s: %v", cmd, err)
+		}
+		return err
+	}
+	if logger := u.nutClient.loggerFor(LogInfo); logger != nil {
+		logger.Printf("Dry run: would send %s", cmd)
+	}
+	return &DryRunError{Command: cmd}
+}
+
 // SendCommand sends a command to the UPS.
-func (u *UPS) SendCommand(commandName string) (bool, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("INSTCMD %s %s", quoteName(u.Name), quoteName(commandName)))
+func (u *UPS) SendCommand(commandName string) (ok bool, err error) {
+	return u.sendCommand(context.Background(), commandName)
+}
+
+func (u *UPS) sendCommand(ctx context.Context, commandName string) (ok bool, err error) {
+	return u.instCmd(ctx, commandName, false)
+}
+
+// instCmd sends an instant command. Destructive commands are refused unless
+// the client allows them or the caller has confirmed them.
+func (u *UPS) instCmd(ctx context.Context, commandName string, confirmed bool) (ok bool, err error) {
+	defer func() { u.audit("INSTCMD", commandName, "", ok, err) }()
+
+	if !confirmed && !u.nutClient.allowDestructive && isDestructiveCommand(commandName) {
+		return false, fmt.Errorf("%s: %w", commandName, ErrDestructiveNotAllowed)
+	}
+	if confirmed {
+		ctx = withDestructiveConfirmed(ctx)
+	}
+	cmd := fmt.Sprintf("INSTCMD %s %s", quoteName(u.Name), quoteName(commandName))
+	if u.nutClient.dryRun {
+		return false, u.dryRun(cmd, func() error {
+			names, err := u.commandNames()
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if name == commandName {
+					return nil
+				}
+			}
+			return errorForMessage(ErrCodeCmdNotSupported)
+		})
+	}
+
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
 	if err != nil {
 		return false, err
 	}
@@ -387,17 +720,150 @@ func (u *UPS) SendCommand(commandName string) (bool, error) {
 	return false, nil
 }
 
+// isDestructiveCommand reports whether an instant command cuts power to the
+// load: shutdown.*, load.off, load.off.delay and load.cycle, also per outlet.
+func isDestructiveCommand(name string) bool {
+	if rest, ok := strings.CutPrefix(name, "outlet."); ok {
+		if _, command, found := strings.Cut(rest, "."); found {
+			name = command
+		}
+	}
+	return strings.HasPrefix(name, "shutdown.") || name == "load.off" || name == "load.off.delay" || name == "load.cycle"
+}
+
+type destructiveConfirmedKey struct{}
+
+// withDestructiveConfirmed returns a context under which destructive commands
+// are sent even without WithAllowDestructive, for callers that obtained
+// confirmation otherwise, such as Shutdown with ConfirmShutdown.
+func withDestructiveConfirmed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, destructiveConfirmedKey{}, true)
+}
+
+// checkDestructive refuses FSD and INSTCMD requests for destructive commands
+// unless the client allows them or ctx carries a confirmation. It guards every
+// command, including raw ones sent with SendCommand or Do.
+func (c *Client) checkDestructive(ctx context.Context, cmd string) error {
+	if c.allowDestructive {
+		return nil
+	}
+	fields, err := splitFields(cmd, false)
+	if err != nil || len(fields) < 2 {
+		return nil // Not a well-formed FSD or INSTCMD; upsd rejects it
+	}
+	var refused string
+	switch {
+	case strings.EqualFold(fields[0], "FSD"):
+		refused = "FSD"
+	case strings.EqualFold(fields[0], "INSTCMD") && len(fields) >= 3 && isDestructiveCommand(fields[2]):
+		refused = fields[2]
+	default:
+		return nil
+	}
+	if confirmed, _ := ctx.Value(destructiveConfirmedKey{}).(bool); confirmed {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", refused, ErrDestructiveNotAllowed)
+}
+
+// CommandResult is the outcome of one instant command in SendCommands.
+type CommandResult struct {
+	Name    string
+	OK      bool  // upsd acknowledged the command
+	Err     error // Error sending the command
+	Skipped bool  // Not sent because an earlier command failed
+}
+
+// CommandsOption configures SendCommands.
+type CommandsOption func(*commandsConfig)
+
+type commandsConfig struct {
+	interval time.Duration
+}
+
+// WithCommandInterval waits d between successive commands in SendCommands.
+func WithCommandInterval(d time.Duration) CommandsOption {
+	return func(c *commandsConfig) {
+		c.interval = d
+	}
+}
+
+// SendCommands sends the instant commands in order and returns a result per
+// command. With stopOnError set, the remaining commands are skipped after the
+// first one that fails or is not acknowledged. The returned error reports the
+// first failure.
+func (u *UPS) SendCommands(ctx context.Context, names []string, stopOnError bool, opts ...CommandsOption) ([]CommandResult, error) {
+	config := commandsConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	results := make([]CommandResult, len(names))
+	var firstErr error
+	for i, name := range names {
+		results[i].Name = name
+		if firstErr != nil && stopOnError {
+			results[i].Skipped = true
+			continue
+		}
+
+		if i > 0 && config.interval > 0 {
+			timer := time.NewTimer(config.interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				results[i].Err = ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if results[i].Err == nil {
+			results[i].OK, results[i].Err = u.sendCommand(ctx, name)
+		}
+
+		if firstErr == nil {
+			if results[i].Err != nil {
+				firstErr = fmt.Errorf("INSTCMD %s: %w", name, results[i].Err)
+			} else if !results[i].OK {
+				firstErr = fmt.Errorf("INSTCMD %s: unexpected response", name)
+			}
+		}
+	}
+	return results, firstErr
+}
+
 // ForceShutdown sets the FSD flag on the UPS.
 //
 // This requires "upsmon master" in upsd.users, or "FSD" action granted in upsd.users
 //
+// The client must be created with WithAllowDestructive; otherwise
+// ErrDestructiveNotAllowed is returned and nothing is sent.
+//
 // upsmon in master mode is the primary user of this function. It sets this "forced shutdown" flag on any UPS when it plans to power it off. This is done so that slave systems will know about it and shut down before the power disappears.
 //
 // Setting this flag makes "FSD" appear in a STATUS request for this UPS. Finding "FSD" in a status request should be treated just like a "OB LB".
 //
 // It should be noted that FSD is currently a latch - once set, there is no way to clear it short of restarting upsd or dropping then re-adding it in the ups.conf. This may cause issues when upsd is running on a system that is not shut down due to the UPS event.
-func (u *UPS) ForceShutdown() (bool, error) {
-	resp, err := u.nutClient.SendCommand(fmt.Sprintf("FSD %s", quoteName(u.Name)))
+func (u *UPS) ForceShutdown() (ok bool, err error) {
+	defer func() { u.audit("FSD", "", "", ok, err) }()
+
+	if !u.nutClient.allowDestructive {
+		return false, fmt.Errorf("FSD: %w", ErrDestructiveNotAllowed)
+	}
+	cmd := fmt.Sprintf("FSD %s", quoteName(u.Name))
+	if u.nutClient.dryRun {
+		return false, u.dryRun(cmd, func() error {
+			master, err := u.probePrimary()
+			if err != nil {
+				return err
+			}
+			if !master {
+				return errorForMessage(ErrCodeAccessDenied)
+			}
+			return nil
+		})
+	}
+
+	resp, err := u.nutClient.SendCommand(cmd)
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,156 @@
+package nut_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+	"github.com/bearx3f/go.nut/nuttest"
+)
+
+// newTestServer serves ups1 with the given instant commands and returns the
+// server and a client connected to it.
+func newTestServer(t *testing.T, commands []string, opts ...nut.ClientOption) (*nuttest.Server, *nut.Client) {
+	t.Helper()
+	server, err := nuttest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	server.AddUPS("ups1", "Test UPS", map[string]string{"ups.status": "OL"}, commands...)
+
+	host, port := server.HostPort()
+	client, err := nut.ConnectWithOptionsAndConfig(context.Background(), host, opts, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestDestructiveCommandsRefusedOnEveryPath(t *testing.T) {
+	commands := []string{"load.off", "load.cycle", "outlet.1.load.cycle", "shutdown.return", "beeper.disable"}
+	server, client := newTestServer(t, commands)
+	ctx := context.Background()
+
+	refused := []struct {
+		name string
+		send func() error
+	}{
+		{"SendCommand load.off", func() error { _, err := client.SendCommand("INSTCMD ups1 load.off"); return err }},
+		{"SendCommand quoted load.cycle", func() error { _, err := client.SendCommand(`INSTCMD "ups1" "load.cycle"`); return err }},
+		{"SendCommandWithContext FSD", func() error { _, err := client.SendCommandWithContext(ctx, "FSD ups1"); return err }},
+		{"SendCommand lowercase fsd", func() error { _, err := client.SendCommand("fsd ups1"); return err }},
+		{"Do outlet load.cycle", func() error {
+			_, err := client.Do(ctx, nut.Request{Verb: "INSTCMD", Args: []string{"ups1", "outlet.1.load.cycle"}})
+			return err
+		}},
+		{"Do shutdown.return", func() error {
+			_, err := client.Do(ctx, nut.Request{Verb: "INSTCMD", Args: []string{"ups1", "shutdown.return"}})
+			return err
+		}},
+	}
+	for _, tt := range refused {
+		if err := tt.send(); !errors.Is(err, nut.ErrDestructiveNotAllowed) {
+			t.Errorf("%s: err = %v, want ErrDestructiveNotAllowed", tt.name, err)
+		}
+	}
+	if issued := server.Commands("ups1"); len(issued) != 0 {
+		t.Fatalf("refused commands reached the server: %v", issued)
+	}
+
+	if _, err := client.SendCommand("INSTCMD ups1 beeper.disable"); err != nil {
+		t.Fatalf("non-destructive command refused: %v", err)
+	}
+}
+
+func TestDestructiveCommandsAllowed(t *testing.T) {
+	server, client := newTestServer(t, []string{"load.cycle"}, nut.WithAllowDestructive())
+	if _, err := client.SendCommand("INSTCMD ups1 load.cycle"); err != nil {
+		t.Fatal(err)
+	}
+	if issued := server.Commands("ups1"); len(issued) != 1 || issued[0] != "load.cycle" {
+		t.Fatalf("server received %v", issued)
+	}
+}
+
+func TestShutdownConfirmedWithoutAllowDestructive(t *testing.T) {
+	server, client := newTestServer(t, []string{"shutdown.return"})
+	ups, err := nut.NewUPS("ups1", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := ups.Shutdown(context.Background(), nut.ShutdownReturn, nut.ConfirmShutdown())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued := server.Commands("ups1"); sent != "shutdown.return" || len(issued) != 1 || issued[0] != sent {
+		t.Fatalf("sent %q, server received %v", sent, issued)
+	}
+}
+
+func TestSendCommandRefusesDestructive(t *testing.T) {
+	_, client := newTestServer(t, []string{"outlet.2.load.off", "load.cycle", "outlet.1.load.on"})
+	ups, err := nut.NewUPS("ups1", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		command string
+		refused bool
+	}{
+		{"outlet.2.load.off", true},
+		{"load.cycle", true},
+		{"outlet.1.load.on", false},
+	}
+	for _, tt := range tests {
+		_, err := ups.SendCommand(tt.command)
+		if got := errors.Is(err, nut.ErrDestructiveNotAllowed); got != tt.refused {
+			t.Errorf("SendCommand(%q): err = %v, refused = %v, want %v", tt.command, err, got, tt.refused)
+		}
+	}
+}
+
+func TestCheckIfMaster(t *testing.T) {
+	_, client := newTestServer(t, nil)
+	ups, err := nut.NewUPS("ups1", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := ups.CheckIfMaster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !master {
+		t.Fatal("OK MASTER-GRANTED not recognized")
+	}
+}
+
+func TestForceShutdownDryRun(t *testing.T) {
+	_, client := newTestServer(t, nil, nut.WithAllowDestructive(), nut.WithDryRun())
+	ups, err := nut.NewUPS("ups1", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ups.ForceShutdown()
+	var dryRun *nut.DryRunError
+	if !errors.As(err, &dryRun) || dryRun.Command != "FSD ups1" {
+		t.Fatalf("err = %v, want a dry run of FSD ups1", err)
+	}
+}
+
+func TestShutdownReturnRequiresShutdownReturn(t *testing.T) {
+	server, client := newTestServer(t, []string{"load.off.delay"})
+	ups, err := nut.NewUPS("ups1", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ups.Shutdown(context.Background(), nut.ShutdownReturn, nut.ConfirmShutdown())
+	if code, _ := nut.ErrorCodeOf(err); code != nut.ErrCodeCmdNotSupported {
+		t.Fatalf("err = %v, want CMD-NOT-SUPPORTED", err)
+	}
+	if issued := server.Commands("ups1"); len(issued) != 0 {
+		t.Fatalf("server received %v", issued)
+	}
+}
@@ -0,0 +1,168 @@
+package nut
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MonitorDirective is a MONITOR line of upsmon.conf:
+//
+//	MONITOR <upsname>@<host>[:<port>] <powervalue> <username> <password> (primary|secondary)
+type MonitorDirective struct {
+	Device     DeviceSpec
+	PowerValue int // Number of power supplies of this system fed by the UPS
+	Username   string
+	Password   string
+	Primary    bool // "primary" (or the legacy "master") rather than "secondary"
+}
+
+// ParseMonitorDirective parses a MONITOR line. The password may be quoted.
+func ParseMonitorDirective(line string) (MonitorDirective, error) {
+	fields, err := splitConfigLine(line)
+	if err != nil {
+		return MonitorDirective{}, err
+	}
+	// Errors name fields rather than quoting the line, which holds the password
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "MONITOR") {
+		return MonitorDirective{}, fmt.Errorf("invalid MONITOR directive: expected MONITOR <system> <powervalue> <username> <password> <type>")
+	}
+	if len(fields) != 6 {
+		return MonitorDirective{}, fmt.Errorf("invalid MONITOR directive: expected 6 fields, got %d", len(fields))
+	}
+
+	device, err := ParseDeviceSpec(fields[1])
+	if err != nil {
+		return MonitorDirective{}, err
+	}
+	powerValue, err := strconv.Atoi(fields[2])
+	if err != nil || powerValue < 0 {
+		return MonitorDirective{}, fmt.Errorf("invalid power value %q in MONITOR directive", fields[2])
+	}
+	directive := MonitorDirective{
+		Device:     device,
+		PowerValue: powerValue,
+		Username:   fields[3],
+		Password:   fields[4],
+	}
+	switch strings.ToLower(fields[5]) {
+	case "primary", "master":
+		directive.Primary = true
+	case "secondary", "slave":
+	default:
+		return MonitorDirective{}, fmt.Errorf("invalid type %q in MONITOR directive: expected primary or secondary", fields[5])
+	}
+	return directive, nil
+}
+
+// MonitorConfig returns the configuration of a Monitor for the directive's UPS.
+func (d MonitorDirective) MonitorConfig() MonitorConfig {
+	return MonitorConfig{
+		Host:     d.Device.Host,
+		Port:     d.Device.Port,
+		Username: d.Username,
+		Password: d.Password,
+		UPS:      []string{d.Device.UPS},
+	}
+}
+
+// UpsmonConfig holds the directives of upsmon.conf that drive monitoring.
+type UpsmonConfig struct {
+	Monitors    []MonitorDirective
+	MinSupplies int // MINSUPPLIES, default 1
+}
+
+// ParseUpsmonConf reads the MONITOR and MINSUPPLIES directives of an
+// upsmon.conf file. Other directives and comments are ignored.
+func ParseUpsmonConf(r io.Reader) (UpsmonConfig, error) {
+	config := UpsmonConfig{MinSupplies: 1}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields, err := splitConfigLine(scanner.Text())
+		if err != nil {
+			return config, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "MONITOR":
+			directive, err := ParseMonitorDirective(scanner.Text())
+			if err != nil {
+				return config, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			config.Monitors = append(config.Monitors, directive)
+		case "MINSUPPLIES":
+			if len(fields) != 2 {
+				return config, fmt.Errorf("line %d: expected MINSUPPLIES <num>", lineNo)
+			}
+			minSupplies, err := strconv.Atoi(fields[1])
+			if err != nil || minSupplies < 0 {
+				return config, fmt.Errorf("line %d: invalid MINSUPPLIES %q", lineNo, fields[1])
+			}
+			config.MinSupplies = minSupplies
+		}
+	}
+	return config, scanner.Err()
+}
+
+// FleetConfig returns a Fleet configuration with one endpoint per server.
+// MONITOR lines for the same server are merged; they must use the same
+// credentials, since a Monitor holds a single session per server.
+func (c UpsmonConfig) FleetConfig() (FleetConfig, error) {
+	var fleet FleetConfig
+	index := map[string]int{}
+	for _, directive := range c.Monitors {
+		config := directive.MonitorConfig()
+		server := config.server()
+		i, ok := index[server]
+		if !ok {
+			index[server] = len(fleet.Endpoints)
+			fleet.Endpoints = append(fleet.Endpoints, config)
+			continue
+		}
+		endpoint := &fleet.Endpoints[i]
+		if endpoint.Username != config.Username || endpoint.Password != config.Password {
+			return fleet, fmt.Errorf("MONITOR directives for %s use different credentials", server)
+		}
+		endpoint.UPS = append(endpoint.UPS, directive.Device.UPS)
+	}
+	return fleet, nil
+}
+
+// SuppliesAvailable returns the total power value of the monitored UPSes that
+// are not critical, as upsmon computes it. A UPS is critical when it is on
+// battery with a low battery, or has FSD set. UPSes without a snapshot, e.g.
+// never reached, are assumed to be on line power, as upsmon does.
+func (c UpsmonConfig) SuppliesAvailable(snapshots []Snapshot) int {
+	bySystem := make(map[string]Snapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		bySystem[snapshot.UPS+"@"+snapshot.Server] = snapshot
+	}
+
+	available := 0
+	for _, directive := range c.Monitors {
+		config := directive.MonitorConfig()
+		snapshot, ok := bySystem[directive.Device.UPS+"@"+config.server()]
+		if ok && (snapshot.Status.Has(StatusForcedShutdown) ||
+			snapshot.Status.Has(StatusOnBattery|StatusLowBattery)) {
+			continue
+		}
+		available += directive.PowerValue
+	}
+	return available
+}
+
+// ShouldShutdown reports whether fewer than MinSupplies power supplies remain
+// available, the condition on which upsmon shuts the system down.
+func (c UpsmonConfig) ShouldShutdown(snapshots []Snapshot) bool {
+	return c.SuppliesAvailable(snapshots) < c.MinSupplies
+}
+
+// splitConfigLine splits a NUT configuration line into fields, honoring
+// double quotes and backslash escapes and dropping comments.
+func splitConfigLine(line string) ([]string, error) {
+	return splitFields(line, true)
+}
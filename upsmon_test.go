@@ -0,0 +1,43 @@
+package nut_test
+
+import (
+	"strings"
+	"testing"
+
+	nut "github.com/bearx3f/go.nut"
+)
+
+func TestParseMonitorDirective(t *testing.T) {
+	directive, err := nut.ParseMonitorDirective(`MONITOR ups1@nut.example.com:3494 1 monuser "s3cret pass" primary`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if directive.Device.UPS != "ups1" || directive.Device.Host != "nut.example.com" || directive.Device.Port != 3494 ||
+		directive.PowerValue != 1 || directive.Username != "monuser" || directive.Password != "s3cret pass" || !directive.Primary {
+		t.Fatalf("unexpected directive %+v", directive)
+	}
+}
+
+func TestParseMonitorDirectiveErrorsHidePassword(t *testing.T) {
+	for _, line := range []string{
+		`MONITOR ups1@localhost 1 monuser s3cret`,
+		`MONITOR ups1@localhost 1 monuser "s3cret primary`,
+		`MONITOR ups1@localhost 1 monuser s3cret primary extra`,
+	} {
+		_, err := nut.ParseMonitorDirective(line)
+		if err == nil {
+			t.Errorf("%s: expected an error", line)
+			continue
+		}
+		if strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("error leaks the password: %v", err)
+		}
+	}
+}
+
+func TestParseUpsmonConfErrorsHidePassword(t *testing.T) {
+	_, err := nut.ParseUpsmonConf(strings.NewReader("MINSUPPLIES 1\nMONITOR ups1@localhost 1 monuser \"s3cret primary\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") || strings.Contains(err.Error(), "s3cret") {
+		t.Fatalf("err = %v, want a line 2 error without the password", err)
+	}
+}
@@ -0,0 +1,181 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ServerType describes a variable as reported by upsd in a GET TYPE response.
+// A variable may carry several type flags, e.g. "RW ENUM" or "RW STRING:32".
+type ServerType struct {
+	Writable  bool   // RW flag; false for RO or when the server omits the flag
+	String    bool   // STRING:n
+	MaxLength int    // Maximum length of a STRING value
+	Number    bool   // NUMBER
+	Enum      bool   // ENUM; allowed values are listed by LIST ENUM
+	Range     bool   // RANGE; allowed intervals are listed by LIST RANGE
+	Raw       string // Type flags as sent by upsd
+}
+
+// parseServerType parses the flags of a GET TYPE response.
+func parseServerType(flags string) ServerType {
+	st := ServerType{Raw: flags}
+	for _, flag := range strings.Fields(flags) {
+		switch {
+		case flag == "RW":
+			st.Writable = true
+		case flag == "NUMBER":
+			st.Number = true
+		case flag == "ENUM":
+			st.Enum = true
+		case flag == "RANGE":
+			st.Range = true
+		case flag == "STRING" || strings.HasPrefix(flag, "STRING:"):
+			st.String = true
+			if _, length, ok := strings.Cut(flag, ":"); ok {
+				st.MaxLength, _ = strconv.Atoi(length)
+			}
+		}
+	}
+	return st
+}
+
+// legacyType returns the first type flag without RW/RO and STRING length, as
+// reported by GetVariableType.
+func (st ServerType) legacyType() string {
+	for _, flag := range strings.Fields(st.Raw) {
+		if flag != "RW" && flag != "RO" {
+			name, _, _ := strings.Cut(flag, ":")
+			return name
+		}
+	}
+	return "UNKNOWN"
+}
+
+// ValueKind is the Go type a variable value was decoded to.
+type ValueKind int
+
+const (
+	ValueString  ValueKind = iota // string
+	ValueInteger                  // int64
+	ValueFloat                    // float64
+	ValueBoolean                  // bool ("enabled"/"disabled")
+)
+
+// String returns the name used by Variable.Type for the kind.
+func (k ValueKind) String() string {
+	switch k {
+	case ValueInteger:
+		return "INTEGER"
+	case ValueFloat:
+		return "FLOAT_64"
+	case ValueBoolean:
+		return "BOOLEAN"
+	default:
+		return "STRING"
+	}
+}
+
+// WithRawValues disables value coercion in GetVariables: every Value is the
+// exact string sent by upsd, including leading zeros and surrounding spaces,
+// and Kind is always ValueString.
+func WithRawValues() ClientOption {
+	return func(c *Client) {
+		c.rawValues = true
+	}
+}
+
+// decode converts a raw variable value according to the type reported by upsd:
+// NUMBER values become int64 or float64 and STRING and ENUM values stay strings.
+// Without type information, decodeValue's heuristics apply.
+func (st ServerType) decode(value string) (interface{}, ValueKind) {
+	switch {
+	case st.Number:
+		if converted, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return converted, ValueInteger
+		}
+		if converted, err := strconv.ParseFloat(value, 64); err == nil {
+			return converted, ValueFloat
+		}
+		return value, ValueString
+	case st.String, st.Enum:
+		return value, ValueString
+	default:
+		return decodeValue(value)
+	}
+}
+
+// decodeValue converts a raw variable value to a bool, int64 or float64 where
+// it looks like one, and to a string otherwise.
+func decodeValue(value string) (interface{}, ValueKind) {
+	switch value {
+	case "enabled":
+		return true, ValueBoolean
+	case "disabled":
+		return false, ValueBoolean
+	}
+	if !numericRegex.MatchString(value) {
+		return value, ValueString
+	}
+	if strings.Contains(value, ".") {
+		if converted, err := strconv.ParseFloat(value, 64); err == nil {
+			return converted, ValueFloat
+		}
+	} else if converted, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return converted, ValueInteger
+	}
+	return value, ValueString
+}
+
+// GetVariableServerType returns the type of variableName as reported by upsd.
+func (u *UPS) GetVariableServerType(variableName string) (ServerType, error) {
+	return u.getVariableServerType(context.Background(), variableName)
+}
+
+func (u *UPS) getVariableServerType(ctx context.Context, variableName string) (ServerType, error) {
+	cmd := fmt.Sprintf("GET TYPE %s %s", quoteName(u.Name), quoteName(variableName))
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
+	if err != nil {
+		return ServerType{}, err
+	}
+	if len(resp) < 1 {
+		return ServerType{}, fmt.Errorf("empty response from GET TYPE")
+	}
+	flags, err := u.nutClient.trimPrefix(cmd, resp[0], fmt.Sprintf("TYPE %s %s ", u.Name, variableName))
+	if err != nil {
+		return ServerType{}, err
+	}
+	if strings.TrimSpace(flags) == "" {
+		return ServerType{}, fmt.Errorf("invalid TYPE response format: got empty response after parsing")
+	}
+	return parseServerType(flags), nil
+}
+
+// GetVariableEnum returns the values accepted by variableName, as reported by
+// LIST ENUM. An empty slice means the variable is not an enumeration.
+func (u *UPS) GetVariableEnum(variableName string) ([]string, error) {
+	return u.getVariableEnum(context.Background(), variableName)
+}
+
+func (u *UPS) getVariableEnum(ctx context.Context, variableName string) ([]string, error) {
+	values := []string{}
+	cmd := fmt.Sprintf("LIST ENUM %s %s", quoteName(u.Name), quoteName(variableName))
+	resp, err := u.nutClient.SendCommandWithContext(ctx, cmd)
+	if err != nil {
+		return values, err
+	}
+	lines, err := u.nutClient.listBody(cmd, resp, fmt.Sprintf("ENUM %s %s ", u.Name, variableName))
+	if err != nil {
+		return values, err
+	}
+	for _, line := range lines {
+		value, err := u.nutClient.quotedValue(cmd, line)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
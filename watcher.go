@@ -0,0 +1,379 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultWatchInterval is the polling interval of a Watcher unless configured.
+const defaultWatchInterval = 5 * time.Second
+
+// defaultAdaptiveSettle is how long the status must stay calm before adaptive
+// polling returns to the normal interval.
+const defaultAdaptiveSettle = time.Minute
+
+// urgentStatus are the ups.status flags that switch adaptive polling to the
+// fast interval.
+const urgentStatus = StatusOnBattery | StatusLowBattery | StatusAlarm | StatusForcedShutdown
+
+// Watcher polls a UPS at a fixed interval and emits an Event for every change
+// it observes. By default it watches all variables; WatchClients additionally
+// tracks the clients attached to the UPS.
+type Watcher struct {
+	backend  Backend
+	name     string
+	interval time.Duration
+	handler  func(Event)
+	clock    Clock
+
+	fastInterval time.Duration // Interval while urgent; see WithAdaptiveInterval
+	settle       time.Duration
+
+	watchVariables bool
+	variables      map[string]bool // Restricts variable events when non-empty
+	watchClients   bool
+
+	mu         sync.Mutex
+	lastValues map[string]string
+	lastClient map[string]bool
+	lastUrgent time.Time // Last poll that saw an urgent ups.status
+}
+
+// WatcherOption configures a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithWatchInterval sets the polling interval.
+func WithWatchInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.interval = interval
+	}
+}
+
+// WithAdaptiveInterval makes the watcher poll every fast instead of the normal
+// interval while ups.status reports OB, LB, ALARM or FSD, and until it has not
+// for settle (default 1 minute). The normal interval can then be long, sparing
+// upsd while the UPS is stably online.
+func WithAdaptiveInterval(fast, settle time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.fastInterval = fast
+		w.settle = settle
+	}
+}
+
+// WithClock sets the clock timing polls and events (default the system
+// clock), e.g. a fake clock in tests.
+func WithClock(clock Clock) WatcherOption {
+	return func(w *Watcher) {
+		w.clock = clock
+	}
+}
+
+// WithEventHandler sets the function receiving events. It is called from the
+// watcher's goroutine and should not block for long.
+func WithEventHandler(handler func(Event)) WatcherOption {
+	return func(w *Watcher) {
+		w.handler = handler
+	}
+}
+
+// WatchVariables restricts variable change events to the named variables.
+func WatchVariables(names ...string) WatcherOption {
+	return func(w *Watcher) {
+		w.watchVariables = true
+		for _, name := range names {
+			w.variables[name] = true
+		}
+	}
+}
+
+// WatchClients enables polling LIST CLIENT and emitting EventClientConnected and
+// EventClientDisconnected when clients attach to or detach from the UPS.
+func WatchClients() WatcherOption {
+	return func(w *Watcher) {
+		w.watchClients = true
+	}
+}
+
+// WatchClientsOnly polls only LIST CLIENT, without watching variables.
+func WatchClientsOnly() WatcherOption {
+	return func(w *Watcher) {
+		w.watchClients = true
+		w.watchVariables = false
+	}
+}
+
+// NewWatcher returns a Watcher for ups. Call Run to start polling.
+func NewWatcher(ups *UPS, opts ...WatcherOption) *Watcher {
+	return NewBackendWatcher(NewNUTBackend(ups.nutClient), ups.Name, opts...)
+}
+
+// NewBackendWatcher returns a Watcher for the UPS named name provided by
+// backend. Call Run to start polling.
+func NewBackendWatcher(backend Backend, name string, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		backend:        backend,
+		name:           name,
+		interval:       defaultWatchInterval,
+		watchVariables: true,
+		variables:      map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.settle <= 0 {
+		w.settle = defaultAdaptiveSettle
+	}
+	w.clock = clockOrSystem(w.clock)
+	return w
+}
+
+// Run polls the UPS until ctx is done. The first poll establishes the baseline
+// and emits no change events. Polling errors are reported as EventError and do
+// not stop the watcher.
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		start := w.clock.Now()
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.emit(Event{Type: EventError, Err: err})
+		}
+
+		timer := w.clock.NewTimer(w.nextInterval() - w.clock.Now().Sub(start))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// nextInterval returns the delay between the starts of two polls: the fast
+// interval while the status is or recently was urgent, otherwise the normal one.
+func (w *Watcher) nextInterval() time.Duration {
+	if w.fastInterval > 0 && w.urgentWithin(w.settle) {
+		return w.fastInterval
+	}
+	return w.interval
+}
+
+// urgentWithin reports whether a poll within the last settle saw an urgent
+// ups.status.
+func (w *Watcher) urgentWithin(settle time.Duration) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.lastUrgent.IsZero() && w.clock.Now().Sub(w.lastUrgent) < settle
+}
+
+// Poll performs a single poll and emits events for changes since the last one.
+func (w *Watcher) Poll(ctx context.Context) error {
+	if w.watchVariables {
+		if err := w.pollVariables(ctx); err != nil {
+			return err
+		}
+	}
+	if w.watchClients {
+		if err := w.pollClients(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values returns the variable values as of the last poll.
+func (w *Watcher) Values() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	values := make(map[string]string, len(w.lastValues))
+	for name, value := range w.lastValues {
+		values[name] = value
+	}
+	return values
+}
+
+// Clients returns the clients attached to the UPS as of the last poll.
+func (w *Watcher) Clients() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	clients := make([]string, 0, len(w.lastClient))
+	for client := range w.lastClient {
+		clients = append(clients, client)
+	}
+	sort.Strings(clients)
+	return clients
+}
+
+// MissingClients returns the entries of expected that were not attached to the
+// UPS as of the last poll, e.g. to verify all secondaries are connected.
+func (w *Watcher) MissingClients(expected []string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	missing := []string{}
+	for _, client := range expected {
+		if !w.lastClient[client] {
+			missing = append(missing, client)
+		}
+	}
+	return missing
+}
+
+func (w *Watcher) pollVariables(ctx context.Context) error {
+	values, err := w.backend.Variables(ctx, w.name)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	previous := w.lastValues
+	w.lastValues = values
+	if ParseStatus(values["ups.status"])&urgentStatus != 0 {
+		w.lastUrgent = w.clock.Now()
+	}
+	w.mu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if len(w.variables) == 0 || w.variables[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if old, ok := previous[name]; !ok || old != values[name] {
+			w.emit(Event{Type: EventVariableChanged, Variable: name, OldValue: old, NewValue: values[name]})
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) pollClients(ctx context.Context) error {
+	clients, err := w.backend.Clients(ctx, w.name)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(clients))
+	for _, client := range clients {
+		current[client] = true
+	}
+
+	w.mu.Lock()
+	previous := w.lastClient
+	w.lastClient = current
+	w.mu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+
+	for _, client := range clients {
+		if !previous[client] {
+			w.emit(Event{Type: EventClientConnected, Client: client})
+		}
+	}
+	gone := []string{}
+	for client := range previous {
+		if !current[client] {
+			gone = append(gone, client)
+		}
+	}
+	sort.Strings(gone)
+	for _, client := range gone {
+		w.emit(Event{Type: EventClientDisconnected, Client: client})
+	}
+	return nil
+}
+
+func (w *Watcher) emit(event Event) {
+	if w.handler == nil {
+		return
+	}
+	event.Time = w.clock.Now()
+	event.UPS = w.name
+	w.handler(event)
+}
+
+// VariableUpdate is delivered by WatchVariable for every observed value of a
+// variable, or for a polling error.
+type VariableUpdate struct {
+	Time     time.Time
+	Variable string
+	OldValue string // Previous value, empty for the first update
+	NewValue string
+	Err      error // Polling error; OldValue and NewValue are unset
+}
+
+// WatchVariable polls variableName every interval and sends an update with its
+// initial value and then every time it changes. Polling errors are sent on the
+// channel and do not stop the watch. The channel is closed once ctx is done.
+// An error is returned if the initial value cannot be read. Of the options,
+// only WithClock applies; any other option is rejected with an error.
+func (u *UPS) WatchVariable(ctx context.Context, variableName string, interval time.Duration, opts ...WatcherOption) (<-chan VariableUpdate, error) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	options := Watcher{interval: interval, watchVariables: true, variables: map[string]bool{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.interval != interval || options.fastInterval != 0 || options.settle != 0 ||
+		options.handler != nil || !options.watchVariables || len(options.variables) != 0 || options.watchClients {
+		return nil, fmt.Errorf("watching %s: only the WithClock option applies", variableName)
+	}
+	clock := clockOrSystem(options.clock)
+	value, err := u.getVariableValue(ctx, variableName)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan VariableUpdate, 1)
+	updates <- VariableUpdate{Time: clock.Now(), Variable: variableName, NewValue: value}
+
+	go func() {
+		defer close(updates)
+
+		ticker := clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		last := value
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+
+			update := VariableUpdate{Variable: variableName}
+			current, err := u.getVariableValue(ctx, variableName)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				update.Err = err
+			case current == last:
+				continue
+			default:
+				update.OldValue, update.NewValue = last, current
+				last = current
+			}
+			update.Time = clock.Now()
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
@@ -0,0 +1,161 @@
+package nut
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 of the request body as
+// "sha256=<hex>" when WebhookConfig.Secret is set.
+const WebhookSignatureHeader = "X-NUT-Signature"
+
+// WebhookConfig configures a WebhookNotifier.
+type WebhookConfig struct {
+	URLs       []string          // Endpoints receiving every event
+	Secret     []byte            // Optional HMAC-SHA256 key for WebhookSignatureHeader
+	Headers    map[string]string // Additional request headers, e.g. Authorization
+	Timeout    time.Duration     // Timeout per attempt (default 10s)
+	Retries    int               // Retries after a failed attempt (default 0)
+	RetryDelay time.Duration     // Delay before the first retry, doubled for each further one (default 1s)
+	HTTPClient *http.Client      // Client used for requests (default http.DefaultClient)
+
+	// Body, if set, is a MessageTemplate rendering the request body instead of
+	// the JSON encoded event, e.g. to match a chat service's payload format.
+	// Use json to insert values, so quotes and newlines in them are escaped:
+	//
+	//	{"text": {{json (printf "%s on %s" .Type .UPS)}}, "charge": {{json (var .Snapshot "battery.charge")}}}
+	//
+	// ContentType defaults to application/json.
+	Body        string
+	ContentType string
+	Snapshots   SnapshotSource // Optional UPS state for the Body template
+}
+
+// WebhookNotifier is a Notifier that POSTs every event as a JSON object to one
+// or more URLs.
+type WebhookNotifier struct {
+	config WebhookConfig
+	body   *MessageTemplate
+}
+
+// NewWebhookNotifier validates config and returns a WebhookNotifier.
+func NewWebhookNotifier(config WebhookConfig) (*WebhookNotifier, error) {
+	if len(config.URLs) == 0 {
+		return nil, fmt.Errorf("at least one webhook URL is required")
+	}
+	if config.Retries < 0 {
+		return nil, fmt.Errorf("invalid webhook retries %d", config.Retries)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = time.Second
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.ContentType == "" {
+		config.ContentType = "application/json"
+	}
+
+	n := &WebhookNotifier{config: config}
+	if config.Body != "" {
+		body, err := NewMessageTemplate(config.Body, config.Snapshots)
+		if err != nil {
+			return nil, fmt.Errorf("parsing body template: %w", err)
+		}
+		n.body = body
+	}
+	return n, nil
+}
+
+// Notify posts event to every configured URL, retrying failed deliveries. It
+// returns the errors of the URLs that could not be reached.
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := n.render(event)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, url := range n.config.URLs {
+		if err := n.deliver(ctx, url, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// render returns the request body for event.
+func (n *WebhookNotifier) render(event Event) ([]byte, error) {
+	if n.body == nil {
+		return json.Marshal(event)
+	}
+	body, err := n.body.Render(event)
+	if err != nil {
+		return nil, fmt.Errorf("rendering body: %w", err)
+	}
+	return []byte(body), nil
+}
+
+// deliver posts body to url, retrying on network errors, 429 and 5xx responses.
+func (n *WebhookNotifier) deliver(ctx context.Context, url string, body []byte) error {
+	delay := n.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retryable, err := n.post(ctx, url, body)
+		if err == nil || !retryable || attempt >= n.config.Retries {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, url string, body []byte) (retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", n.config.ContentType)
+	for name, value := range n.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(n.config.Secret) > 0 {
+		mac := hmac.New(sha256.New, n.config.Secret)
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.config.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status %s", resp.Status)
+}
@@ -0,0 +1,44 @@
+package nut
+
+// Windows Event Log severities, as the wType argument of ReportEvent.
+const (
+	winEventError       uint16 = 0x0001
+	winEventWarning     uint16 = 0x0002
+	winEventInformation uint16 = 0x0004
+)
+
+// winEventIDs are the event IDs written by WindowsEventLogNotifier, keyed by
+// upsmon NOTIFYTYPE or, for other events, by event type in upper case. They
+// stay within 1-1000 so that a source registered with EventCreate.exe as its
+// message file displays the text without a custom message DLL.
+var winEventIDs = map[string]struct {
+	id       uint32
+	severity uint16
+}{
+	"ONLINE":              {100, winEventInformation},
+	"ONBATT":              {101, winEventWarning},
+	"LOWBATT":             {102, winEventError},
+	"FSD":                 {103, winEventError},
+	"REPLBATT":            {104, winEventWarning},
+	"COMMOK":              {110, winEventInformation},
+	"COMMBAD":             {111, winEventError},
+	"VARIABLE_CHANGED":    {120, winEventInformation},
+	"CLIENT_CONNECTED":    {121, winEventInformation},
+	"CLIENT_DISCONNECTED": {122, winEventInformation},
+	"ERROR":               {130, winEventWarning},
+	"ALERT_RAISED":        {140, winEventWarning},
+	"ALERT_CLEARED":       {141, winEventInformation},
+	"SELFTEST_PASSED":     {150, winEventInformation},
+	"SELFTEST_FAILED":     {151, winEventError},
+	"":                    {199, winEventInformation}, // Any other event
+}
+
+// winEventRecord returns the event ID, severity and message for event.
+func winEventRecord(event Event) (id uint32, severity uint16, message string) {
+	notifyType, message := upsmonNotification(event)
+	entry, ok := winEventIDs[notifyType]
+	if !ok {
+		entry = winEventIDs[""]
+	}
+	return entry.id, entry.severity, message
+}
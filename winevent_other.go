@@ -0,0 +1,29 @@
+//go:build !windows
+
+package nut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WindowsEventLogNotifier is a Notifier writing events to the Windows
+// Application event log. It is only available on Windows.
+type WindowsEventLogNotifier struct{}
+
+// NewWindowsEventLogNotifier returns an error wrapping errors.ErrUnsupported
+// on platforms other than Windows.
+func NewWindowsEventLogNotifier(source string) (*WindowsEventLogNotifier, error) {
+	return nil, fmt.Errorf("windows event log: %w", errors.ErrUnsupported)
+}
+
+// Notify returns an error wrapping errors.ErrUnsupported.
+func (n *WindowsEventLogNotifier) Notify(ctx context.Context, event Event) error {
+	return fmt.Errorf("windows event log: %w", errors.ErrUnsupported)
+}
+
+// Close does nothing.
+func (n *WindowsEventLogNotifier) Close() error {
+	return nil
+}
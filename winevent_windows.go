@@ -0,0 +1,82 @@
+package nut
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSourceW  = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+	procReportEventW          = advapi32.NewProc("ReportEventW")
+)
+
+// WindowsEventLogNotifier is a Notifier writing events to the Windows
+// Application event log. Power failures, low battery, forced shutdowns, lost
+// communication and failed self-tests are logged as errors, other changes to
+// the power state and raised alerts as warnings, and the rest as information.
+//
+// The source should be registered beforehand, e.g. with
+//
+//	eventcreate /ID 1 /L APPLICATION /T INFORMATION /SO <source> /D "registered"
+//
+// or New-EventLog in PowerShell; otherwise Event Viewer shows the message
+// with a note that the description could not be found.
+type WindowsEventLogNotifier struct {
+	mu     sync.Mutex
+	handle uintptr
+}
+
+// NewWindowsEventLogNotifier opens the event log for source.
+func NewWindowsEventLogNotifier(source string) (*WindowsEventLogNotifier, error) {
+	name, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return nil, err
+	}
+	handle, _, err := procRegisterEventSourceW.Call(0, uintptr(unsafe.Pointer(name)))
+	if handle == 0 {
+		return nil, fmt.Errorf("registering event source %q: %w", source, err)
+	}
+	return &WindowsEventLogNotifier{handle: handle}, nil
+}
+
+// Notify writes event to the event log.
+func (n *WindowsEventLogNotifier) Notify(ctx context.Context, event Event) error {
+	id, severity, message := winEventRecord(event)
+	text, err := syscall.UTF16PtrFromString(message)
+	if err != nil {
+		return err
+	}
+	lines := []*uint16{text}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.handle == 0 {
+		return ErrClosed
+	}
+	ok, _, err := procReportEventW.Call(n.handle, uintptr(severity), 0, uintptr(id), 0,
+		uintptr(len(lines)), 0, uintptr(unsafe.Pointer(&lines[0])), 0)
+	if ok == 0 {
+		return fmt.Errorf("reporting event: %w", err)
+	}
+	return nil
+}
+
+// Close closes the event log.
+func (n *WindowsEventLogNotifier) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.handle == 0 {
+		return nil
+	}
+	ok, _, err := procDeregisterEventSource.Call(n.handle)
+	n.handle = 0
+	if ok == 0 {
+		return err
+	}
+	return nil
+}